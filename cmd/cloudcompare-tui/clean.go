@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cloudcompare-automation/internal/cleanup"
+	"github.com/cloudcompare-automation/internal/humanize"
+)
+
+// runClean removes old outputs, reports, history and workspace
+// directories, as the policy flags select
+func runClean(args []string) int {
+	fs := flag.NewFlagSet("clean", flag.ContinueOnError)
+	days := fs.Int("older-than", 0, "only runs that finished, and directories last written, more than this many days ago")
+	failedOnly := fs.Bool("failed-only", false, "only the outputs of failed files, runs where every file failed, and the workspace")
+	workspacesOnly := fs.Bool("workspaces-only", false, "only the workspace, stage caches and ended sessions, keeping outputs, reports and history")
+	dryRun := fs.Bool("dry-run", false, "list what would be removed without removing it")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: cloudcompare-tui clean [--older-than DAYS] [--failed-only] [--workspaces-only] [--dry-run]\n\n")
+		fmt.Fprintf(fs.Output(), "Remove the outputs and reports of the runs in the history, their history, the\n")
+		fmt.Fprintf(fs.Output(), "workspace and the journals of ended background sessions. At least one of\n")
+		fmt.Fprintf(fs.Output(), "--older-than, --failed-only and --workspaces-only must be given.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 || *days < 0 || (*days == 0 && !*failedOnly && !*workspacesOnly) {
+		fs.Usage()
+		return 2
+	}
+
+	policy := cleanup.Policy{
+		OlderThan:      time.Duration(*days) * 24 * time.Hour,
+		FailedOnly:     *failedOnly,
+		WorkspacesOnly: *workspacesOnly,
+	}
+	items, skipped, err := cleanup.Plan(policy, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	for _, s := range skipped {
+		fmt.Printf("[INFO] Leaving %s: %s\n", s.Path, s.Reason)
+	}
+	if len(items) == 0 {
+		fmt.Println("Nothing to clean up")
+		return 0
+	}
+
+	if *dryRun {
+		var total int64
+		for _, item := range items {
+			total += item.Size
+			fmt.Printf("%-9s %10s  %s\n", item.Kind, humanize.Bytes(uint64(item.Size)), item.Path)
+		}
+		fmt.Printf("[INFO] Would remove %d item(s), %s\n", len(items), humanize.Bytes(uint64(total)))
+		return 0
+	}
+
+	failed := 0
+	freed := cleanup.Remove(items, func(item cleanup.Item, err error) {
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			return
+		}
+		fmt.Printf("Removed %s %s\n", item.Kind, item.Path)
+	})
+	fmt.Printf("[INFO] Removed %d item(s), freed %s\n", len(items)-failed, humanize.Bytes(uint64(freed)))
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudcompare-automation/internal/history"
+	"github.com/cloudcompare-automation/internal/processor"
+	"github.com/cloudcompare-automation/internal/report"
+)
+
+// abSides names the sides of an A/B comparison, in the order they run
+var abSides = []string{"A", "B"}
+
+// runCompare processes the LAS files of a directory twice, with two sets
+// of parameters into separate output folders, and writes a report setting
+// the runs side by side
+func runCompare(args []string) int {
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	params := defaultParams()
+
+	pipeline, err := selectPipeline(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 2
+	}
+	addParamFlags(fs, &params, pipeline.Params)
+	project, err := applyDirSettings(args, &params, pipeline.Params)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	fs.String("pipeline", "", "pipeline to run, by name or script file name (default: "+processor.DefaultPipeline+")")
+
+	overrides := map[string]map[string]string{"A": {}, "B": {}}
+	for _, side := range abSides {
+		side := side
+		fs.Func(strings.ToLower(side), fmt.Sprintf("parameter of run %s as NAME=VALUE, e.g. octree-depth=11 (repeatable)", side), func(value string) error {
+			name, val, ok := strings.Cut(value, "=")
+			name = strings.TrimPrefix(name, "--")
+			if !ok || name == "" {
+				return fmt.Errorf("expected NAME=VALUE, got %q", value)
+			}
+			for _, spec := range pipeline.Params {
+				if spec.Name == name {
+					v, err := spec.Validate(val)
+					if err != nil {
+						return fmt.Errorf("%s: %v", name, err)
+					}
+					overrides[side][name] = v
+					return nil
+				}
+			}
+			return fmt.Errorf("unknown pipeline parameter: %s", name)
+		})
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: cloudcompare-tui compare [flags] --a NAME=VALUE --b NAME=VALUE DIR\n\n")
+		fmt.Fprintf(fs.Output(), "Process the LAS files of a directory with two sets of parameters, into\n")
+		fmt.Fprintf(fs.Output(), "A and B folders of the output directory, and compare the runs.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	dir, ok := inputDirArg(fs)
+	if !ok {
+		fs.Usage()
+		return 2
+	}
+	params.InputDir = dir
+	if project != "" {
+		fmt.Printf("[INFO] Project config: %s\n", project)
+	}
+	if pipeline.Name != processor.DefaultPipeline {
+		params.Script = pipeline.Script
+		fmt.Printf("[INFO] Pipeline: %s\n", pipeline.Name)
+	}
+
+	runs := make(map[string]processor.Params, len(abSides))
+	for _, side := range abSides {
+		p := params
+		p.Values = make(map[string]string, len(params.Values))
+		for name, value := range params.Values {
+			p.Values[name] = value
+		}
+		for name, value := range overrides[side] {
+			p.Values[name] = value
+		}
+		p.OutputSubdir = filepath.Join(params.OutputSubdir, side)
+		p.Labels = history.ParseLabels(strings.Join(append(append([]string(nil), params.Labels...), "compare-"+strings.ToLower(side)), ","))
+		runs[side] = p
+	}
+	differences := changedParams(runs["A"].Values, runs["B"].Values)
+	if len(differences) == 0 {
+		fmt.Fprintf(os.Stderr, "[ERROR] Runs A and B have the same parameters; set what differs with --a and --b\n")
+		return 2
+	}
+	fmt.Printf("[INFO] Comparing %s\n", strings.Join(differences, ", "))
+
+	ctx := signalContext()
+	reports := make(map[string]processor.ProcessingResult, len(abSides))
+	for _, side := range abSides {
+		fmt.Printf("[INFO] Run %s into %s\n", side, runs[side].OutputSubdir)
+		result, err := runHeadless(ctx, runs[side])
+		if err != nil {
+			return 1
+		}
+		fmt.Printf("[INFO] Run %s processed %d file(s): %d succeeded, %d with warnings, %d failed\n",
+			side, len(result.Files), result.SuccessCount-result.WarningCount, result.WarningCount, result.FailedCount)
+		if result.Stopped {
+			fmt.Fprintf(os.Stderr, "[ERROR] Run %s was stopped; nothing to compare\n", side)
+			return 1
+		}
+		if result.ReportPath == "" {
+			fmt.Fprintf(os.Stderr, "[ERROR] Run %s wrote no report to compare\n", side)
+			return 1
+		}
+		reports[side] = result
+	}
+
+	c, err := report.Compare(reports["A"].ReportPath, reports["B"].ReportPath)
+	if err == nil {
+		var path string
+		path, err = report.WriteComparison(c, commonDir(reports["A"].OutputDir, reports["B"].OutputDir))
+		if err == nil {
+			fmt.Printf("[SUCCESS] Comparison: %s\n", path)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	if reports["A"].FailedCount > 0 || reports["B"].FailedCount > 0 {
+		return 1
+	}
+	return 0
+}
+
+// changedParams lists the parameters a and b, holding the same names, set
+// differently, e.g. "octree-depth: 11 → 12"
+func changedParams(a, b map[string]string) []string {
+	var changed []string
+	for _, name := range sortedKeys(a) {
+		if a[name] != b[name] {
+			changed = append(changed, fmt.Sprintf("%s: %s → %s", name, a[name], b[name]))
+		}
+	}
+	return changed
+}
+
+// commonDir returns the deepest directory holding both a and b, where the
+// comparison of their runs goes
+func commonDir(a, b string) string {
+	a, b = filepath.Clean(a), filepath.Clean(b)
+	for {
+		rel, err := filepath.Rel(a, b)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return a
+		}
+		parent := filepath.Dir(a)
+		if parent == a {
+			return a
+		}
+		a = parent
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cloudcompare-automation/internal/config"
+	"github.com/cloudcompare-automation/internal/processor"
+)
+
+// runConfig exports the configuration and pipeline scripts as a bundle,
+// or imports one on another workstation
+func runConfig(args []string) int {
+	if len(args) != 2 || (args[0] != "export" && args[0] != "import") {
+		fmt.Fprintf(os.Stderr, "Usage: cloudcompare-tui config <export|import> FILE\n")
+		return 2
+	}
+
+	if args[0] == "export" {
+		// Discovered *_pipeline.py scripts are part of the setup too; the
+		// default pipeline ships with every installation
+		pipelines, err := processor.DiscoverPipelines()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[WARNING] %v\n", err)
+		}
+		if len(pipelines) > 0 {
+			pipelines = pipelines[1:]
+		}
+		if err := config.ExportBundle(args[1], pipelines); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			return 1
+		}
+		fmt.Printf("[SUCCESS] Saved: %s\n", args[1])
+		return 0
+	}
+
+	manifest, installed, err := config.ImportBundle(args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	if !manifest.CreatedAt.IsZero() {
+		fmt.Printf("[INFO] Bundle from %s, created %s\n", manifest.Host, manifest.CreatedAt.Format("2006-01-02 15:04"))
+	}
+	for _, path := range installed {
+		fmt.Printf("[SUCCESS] Installed: %s\n", path)
+	}
+	fmt.Printf("[INFO] Any previous config was kept as %s.bak\n", config.FileName)
+	return 0
+}
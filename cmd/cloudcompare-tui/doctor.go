@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/cloudcompare-automation/internal/processor"
+)
+
+// runDoctor checks that this machine can process, printing a line per
+// check, and fails if any check does
+func runDoctor(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: cloudcompare-tui doctor\n\n")
+		fmt.Fprintf(fs.Output(), "Check the pipeline script, Python environment and CloudComPy installation.\n")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	fmt.Printf("cloudcompare-tui %s (%s/%s)\n", version, runtime.GOOS, runtime.GOARCH)
+
+	// Importing CloudComPy can take a while on a cold disk
+	ctx, cancel := context.WithTimeout(signalContext(), 2*time.Minute)
+	defer cancel()
+
+	failed := 0
+	for _, check := range processor.Doctor(ctx, defaultParams()) {
+		fmt.Printf("[%s] %s: %s\n", check.Level, check.Name, check.Detail)
+		if check.Level == processor.LogError {
+			failed++
+		}
+	}
+	if failed > 0 {
+		fmt.Printf("[ERROR] %d check(s) failed\n", failed)
+		return 1
+	}
+	return 0
+}
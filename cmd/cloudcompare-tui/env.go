@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cloudcompare-automation/internal/config"
+	"github.com/cloudcompare-automation/internal/processor"
+)
+
+// Besides the settings of the config file, the environment can set what
+// run, validate and worker are usually given on the command line: the
+// directory, the pipeline and its parameter values
+var (
+	inputDirEnv = config.EnvPrefix + "INPUT_DIR"
+	pipelineEnv = config.EnvPrefix + "PIPELINE"
+	paramEnv    = config.EnvPrefix + "PARAM_" // And the parameter name, e.g. CCAUTO_PARAM_OCTREE_DEPTH
+)
+
+// paramEnvName returns the variable setting a pipeline parameter
+func paramEnvName(param string) string {
+	return paramEnv + strings.ToUpper(strings.ReplaceAll(param, "-", "_"))
+}
+
+// applyEnvParams sets the pipeline parameter values that have a
+// CCAUTO_PARAM_* variable
+func applyEnvParams(params *processor.Params, schema []processor.ParamSpec) error {
+	for _, spec := range schema {
+		name := paramEnvName(spec.Name)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		v, err := spec.Validate(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %v", name, err)
+		}
+		params.Values[spec.Name] = v
+	}
+	return nil
+}
+
+// commandDir returns the directory run, validate and worker will process,
+// before their flags are parsed: the last argument when it is a
+// directory, else CCAUTO_INPUT_DIR
+func commandDir(args []string) string {
+	if len(args) > 0 {
+		if info, err := os.Stat(args[len(args)-1]); err == nil && info.IsDir() {
+			return args[len(args)-1]
+		}
+	}
+	return os.Getenv(inputDirEnv)
+}
+
+// inputDirArg returns the DIR argument after the flags are parsed, or
+// CCAUTO_INPUT_DIR without one
+func inputDirArg(fs *flag.FlagSet) (string, bool) {
+	switch {
+	case fs.NArg() == 1:
+		return fs.Arg(0), true
+	case fs.NArg() == 0 && os.Getenv(inputDirEnv) != "":
+		return os.Getenv(inputDirEnv), true
+	}
+	return "", false
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudcompare-automation/internal/history"
+	"github.com/cloudcompare-automation/internal/humanize"
+	"github.com/cloudcompare-automation/internal/processor"
+)
+
+// runHistory dispatches the run history subcommands, the command-line
+// counterpart of the TUI's history screen
+func runHistory(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: cloudcompare-tui history <list|show|rerun> ...\n")
+		return 2
+	}
+
+	switch args[0] {
+	case "list":
+		return runHistoryList(args[1:])
+	case "show":
+		return runHistoryShow(args[1:])
+	case "rerun":
+		return runHistoryRerun(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "[ERROR] Unknown history command: %s\n", args[0])
+		return 2
+	}
+}
+
+// runHistoryList lists the recorded runs matching the filters, newest
+// first
+func runHistoryList(args []string) int {
+	fs := flag.NewFlagSet("history list", flag.ContinueOnError)
+	since := fs.String("since", "", "only runs started on or after this day (YYYY-MM-DD)")
+	until := fs.String("until", "", "only runs started on or before this day (YYYY-MM-DD)")
+	label := fs.String("label", "", "only runs with a label containing this text")
+	dir := fs.String("dir", "", "only runs whose input path contains this text")
+	outcome := fs.String("outcome", "", "only runs with this outcome: "+strings.Join(history.Outcomes, ", "))
+	asJSON := fs.Bool("json", false, "print the runs as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fmt.Fprintf(os.Stderr, "Usage: cloudcompare-tui history list [--since DAY] [--until DAY] [--label TEXT] [--dir TEXT] [--outcome OUTCOME] [--json]\n")
+		return 2
+	}
+
+	filter := history.Filter{Label: *label, Dir: *dir, Outcome: *outcome}
+	var err error
+	if filter.Since, err = parseDay(*since); err == nil {
+		filter.Until, err = parseDay(*until)
+	}
+	if err == nil && *outcome != "" {
+		err = history.CheckOutcome(*outcome)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 2
+	}
+	if !filter.Until.IsZero() {
+		// Through the end of the day
+		filter.Until = filter.Until.AddDate(0, 0, 1)
+	}
+
+	runs, err := history.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	matching := []history.Entry{}
+	for _, run := range runs {
+		if filter.Match(run) {
+			matching = append(matching, run)
+		}
+	}
+
+	if *asJSON {
+		return printJSON(matching)
+	}
+	if len(matching) == 0 {
+		fmt.Println("No matching runs")
+		return 0
+	}
+	for _, run := range matching {
+		line := fmt.Sprintf("%s  %-8s %3d/%-3d  %s", run.ID, run.Outcome(), run.Succeeded+run.Warned, run.Total, run.Input)
+		if len(run.Labels) > 0 {
+			line += "  [" + strings.Join(run.Labels, ", ") + "]"
+		}
+		fmt.Println(line)
+	}
+	return 0
+}
+
+// runHistoryShow prints everything recorded about a run
+func runHistoryShow(args []string) int {
+	fs := flag.NewFlagSet("history show", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the run as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: cloudcompare-tui history show [--json] ID\n")
+		return 2
+	}
+	run, err := findRun(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	if *asJSON {
+		return printJSON(run)
+	}
+
+	fmt.Printf("Run:      %s\n", run.ID)
+	fmt.Printf("Pipeline: %s\n", run.Pipeline)
+	fmt.Printf("Input:    %s\n", run.Input)
+	fmt.Printf("Output:   %s\n", run.OutputDir)
+	fmt.Printf("Started:  %s\n", run.StartedAt.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("Finished: %s (%s)\n", run.FinishedAt.Local().Format("2006-01-02 15:04:05"), humanize.Duration(run.FinishedAt.Sub(run.StartedAt)))
+	files := fmt.Sprintf("%d: %d succeeded, %d with warnings, %d failed", run.Total, run.Succeeded, run.Warned, run.Failed)
+	if run.Stopped {
+		files += ", stopped"
+	}
+	if run.Aborted != "" {
+		files += ", aborted: " + run.Aborted
+	}
+	fmt.Printf("Files:    %s\n", files)
+	if run.Points > 0 {
+		fmt.Printf("Points:   %s\n", humanize.Count(run.Points))
+	}
+	if run.CPUSeconds > 0 {
+		fmt.Printf("CPU time: %s\n", humanize.Duration(time.Duration(run.CPUSeconds*float64(time.Second))))
+	}
+	if run.Estimate != nil {
+		fmt.Printf("Estimate: %s\n", processor.FormatEstimate(run.Estimate))
+	}
+	if len(run.Labels) > 0 {
+		fmt.Printf("Labels:   %s\n", strings.Join(run.Labels, ", "))
+	}
+	if run.Delivered != nil {
+		fmt.Printf("Delivery: %s\n", run.Delivered)
+	}
+	if len(run.Params) > 0 {
+		fmt.Println("Params:")
+	}
+	for _, name := range sortedKeys(run.Params) {
+		fmt.Printf("  %s = %s\n", name, run.Params[name])
+	}
+	if len(run.Reviews) > 0 {
+		fmt.Println("Reviews:")
+	}
+	inputs := make([]string, 0, len(run.Reviews))
+	for input := range run.Reviews {
+		inputs = append(inputs, input)
+	}
+	sort.Strings(inputs)
+	for _, input := range inputs {
+		review := run.Reviews[input]
+		line := "  " + filepath.Base(input) + ":"
+		if review.Reviewed {
+			line += " reviewed"
+			if review.ReviewedBy != "" {
+				line += " by " + review.ReviewedBy
+			}
+			if review.ReviewedAt != nil {
+				line += " on " + review.ReviewedAt.Local().Format("2006-01-02 15:04")
+			}
+			line += "."
+		}
+		if review.Note != "" {
+			line += " " + review.Note
+		}
+		fmt.Println(line)
+	}
+	if run.Report != "" {
+		fmt.Printf("Report:   %s\n", run.Report)
+	}
+	return 0
+}
+
+// runHistoryRerun processes a recorded run's input again with its
+// settings, headless like run
+func runHistoryRerun(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: cloudcompare-tui history rerun ID\n")
+		return 2
+	}
+	run, err := findRun(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	params, err := processor.RerunParams(defaultParams(), loadPipelines(), run)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("[INFO] Repeating the run of %s on %s\n", run.StartedAt.Local().Format("2006-01-02 15:04"), run.Input)
+	result, err := runHeadless(signalContext(), params)
+	if err != nil {
+		return 1
+	}
+	fmt.Printf("[INFO] Processed %d file(s): %d succeeded, %d with warnings, %d failed\n",
+		len(result.Files), result.SuccessCount-result.WarningCount, result.WarningCount, result.FailedCount)
+	if result.Stopped || result.FailedCount > 0 {
+		return 1
+	}
+	return 0
+}
+
+// findRun looks a run up in the history by its ID or the start of it
+func findRun(id string) (history.Entry, error) {
+	runs, err := history.List()
+	if err != nil {
+		return history.Entry{}, err
+	}
+	return history.Find(runs, id)
+}
+
+// parseDay parses a YYYY-MM-DD day in local time; empty gives the zero time
+func parseDay(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	day, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid day %q, expected YYYY-MM-DD", s)
+	}
+	return day, nil
+}
+
+// printJSON prints v as indented JSON
+func printJSON(v any) int {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	fmt.Println(string(data))
+	return 0
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
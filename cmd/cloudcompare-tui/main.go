@@ -1,28 +1,212 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 
+	"github.com/cloudcompare-automation/internal/config"
+	"github.com/cloudcompare-automation/internal/descriptor"
+	"github.com/cloudcompare-automation/internal/profile"
 	"github.com/cloudcompare-automation/internal/tui"
 )
 
+// version is set at build time via -ldflags "-X main.version=..."
+var version = "dev"
+
 func main() {
+	// A leading --profile applies to the TUI and every subcommand
+	args, err := takeProfile(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		os.Exit(2)
+	}
+	chosen := profile.Active() != ""
+
+	// Subcommands run headless; no arguments starts the TUI
+	if len(args) > 0 {
+		switch args[0] {
+		case "version":
+			os.Exit(runVersion(args[1:]))
+		case "update":
+			os.Exit(runUpdate(args[1:]))
+		case "run":
+			os.Exit(runRun(args[1:]))
+		case "compare":
+			os.Exit(runCompare(args[1:]))
+		case "worker":
+			os.Exit(runWorker(args[1:]))
+		case "queue":
+			os.Exit(runQueue(args[1:]))
+		case "session":
+			os.Exit(runSession(args[1:]))
+		case "config":
+			os.Exit(runConfig(args[1:]))
+		case "doctor":
+			os.Exit(runDoctor(args[1:]))
+		case "validate":
+			os.Exit(runValidate(args[1:]))
+		case "report":
+			os.Exit(runReport(args[1:]))
+		case "history":
+			os.Exit(runHistory(args[1:]))
+		case "clean":
+			os.Exit(runClean(args[1:]))
+		case "profile":
+			os.Exit(runProfile(args[1:]))
+		case "watch":
+			os.Exit(runWatch(args[1:]))
+		}
+	}
+
+	fs := flag.NewFlagSet("cloudcompare-tui", flag.ExitOnError)
+	inline := fs.Bool("inline", false, "render inline without the alternate screen, keeping output in scrollback (tmux/SSH friendly)")
+	from := fs.String("from", "", "open with the settings and files of a run descriptor, to repeat that run")
+	fresh := fs.Bool("fresh", false, "start with the settings of the config file instead of the form as it was left")
+	accessible := fs.Bool("accessible", os.Getenv("ACCESSIBLE") != "", "plain inline output for screen readers: no colors, animations or decorative glyphs, and a line for each change of state (default when ACCESSIBLE is set)")
+	name := fs.String("profile", "", "use the form, last run, history and settings of this profile instead of the shared ones (or set "+profile.EnvVar+")")
+	fs.Parse(args)
+	if *name != "" {
+		chosen = true
+		if err := profile.Set(*name); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			os.Exit(2)
+		}
+	}
+	// On a machine with profiles, ask whose state to start with
+	if !chosen && isTerminal(os.Stdin) {
+		if names, _ := profile.List(); len(names) > 0 {
+			palette, _ := loadDisplay()
+			if *accessible {
+				lipgloss.SetColorProfile(termenv.Ascii)
+			}
+			picked, ok, err := tui.PickProfile(names, palette)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+				os.Exit(1)
+			}
+			if !ok {
+				return
+			}
+			profile.Set(picked)
+		}
+	}
+	palette, corporate := loadDisplay()
+	if *accessible {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+
+	var imported *descriptor.Descriptor
+	if *from != "" {
+		d, err := descriptor.Load(*from)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			os.Exit(1)
+		}
+		imported = &d
+	}
+
 	// Create the TUI model
-	model := tui.New()
+	model := tui.New(tui.Options{
+		Inline:     *inline,
+		Params:     defaultParams(),
+		Pipelines:  loadPipelines(),
+		Version:    version,
+		Descriptor: imported,
+		Palette:    palette,
+		Accessible: *accessible,
+		Corporate:  corporate,
 
+		RestoreForm:       !*fresh,
+		DeliveryChecklist: deliveryChecklist(),
+	})
+	os.Exit(runTUI(model, *inline || *accessible))
+}
+
+// runTUI runs the TUI with model until it quits, returning the exit code
+func runTUI(model tui.Model, inline bool) int {
 	// Create the Bubble Tea program with options
-	p := tea.NewProgram(
-		model,
-		tea.WithAltScreen(),       // Use alternate screen buffer
-		tea.WithMouseCellMotion(), // Enable mouse support
-	)
-
-	// Run the program
-	if _, err := p.Run(); err != nil {
+	var opts []tea.ProgramOption
+	if !inline {
+		opts = append(opts,
+			tea.WithAltScreen(),       // Use alternate screen buffer
+			tea.WithMouseCellMotion(), // Enable mouse support
+		)
+	}
+	p := tea.NewProgram(model, opts...)
+
+	// Bubble Tea quits on SIGINT/SIGTERM; also quit cleanly when the
+	// terminal closes instead of dying mid-batch
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		<-hangup
+		p.Quit()
+	}()
+
+	// Run the program, with the terminal reporting focus changes so the
+	// animations pause while it's in the background
+	fmt.Print(tui.FocusReportsOn)
+	final, err := p.Run()
+	fmt.Print(tui.FocusReportsOff)
+	if m, ok := final.(tui.Model); ok {
+		m.Shutdown()
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error running TUI: %v\n", err)
-		os.Exit(1)
+		return 1
+	}
+	return 0
+}
+
+// deliveryChecklist reports whether the config file asks for the
+// delivery checklist after every batch
+func deliveryChecklist() bool {
+	cfg, err := config.Load()
+	return err == nil && cfg.DeliveryChecklist
+}
+
+// colorProfiles are the color profiles the colors setting can force
+var colorProfiles = map[string]termenv.Profile{
+	"truecolor": termenv.TrueColor,
+	"256":       termenv.ANSI256,
+	"16":        termenv.ANSI,
+	"none":      termenv.Ascii,
+}
+
+// loadDisplay applies the colors setting of the config file and returns
+// its palette and whether corporate mode is on, warning about unknown
+// values. Without a colors setting, the colors are those the terminal is
+// detected to support, none if NO_COLOR is set. Corporate mode defaults
+// to the muted palette.
+func loadDisplay() (palette string, corporate bool) {
+	cfg, err := config.Load()
+	if err != nil {
+		return tui.PaletteStandard, false
+	}
+	if cfg.Colors != "" && cfg.Colors != "auto" {
+		if profile, ok := colorProfiles[cfg.Colors]; ok {
+			lipgloss.SetColorProfile(profile)
+		} else {
+			fmt.Fprintf(os.Stderr, "[WARNING] colors must be auto, truecolor, 256, 16 or none\n")
+		}
+	}
+	palette = tui.PaletteStandard
+	if cfg.Corporate {
+		palette = tui.PaletteMuted
+	}
+	if cfg.Palette == "" {
+		return palette, cfg.Corporate
+	}
+	if err := tui.CheckPalette(cfg.Palette); err != nil {
+		fmt.Fprintf(os.Stderr, "[WARNING] %v\n", err)
+		return palette, cfg.Corporate
 	}
+	return cfg.Palette, cfg.Corporate
 }
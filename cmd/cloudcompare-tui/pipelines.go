@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cloudcompare-automation/internal/config"
+	"github.com/cloudcompare-automation/internal/processor"
+)
+
+// loadPipelines returns the discovered pipeline scripts together with the
+// ones declared in the user config. Problems are reported as warnings so
+// the default pipeline stays usable.
+func loadPipelines() []processor.Pipeline {
+	pipelines, err := processor.DiscoverPipelines()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[WARNING] %v\n", err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return pipelines
+	}
+	dir, _ := config.Dir()
+	for _, declared := range cfg.Pipelines {
+		pipelines, err = processor.AddPipeline(pipelines, processor.Pipeline{
+			Name:        declared.Name,
+			Script:      declared.Script,
+			Description: declared.Description,
+			Params:      declared.Params,
+		}, dir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[WARNING] %v\n", err)
+		}
+	}
+	return pipelines
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudcompare-automation/internal/config"
+	"github.com/cloudcompare-automation/internal/profile"
+)
+
+// takeProfile activates the profile given by a leading --profile NAME or
+// --profile=NAME, for the TUI and every subcommand, or else by
+// CCAUTO_PROFILE, and returns the arguments after it
+func takeProfile(args []string) ([]string, error) {
+	name := profile.Active()
+	switch {
+	case len(args) == 0:
+	case args[0] == "--profile" || args[0] == "-profile":
+		if len(args) < 2 {
+			return nil, fmt.Errorf("--profile needs a profile name")
+		}
+		name, args = args[1], args[2:]
+	case strings.HasPrefix(args[0], "--profile="), strings.HasPrefix(args[0], "-profile="):
+		_, name, _ = strings.Cut(args[0], "=")
+		args = args[1:]
+	}
+	if err := profile.Set(name); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+// isTerminal reports whether f is a terminal, not a pipe or a file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// runProfile lists and creates the profiles of this machine
+func runProfile(args []string) int {
+	switch {
+	case len(args) == 1 && args[0] == "list":
+		names, err := profile.List()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			return 1
+		}
+		if len(names) == 0 {
+			fmt.Println("No profiles; the shared state is used")
+			return 0
+		}
+		for _, name := range names {
+			if name == profile.Active() {
+				fmt.Printf("%s (active)\n", name)
+			} else {
+				fmt.Println(name)
+			}
+		}
+		return 0
+	case len(args) == 2 && args[0] == "create":
+		if err := profile.Create(args[1]); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			return 1
+		}
+		dir, _ := profile.DirOf(args[1])
+		fmt.Printf("[SUCCESS] Created profile %s; its settings go in %s\n", args[1], filepath.Join(dir, config.FileName))
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "Usage: cloudcompare-tui profile <list|create NAME>\n")
+	return 2
+}
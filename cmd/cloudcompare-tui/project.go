@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/cloudcompare-automation/internal/config"
+	"github.com/cloudcompare-automation/internal/processor"
+)
+
+// applyDirSettings applies the CCAUTO_PARAM_* variables, then the project
+// configuration of the directory the command will process. They are
+// applied before the flags are parsed, so the flags win over both. It
+// returns the project configuration's path, "" when there is none.
+func applyDirSettings(args []string, params *processor.Params, schema []processor.ParamSpec) (string, error) {
+	if err := applyEnvParams(params, schema); err != nil {
+		return "", err
+	}
+	dir := commandDir(args)
+	if dir == "" {
+		return "", nil
+	}
+	project, path, err := config.LoadProject(dir)
+	if err != nil || path == "" {
+		return path, err
+	}
+	if err := project.Apply(params, schema); err != nil {
+		return path, fmt.Errorf("invalid project config %s: %v", path, err)
+	}
+	return path, nil
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/cloudcompare-automation/internal/processor"
+	"github.com/cloudcompare-automation/internal/queue"
+)
+
+// runQueue dispatches the queue inspection and management subcommands
+func runQueue(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: cloudcompare-tui queue <status|priority|window|cancel|add|pause|resume> ...\n")
+		return 2
+	}
+
+	switch args[0] {
+	case "status":
+		return runQueueStatus(args[1:])
+	case "priority":
+		return runQueuePriority(args[1:])
+	case "window":
+		return runQueueWindow(args[1:])
+	case "cancel":
+		return runQueueMark(args[1:], "cancel")
+	case "add":
+		return runQueueMark(args[1:], "add")
+	case "pause":
+		return runQueuePause(args[1:], true)
+	case "resume":
+		return runQueuePause(args[1:], false)
+	default:
+		fmt.Fprintf(os.Stderr, "[ERROR] Unknown queue command: %s\n", args[0])
+		return 2
+	}
+}
+
+// runQueueStatus lists the files of a shared queue in claim order with their state
+func runQueueStatus(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: cloudcompare-tui queue status DIR\n")
+		return 2
+	}
+
+	q, files, err := openQueue(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	window, err := q.Window()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+
+	counts := q.Status(files)
+	fmt.Printf("Queue: %s\n", q.Dir)
+	fmt.Printf("%d pending, %d in progress, %d done, %d failed, %d cancelled\n",
+		counts[queue.StatePending], counts[queue.StateLeased], counts[queue.StateDone], counts[queue.StateFailed], counts[queue.StateCancelled])
+	if q.Paused() {
+		fmt.Println("Paused: workers claim no new files until resumed")
+	}
+	fmt.Printf("Window: %s\n", window)
+	if counts[queue.StatePending] > 0 && !window.IsAlways() && !q.Paused() {
+		fmt.Printf("Next start: %s\n", window.NextStart(time.Now()).Format("Mon 2006-01-02 15:04"))
+	}
+	fmt.Println()
+
+	for _, file := range q.Order(files) {
+		fmt.Printf("  %-9s %4d  %s\n", q.State(file), q.Priority(file), filepath.Base(file))
+	}
+	return 0
+}
+
+// runQueuePriority assigns a priority to every file matching a glob pattern
+func runQueuePriority(args []string) int {
+	if len(args) != 3 {
+		fmt.Fprintf(os.Stderr, "Usage: cloudcompare-tui queue priority DIR PATTERN N\n")
+		return 2
+	}
+	priority, err := strconv.Atoi(args[2])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Invalid priority: %s\n", args[2])
+		return 2
+	}
+
+	q, files, err := openQueue(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	matched, err := matchFiles(files, args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 2
+	}
+
+	for _, file := range matched {
+		if err := q.SetPriority(file, priority); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			return 1
+		}
+	}
+
+	fmt.Printf("[INFO] Set priority %d on %d file(s)\n", priority, len(matched))
+	return 0
+}
+
+// runQueueMark cancels the files matching a glob pattern, or adds
+// cancelled and failed ones back to the queue
+func runQueueMark(args []string, action string) int {
+	if len(args) != 2 {
+		fmt.Fprintf(os.Stderr, "Usage: cloudcompare-tui queue %s DIR PATTERN\n", action)
+		return 2
+	}
+	q, files, err := openQueue(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	matched, err := matchFiles(files, args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 2
+	}
+
+	changed := 0
+	for _, file := range matched {
+		mark := q.Requeue
+		if action == "cancel" {
+			mark = q.Cancel
+		}
+		ok, err := mark(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			return 1
+		}
+		if ok {
+			changed++
+		}
+	}
+
+	if action == "cancel" {
+		fmt.Printf("[INFO] Cancelled %d file(s); files being processed are finished\n", changed)
+	} else {
+		fmt.Printf("[INFO] Added %d cancelled or failed file(s) back to the queue\n", changed)
+	}
+	return 0
+}
+
+// runQueuePause pauses or resumes a shared queue for all its workers
+func runQueuePause(args []string, paused bool) int {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: cloudcompare-tui queue pause|resume DIR\n")
+		return 2
+	}
+	q, err := queue.Open(args[0], queue.DefaultWorkerID(), queue.DefaultLeaseTTL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	if err := q.SetPaused(paused); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	if paused {
+		fmt.Println("[INFO] Queue paused; workers finish their current file and wait")
+	} else {
+		fmt.Println("[INFO] Queue resumed")
+	}
+	return 0
+}
+
+// matchFiles returns the files whose name matches a glob pattern
+func matchFiles(files []string, pattern string) ([]string, error) {
+	var matched []string
+	for _, file := range files {
+		ok, err := filepath.Match(pattern, filepath.Base(file))
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %v", err)
+		}
+		if ok {
+			matched = append(matched, file)
+		}
+	}
+	return matched, nil
+}
+
+// runQueueWindow shows or sets the processing window of a shared queue
+func runQueueWindow(args []string) int {
+	if len(args) != 1 && len(args) != 2 {
+		fmt.Fprintf(os.Stderr, "Usage: cloudcompare-tui queue window DIR [HH:MM-HH:MM|always]\n")
+		return 2
+	}
+	q, err := queue.Open(args[0], queue.DefaultWorkerID(), queue.DefaultLeaseTTL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+
+	if len(args) == 1 {
+		window, err := q.Window()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			return 1
+		}
+		fmt.Println(window)
+		return 0
+	}
+
+	value := args[1]
+	if value == "always" {
+		value = ""
+	}
+	window, err := queue.ParseWindow(value)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 2
+	}
+	if err := q.SetWindow(window); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	fmt.Printf("[INFO] Processing window: %s\n", window)
+	return 0
+}
+
+func openQueue(dir string) (*queue.Queue, []string, error) {
+	q, err := queue.Open(dir, queue.DefaultWorkerID(), queue.DefaultLeaseTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+	params := defaultParams()
+	params.InputDir = dir
+	files, err := processor.New(params).ListLASFiles()
+	if err != nil {
+		return nil, nil, err
+	}
+	return q, files, nil
+}
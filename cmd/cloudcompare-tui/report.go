@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudcompare-automation/internal/report"
+	"github.com/cloudcompare-automation/internal/session"
+)
+
+// runReport writes a run's HTML report again from its JSON report, e.g.
+// after the layout changed, or converts it to another format, without
+// processing anything again
+func runReport(args []string) int {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	format := fs.String("format", "", "convert to html, csv or md instead of rewriting the HTML report")
+	out := fs.String("o", "", "file to write the converted report to (default: next to the report)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: cloudcompare-tui report [--format html|csv|md] [-o FILE] REPORT\n\n")
+		fmt.Fprintf(fs.Output(), "Write the HTML version of a run report again, or convert the report. REPORT is\n")
+		fmt.Fprintf(fs.Output(), "the report's JSON file, or the journal or directory of the background session\n")
+		fmt.Fprintf(fs.Output(), "that ran it.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 || (*out != "" && *format == "") {
+		fs.Usage()
+		return 2
+	}
+
+	// A session's journal names the report its run wrote
+	path := fs.Arg(0)
+	if !strings.HasSuffix(path, ".json") {
+		result, err := session.Result(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			return 1
+		}
+		if result.ReportPath == "" {
+			fmt.Fprintf(os.Stderr, "[ERROR] The run of %s wrote no report\n", path)
+			return 1
+		}
+		path = result.ReportPath
+	}
+
+	if *format == "" {
+		html, err := report.Regenerate(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			return 1
+		}
+		fmt.Printf("[SUCCESS] Saved: %s\n", html)
+		return 0
+	}
+
+	ext, ok := report.Formats[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "[ERROR] --format must be html, csv or md\n")
+		return 2
+	}
+	if *out == "" {
+		*out = strings.TrimSuffix(path, filepath.Ext(path)) + ext
+	}
+	if err := report.Convert(path, *format, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	fmt.Printf("[SUCCESS] Saved: %s\n", *out)
+	return 0
+}
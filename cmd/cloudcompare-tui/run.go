@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cloudcompare-automation/internal/descriptor"
+	"github.com/cloudcompare-automation/internal/processor"
+)
+
+// runRun processes the LAS files of a directory without the TUI, or
+// repeats a run from its descriptor
+func runRun(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	params := defaultParams()
+
+	pipeline, err := selectPipeline(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 2
+	}
+	addParamFlags(fs, &params, pipeline.Params)
+	project, err := applyDirSettings(args, &params, pipeline.Params)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	from := fs.String("from", "", "repeat the run described by this run descriptor instead of processing DIR")
+	strict := fs.Bool("strict", false, "with --from, refuse to run when the files, pipeline script, version or platform differ")
+	export := fs.String("export", "", "write a run descriptor of the run to this file instead of processing")
+	fs.String("pipeline", "", "pipeline to run, by name or script file name (default: "+processor.DefaultPipeline+")")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: cloudcompare-tui run [flags] DIR\n")
+		fmt.Fprintf(fs.Output(), "       cloudcompare-tui run --from FILE [--strict] [--export FILE]\n\n")
+		fmt.Fprintf(fs.Output(), "Process the LAS files of a directory, or repeat a run from its descriptor.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *from != "" {
+		// The descriptor's settings replace the config and flags entirely
+		var other []string
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name != "from" && f.Name != "strict" && f.Name != "export" {
+				other = append(other, "--"+f.Name)
+			}
+		})
+		if len(other) > 0 {
+			fmt.Fprintf(os.Stderr, "[ERROR] --from repeats the descriptor's settings; %s can't be used with it\n", strings.Join(other, ", "))
+			return 2
+		}
+		if fs.NArg() != 0 {
+			fs.Usage()
+			return 2
+		}
+		d, err := descriptor.Load(*from)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			return 1
+		}
+		// The Python environment is this machine's
+		python := params.Python
+		params = d.Params
+		params.Python = python
+
+		proc := processor.New(params)
+		if err := proc.FindScripts(); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			return 1
+		}
+		fmt.Printf("[INFO] Repeating the run of %s from %s: %d file(s)\n", d.CreatedAt.Format("2006-01-02 15:04"), d.Host.Name, len(d.Files))
+		diffs := d.Differences(proc.ScriptPath(), version)
+		for _, diff := range diffs {
+			fmt.Printf("[WARNING] %s\n", diff)
+		}
+		if *strict && len(diffs) > 0 {
+			fmt.Fprintf(os.Stderr, "[ERROR] The run can't be repeated exactly (%d difference(s))\n", len(diffs))
+			return 1
+		}
+	} else {
+		dir, ok := inputDirArg(fs)
+		if !ok {
+			fs.Usage()
+			return 2
+		}
+		params.InputDir = dir
+		if project != "" {
+			fmt.Printf("[INFO] Project config: %s\n", project)
+		}
+		if pipeline.Name != processor.DefaultPipeline {
+			params.Script = pipeline.Script
+			fmt.Printf("[INFO] Pipeline: %s\n", pipeline.Name)
+		}
+	}
+
+	if *export != "" {
+		d, err := descriptor.Describe(processor.New(params), version)
+		if err == nil {
+			err = d.Save(*export)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			return 1
+		}
+		fmt.Printf("[SUCCESS] Saved run descriptor of %d file(s): %s\n", len(d.Files), *export)
+		return 0
+	}
+
+	result, err := runHeadless(signalContext(), params)
+	if err != nil {
+		return 1
+	}
+	fmt.Printf("[INFO] Processed %d file(s): %d succeeded, %d with warnings, %d failed\n",
+		len(result.Files), result.SuccessCount-result.WarningCount, result.WarningCount, result.FailedCount)
+	if result.Stopped || result.FailedCount > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cloudcompare-automation/internal/session"
+)
+
+// runSession handles background session commands. "run" is invoked by the
+// TUI when it launches a detached batch; "list" shows sessions still running.
+func runSession(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: cloudcompare-tui session <list|run DIR>\n")
+		return 2
+	}
+
+	switch args[0] {
+	case "run":
+		if len(args) != 2 {
+			fmt.Fprintf(os.Stderr, "Usage: cloudcompare-tui session run DIR\n")
+			return 2
+		}
+		if err := session.Run(signalContext(), args[1]); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			return 1
+		}
+		return 0
+
+	case "list":
+		running, err := session.Running()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			return 1
+		}
+		if len(running) == 0 {
+			fmt.Println("No background sessions running")
+			return 0
+		}
+		for _, state := range running {
+			fmt.Printf("%s  pid %-7d  running %-10s  %s\n",
+				state.ID, state.PID, time.Since(state.StartedAt).Round(time.Second), state.Params.InputDir)
+		}
+		return 0
+
+	default:
+		fmt.Fprintf(os.Stderr, "[ERROR] Unknown session command: %s\n", args[0])
+		return 2
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// shutdownSignals are the signals that stop headless commands: Ctrl+C,
+// service managers stopping us, and the terminal closing
+var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP}
+
+// signalContext returns a context cancelled by the first shutdown signal.
+// A second signal is not caught, so it terminates the process immediately.
+func signalContext() context.Context {
+	ctx, stop := signal.NotifyContext(context.Background(), shutdownSignals...)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cloudcompare-automation/internal/processor"
+)
+
+// runValidate checks that a directory can be processed as run would with
+// the same flags, without processing it: the input files, their LAS
+// headers, the environment doctor checks, and disk space and memory. It
+// prints a line per check and fails if any check does, so pipelines can
+// gate on it before submitting jobs.
+func runValidate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	params := defaultParams()
+
+	pipeline, err := selectPipeline(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 2
+	}
+	addParamFlags(fs, &params, pipeline.Params)
+	project, err := applyDirSettings(args, &params, pipeline.Params)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	fs.String("pipeline", "", "pipeline to check for, by name or script file name (default: "+processor.DefaultPipeline+")")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: cloudcompare-tui validate [flags] DIR\n\n")
+		fmt.Fprintf(fs.Output(), "Check that DIR can be processed with the given flags, without processing it.\n")
+		fmt.Fprintf(fs.Output(), "Exits with 1 if any check fails.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	dir, ok := inputDirArg(fs)
+	if !ok {
+		fs.Usage()
+		return 2
+	}
+	params.InputDir = dir
+	if project != "" {
+		fmt.Printf("[INFO] Project config: %s\n", project)
+	}
+	if pipeline.Name != processor.DefaultPipeline {
+		params.Script = pipeline.Script
+	}
+
+	var checks []processor.Check
+	proc := processor.New(params)
+	err = proc.ValidateInputDir()
+	var files []string
+	if err == nil {
+		files, err = proc.ListLASFiles()
+	}
+	if err != nil {
+		checks = append(checks, processor.Check{Name: "Input", Level: processor.LogError, Detail: err.Error()})
+	} else {
+		checks = append(checks, processor.Check{Name: "Input", Level: processor.LogSuccess, Detail: fmt.Sprintf("%d LAS file(s) in %s", len(files), params.InputDir)})
+		checks = append(checks, processor.Preflight(params, files)...)
+	}
+
+	// Importing CloudComPy can take a while on a cold disk
+	ctx, cancel := context.WithTimeout(signalContext(), 2*time.Minute)
+	defer cancel()
+	checks = append(checks, processor.Doctor(ctx, params)...)
+
+	failed := 0
+	for _, check := range checks {
+		fmt.Printf("[%s] %s: %s\n", check.Level, check.Name, check.Detail)
+		if check.Level == processor.LogError {
+			failed++
+		}
+	}
+	if failed > 0 {
+		fmt.Printf("[ERROR] %d check(s) failed\n", failed)
+		return 1
+	}
+	fmt.Printf("[SUCCESS] %s can be processed (%s)\n", params.InputDir, pipeline.Name)
+	return 0
+}
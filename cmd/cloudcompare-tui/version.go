@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/cloudcompare-automation/internal/processor"
+	"github.com/cloudcompare-automation/internal/update"
+)
+
+// runVersion prints the binary and pipeline script versions,
+// optionally checking the release endpoint for a newer release
+func runVersion(args []string) int {
+	fs := flag.NewFlagSet("version", flag.ContinueOnError)
+	check := fs.Bool("check", false, "check for a newer release")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	scriptVersion := printLocalVersions()
+
+	if !*check {
+		return 0
+	}
+	release, err := latestRelease()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	reportRelease(release, scriptVersion)
+	return 0
+}
+
+// runUpdate checks for a newer release and downloads its archive for this platform
+func runUpdate(args []string) int {
+	fs := flag.NewFlagSet("update", flag.ContinueOnError)
+	dir := fs.String("dir", ".", "directory to download the release archive into")
+	force := fs.Bool("force", false, "download even if already up to date")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	scriptVersion := printLocalVersions()
+
+	release, err := latestRelease()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	if !reportRelease(release, scriptVersion) && !*force {
+		return 0
+	}
+
+	asset, ok := release.AssetFor(runtime.GOOS, runtime.GOARCH)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "[ERROR] No release archive for %s/%s in %s\n", runtime.GOOS, runtime.GOARCH, release.TagName)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	fmt.Printf("[INFO] Downloading %s...\n", asset.Name)
+	path, err := update.Download(ctx, asset, *dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	fmt.Printf("[SUCCESS] Saved: %s\n", path)
+	fmt.Println("[INFO] Extract the archive over this installation to update the binary and pipeline script")
+	return 0
+}
+
+// printLocalVersions prints the installed versions and returns the
+// pipeline script version ("" when the script cannot be found)
+func printLocalVersions() string {
+	fmt.Printf("cloudcompare-tui %s (%s/%s)\n", version, runtime.GOOS, runtime.GOARCH)
+
+	proc := processor.New(processor.DefaultParams())
+	if err := proc.FindScripts(); err != nil {
+		fmt.Printf("pipeline script: not found\n")
+		return ""
+	}
+	scriptVersion, err := update.ScriptVersion(proc.ScriptPath())
+	if err != nil {
+		scriptVersion = "unknown"
+	}
+	fmt.Printf("pipeline script %s (%s)\n", scriptVersion, proc.ScriptPath())
+	return scriptVersion
+}
+
+func latestRelease() (*update.Release, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	return update.Latest(ctx)
+}
+
+// reportRelease prints how the latest release compares to the installed
+// binary, and whether the pipeline script is recent enough for the binary,
+// and returns true when an update is needed. The script is versioned on its
+// own, so it isn't compared with the release tag.
+func reportRelease(release *update.Release, scriptVersion string) bool {
+	binaryOutdated := update.IsNewer(release.Version(), version)
+	scriptOutdated := update.IsNewer(processor.MinScriptVersion, scriptVersion)
+
+	if version == "dev" {
+		fmt.Printf("[INFO] Development build; latest release is %s\n", release.TagName)
+	} else if binaryOutdated {
+		fmt.Printf("[WARNING] A newer release is available: %s (installed %s)\n", release.TagName, version)
+	}
+	if scriptOutdated {
+		fmt.Printf("[WARNING] Pipeline script %s is older than %s, which this binary needs\n", scriptVersion, processor.MinScriptVersion)
+	}
+
+	if !binaryOutdated && !scriptOutdated {
+		fmt.Printf("[SUCCESS] Up to date (latest release is %s)\n", release.TagName)
+		return false
+	}
+	fmt.Printf("[INFO] %s\n", release.URL)
+	return true
+}
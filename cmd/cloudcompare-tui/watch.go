@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+
+	"github.com/cloudcompare-automation/internal/session"
+	"github.com/cloudcompare-automation/internal/tui"
+)
+
+// runWatch opens the TUI in observer mode, following the progress and
+// log of background sessions without being able to start or cancel
+// anything
+func runWatch(args []string) int {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	inline := fs.Bool("inline", false, "render inline without the alternate screen, keeping output in scrollback")
+	accessible := fs.Bool("accessible", os.Getenv("ACCESSIBLE") != "", "plain inline output for screen readers (default when ACCESSIBLE is set)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: cloudcompare-tui watch [--inline] [--accessible] [SESSION]\n\n")
+		fmt.Fprintf(fs.Output(), "Watch the background batch SESSION, as listed by \"session list\", or any\n")
+		fmt.Fprintf(fs.Output(), "batch that runs, read-only. Quitting leaves the batch running.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	id := fs.Arg(0)
+	if id != "" {
+		running, err := session.Running()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			return 1
+		}
+		found := false
+		for _, state := range running {
+			found = found || state.ID == id
+		}
+		if !found {
+			fmt.Fprintf(os.Stderr, "[ERROR] No background session %s is running\n", id)
+			return 1
+		}
+	}
+
+	palette, corporate := loadDisplay()
+	if *accessible {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+	model := tui.New(tui.Options{
+		Inline:     *inline,
+		Version:    version,
+		Palette:    palette,
+		Accessible: *accessible,
+		Corporate:  corporate,
+
+		Observe:        true,
+		ObserveSession: id,
+	})
+	return runTUI(model, *inline || *accessible)
+}
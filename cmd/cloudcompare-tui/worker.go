@@ -0,0 +1,514 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudcompare-automation/internal/config"
+	"github.com/cloudcompare-automation/internal/history"
+	"github.com/cloudcompare-automation/internal/humanize"
+	"github.com/cloudcompare-automation/internal/lock"
+	"github.com/cloudcompare-automation/internal/processor"
+	"github.com/cloudcompare-automation/internal/queue"
+)
+
+// runWorker joins a shared queue: it repeatedly claims one unprocessed LAS
+// file from a shared directory, processes it and records the outcome, so
+// several machines can work through the same survey cooperatively
+func runWorker(args []string) int {
+	fs := flag.NewFlagSet("worker", flag.ContinueOnError)
+	params := defaultParams()
+
+	// The pipeline decides which parameter flags exist, so it is picked
+	// before the flags are registered
+	pipeline, err := selectPipeline(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 2
+	}
+	addParamFlags(fs, &params, pipeline.Params)
+	project, err := applyDirSettings(args, &params, pipeline.Params)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	wait := fs.Bool("wait", false, "keep polling for new files when the queue is empty")
+	poll := fs.Duration("poll", 30*time.Second, "interval between queue polls with --wait")
+	ttl := fs.Duration("lease-ttl", queue.DefaultLeaseTTL, "time after which an unrefreshed lease is reclaimed")
+	workerID := fs.String("id", queue.DefaultWorkerID(), "worker identifier recorded in leases")
+	windowFlag := fs.String("window", "", "daily window for starting files, e.g. 19:00-07:00, stored with the queue for all its workers (default: the queue's)")
+	settle := fs.Duration("settle", 0, "only claim files whose size has not changed for this long, or that have a .done marker (default: claim at once)")
+	fs.String("pipeline", "", "pipeline to run, by name or script file name (default: "+processor.DefaultPipeline+")")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: cloudcompare-tui worker [flags] DIR\n\n")
+		fmt.Fprintf(fs.Output(), "Process LAS files from a directory shared between several workers.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	dir, ok := inputDirArg(fs)
+	if !ok {
+		fs.Usage()
+		return 2
+	}
+	params.InputDir = dir
+	if project != "" {
+		fmt.Printf("[INFO] Project config: %s\n", project)
+	}
+	// Each claimed file is a batch of its own; they share one run folder
+	params.RunStarted = time.Now()
+
+	if pipeline.Name != processor.DefaultPipeline {
+		params.Script = pipeline.Script
+		fmt.Printf("[INFO] Pipeline: %s\n", pipeline.Name)
+	}
+
+	// A machine that can't process any file mustn't fail the queue's
+	// files one by one
+	if err := processor.CheckSetup(params); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] Not joining the queue: %v\n", err)
+		return 1
+	}
+
+	window, err := queue.ParseWindow(*windowFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 2
+	}
+
+	q, err := queue.Open(params.InputDir, *workerID, *ttl)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	// The window is the queue's, so workers started without the flag
+	// keep to it too
+	if *windowFlag != "" {
+		if err := q.SetWindow(window); err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Failed to store the processing window: %v\n", err)
+			return 1
+		}
+	}
+	if window, err = q.Window(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	lister := processor.New(params)
+	ctx := signalContext()
+
+	// Workers share the output directory with each other, but not with
+	// a TUI or session run
+	outputLock, err := lock.AcquireShared(filepath.Join(q.Dir, params.OutputSubdir))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return 1
+	}
+	defer outputLock.Release()
+	params.SharedOutput = true
+
+	fmt.Printf("[INFO] Worker %s joined queue: %s\n", q.WorkerID, q.Dir)
+	if !window.IsAlways() {
+		fmt.Printf("[INFO] Processing window: %s\n", window)
+	}
+
+	settler := queue.NewSettler(*settle)
+	processed, failed, warned := 0, 0, 0
+	streak := 0     // Files failed in a row
+	broken := false // Stopped by an error that isn't the file's
+	for ctx.Err() == nil {
+		// Only start new files inside the processing window, which may
+		// have been changed since the last file
+		current, err := q.Window()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			return 1
+		}
+		if current != window {
+			window = current
+			fmt.Printf("[INFO] Processing window changed: %s\n", window)
+		}
+		if now := time.Now(); !window.Contains(now) {
+			next := window.NextStart(now)
+			fmt.Printf("[INFO] Outside processing window %s; next start scheduled at %s\n", window, next.Format("Mon 15:04"))
+			sleep(ctx, time.Until(next))
+			continue
+		}
+
+		// A paused queue holds its workers, with or without --wait
+		if q.Paused() {
+			fmt.Printf("[INFO] Queue paused; checking again in %s\n", *poll)
+			sleep(ctx, *poll)
+			continue
+		}
+
+		files, err := lister.ListLASFiles()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] Failed to read queue directory: %v\n", err)
+			return 1
+		}
+
+		// Files still being copied in are left for a later poll
+		ready, settling := settler.Ready(files, time.Now())
+		lease, err := q.Claim(ready)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+			return 1
+		}
+		if lease == nil && q.Paused() {
+			continue
+		}
+
+		if lease == nil && unfinished(q, settling) > 0 {
+			recheck := min(*poll, *settle)
+			fmt.Printf("[INFO] %d file(s) may still be copying; checking again in %s\n", unfinished(q, settling), recheck)
+			sleep(ctx, recheck)
+			continue
+		}
+		if lease == nil {
+			counts := q.Status(files)
+			if !*wait {
+				fmt.Printf("[INFO] Queue drained: %d done, %d failed, %d cancelled, %d in progress elsewhere\n",
+					counts[queue.StateDone], counts[queue.StateFailed], counts[queue.StateCancelled], counts[queue.StateLeased])
+				break
+			}
+			fmt.Printf("[INFO] No files to claim (%d pending, %d in progress); polling again in %s\n", counts[queue.StatePending], counts[queue.StateLeased], *poll)
+			sleep(ctx, *poll)
+			continue
+		}
+
+		fmt.Printf("[INFO] Claimed: %s\n", filepath.Base(lease.File))
+
+		fileParams := params
+		fileParams.InputFile = lease.File
+		result, err := runHeadless(ctx, fileParams)
+
+		// An interrupted file goes back to the queue for another worker
+		if result.Stopped {
+			if err := lease.Release(); err != nil {
+				fmt.Fprintf(os.Stderr, "[WARNING] %v\n", err)
+			}
+			fmt.Printf("[WARNING] Interrupted; returned %s to the queue\n", filepath.Base(lease.File))
+			break
+		}
+
+		// So does a file this machine couldn't get to, e.g. with its
+		// output directory locked or the environment gone; the worker
+		// stops rather than fail the rest of the queue
+		if err != nil || result.SetupError != "" {
+			if err := lease.Release(); err != nil {
+				fmt.Fprintf(os.Stderr, "[WARNING] %v\n", err)
+			}
+			fmt.Printf("[ERROR] Worker stopped: this machine can't process; returned %s to the queue\n", filepath.Base(lease.File))
+			broken = true
+			break
+		}
+
+		success := result.FailedCount == 0 && (result.SuccessCount > 0 || result.Skipped > 0)
+		detail := ""
+		if !success && len(result.Files) > 0 {
+			detail = result.Files[0].Error
+		}
+		for _, f := range result.Files {
+			if f.Outcome() == processor.OutcomeWarning {
+				detail = "completed with warnings: " + strings.Join(f.Warnings, "; ")
+				warned++
+			}
+		}
+		if err := lease.Complete(success, detail); err != nil {
+			fmt.Fprintf(os.Stderr, "[WARNING] %v\n", err)
+		}
+
+		processed++
+		if !success {
+			failed++
+			streak++
+		} else {
+			streak = 0
+		}
+
+		// Each file is a batch of its own, so the abort policy and crash
+		// recovery apply to the worker's files together; the rest are left
+		// to other workers
+		if reason := params.Abort.Reason(processed, failed, streak); reason != "" {
+			fmt.Printf("[ERROR] Worker stopped: %s\n", reason)
+			break
+		}
+		if params.CrashRecovery && len(result.Files) == 1 && result.Files[0].Crashed {
+			fmt.Println("[WARNING] The script crashed; checking the Python environment before claiming the next file")
+			if err := params.RecoverFromCrash(ctx); err != nil {
+				fmt.Printf("[ERROR] Worker stopped: environment broken after a crash: %v\n", err)
+				break
+			}
+		}
+	}
+
+	fmt.Printf("[INFO] Worker %s processed %d file(s), %d failed, %d with warnings\n", q.WorkerID, processed, failed, warned)
+	if failed > 0 || broken || ctx.Err() != nil {
+		return 1
+	}
+	return 0
+}
+
+// unfinished counts the files that are neither done, failed nor cancelled
+func unfinished(q *queue.Queue, files []string) int {
+	n := 0
+	for _, file := range files {
+		if !q.IsFinished(file) {
+			n++
+		}
+	}
+	return n
+}
+
+// selectPipeline returns the pipeline named by a --pipeline flag in args,
+// or by CCAUTO_PIPELINE, or the default pipeline
+func selectPipeline(args []string) (processor.Pipeline, error) {
+	name := os.Getenv(pipelineEnv)
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		flagName, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || flagName != "pipeline" {
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			value = args[i+1]
+		}
+		name = value
+	}
+
+	pipelines := loadPipelines()
+	if name == "" {
+		return pipelines[0], nil
+	}
+	pipeline, ok := processor.FindPipeline(pipelines, name)
+	if !ok {
+		return processor.Pipeline{}, fmt.Errorf("unknown pipeline: %s", name)
+	}
+	return pipeline, nil
+}
+
+// addParamFlags registers the processing parameters as command-line flags,
+// with one flag per parameter in the pipeline's schema
+func addParamFlags(fs *flag.FlagSet, params *processor.Params, schema []processor.ParamSpec) {
+	fs.StringVar(&params.OutputSubdir, "output-dir", params.OutputSubdir, "subdirectory name for output files")
+	params.Values = processor.Defaults(schema)
+	for _, spec := range schema {
+		spec := spec
+		usage := spec.Help
+		if usage == "" {
+			usage = spec.DisplayLabel(false)
+		}
+		fs.Func(spec.Name, fmt.Sprintf("%s (default %s)", usage, spec.Default), func(value string) error {
+			v, err := spec.Validate(value)
+			if err != nil {
+				return err
+			}
+			params.Values[spec.Name] = v
+			return nil
+		})
+	}
+	fs.IntVar(&params.Threads, "threads", params.Threads, "cap the script's worker threads (0 = no cap)")
+	fs.BoolVar(&params.LowPriority, "low-priority", params.LowPriority, "run the script at low CPU and I/O priority")
+	fs.BoolVar(&params.Deterministic, "deterministic", params.Deterministic, "run the script single-threaded so outputs can be reproduced exactly")
+	fs.BoolVar(&params.BoostSave, "boost-save", params.BoostSave, "raise the script's I/O priority while it saves the project and flush it to disk")
+	fs.BoolVar(&params.SkipExisting, "skip-existing", params.SkipExisting, "mark files whose project already exists as done without processing them")
+	fs.DurationVar(&params.StallAfter, "stall-after", params.StallAfter, "warn when the script prints nothing for this long (0 = 10m)")
+	fs.BoolVar(&params.CrashRecovery, "crash-recovery", params.CrashRecovery, "after the script crashed, wait and check the Python environment before the next file")
+	fs.DurationVar(&params.CrashCooldown, "crash-cooldown", params.CrashCooldown, "how long to wait after a crash with --crash-recovery (0 = 30s)")
+	fs.IntVar(&params.Prefetch, "prefetch", params.Prefetch, "copy this many of the next files to the local workspace while one is processing (0 = none)")
+	fs.Func("memory-limit-gb", "kill the script of a file once it uses this many GB of memory (0 = no limit)", func(value string) error {
+		gb, err := strconv.ParseFloat(value, 64)
+		if err != nil || gb < 0 {
+			return fmt.Errorf("expected a number of GB, got %q", value)
+		}
+		params.MemoryLimit = uint64(gb * (1 << 30))
+		return nil
+	})
+	fs.Func("abort-after", "abort the batch after this many files failed in a row (0 = never)", func(value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("expected a whole number, got %q", value)
+		}
+		params.Abort.After = n
+		return processor.CheckAbort(params.Abort)
+	})
+	fs.Func("abort-rate", "abort the batch once more than this percentage of files failed (0 = never)", func(value string) error {
+		rate, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil {
+			return fmt.Errorf("expected a percentage, got %q", value)
+		}
+		params.Abort.Rate = rate
+		return processor.CheckAbort(params.Abort)
+	})
+	fs.Func("env", "extra environment variable for the script as KEY=VALUE (repeatable)", func(value string) error {
+		key, val, ok := strings.Cut(value, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("expected KEY=VALUE, got %q", value)
+		}
+		params.Env[key] = val
+		return nil
+	})
+	fs.Func("gpu", "pin processing to this GPU (sets CUDA_VISIBLE_DEVICES)", func(value string) error {
+		params.Env["CUDA_VISIBLE_DEVICES"] = value
+		return nil
+	})
+	fs.Func("meta", "run metadata as KEY=VALUE, e.g. project=Harbour (repeatable)", func(value string) error {
+		key, val, ok := strings.Cut(value, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("expected KEY=VALUE, got %q", value)
+		}
+		params.Metadata[key] = val
+		return nil
+	})
+	fs.StringVar(&params.OutputName, "output-name", params.OutputName, "output file name template, e.g. {project}_{name}")
+	fs.Func("metadata-csv", "CSV table of metadata by file name pattern, instead of the configured lookup", func(value string) error {
+		params.MetadataLookup = processor.MetadataLookup{CSV: value}
+		return nil
+	})
+	fs.Func("output-layout", "where outputs go in the output directory: flat, per-file, dated or mirror", func(value string) error {
+		if err := processor.CheckOutputLayout(value); err != nil {
+			return err
+		}
+		params.OutputLayout = value
+		return nil
+	})
+	fs.Func("label", "label the run in the history and report, e.g. delivery-v2 (repeatable)", func(value string) error {
+		params.Labels = history.ParseLabels(strings.Join(append(params.Labels, value), ","))
+		return nil
+	})
+	fs.Func("include", "only process files matching these name patterns, e.g. tile_??.las (repeatable, replaces the config)", patternsFlag(&params.Include))
+	fs.Func("exclude", "skip files matching these name patterns, e.g. *_preview.las (repeatable, replaces the config)", patternsFlag(&params.Exclude))
+	fs.Func("web-export", "convert each processed cloud for web viewers: none, potree or 3dtiles", func(value string) error {
+		if err := processor.CheckWebExport(value); err != nil {
+			return err
+		}
+		params.WebExport = value
+		return nil
+	})
+	fs.Func("archive", "pack the outputs of each processed file: none, gzip or zip", func(value string) error {
+		if err := processor.CheckArchive(value); err != nil {
+			return err
+		}
+		params.Archive = value
+		return nil
+	})
+}
+
+// patternsFlag parses a repeatable list of file name patterns into
+// target; the first use replaces the patterns from the config file
+func patternsFlag(target *[]string) func(string) error {
+	set := false
+	return func(value string) error {
+		patterns := processor.ParsePatterns(value)
+		if err := processor.CheckPatterns(patterns); err != nil {
+			return err
+		}
+		if !set {
+			*target = nil
+			set = true
+		}
+		*target = append(*target, patterns...)
+		return nil
+	}
+}
+
+// defaultParams returns the default processing parameters with the
+// environment and priority settings from the user config applied
+func defaultParams() processor.Params {
+	params := processor.DefaultParams()
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[WARNING] %v\n", err)
+	}
+	params.Env = cfg.ProcessEnv()
+	params.Include = cfg.Include
+	params.Exclude = cfg.Exclude
+	params.Threads = cfg.Threads
+	params.LowPriority = cfg.LowPriority
+	params.Deterministic = cfg.Deterministic
+	params.BoostSave = cfg.BoostSave
+	params.SkipExisting = cfg.SkipExisting
+	params.StallAfter = time.Duration(cfg.StallMinutes) * time.Minute
+	params.Abort = processor.AbortPolicy{After: cfg.AbortAfterFailures, Rate: cfg.AbortFailureRate}
+	params.Acceptance = cfg.Acceptance.Rules()
+	params.CrashRecovery = cfg.CrashRecovery
+	params.CrashCooldown = time.Duration(cfg.CrashCooldownSeconds) * time.Second
+	params.MemoryLimit = uint64(cfg.MemoryLimitGB * (1 << 30))
+	params.Prefetch = cfg.Prefetch
+	params.LogBuffer = cfg.LogBuffer
+	params.LogOverflow = cfg.LogOverflow
+	params.Metadata = make(map[string]string)
+	for key, value := range cfg.Metadata {
+		params.Metadata[key] = value
+	}
+	params.MetadataLookup = cfg.MetadataLookup.Lookup()
+	params.OutputName = cfg.OutputName
+	params.OutputLayout = cfg.OutputLayout
+	params.Rates = cfg.Cost.Rates()
+	params.PostProcess = cfg.PostCommands()
+	params.WebExport = cfg.WebExport
+	params.Archive = cfg.Archive
+	params.PotreeConverter = cfg.PotreeConverter
+	params.TilesOrigin = cfg.TilesOrigin
+	params.Python = cfg.Python.Env()
+	// Numbers in the TUI, logs and reports follow the configured locale
+	humanize.SetLocale(cfg.Locale)
+	return params
+}
+
+// runHeadless processes with the given parameters, printing log entries
+// to stdout, and returns the final result. Cancelling ctx stops the script
+// and returns the partial result marked Stopped.
+func runHeadless(ctx context.Context, params processor.Params) (processor.ProcessingResult, error) {
+	proc := processor.New(params)
+	if err := proc.ValidateInputDir(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return processor.ProcessingResult{}, err
+	}
+	if err := proc.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "[ERROR] %v\n", err)
+		return processor.ProcessingResult{}, err
+	}
+
+	done := ctx.Done()
+	for {
+		select {
+		case <-done:
+			fmt.Println("[WARNING] Interrupted; stopping the current file")
+			proc.Stop()
+			done = nil
+		case entry, ok := <-proc.LogChan():
+			if !ok {
+				// Closed after the last entry, just before the result
+				return <-proc.ResultChan(), nil
+			}
+			printLogEntry(entry)
+		}
+	}
+}
+
+// sleep waits for d, returning early if ctx is cancelled
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+// printLogEntry prints a log entry, leaving out the progress updates of
+// prefetches; their start and end are printed
+func printLogEntry(entry processor.LogEntry) {
+	if entry.IsProgress() {
+		return
+	}
+	fmt.Printf("[%s] %s\n", entry.Level, entry.Message)
+}
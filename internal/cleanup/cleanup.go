@@ -0,0 +1,234 @@
+package cleanup
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cloudcompare-automation/internal/history"
+	"github.com/cloudcompare-automation/internal/lock"
+	"github.com/cloudcompare-automation/internal/processor"
+	"github.com/cloudcompare-automation/internal/report"
+	"github.com/cloudcompare-automation/internal/session"
+	"github.com/cloudcompare-automation/internal/workspace"
+)
+
+// Processed data adds up to terabytes quickly. Cleaning up removes what
+// the tool itself made: the outputs and reports of the runs in the
+// history, the history entries, the workspace with its stage caches, and
+// the journals of ended background sessions. Outputs are found through
+// the reports, so nothing a run didn't write is touched: a file is only
+// removed if it was last written while its run was going, which leaves
+// earlier outputs a failed file kept and outputs a later run replaced.
+
+// Kinds of what is cleaned up
+const (
+	KindOutput    = "output"    // An output of a processed file
+	KindReport    = "report"    // A run's report, as JSON or HTML
+	KindHistory   = "history"   // A run's history entry
+	KindWorkspace = "workspace" // A batch's run directory, with the files its scripts left
+	KindStage     = "stage"     // The stage cache of a LAS file
+	KindSession   = "session"   // The journal of an ended background session
+)
+
+// Policy selects what to clean up
+type Policy struct {
+	// OlderThan only selects runs that finished, and workspace and
+	// session directories last written, longer ago; 0 selects all
+	OlderThan time.Duration
+
+	// FailedOnly only selects the outputs of failed files, the reports
+	// and history of runs where every file failed, and the run
+	// directories scripts left files in
+	FailedOnly bool
+
+	// WorkspacesOnly leaves the outputs, reports and history alone
+	WorkspacesOnly bool
+}
+
+// Item is a file or directory to remove
+type Item struct {
+	Kind string
+	Path string
+	Size int64  // Bytes, with everything in a directory
+	Run  string // ID of the run in the history it belongs to, if any
+
+	// root is the output directory of the run; the directories between it
+	// and the item are removed too once empty
+	root string
+}
+
+// Skipped is a run or directory left alone as it is in use
+type Skipped struct {
+	Path   string
+	Reason string
+}
+
+// Plan lists what policy selects at now, and what it leaves alone as a
+// run is using it
+func Plan(policy Policy, now time.Time) ([]Item, []Skipped, error) {
+	var cutoff time.Time
+	if policy.OlderThan > 0 {
+		cutoff = now.Add(-policy.OlderThan)
+	}
+	old := func(t time.Time) bool { return cutoff.IsZero() || t.Before(cutoff) }
+
+	var items []Item
+	var skipped []Skipped
+	if !policy.WorkspacesOnly {
+		runs, err := history.List()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, run := range runs {
+			if !old(run.FinishedAt) {
+				continue
+			}
+			if _, ok := lock.Active(run.OutputDir); ok {
+				skipped = append(skipped, Skipped{Path: run.OutputDir, Reason: "a run is writing to it"})
+				continue
+			}
+			items = append(items, runItems(run, policy.FailedOnly)...)
+		}
+	}
+
+	entries, err := workspace.List()
+	if err != nil {
+		return nil, nil, err
+	}
+	// A running batch may be using any stage cache
+	busy := false
+	for _, entry := range entries {
+		if entry.Active {
+			busy = true
+			skipped = append(skipped, Skipped{Path: entry.Path, Reason: "a batch is running in it"})
+		}
+	}
+	for _, entry := range entries {
+		switch {
+		case entry.Active || !old(entry.Modified):
+		case entry.Stages && (busy || policy.FailedOnly):
+		case entry.Stages:
+			items = append(items, Item{Kind: KindStage, Path: entry.Path, Size: size(entry.Path)})
+		default:
+			items = append(items, Item{Kind: KindWorkspace, Path: entry.Path, Size: size(entry.Path)})
+		}
+	}
+	if busy && !policy.FailedOnly {
+		skipped = append(skipped, Skipped{Path: "stage caches", Reason: "a batch is running"})
+	}
+
+	if !policy.FailedOnly {
+		ended, err := session.Ended()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, state := range ended {
+			if old(state.StartedAt) {
+				items = append(items, Item{Kind: KindSession, Path: state.Dir, Size: size(state.Dir)})
+			}
+		}
+	}
+	return items, skipped, nil
+}
+
+// runItems lists the outputs, report and history entry of a run
+func runItems(run history.Entry, failedOnly bool) []Item {
+	var r report.Report
+	if run.Report != "" {
+		r, _ = report.Load(run.Report)
+	}
+
+	var items []Item
+	add := func(kind, path string) {
+		if path == "" {
+			return
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return
+		}
+		// Written by a later run, or kept from an earlier one
+		written := info.ModTime()
+		if kind == KindOutput && (written.Before(run.StartedAt.Truncate(time.Second)) || written.After(run.FinishedAt.Add(time.Minute))) {
+			return
+		}
+		items = append(items, Item{Kind: kind, Path: path, Size: size(path), Run: run.ID, root: run.OutputDir})
+	}
+
+	for _, f := range r.Files {
+		if failedOnly && f.Outcome != "failed" {
+			continue
+		}
+		for _, output := range []string{f.Output, f.Mesh} {
+			if output != "" {
+				add(KindOutput, output)
+				add(KindOutput, processor.ProvenancePath(output))
+			}
+		}
+		for _, snapshot := range f.Snapshots {
+			add(KindOutput, snapshot)
+		}
+		add(KindOutput, f.Web)
+		add(KindOutput, f.Archive)
+	}
+
+	if failedOnly && run.Succeeded+run.Warned > 0 {
+		return items
+	}
+	if run.Report != "" {
+		add(KindReport, run.Report)
+		add(KindReport, report.HTMLPath(run.Report))
+	}
+	if path, err := history.Path(run); err == nil {
+		// The history isn't under the output directory
+		items = append(items, Item{Kind: KindHistory, Path: path, Size: size(path), Run: run.ID})
+	}
+	return items
+}
+
+// Remove removes the items, and the directories of outputs and reports
+// left empty, calling done after each, and returns the bytes freed
+func Remove(items []Item, done func(Item, error)) int64 {
+	var freed int64
+	for _, item := range items {
+		err := os.RemoveAll(item.Path)
+		done(item, err)
+		if err != nil {
+			continue
+		}
+		freed += item.Size
+		if item.root == "" {
+			continue
+		}
+		for dir := filepath.Dir(item.Path); within(item.root, dir); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+	return freed
+}
+
+// within reports whether dir is root or a directory under it
+func within(root, dir string) bool {
+	rel, err := filepath.Rel(root, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// size returns the bytes in the file or directory at path
+func size(path string) int64 {
+	var total int64
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && !d.IsDir() {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
@@ -0,0 +1,240 @@
+package config
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cloudcompare-automation/internal/processor"
+)
+
+// A bundle is a zip file holding config.yaml and every pipeline script it
+// uses, with the script's parameter schema, so one workstation's setup
+// can be rolled out to the others. Scripts are stored under pipelines/
+// and the bundled config declares them by that relative path, which
+// resolves against the config directory once imported.
+
+const (
+	bundleManifest  = "bundle.yaml"
+	bundlePipelines = "pipelines"
+	schemaSuffix    = ".params.yaml"
+)
+
+// Manifest describes where and when a bundle was made
+type Manifest struct {
+	CreatedAt time.Time `yaml:"created_at"`
+	Host      string    `yaml:"host,omitempty"`
+}
+
+// ExportBundle writes the configuration to a bundle at dest, together with
+// the scripts of its declared pipelines and of the extra pipelines given
+// (typically the *_pipeline.py scripts discovered next to the default
+// one), which the bundled config declares as well
+func ExportBundle(dest string, extra []processor.Pipeline) error {
+	cfg, err := Load()
+	if err != nil {
+		return err
+	}
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+
+	// Bundle each script once, under a unique name
+	bundled := make(map[string]string) // script path -> bundle path
+	add := func(script string) (string, error) {
+		if name, ok := bundled[script]; ok {
+			return name, nil
+		}
+		name := path.Join(bundlePipelines, filepath.Base(script))
+		for i := 2; taken(bundled, name); i++ {
+			name = path.Join(bundlePipelines, fmt.Sprintf("%d_%s", i, filepath.Base(script)))
+		}
+		if err := addFile(zw, name, script); err != nil {
+			return "", err
+		}
+		schema := strings.TrimSuffix(script, ".py") + schemaSuffix
+		if _, err := os.Stat(schema); err == nil {
+			if err := addFile(zw, strings.TrimSuffix(name, ".py")+schemaSuffix, schema); err != nil {
+				return "", err
+			}
+		}
+		bundled[script] = name
+		return name, nil
+	}
+
+	declared := make(map[string]bool)
+	for i, pipeline := range cfg.Pipelines {
+		script := pipeline.Script
+		if !filepath.IsAbs(script) {
+			script = filepath.Join(dir, script)
+		}
+		name, err := add(script)
+		if err != nil {
+			return fmt.Errorf("pipeline %q: %v", pipeline.Name, err)
+		}
+		cfg.Pipelines[i].Script = name
+		declared[strings.ToLower(pipeline.Name)] = true
+	}
+	for _, pipeline := range extra {
+		if declared[strings.ToLower(pipeline.Name)] {
+			continue
+		}
+		name, err := add(pipeline.Script)
+		if err != nil {
+			return fmt.Errorf("pipeline %q: %v", pipeline.Name, err)
+		}
+		cfg.Pipelines = append(cfg.Pipelines, Pipeline{Name: pipeline.Name, Script: name, Description: pipeline.Description})
+	}
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if err := addData(zw, FileName, data); err != nil {
+		return err
+	}
+
+	host, _ := os.Hostname()
+	manifest, err := yaml.Marshal(Manifest{CreatedAt: time.Now(), Host: host})
+	if err != nil {
+		return err
+	}
+	if err := addData(zw, bundleManifest, manifest); err != nil {
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// taken reports whether a bundle path is already used
+func taken(bundled map[string]string, name string) bool {
+	for _, used := range bundled {
+		if used == name {
+			return true
+		}
+	}
+	return false
+}
+
+// addFile copies a file into the bundle
+func addFile(zw *zip.Writer, name, src string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return addData(zw, name, data)
+}
+
+// addData writes data to the bundle as name
+func addData(zw *zip.Writer, name string, data []byte) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// ImportBundle installs a bundle into the config directory: its scripts
+// go to pipelines/ and its config.yaml replaces the current one, which is
+// kept as config.yaml.bak. It returns the bundle's manifest and the
+// installed files.
+func ImportBundle(src string) (Manifest, []string, error) {
+	var manifest Manifest
+	dir, err := Dir()
+	if err != nil {
+		return manifest, nil, err
+	}
+
+	zr, err := zip.OpenReader(src)
+	if err != nil {
+		return manifest, nil, fmt.Errorf("not a bundle: %v", err)
+	}
+	defer zr.Close()
+
+	// Check the whole bundle before changing anything
+	files := make(map[string]*zip.File)
+	for _, file := range zr.File {
+		switch {
+		case file.Name == FileName || file.Name == bundleManifest:
+		case path.Dir(file.Name) == bundlePipelines && !strings.HasPrefix(path.Base(file.Name), "."):
+		default:
+			return manifest, nil, fmt.Errorf("unexpected file in bundle: %s", file.Name)
+		}
+		files[file.Name] = file
+	}
+	if files[FileName] == nil {
+		return manifest, nil, fmt.Errorf("bundle has no %s", FileName)
+	}
+	data, err := readZipFile(files[FileName])
+	if err != nil {
+		return manifest, nil, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return manifest, nil, fmt.Errorf("invalid %s in bundle: %v", FileName, err)
+	}
+	if file := files[bundleManifest]; file != nil {
+		if data, err := readZipFile(file); err == nil {
+			yaml.Unmarshal(data, &manifest)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, bundlePipelines), 0o755); err != nil {
+		return manifest, nil, err
+	}
+	var installed []string
+	for _, file := range zr.File {
+		name := file.Name
+		if path.Dir(name) != bundlePipelines {
+			continue
+		}
+		data, err := readZipFile(file)
+		if err != nil {
+			return manifest, installed, err
+		}
+		dest := filepath.Join(dir, bundlePipelines, path.Base(name))
+		if err := os.WriteFile(dest, data, 0o644); err != nil {
+			return manifest, installed, err
+		}
+		installed = append(installed, dest)
+	}
+
+	dest := filepath.Join(dir, FileName)
+	if err := os.Rename(dest, dest+".bak"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return manifest, installed, fmt.Errorf("failed to keep the current config: %v", err)
+	}
+	if err := os.WriteFile(dest, data, 0o644); err != nil {
+		return manifest, installed, err
+	}
+	return manifest, append(installed, dest), nil
+}
+
+// readZipFile returns the contents of a bundle entry
+func readZipFile(file *zip.File) ([]byte, error) {
+	r, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
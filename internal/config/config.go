@@ -0,0 +1,350 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cloudcompare-automation/internal/processor"
+	"github.com/cloudcompare-automation/internal/profile"
+	"github.com/cloudcompare-automation/internal/tiles"
+)
+
+// FileName is the name of the user configuration file
+const FileName = "config.yaml"
+
+// Config holds user settings shared by the TUI and the headless commands
+type Config struct {
+	// Env is added to the environment of the processing script, e.g.
+	// OMP_NUM_THREADS to control CloudComPy's threading
+	Env map[string]string `yaml:"env,omitempty"`
+
+	// GPU pins processing to one GPU via CUDA_VISIBLE_DEVICES
+	GPU string `yaml:"gpu,omitempty"`
+
+	// Threads caps the script's worker threads (0 = no cap)
+	Threads int `yaml:"threads,omitempty"`
+
+	// LowPriority runs the script at low CPU and I/O priority so a batch
+	// can run on a machine that is also in daily use
+	LowPriority bool `yaml:"low_priority,omitempty"`
+
+	// Deterministic runs the script single-threaded so a delivered mesh
+	// can be reproduced exactly; it overrides Threads
+	Deterministic bool `yaml:"deterministic,omitempty"`
+
+	// BoostSave raises the script's I/O priority for the final project
+	// write and flushes it to disk, for busy disks and NAS targets
+	BoostSave bool `yaml:"boost_save,omitempty"`
+
+	// SkipExisting leaves out LAS files whose project is already in the
+	// output directory
+	SkipExisting bool `yaml:"skip_existing,omitempty"`
+
+	// StallMinutes is how long the script may print nothing before it is
+	// warned about as possibly stalled (0 = 10 minutes)
+	StallMinutes int `yaml:"stall_minutes,omitempty"`
+
+	// AbortAfterFailures ends a batch after this many files failed in a
+	// row, and AbortFailureRate once more than this percentage of its
+	// files failed (0 = never)
+	AbortAfterFailures int     `yaml:"abort_after_failures,omitempty"`
+	AbortFailureRate   float64 `yaml:"abort_failure_rate,omitempty"`
+
+	// CrashRecovery waits CrashCooldownSeconds (0 = 30) after the script
+	// crashed, then checks the Python environment before the next file
+	CrashRecovery        bool `yaml:"crash_recovery,omitempty"`
+	CrashCooldownSeconds int  `yaml:"crash_cooldown_seconds,omitempty"`
+
+	// MemoryLimitGB caps the memory of each file's script process, which
+	// fails the file when it runs over (0 = no cap)
+	MemoryLimitGB float64 `yaml:"memory_limit_gb,omitempty"`
+
+	// Prefetch copies this many of the next files into the workspace
+	// while one is processing, for inputs on slow network storage
+	Prefetch int `yaml:"prefetch,omitempty"`
+
+	// LogBuffer is how many log entries are held for a display that has
+	// fallen behind (0 = 500), and LogOverflow what happens to those that
+	// don't fit: drop-oldest, block or spill
+	LogBuffer   int    `yaml:"log_buffer,omitempty"`
+	LogOverflow string `yaml:"log_overflow,omitempty"`
+
+	// Pipelines declares extra pipeline scripts besides the discovered
+	// *_pipeline.py ones; a relative script path is relative to Dir
+	Pipelines []Pipeline `yaml:"pipelines,omitempty"`
+
+	// Metadata is recorded in run reports and saved projects, e.g.
+	// project, client, operator and capture_date
+	Metadata map[string]string `yaml:"metadata,omitempty"`
+
+	// MetadataLookup fills in the metadata of each file from a project
+	// management table, by file name pattern
+	MetadataLookup MetadataLookup `yaml:"metadata_lookup,omitempty"`
+
+	// OutputName is the output file name template, e.g. "{project}_{name}"
+	OutputName string `yaml:"output_name,omitempty"`
+
+	// OutputLayout places the outputs in the output directory: flat,
+	// per-file, dated or mirror
+	OutputLayout string `yaml:"output_layout,omitempty"`
+
+	// Include and Exclude select the LAS files of a directory by name
+	// pattern, e.g. tile_??.las and *_preview.las
+	Include []string `yaml:"include,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty"`
+
+	// Cost sets the rates for the cost and energy estimates in reports
+	Cost Cost `yaml:"cost,omitempty"`
+
+	// PostProcess lists commands run on the outputs of every successfully
+	// processed file, in order
+	PostProcess []PostCommand `yaml:"post_process,omitempty"`
+
+	// WebExport converts every processed cloud for browser viewing:
+	// potree or 3dtiles
+	WebExport string `yaml:"web_export,omitempty"`
+
+	// Archive packs the outputs of every processed file: gzip or zip
+	Archive string `yaml:"archive,omitempty"`
+
+	// PotreeConverter is the PotreeConverter executable, if not on PATH
+	PotreeConverter string `yaml:"potree_converter,omitempty"`
+
+	// TilesOrigin places 3D Tiles exports on the globe
+	TilesOrigin *tiles.Origin `yaml:"tiles_origin,omitempty"`
+
+	// Python locates the conda environment and CloudComPy on Windows
+	Python Python `yaml:"python,omitempty"`
+
+	// Locale sets the digit grouping and decimal mark of numbers, e.g. de
+	// or fr_CH; by default it comes from LC_ALL, LC_NUMERIC or LANG
+	Locale string `yaml:"locale,omitempty"`
+
+	// Palette colors the TUI: standard, colorblind for status colors and
+	// icons that don't depend on telling red from green, or muted
+	Palette string `yaml:"palette,omitempty"`
+
+	// Colors overrides the colors the terminal is detected to support:
+	// auto, truecolor, 256, 16 or none
+	Colors string `yaml:"colors,omitempty"`
+
+	// Corporate keeps the TUI to plain ASCII and the muted palette,
+	// without animations or celebrations
+	Corporate bool `yaml:"corporate,omitempty"`
+
+	// DeliveryChecklist opens the delivery checklist after every batch in
+	// the TUI, to be acknowledged before the run is marked delivered
+	DeliveryChecklist bool `yaml:"delivery_checklist,omitempty"`
+
+	// Acceptance sets the rules the outputs of every file must meet, e.g.
+	// a minimum face count
+	Acceptance Acceptance `yaml:"acceptance,omitempty"`
+}
+
+// PostCommand is a command template run on a processed file's outputs,
+// e.g. "gltf-transform draco {mesh} {mesh%.glb}.draco.glb"
+type PostCommand struct {
+	Name    string `yaml:"name"`
+	Command string `yaml:"command"`
+}
+
+// Cost holds the rates compute time is billed back to projects at
+type Cost struct {
+	CPUHourRate float64 `yaml:"cpu_hour_rate,omitempty"` // Price of one CPU hour
+	Currency    string  `yaml:"currency,omitempty"`
+	Watts       float64 `yaml:"watts,omitempty"` // Average power draw while processing
+}
+
+// MetadataLookup is a table of metadata by file name pattern, in a CSV
+// file or printed as CSV by a command, e.g. a database query
+type MetadataLookup struct {
+	CSV     string `yaml:"csv,omitempty"`
+	Command string `yaml:"command,omitempty"`
+}
+
+// Lookup returns the metadata lookup for the processor
+func (l MetadataLookup) Lookup() processor.MetadataLookup {
+	return processor.MetadataLookup{CSV: l.CSV, Command: l.Command}
+}
+
+// Acceptance holds the acceptance rules of processed files, and whether
+// breaking one fails the file or only warns
+type Acceptance struct {
+	MinFaces     int     `yaml:"min_faces,omitempty"`
+	MaxDeviation float64 `yaml:"max_deviation,omitempty"` // 95th percentile cloud to mesh distance
+	MinDensity   float64 `yaml:"min_density,omitempty"`   // Points per square unit
+	OnViolation  string  `yaml:"on_violation,omitempty"`  // warning (default) or failed
+}
+
+// Rules returns the acceptance rules for the processor
+func (a Acceptance) Rules() processor.Acceptance {
+	return processor.Acceptance{
+		MinFaces:     a.MinFaces,
+		MaxDeviation: a.MaxDeviation,
+		MinDensity:   a.MinDensity,
+		Fail:         a.OnViolation == "failed",
+	}
+}
+
+// Python locates the environment the processing script runs in
+type Python struct {
+	CondaEnv    string `yaml:"conda_env,omitempty"`    // Environment name (default: CloudComPy311)
+	CondaPrefix string `yaml:"conda_prefix,omitempty"` // Environment directory, if conda can't tell
+	CloudComPy  string `yaml:"cloudcompy,omitempty"`   // CloudComPy installation directory
+}
+
+// PostCommands returns the post-processing commands for the processor
+func (c Config) PostCommands() []processor.PostCommand {
+	var commands []processor.PostCommand
+	for _, pc := range c.PostProcess {
+		commands = append(commands, processor.PostCommand{Name: pc.Name, Command: pc.Command})
+	}
+	return commands
+}
+
+// Env returns the configured Python environment for the processor
+func (p Python) Env() processor.PythonEnv {
+	return processor.PythonEnv{CondaEnv: p.CondaEnv, CondaPrefix: p.CondaPrefix, CloudComPy: p.CloudComPy}
+}
+
+// Rates returns the configured cost rates for the processor
+func (c Cost) Rates() processor.CostRates {
+	return processor.CostRates{CPUHour: c.CPUHourRate, Currency: c.Currency, Watts: c.Watts}
+}
+
+// Pipeline declares a selectable pipeline script. Params overrides the
+// schema file shipped next to the script.
+type Pipeline struct {
+	Name        string                `yaml:"name"`
+	Script      string                `yaml:"script"`
+	Description string                `yaml:"description,omitempty"`
+	Params      []processor.ParamSpec `yaml:"params,omitempty"`
+}
+
+// Dir returns the directory holding the shared configuration file, which
+// relative pipeline scripts are found in
+func Dir() (string, error) {
+	return profile.Base()
+}
+
+// Path returns the location of the configuration file
+func Path() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+// Load reads the configuration file, with the active profile's applied
+// over it and the CCAUTO_* environment variables over both. A missing
+// file is not an error and yields the configuration of the environment
+// alone.
+func Load() (Config, error) {
+	var cfg Config
+	path, err := Path()
+	if err != nil {
+		return cfg, err
+	}
+	if _, err := readFile(path, &cfg); err != nil {
+		return cfg, err
+	}
+
+	// The profile's settings replace the shared ones they set
+	if dir, err := profile.Dir(); err != nil {
+		return cfg, err
+	} else if own := filepath.Join(dir, FileName); own != path {
+		found, err := readFile(own, &cfg)
+		if err != nil {
+			return cfg, err
+		}
+		if found {
+			path = own
+		}
+	}
+	if err := applyEnv(&cfg); err != nil {
+		return cfg, err
+	}
+	for _, patterns := range [][]string{cfg.Include, cfg.Exclude} {
+		if err := processor.CheckPatterns(patterns); err != nil {
+			return cfg, fmt.Errorf("invalid config %s: %v", path, err)
+		}
+	}
+	if cfg.WebExport != "" {
+		if err := processor.CheckWebExport(cfg.WebExport); err != nil {
+			return cfg, fmt.Errorf("invalid config %s: %v", path, err)
+		}
+	}
+	if cfg.OutputLayout != "" {
+		if err := processor.CheckOutputLayout(cfg.OutputLayout); err != nil {
+			return cfg, fmt.Errorf("invalid config %s: %v", path, err)
+		}
+	}
+	if cfg.Archive != "" {
+		if err := processor.CheckArchive(cfg.Archive); err != nil {
+			return cfg, fmt.Errorf("invalid config %s: %v", path, err)
+		}
+	}
+	if cfg.LogOverflow != "" {
+		if err := processor.CheckLogOverflow(cfg.LogOverflow); err != nil {
+			return cfg, fmt.Errorf("invalid config %s: %v", path, err)
+		}
+	}
+	for _, c := range cfg.PostCommands() {
+		if err := processor.CheckPostCommand(c); err != nil {
+			return cfg, fmt.Errorf("invalid config %s: %v", path, err)
+		}
+	}
+	if err := processor.CheckMetadataLookup(cfg.MetadataLookup.Lookup()); err != nil {
+		return cfg, fmt.Errorf("invalid config %s: %v", path, err)
+	}
+	if err := processor.CheckAbort(processor.AbortPolicy{After: cfg.AbortAfterFailures, Rate: cfg.AbortFailureRate}); err != nil {
+		return cfg, fmt.Errorf("invalid config %s: %v", path, err)
+	}
+	if err := processor.CheckAcceptance(cfg.Acceptance.Rules()); err != nil {
+		return cfg, fmt.Errorf("invalid config %s: %v", path, err)
+	}
+	if v := cfg.Acceptance.OnViolation; v != "" && v != "warning" && v != "failed" {
+		return cfg, fmt.Errorf("invalid config %s: acceptance on_violation must be warning or failed, not %q", path, v)
+	}
+	if cfg.MemoryLimitGB < 0 {
+		return cfg, fmt.Errorf("invalid config %s: memory_limit_gb must not be negative", path)
+	}
+	if cfg.Prefetch < 0 {
+		return cfg, fmt.Errorf("invalid config %s: prefetch must not be negative", path)
+	}
+	return cfg, nil
+}
+
+// readFile reads the configuration file at path into cfg, reporting
+// whether there is one
+func readFile(path string, cfg *Config) (bool, error) {
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return false, fmt.Errorf("invalid config %s: %v", path, err)
+	}
+	return true, nil
+}
+
+// ProcessEnv returns the variables to inject into the processing script,
+// with the GPU selection applied on top of Env
+func (c Config) ProcessEnv() map[string]string {
+	env := make(map[string]string, len(c.Env)+1)
+	for key, value := range c.Env {
+		env[key] = value
+	}
+	if c.GPU != "" {
+		env["CUDA_VISIBLE_DEVICES"] = c.GPU
+	}
+	return env
+}
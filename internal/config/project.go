@@ -0,0 +1,165 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cloudcompare-automation/internal/processor"
+)
+
+// ProjectFileName is the name of a dataset's project configuration file,
+// looked up in the input directory and its parents
+const ProjectFileName = ".cloudcompare.yaml"
+
+// Project holds the settings of one dataset. They override the user
+// configuration for the directory the file is in and those below it, and
+// are overridden in turn by command-line flags and the form.
+type Project struct {
+	// Params sets pipeline parameter values by name; those the selected
+	// pipeline doesn't have are ignored
+	Params map[string]string `yaml:"params,omitempty"`
+
+	OutputSubdir string            `yaml:"output_subdir,omitempty"`
+	OutputName   string            `yaml:"output_name,omitempty"`
+	OutputLayout string            `yaml:"output_layout,omitempty"`
+	Metadata     map[string]string `yaml:"metadata,omitempty"` // Added to the user configuration's
+	Labels       []string          `yaml:"labels,omitempty"`
+	Include      []string          `yaml:"include,omitempty"`
+	Exclude      []string          `yaml:"exclude,omitempty"`
+	WebExport    string            `yaml:"web_export,omitempty"`
+	SkipExisting *bool             `yaml:"skip_existing,omitempty"`
+}
+
+// FindProject returns the project configuration file that applies to dir:
+// the one in dir or in the closest of its parents, or "" when there is
+// none
+func FindProject(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return "", nil
+	}
+	for {
+		path := filepath.Join(dir, ProjectFileName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// LoadProject reads the project configuration file that applies to dir,
+// and returns it with its path. Without one, it returns an empty project
+// and "".
+func LoadProject(dir string) (Project, string, error) {
+	var p Project
+	path, err := FindProject(dir)
+	if err != nil || path == "" {
+		return p, "", err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return p, "", nil
+		}
+		return p, path, err
+	}
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return p, path, fmt.Errorf("invalid project config %s: %v", path, err)
+	}
+	for _, patterns := range [][]string{p.Include, p.Exclude} {
+		if err := processor.CheckPatterns(patterns); err != nil {
+			return p, path, fmt.Errorf("invalid project config %s: %v", path, err)
+		}
+	}
+	if p.WebExport != "" {
+		if err := processor.CheckWebExport(p.WebExport); err != nil {
+			return p, path, fmt.Errorf("invalid project config %s: %v", path, err)
+		}
+	}
+	if p.OutputLayout != "" {
+		if err := processor.CheckOutputLayout(p.OutputLayout); err != nil {
+			return p, path, fmt.Errorf("invalid project config %s: %v", path, err)
+		}
+	}
+	return p, path, nil
+}
+
+// Apply sets the project's settings on params, checking its parameter
+// values against the pipeline's schema
+func (p Project) Apply(params *processor.Params, schema []processor.ParamSpec) error {
+	values, err := p.Values(schema)
+	if err != nil {
+		return err
+	}
+	if len(values) > 0 && params.Values == nil {
+		params.Values = make(map[string]string)
+	}
+	for name, value := range values {
+		params.Values[name] = value
+	}
+
+	if p.OutputSubdir != "" {
+		params.OutputSubdir = p.OutputSubdir
+	}
+	if p.OutputName != "" {
+		params.OutputName = p.OutputName
+	}
+	if p.OutputLayout != "" {
+		params.OutputLayout = p.OutputLayout
+	}
+	if len(p.Metadata) > 0 {
+		metadata := make(map[string]string, len(params.Metadata)+len(p.Metadata))
+		for key, value := range params.Metadata {
+			metadata[key] = value
+		}
+		for key, value := range p.Metadata {
+			metadata[key] = value
+		}
+		params.Metadata = metadata
+	}
+	if len(p.Labels) > 0 {
+		params.Labels = p.Labels
+	}
+	if len(p.Include) > 0 {
+		params.Include = p.Include
+	}
+	if len(p.Exclude) > 0 {
+		params.Exclude = p.Exclude
+	}
+	if p.WebExport != "" {
+		params.WebExport = p.WebExport
+	}
+	if p.SkipExisting != nil {
+		params.SkipExisting = *p.SkipExisting
+	}
+	return nil
+}
+
+// Values returns the project's parameter values the schema has, checked
+func (p Project) Values(schema []processor.ParamSpec) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, spec := range schema {
+		value, ok := p.Params[spec.Name]
+		if !ok {
+			continue
+		}
+		v, err := spec.Validate(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", spec.Name, err)
+		}
+		values[spec.Name] = v
+	}
+	return values, nil
+}
@@ -0,0 +1,185 @@
+package descriptor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/cloudcompare-automation/internal/processor"
+	"github.com/cloudcompare-automation/internal/report"
+	"github.com/cloudcompare-automation/internal/update"
+)
+
+// A descriptor records a run completely: the parameters, the input files
+// as they were and the software that processed them. Loading it later
+// repeats the run on the same files with the same settings, and reports
+// what changed since.
+
+// Extension is the file name extension of descriptors
+const Extension = ".ccrun.json"
+
+// formatVersion is increased when the format changes incompatibly
+const formatVersion = 1
+
+// Descriptor is a complete description of a run
+type Descriptor struct {
+	FormatVersion int              `json:"format_version"`
+	CreatedAt     time.Time        `json:"created_at"`
+	Params        processor.Params `json:"params"` // Files lists the inputs, so the same files run again
+	Files         []File           `json:"files"`
+	Script        Script           `json:"script"`
+	Tool          string           `json:"tool"` // cloudcompare-tui version
+	Host          Host             `json:"host"`
+}
+
+// File is an input file as it was when the descriptor was made
+type File struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Script identifies the pipeline script that ran
+type Script struct {
+	Path    string `json:"path"`
+	Version string `json:"version,omitempty"`
+	SHA256  string `json:"sha256"`
+}
+
+// Host is the machine the descriptor was made on
+type Host struct {
+	Name string `json:"name"`
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+}
+
+// New describes a run of params on files with the script at scriptPath
+func New(params processor.Params, files []string, scriptPath, tool string) (Descriptor, error) {
+	d := Descriptor{
+		FormatVersion: formatVersion,
+		CreatedAt:     time.Now(),
+		Params:        params,
+		Tool:          tool,
+		Host:          currentHost(),
+	}
+	d.Params.InputFile = ""
+	d.Params.Files = files
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			return Descriptor{}, err
+		}
+		d.Files = append(d.Files, File{Path: path, Size: info.Size(), ModTime: info.ModTime()})
+	}
+
+	script, err := describeScript(scriptPath)
+	if err != nil {
+		return Descriptor{}, err
+	}
+	d.Script = script
+	return d, nil
+}
+
+// Describe describes the run p would do now, on the files it would list
+func Describe(p *processor.Processor, tool string) (Descriptor, error) {
+	if p.ScriptPath() == "" {
+		if err := p.FindScripts(); err != nil {
+			return Descriptor{}, err
+		}
+	}
+	if err := p.ValidateInputDir(); err != nil {
+		return Descriptor{}, err
+	}
+	files, err := p.ListLASFiles()
+	if err != nil {
+		return Descriptor{}, err
+	}
+	return New(p.GetParams(), files, p.ScriptPath(), tool)
+}
+
+// Save writes the descriptor to path
+func (d Descriptor) Save(path string) error {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write run descriptor: %v", err)
+	}
+	return nil
+}
+
+// Load reads the descriptor at path
+func Load(path string) (Descriptor, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Descriptor{}, fmt.Errorf("failed to read run descriptor: %v", err)
+	}
+	var d Descriptor
+	if err := json.Unmarshal(data, &d); err != nil {
+		return Descriptor{}, fmt.Errorf("invalid run descriptor %s: %v", filepath.Base(path), err)
+	}
+	if d.FormatVersion != formatVersion {
+		return Descriptor{}, fmt.Errorf("run descriptor %s has format version %d, expected %d", filepath.Base(path), d.FormatVersion, formatVersion)
+	}
+	if len(d.Params.Files) == 0 {
+		return Descriptor{}, fmt.Errorf("run descriptor %s lists no files", filepath.Base(path))
+	}
+	return d, nil
+}
+
+// Differences lists what would make repeating the run differ from the
+// original: input files that changed or are missing, another pipeline
+// script, tool version or platform. scriptPath is the script that would
+// run now.
+func (d Descriptor) Differences(scriptPath, tool string) []string {
+	var diffs []string
+	for _, f := range d.Files {
+		info, err := os.Stat(f.Path)
+		switch {
+		case err != nil:
+			diffs = append(diffs, fmt.Sprintf("%s is missing", f.Path))
+		case info.Size() != f.Size || !info.ModTime().Equal(f.ModTime):
+			diffs = append(diffs, fmt.Sprintf("%s changed since %s", f.Path, f.ModTime.Format("2006-01-02 15:04")))
+		}
+	}
+
+	if script, err := describeScript(scriptPath); err != nil {
+		diffs = append(diffs, fmt.Sprintf("pipeline script: %v", err))
+	} else if script.SHA256 != d.Script.SHA256 {
+		diffs = append(diffs, fmt.Sprintf("pipeline script differs: %s (%s) instead of %s (%s)",
+			script.Path, versionOrUnknown(script.Version), d.Script.Path, versionOrUnknown(d.Script.Version)))
+	}
+	if tool != d.Tool {
+		diffs = append(diffs, fmt.Sprintf("cloudcompare-tui %s instead of %s", tool, d.Tool))
+	}
+	if host := currentHost(); host.OS != d.Host.OS || host.Arch != d.Host.Arch {
+		diffs = append(diffs, fmt.Sprintf("running on %s/%s instead of %s/%s", host.OS, host.Arch, d.Host.OS, d.Host.Arch))
+	}
+	return diffs
+}
+
+// describeScript hashes the script at path and reads its version
+func describeScript(path string) (Script, error) {
+	sum, err := report.FileSHA256(path)
+	if err != nil {
+		return Script{}, err
+	}
+	version, _ := update.ScriptVersion(path)
+	return Script{Path: path, Version: version, SHA256: sum}, nil
+}
+
+func currentHost() Host {
+	name, _ := os.Hostname()
+	return Host{Name: name, OS: runtime.GOOS, Arch: runtime.GOARCH}
+}
+
+func versionOrUnknown(v string) string {
+	if v == "" {
+		return "unknown version"
+	}
+	return v
+}
@@ -0,0 +1,64 @@
+package history
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// Calibration is the processing speed measured in earlier runs, by octree
+// depth
+type Calibration map[int]Speed
+
+// Speed is the time earlier runs at one depth took for their points
+type Speed struct {
+	Runs    int
+	Points  int64
+	Seconds float64
+}
+
+// Calibrate measures the processing speed of the complete runs in entries.
+// Runs with failures, without a point count or with an automatic depth
+// are left out, as their time doesn't match their points or depth.
+func Calibrate(entries []Entry) Calibration {
+	c := make(Calibration)
+	for _, e := range entries {
+		depth, err := strconv.Atoi(e.Params["octree-depth"])
+		if err != nil || e.Points == 0 || e.Failed > 0 || e.Stopped || !e.FinishedAt.After(e.StartedAt) {
+			continue
+		}
+		s := c[depth]
+		s.Runs++
+		s.Points += e.Points
+		s.Seconds += e.FinishedAt.Sub(e.StartedAt).Seconds()
+		c[depth] = s
+	}
+	return c
+}
+
+// Estimate returns the time to process points at depth. Without runs at
+// that depth the speed of the nearest measured depth is scaled by 4 per
+// level, as PoissonRecon's leaf cells on a surface quadruple with each
+// level; from is the depth the estimate is based on. ok is false when
+// nothing has been measured.
+func (c Calibration) Estimate(points uint64, depth int) (d time.Duration, from int, ok bool) {
+	from = -1
+	for measured := range c {
+		if from < 0 || abs(measured-depth) < abs(from-depth) || (abs(measured-depth) == abs(from-depth) && measured > from) {
+			from = measured
+		}
+	}
+	if from < 0 {
+		return 0, 0, false
+	}
+	s := c[from]
+	seconds := s.Seconds / float64(s.Points) * float64(points) * math.Pow(4, float64(depth-from))
+	return time.Duration(seconds * float64(time.Second)), from, true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
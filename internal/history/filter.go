@@ -0,0 +1,80 @@
+package history
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Outcomes of a run as a whole, from worst to best
+var Outcomes = []string{"stopped", "aborted", "failed", "warning", "success"}
+
+// Outcome returns how the run went as a whole: stopped, aborted when too
+// many files failed, failed when a file failed, warning when one completed
+// with warnings, or success
+func (e Entry) Outcome() string {
+	switch {
+	case e.Stopped:
+		return "stopped"
+	case e.Aborted != "":
+		return "aborted"
+	case e.Failed > 0:
+		return "failed"
+	case e.Warned > 0:
+		return "warning"
+	}
+	return "success"
+}
+
+// Filter selects runs; zero fields select every run
+type Filter struct {
+	Since   time.Time // Started at or after
+	Until   time.Time // Started before
+	Label   string    // Has a label containing it, see Matches
+	Dir     string    // Input path containing it, ignoring case
+	Outcome string    // One of Outcomes
+}
+
+// CheckOutcome reports an unknown outcome name
+func CheckOutcome(outcome string) error {
+	if slices.Contains(Outcomes, outcome) {
+		return nil
+	}
+	return fmt.Errorf("outcome must be one of %s", strings.Join(Outcomes, ", "))
+}
+
+// Match reports whether the run passes the filter
+func (f Filter) Match(e Entry) bool {
+	switch {
+	case !f.Since.IsZero() && e.StartedAt.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !e.StartedAt.Before(f.Until):
+		return false
+	case f.Dir != "" && !strings.Contains(strings.ToLower(e.Input), strings.ToLower(f.Dir)):
+		return false
+	case f.Outcome != "" && e.Outcome() != f.Outcome:
+		return false
+	}
+	return e.Matches(f.Label)
+}
+
+// Find returns the run whose ID is id, or the only one starting with it
+func Find(entries []Entry, id string) (Entry, error) {
+	var found []Entry
+	for _, e := range entries {
+		if e.ID == id {
+			return e, nil
+		}
+		if strings.HasPrefix(e.ID, id) {
+			found = append(found, e)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Entry{}, fmt.Errorf("no run %s in the history", id)
+	case 1:
+		return found[0], nil
+	}
+	return Entry{}, fmt.Errorf("%d runs start with %s; give more of the ID", len(found), id)
+}
@@ -0,0 +1,263 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudcompare-automation/internal/profile"
+	"github.com/cloudcompare-automation/internal/report"
+)
+
+// The history keeps a summary of every finished run, one JSON file per run
+// so concurrent workers never write the same file. Unlike the sessions and
+// the workspace it lives in the config directory, as it is not a cache,
+// and each profile has its own.
+
+// Entry summarizes one run
+type Entry struct {
+	ID         string            `json:"id"`
+	Pipeline   string            `json:"pipeline"`
+	Input      string            `json:"input"`
+	OutputDir  string            `json:"output_dir"`
+	Params     map[string]string `json:"params"`
+	Labels     []string          `json:"labels,omitempty"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+	Total      int               `json:"total"`
+	Succeeded  int               `json:"succeeded"` // Without warnings
+	Warned     int               `json:"warned"`
+	Failed     int               `json:"failed"`
+	Stopped    bool              `json:"stopped,omitempty"`
+	Aborted    string            `json:"aborted,omitempty"`
+	Points     int64             `json:"points,omitempty"` // Points in the processed input clouds
+	CPUSeconds float64           `json:"cpu_seconds,omitempty"`
+	Estimate   *report.Estimate  `json:"estimate,omitempty"`
+	Report     string            `json:"report,omitempty"` // Path of the run's report
+
+	// Reviews holds the operator's notes and sign-offs, by input file
+	Reviews map[string]report.Review `json:"reviews,omitempty"`
+
+	// Delivered is set once the delivery checklist of the run was
+	// acknowledged
+	Delivered *Delivery `json:"delivered,omitempty"`
+}
+
+// Delivery records who acknowledged the delivery checklist of a run, and
+// when
+type Delivery struct {
+	At time.Time `json:"at"`
+	By string    `json:"by,omitempty"`
+
+	// Waived are the checklist items that weren't met but were
+	// acknowledged anyway
+	Waived []string `json:"waived,omitempty"`
+}
+
+// Root returns the directory holding the history entries of the active
+// profile
+func Root() (string, error) {
+	dir, err := profile.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history"), nil
+}
+
+// FromReport summarizes a run from its report, written to reportPath
+func FromReport(r report.Report, reportPath string) Entry {
+	var points int64
+	for _, f := range r.Files {
+		points += f.Points
+	}
+	return Entry{
+		Pipeline:   r.Pipeline,
+		Input:      r.Input,
+		OutputDir:  r.OutputDir,
+		Params:     r.Params,
+		Labels:     r.Labels,
+		StartedAt:  r.StartedAt,
+		FinishedAt: r.FinishedAt,
+		Total:      r.Total,
+		Succeeded:  r.Succeeded,
+		Warned:     r.Warned,
+		Failed:     r.Failed,
+		Stopped:    r.Stopped,
+		Aborted:    r.Aborted,
+		Points:     points,
+		CPUSeconds: r.CPUSeconds,
+		Estimate:   r.Estimate,
+		Report:     reportPath,
+	}
+}
+
+// Record adds a run to the history and returns it with its ID set
+func Record(e Entry) (Entry, error) {
+	// The pid keeps runs of workers started in the same millisecond apart
+	e.ID = fmt.Sprintf("%s-%d", e.StartedAt.Format("20060102-150405.000"), os.Getpid())
+	return e, write(e)
+}
+
+// List returns the recorded runs, newest first. Unreadable entries are
+// skipped.
+func List() ([]Entry, error) {
+	root, err := Root()
+	if err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(root, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(data, &e); err != nil || e.ID == "" {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].StartedAt.After(entries[j].StartedAt) })
+	return entries, nil
+}
+
+// SetLabels replaces the labels of a recorded run, in the history and in
+// the run's report
+func SetLabels(e Entry, labels []string) (Entry, error) {
+	e.Labels = labels
+	if err := write(e); err != nil {
+		return e, err
+	}
+	if e.Report != "" {
+		if err := report.SetLabels(e.Report, labels); err != nil {
+			return e, err
+		}
+	}
+	return e, nil
+}
+
+// SetReview replaces the review of a file in the report at reportPath and
+// in the run of the history that wrote it; a zero review removes it
+func SetReview(reportPath, input string, review report.Review) error {
+	if err := report.SetReview(reportPath, input, review); err != nil {
+		return err
+	}
+
+	entries, err := List()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.Report != reportPath {
+			continue
+		}
+		if review.IsZero() {
+			delete(e.Reviews, input)
+		} else {
+			if e.Reviews == nil {
+				e.Reviews = make(map[string]report.Review)
+			}
+			e.Reviews[input] = review
+		}
+		return write(e)
+	}
+	// The run isn't in the history when recording it failed
+	return nil
+}
+
+// String describes the delivery, e.g. "2026-03-14 15:04 by jsmith"
+func (d Delivery) String() string {
+	s := d.At.Local().Format("2006-01-02 15:04")
+	if d.By != "" {
+		s += " by " + d.By
+	}
+	if len(d.Waived) > 0 {
+		s += ", waiving: " + strings.Join(d.Waived, ", ")
+	}
+	return s
+}
+
+// SetDelivered marks the run of the history that wrote the report at
+// reportPath as delivered
+func SetDelivered(reportPath string, d Delivery) (Entry, error) {
+	entries, err := List()
+	if err != nil {
+		return Entry{}, err
+	}
+	for _, e := range entries {
+		if e.Report != reportPath {
+			continue
+		}
+		e.Delivered = &d
+		return e, write(e)
+	}
+	return Entry{}, fmt.Errorf("the run isn't in the history")
+}
+
+// Path returns the file an entry is saved in
+func Path(e Entry) (string, error) {
+	root, err := Root()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, e.ID+".json"), nil
+}
+
+// write saves an entry under its ID
+func write(e Entry) error {
+	root, err := Root()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return fmt.Errorf("failed to create history directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(root, e.ID+".json"), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write history: %v", err)
+	}
+	return nil
+}
+
+// ParseLabels splits a comma or space separated list of labels, dropping
+// duplicates (case-insensitively) and keeping the first spelling
+func ParseLabels(s string) []string {
+	var labels []string
+	seen := make(map[string]bool)
+	for _, label := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		key := strings.ToLower(label)
+		if !seen[key] {
+			seen[key] = true
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// Matches reports whether the run has a label containing filter
+// (case-insensitively); every run matches an empty filter
+func (e Entry) Matches(filter string) bool {
+	filter = strings.ToLower(strings.TrimSpace(filter))
+	if filter == "" {
+		return true
+	}
+	for _, label := range e.Labels {
+		if strings.Contains(strings.ToLower(label), filter) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package history
+
+import "time"
+
+// Period aggregates the runs that started in one calendar month
+type Period struct {
+	Start  time.Time // First day of the month
+	Runs   int
+	Files  int // Files processed: succeeded, warned or failed
+	Failed int
+	Points int64
+	Hours  float64 // Wall-clock compute time
+}
+
+// FailureRate returns the share of processed files that failed, 0-1
+func (p Period) FailureRate() float64 {
+	if p.Files == 0 {
+		return 0
+	}
+	return float64(p.Failed) / float64(p.Files)
+}
+
+// Monthly aggregates entries into the given number of calendar months
+// ending with the month of now, oldest first. Runs outside that range
+// are ignored.
+func Monthly(entries []Entry, months int, now time.Time) []Period {
+	first := time.Date(now.Year(), now.Month()-time.Month(months-1), 1, 0, 0, 0, 0, now.Location())
+	periods := make([]Period, months)
+	for i := range periods {
+		periods[i].Start = first.AddDate(0, i, 0)
+	}
+
+	for _, e := range entries {
+		started := e.StartedAt.In(now.Location())
+		i := (started.Year()-first.Year())*12 + int(started.Month()-first.Month())
+		if i < 0 || i >= months {
+			continue
+		}
+		p := &periods[i]
+		p.Runs++
+		p.Files += e.Succeeded + e.Warned + e.Failed
+		p.Failed += e.Failed
+		p.Points += e.Points
+		if e.FinishedAt.After(e.StartedAt) {
+			p.Hours += e.FinishedAt.Sub(e.StartedAt).Hours()
+		}
+	}
+	return periods
+}
+
+// Total sums periods into one
+func Total(periods []Period) Period {
+	var total Period
+	for _, p := range periods {
+		total.Runs += p.Runs
+		total.Files += p.Files
+		total.Failed += p.Failed
+		total.Points += p.Points
+		total.Hours += p.Hours
+	}
+	if len(periods) > 0 {
+		total.Start = periods[0].Start
+	}
+	return total
+}
@@ -0,0 +1,169 @@
+package humanize
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Point counts, face counts and durations are shown on the processing and
+// results screens, in logs and in reports; formatting them all here keeps
+// them alike, e.g. "182.4M points" and "2h 14m", with the digit grouping
+// and decimal mark of the user's locale.
+
+// separators are the digit grouping and decimal marks of a locale
+type separators struct {
+	group, decimal string
+}
+
+var (
+	english = separators{",", "."}
+	dotted  = separators{".", ","}
+	spaced  = separators{"\u00a0", ","} // A space that doesn't break lines
+	swiss   = separators{"'", "."}
+)
+
+// languages maps languages to their separators; others are written as in
+// English
+var languages = map[string]separators{
+	"da": dotted, "de": dotted, "el": dotted, "es": dotted, "hr": dotted, "id": dotted,
+	"it": dotted, "nl": dotted, "pt": dotted, "ro": dotted, "sl": dotted, "tr": dotted,
+	"bg": spaced, "cs": spaced, "et": spaced, "fi": spaced, "fr": spaced, "hu": spaced,
+	"lt": spaced, "lv": spaced, "nb": spaced, "nn": spaced, "no": spaced, "pl": spaced,
+	"ru": spaced, "sk": spaced, "sv": spaced, "uk": spaced,
+}
+
+var (
+	mu      sync.RWMutex
+	current = lookup(envLocale())
+)
+
+// envLocale returns the locale of the environment, as POSIX programs
+// choose it for numbers; Windows usually sets none of these
+func envLocale() string {
+	for _, name := range []string{"LC_ALL", "LC_NUMERIC", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// lookup returns the separators of a locale such as de_DE.UTF-8, fr-CH or
+// nl
+func lookup(locale string) separators {
+	locale, _, _ = strings.Cut(locale, ".")
+	locale, _, _ = strings.Cut(locale, "@")
+	lang, region, _ := strings.Cut(strings.ReplaceAll(locale, "-", "_"), "_")
+	lang, region = strings.ToLower(lang), strings.ToUpper(region)
+	if region == "CH" || region == "LI" {
+		return swiss
+	}
+	if s, ok := languages[lang]; ok {
+		return s
+	}
+	return english
+}
+
+// SetLocale formats numbers for locale, e.g. de or fr_CH; an empty locale
+// keeps the one of the environment
+func SetLocale(locale string) {
+	if locale == "" {
+		return
+	}
+	mu.Lock()
+	current = lookup(locale)
+	mu.Unlock()
+}
+
+func seps() separators {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Int formats n in full with digit grouping, e.g. 182,400,000
+func Int(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	sign := ""
+	if n < 0 {
+		sign, s = "-", s[1:]
+	}
+	group := seps().group
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + group + s[i:]
+	}
+	return sign + s
+}
+
+// Float formats v with prec decimals and the locale's decimal mark
+func Float(v float64, prec int) string {
+	s := strconv.FormatFloat(v, 'f', prec, 64)
+	return strings.Replace(s, ".", seps().decimal, 1)
+}
+
+// Count shortens a large count to three or four digits, e.g. 950, 9,500,
+// 12.3K, 182.4M or 1.2B
+func Count(n int64) string {
+	v := float64(n)
+	switch {
+	case n >= 1e9 || n <= -1e9:
+		return Float(v/1e9, 1) + "B"
+	case n >= 1e6 || n <= -1e6:
+		return Float(v/1e6, 1) + "M"
+	case n >= 1e4 || n <= -1e4:
+		return Float(v/1e3, 1) + "K"
+	default:
+		return Int(n)
+	}
+}
+
+// Bytes formats a size in bytes, e.g. 312 B, 48 KB, 512 MB or 6.1 GB
+func Bytes(n uint64) string {
+	switch {
+	case n >= 1<<30:
+		return Float(float64(n)/(1<<30), 1) + " GB"
+	case n >= 1<<20:
+		return fmt.Sprintf("%d MB", n>>20)
+	case n >= 1<<10:
+		return fmt.Sprintf("%d KB", n>>10)
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+// Duration formats d in its two largest units, e.g. 2h 14m, 3m 5s, 45s,
+// or 4.2s below ten seconds
+func Duration(d time.Duration) string {
+	if d < 0 {
+		return "-" + Duration(-d)
+	}
+	switch {
+	case d >= 24*time.Hour:
+		d = d.Round(time.Hour)
+		return fmt.Sprintf("%dd %dh", d/(24*time.Hour), d%(24*time.Hour)/time.Hour)
+	case d >= time.Hour:
+		if d = d.Round(time.Minute); d >= 24*time.Hour {
+			return Duration(d)
+		}
+		return fmt.Sprintf("%dh %dm", d/time.Hour, d%time.Hour/time.Minute)
+	case d >= time.Minute:
+		if d = d.Round(time.Second); d >= time.Hour {
+			return Duration(d)
+		}
+		return fmt.Sprintf("%dm %ds", d/time.Minute, d%time.Minute/time.Second)
+	case d >= 10*time.Second:
+		if d = d.Round(time.Second); d >= time.Minute {
+			return Duration(d)
+		}
+		return fmt.Sprintf("%ds", d/time.Second)
+	default:
+		if d = d.Round(100 * time.Millisecond); d >= 10*time.Second {
+			return Duration(d)
+		}
+		return Float(d.Seconds(), 1) + "s"
+	}
+}
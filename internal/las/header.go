@@ -0,0 +1,116 @@
+package las
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// Header holds the parts of a LAS public header block needed to size
+// processing, the point count and the bounding box, and to read the points
+type Header struct {
+	VersionMajor uint8
+	VersionMinor uint8
+	PointCount   uint64
+	MinX, MaxX   float64
+	MinY, MaxY   float64
+	MinZ, MaxZ   float64
+
+	DataOffset   uint32     // Start of the point records
+	PointFormat  uint8      // Point data record format; bits 6-7 mark LAZ compression
+	RecordLength uint16     // Size of a point record
+	Scale        [3]float64 // Scale factors of the X, Y and Z integers
+	Offset       [3]float64 // Offsets added to the scaled X, Y and Z
+}
+
+// Offsets in the public header block (LAS 1.0-1.4)
+const (
+	offsetVersion     = 24
+	offsetDataOffset  = 96
+	offsetPointFormat = 104
+	offsetRecordLen   = 105
+	offsetScale       = 131 // X, Y, Z scale factors, then X, Y, Z offsets
+	offsetLegacyCount = 107
+	offsetBounds      = 179 // Max X, Min X, Max Y, Min Y, Max Z, Min Z
+	offsetCount14     = 247 // 64-bit point count, LAS 1.4 only
+	minHeaderSize     = 227
+	header14Size      = 375
+)
+
+// ReadHeader reads the public header block of a LAS file
+func ReadHeader(path string) (Header, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Header{}, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, header14Size)
+	n, err := io.ReadAtLeast(f, buf, minHeaderSize)
+	if err != nil {
+		return Header{}, fmt.Errorf("%s: header too short: %v", path, err)
+	}
+	buf = buf[:n]
+	if string(buf[:4]) != "LASF" {
+		return Header{}, fmt.Errorf("%s: not a LAS file", path)
+	}
+
+	le := binary.LittleEndian
+	float := func(off int) float64 { return math.Float64frombits(le.Uint64(buf[off:])) }
+
+	h := Header{
+		VersionMajor: buf[offsetVersion],
+		VersionMinor: buf[offsetVersion+1],
+		PointCount:   uint64(le.Uint32(buf[offsetLegacyCount:])),
+		MaxX:         float(offsetBounds),
+		MinX:         float(offsetBounds + 8),
+		MaxY:         float(offsetBounds + 16),
+		MinY:         float(offsetBounds + 24),
+		MaxZ:         float(offsetBounds + 32),
+		MinZ:         float(offsetBounds + 40),
+		DataOffset:   le.Uint32(buf[offsetDataOffset:]),
+		PointFormat:  buf[offsetPointFormat],
+		RecordLength: le.Uint16(buf[offsetRecordLen:]),
+	}
+	for i := 0; i < 3; i++ {
+		h.Scale[i] = float(offsetScale + 8*i)
+		h.Offset[i] = float(offsetScale + 24 + 8*i)
+	}
+	// LAS 1.4 files may leave the legacy count at zero
+	if h.VersionMajor == 1 && h.VersionMinor >= 4 && len(buf) >= header14Size {
+		if count := le.Uint64(buf[offsetCount14:]); count > 0 {
+			h.PointCount = count
+		}
+	}
+	return h, nil
+}
+
+// Spacing returns the average point spacing, with the points spread
+// over the surface Area
+func (h Header) Spacing() float64 {
+	if h.PointCount == 0 {
+		return 0
+	}
+	return math.Sqrt(h.Area() / float64(h.PointCount))
+}
+
+// Area returns the surface the survey covers. Surveys are treated as
+// surfaces, so this is the horizontal extent, or the vertical one for a
+// cloud that is flat in X or Y (a wall).
+func (h Header) Area() float64 {
+	dx, dy, dz := h.MaxX-h.MinX, h.MaxY-h.MinY, h.MaxZ-h.MinZ
+	if area := dx * dy; area > 0 {
+		return area
+	}
+	if wall := math.Max(dx, dy) * dz; wall > 0 {
+		return wall
+	}
+	return 0
+}
+
+// Extent returns the largest side of the bounding box
+func (h Header) Extent() float64 {
+	return math.Max(h.MaxX-h.MinX, math.Max(h.MaxY-h.MinY, h.MaxZ-h.MinZ))
+}
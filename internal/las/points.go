@@ -0,0 +1,91 @@
+package las
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Point is a point of a LAS file in real-world coordinates
+type Point struct {
+	X, Y, Z float64
+	R, G, B uint16 // Zero when the format has no colors
+}
+
+// colorOffset returns the offset of the RGB values in a point record of
+// the given format, or -1 for formats without colors
+func colorOffset(format uint8) int {
+	switch format {
+	case 2:
+		return 20
+	case 3, 5:
+		return 28
+	case 7, 8, 10:
+		return 30
+	}
+	return -1
+}
+
+// HasColor reports whether the points carry RGB values
+func (h Header) HasColor() bool {
+	return colorOffset(h.PointFormat) >= 0
+}
+
+// ReadPoints reads the header of a LAS file and calls fn with every step-th
+// point, for sampling large files; a step of 1 reads every point.
+// Compressed (LAZ) point data is not supported.
+func ReadPoints(path string, step int, fn func(Point)) (Header, error) {
+	h, err := ReadHeader(path)
+	if err != nil {
+		return h, err
+	}
+	if h.PointFormat&0xC0 != 0 {
+		return h, fmt.Errorf("%s: compressed point data is not supported", path)
+	}
+	record := int(h.RecordLength)
+	color := colorOffset(h.PointFormat)
+	if record < 12 || (color >= 0 && record < color+6) {
+		return h, fmt.Errorf("%s: invalid point record length %d", path, record)
+	}
+	if step < 1 {
+		step = 1
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return h, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(int64(h.DataOffset), io.SeekStart); err != nil {
+		return h, err
+	}
+
+	r := bufio.NewReaderSize(f, 1<<20)
+	le := binary.LittleEndian
+	buf := make([]byte, record)
+	for i := uint64(0); i < h.PointCount; i++ {
+		if i%uint64(step) != 0 {
+			if _, err := r.Discard(record); err != nil {
+				return h, fmt.Errorf("%s: point data truncated", path)
+			}
+			continue
+		}
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return h, fmt.Errorf("%s: point data truncated", path)
+		}
+		p := Point{
+			X: float64(int32(le.Uint32(buf[0:])))*h.Scale[0] + h.Offset[0],
+			Y: float64(int32(le.Uint32(buf[4:])))*h.Scale[1] + h.Offset[1],
+			Z: float64(int32(le.Uint32(buf[8:])))*h.Scale[2] + h.Offset[2],
+		}
+		if color >= 0 {
+			p.R = le.Uint16(buf[color:])
+			p.G = le.Uint16(buf[color+2:])
+			p.B = le.Uint16(buf[color+4:])
+		}
+		fn(p)
+	}
+	return h, nil
+}
@@ -0,0 +1,240 @@
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Runs lock their output directory so two instances never write the same
+// outputs. A TUI or session run takes the exclusive lock; queue workers,
+// which share an output directory by design, take shared locks that only
+// keep exclusive runs out. Locks are files in DirName under the output
+// directory, kept fresh by a heartbeat, so a lock left by a crashed or
+// powered-off machine expires on its own.
+
+const (
+	// DirName holds the lock files inside the output directory
+	DirName = ".cclock"
+
+	exclusiveFile = "run.lock"
+	sharedPrefix  = "worker-"
+
+	// heartbeatInterval is how often a held lock is touched
+	heartbeatInterval = 30 * time.Second
+	// staleAfter is how long without a heartbeat before a lock is ignored.
+	// The heartbeat is compared with this machine's clock, and network
+	// shares cache modification times, so it allows for clocks minutes
+	// apart, like the leases of the queue.
+	staleAfter = 10 * time.Minute
+)
+
+// Holder describes the process holding a lock
+type Holder struct {
+	PID       int       `json:"pid"`
+	Host      string    `json:"host"`
+	StartedAt time.Time `json:"started_at"`
+	Shared    bool      `json:"shared,omitempty"`
+}
+
+// BusyError is returned when another run holds the output directory
+type BusyError struct {
+	Dir    string
+	Holder Holder
+}
+
+func (e *BusyError) Error() string {
+	kind := "another run"
+	if e.Holder.Shared {
+		kind = "a queue worker"
+	}
+	return fmt.Sprintf("%s is active in %s (PID %d on %s, started %s)",
+		kind, e.Dir, e.Holder.PID, e.Holder.Host, e.Holder.StartedAt.Format("2006-01-02 15:04"))
+}
+
+// Lock is a held lock; Release it when the run ends
+type Lock struct {
+	path   string
+	holder Holder
+	stop   chan struct{}
+}
+
+// Acquire takes the exclusive lock on an output directory. It fails with
+// a *BusyError while any other run or worker holds a lock on it.
+func Acquire(dir string) (*Lock, error) {
+	lockDir := filepath.Join(dir, DirName)
+	path := filepath.Join(lockDir, exclusiveFile)
+	l, err := create(lockDir, path, false)
+	if err != nil {
+		return nil, err
+	}
+	// A worker may have joined in the meantime
+	if holder, ok := liveShared(lockDir); ok {
+		l.Release()
+		return nil, &BusyError{Dir: dir, Holder: holder}
+	}
+	return l, nil
+}
+
+// AcquireShared takes a shared lock on an output directory for a queue
+// worker. It fails with a *BusyError while an exclusive run holds it.
+func AcquireShared(dir string) (*Lock, error) {
+	lockDir := filepath.Join(dir, DirName)
+	host, _ := os.Hostname()
+	path := filepath.Join(lockDir, fmt.Sprintf("%s%s-%d.lock", sharedPrefix, host, os.Getpid()))
+	l, err := create(lockDir, path, true)
+	if err != nil {
+		return nil, err
+	}
+	if holder, ok := live(filepath.Join(lockDir, exclusiveFile)); ok {
+		l.Release()
+		return nil, &BusyError{Dir: dir, Holder: holder}
+	}
+	return l, nil
+}
+
+// Active returns the holder of a live lock on an output directory, if any
+func Active(dir string) (Holder, bool) {
+	lockDir := filepath.Join(dir, DirName)
+	if holder, ok := live(filepath.Join(lockDir, exclusiveFile)); ok {
+		return holder, true
+	}
+	return liveShared(lockDir)
+}
+
+// Release removes the lock
+func (l *Lock) Release() {
+	if l == nil {
+		return
+	}
+	close(l.stop)
+	// Unless the lock went stale and another run took it over
+	var holder Holder
+	if data, err := os.ReadFile(l.path); err == nil && json.Unmarshal(data, &holder) == nil && holder.same(l.holder) {
+		os.Remove(l.path)
+	}
+	os.Remove(filepath.Dir(l.path)) // Only succeeds once no lock is left
+}
+
+// create writes the lock file at path and starts its heartbeat. An
+// existing live lock there is reported as busy; a stale one is replaced.
+func create(lockDir, path string, shared bool) (*Lock, error) {
+	if err := os.MkdirAll(lockDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %v", err)
+	}
+	host, _ := os.Hostname()
+	holder := Holder{PID: os.Getpid(), Host: host, StartedAt: time.Now(), Shared: shared}
+	data, err := json.Marshal(holder)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			_, err = f.Write(data)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, fmt.Errorf("failed to write lock: %v", err)
+			}
+			break
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock: %v", err)
+		}
+		if holder, ok := live(path); ok {
+			return nil, &BusyError{Dir: filepath.Dir(lockDir), Holder: holder}
+		}
+		if attempt > 0 {
+			return nil, fmt.Errorf("failed to create lock: %v", err)
+		}
+		removeStale(path)
+	}
+
+	l := &Lock{path: path, holder: holder, stop: make(chan struct{})}
+	go l.heartbeat()
+	return l, nil
+}
+
+// removeStale removes the stale lock at path. Another run that found it
+// stale too may have replaced it with its own lock since, so it is moved
+// aside first, which only one run can do, and put back if it turns out
+// to be live.
+func removeStale(path string) {
+	aside := fmt.Sprintf("%s.stale-%d", path, os.Getpid())
+	if err := os.Rename(path, aside); err != nil {
+		return
+	}
+	if _, ok := live(aside); ok {
+		// Unless yet another run took the lock in the meantime
+		err := os.Link(aside, path)
+		if err != nil && !errors.Is(err, os.ErrExist) {
+			if _, serr := os.Stat(path); serr != nil {
+				// Hard links aren't supported everywhere
+				os.Rename(aside, path)
+			}
+		}
+	}
+	os.Remove(aside)
+}
+
+// same reports whether h and other are the same holder
+func (h Holder) same(other Holder) bool {
+	return h.PID == other.PID && h.Host == other.Host && h.StartedAt.Equal(other.StartedAt)
+}
+
+// heartbeat keeps the lock file fresh until the lock is released
+func (l *Lock) heartbeat() {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			os.Chtimes(l.path, now, now)
+		}
+	}
+}
+
+// live returns the holder of the lock file at path if it has a recent
+// heartbeat
+func live(path string) (Holder, bool) {
+	var holder Holder
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) >= staleAfter {
+		return holder, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return holder, false
+	}
+	// A lock being written is live but may not be readable yet
+	json.Unmarshal(data, &holder)
+	return holder, true
+}
+
+// liveShared returns the holder of a live worker lock in lockDir, if any
+func liveShared(lockDir string) (Holder, bool) {
+	entries, err := os.ReadDir(lockDir)
+	if err != nil {
+		return Holder{}, false
+	}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), sharedPrefix) {
+			continue
+		}
+		if holder, ok := live(filepath.Join(lockDir, entry.Name())); ok {
+			return holder, true
+		}
+	}
+	return Holder{}, false
+}
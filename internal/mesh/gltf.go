@@ -0,0 +1,172 @@
+package mesh
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+)
+
+// glTF constants used by the writer
+const (
+	gltfFloat         = 5126
+	gltfUnsignedByte  = 5121
+	gltfUnsignedInt   = 5125
+	gltfArrayBuffer   = 34962
+	gltfElementBuffer = 34963
+	glbMagic          = 0x46546C67 // "glTF"
+	glbChunkJSON      = 0x4E4F534A // "JSON"
+	glbChunkBIN       = 0x004E4942 // "BIN\0"
+)
+
+// gltfDocument is the subset of the glTF 2.0 JSON the writer produces
+type gltfDocument struct {
+	Asset       map[string]any   `json:"asset"`
+	Scene       int              `json:"scene"`
+	Scenes      []map[string]any `json:"scenes"`
+	Nodes       []map[string]any `json:"nodes"`
+	Meshes      []map[string]any `json:"meshes"`
+	Materials   []map[string]any `json:"materials"`
+	Accessors   []map[string]any `json:"accessors"`
+	BufferViews []map[string]any `json:"bufferViews"`
+	Buffers     []map[string]any `json:"buffers"`
+}
+
+// WriteGLB writes the mesh as binary glTF
+func WriteGLB(w io.Writer, m *Mesh) error {
+	doc, bin, err := buildGLTF(m)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	// Chunks are padded to 4 bytes: JSON with spaces, binary with zeros
+	for len(data)%4 != 0 {
+		data = append(data, ' ')
+	}
+	for len(bin)%4 != 0 {
+		bin = append(bin, 0)
+	}
+
+	var out bytes.Buffer
+	le := binary.LittleEndian
+	binary.Write(&out, le, []uint32{glbMagic, 2, uint32(12 + 8 + len(data) + 8 + len(bin))})
+	binary.Write(&out, le, []uint32{uint32(len(data)), glbChunkJSON})
+	out.Write(data)
+	binary.Write(&out, le, []uint32{uint32(len(bin)), glbChunkBIN})
+	out.Write(bin)
+	_, err = out.WriteTo(w)
+	return err
+}
+
+// WriteGLTF writes the mesh as a single glTF JSON file, with the binary
+// data embedded as a data URI
+func WriteGLTF(w io.Writer, m *Mesh) error {
+	doc, bin, err := buildGLTF(m)
+	if err != nil {
+		return err
+	}
+	doc.Buffers[0]["uri"] = "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(bin)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// buildGLTF lays the mesh out as glTF accessors over one binary buffer.
+// Survey coordinates are too large for glTF's 32-bit floats, so vertices
+// are stored relative to the center of the bounding box, which is kept in
+// the node's extras as "origin". glTF is Y-up, so the survey's Z axis
+// becomes Y.
+func buildGLTF(m *Mesh) (*gltfDocument, []byte, error) {
+	if len(m.Positions) == 0 || len(m.Triangles) == 0 {
+		return nil, nil, fmt.Errorf("mesh is empty")
+	}
+
+	lo, hi := bounds(m.Positions)
+	var origin [3]float64
+	for i := range origin {
+		origin[i] = (lo[i] + hi[i]) / 2
+	}
+
+	var bin bytes.Buffer
+	le := binary.LittleEndian
+	minPos := []float32{math.MaxFloat32, math.MaxFloat32, math.MaxFloat32}
+	maxPos := []float32{-math.MaxFloat32, -math.MaxFloat32, -math.MaxFloat32}
+	for _, p := range m.Positions {
+		v := [3]float32{
+			float32(p[0] - origin[0]),
+			float32(p[2] - origin[2]),
+			float32(origin[1] - p[1]),
+		}
+		for i := range v {
+			minPos[i] = float32(math.Min(float64(minPos[i]), float64(v[i])))
+			maxPos[i] = float32(math.Max(float64(maxPos[i]), float64(v[i])))
+		}
+		binary.Write(&bin, le, v)
+	}
+	positionsLen := bin.Len()
+
+	attributes := map[string]any{"POSITION": 0}
+	accessors := []map[string]any{{
+		"bufferView": 0, "componentType": gltfFloat, "count": len(m.Positions),
+		"type": "VEC3", "min": minPos, "max": maxPos,
+	}}
+	views := []map[string]any{{
+		"buffer": 0, "byteOffset": 0, "byteLength": positionsLen, "target": gltfArrayBuffer,
+	}}
+
+	// Colors as RGBA bytes, as vertex attributes must be 4-byte aligned
+	if len(m.Colors) == len(m.Positions) {
+		offset := bin.Len()
+		for _, c := range m.Colors {
+			bin.Write([]byte{c[0], c[1], c[2], 255})
+		}
+		attributes["COLOR_0"] = len(accessors)
+		accessors = append(accessors, map[string]any{
+			"bufferView": len(views), "componentType": gltfUnsignedByte, "normalized": true,
+			"count": len(m.Colors), "type": "VEC4",
+		})
+		views = append(views, map[string]any{
+			"buffer": 0, "byteOffset": offset, "byteLength": bin.Len() - offset, "target": gltfArrayBuffer,
+		})
+	}
+
+	offset := bin.Len()
+	for _, t := range m.Triangles {
+		binary.Write(&bin, le, t)
+	}
+	indices := len(accessors)
+	accessors = append(accessors, map[string]any{
+		"bufferView": len(views), "componentType": gltfUnsignedInt,
+		"count": 3 * len(m.Triangles), "type": "SCALAR",
+	})
+	views = append(views, map[string]any{
+		"buffer": 0, "byteOffset": offset, "byteLength": bin.Len() - offset, "target": gltfElementBuffer,
+	})
+
+	doc := &gltfDocument{
+		Asset:  map[string]any{"version": "2.0", "generator": "cloudcompare-automation"},
+		Scenes: []map[string]any{{"nodes": []int{0}}},
+		Nodes: []map[string]any{{
+			"mesh":   0,
+			"extras": map[string]any{"origin": origin},
+		}},
+		Meshes: []map[string]any{{
+			"primitives": []map[string]any{{"attributes": attributes, "indices": indices, "material": 0}},
+		}},
+		Materials: []map[string]any{{
+			"pbrMetallicRoughness": map[string]any{"metallicFactor": 0, "roughnessFactor": 1},
+			"doubleSided":          true,
+		}},
+		Accessors:   accessors,
+		BufferViews: views,
+		Buffers:     []map[string]any{{"byteLength": bin.Len()}},
+	}
+	return doc, bin.Bytes(), nil
+}
@@ -0,0 +1,316 @@
+// Package mesh converts the meshes CloudComPy exports as PLY into glTF,
+// for web viewers and game engines, and renders snapshots of them.
+package mesh
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Mesh is a triangle mesh with optional vertex colors
+type Mesh struct {
+	Positions [][3]float64
+	Colors    [][3]uint8 // Empty when the vertices have no colors
+	Triangles [][3]uint32
+}
+
+// bounds returns the corners of the bounding box of positions, which must
+// not be empty
+func bounds(positions [][3]float64) (lo, hi [3]float64) {
+	lo, hi = positions[0], positions[0]
+	for _, p := range positions {
+		for i := range p {
+			lo[i] = math.Min(lo[i], p[i])
+			hi[i] = math.Max(hi[i], p[i])
+		}
+	}
+	return lo, hi
+}
+
+// plyProperty is a property of a PLY element; a list property has a
+// count type besides its item type
+type plyProperty struct {
+	name      string
+	typ       string
+	countType string
+}
+
+// plyElement is an element declared in a PLY header
+type plyElement struct {
+	name       string
+	count      int
+	properties []plyProperty
+}
+
+// ReadPLY reads a PLY mesh in ASCII or binary format. Polygons are split
+// into triangles; properties other than positions and colors are skipped.
+func ReadPLY(path string) (*Mesh, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	format, elements, err := readPLYHeader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	var values plyValues
+	switch format {
+	case "ascii":
+		values = &asciiValues{r: r}
+	case "binary_little_endian":
+		values = &binaryValues{r: r, order: binary.LittleEndian}
+	case "binary_big_endian":
+		values = &binaryValues{r: r, order: binary.BigEndian}
+	default:
+		return nil, fmt.Errorf("%s: unsupported PLY format %q", path, format)
+	}
+
+	m := &Mesh{}
+	for _, e := range elements {
+		if err := m.readElement(values, e); err != nil {
+			return nil, fmt.Errorf("%s: %s: %v", path, e.name, err)
+		}
+	}
+	for _, t := range m.Triangles {
+		for _, i := range t {
+			if int(i) >= len(m.Positions) {
+				return nil, fmt.Errorf("%s: face refers to vertex %d of %d", path, i, len(m.Positions))
+			}
+		}
+	}
+	return m, nil
+}
+
+// readPLYHeader reads the header up to end_header
+func readPLYHeader(r *bufio.Reader) (format string, elements []plyElement, err error) {
+	line, err := r.ReadString('\n')
+	if err != nil || strings.TrimSpace(line) != "ply" {
+		return "", nil, fmt.Errorf("not a PLY file")
+	}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", nil, fmt.Errorf("truncated header")
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "format":
+			if len(fields) < 2 {
+				return "", nil, fmt.Errorf("invalid format line")
+			}
+			format = fields[1]
+		case "element":
+			if len(fields) != 3 {
+				return "", nil, fmt.Errorf("invalid element line %q", strings.TrimSpace(line))
+			}
+			count, err := strconv.Atoi(fields[2])
+			if err != nil || count < 0 {
+				return "", nil, fmt.Errorf("invalid element count %q", fields[2])
+			}
+			elements = append(elements, plyElement{name: fields[1], count: count})
+		case "property":
+			if len(elements) == 0 {
+				return "", nil, fmt.Errorf("property before any element")
+			}
+			var p plyProperty
+			switch {
+			case len(fields) == 5 && fields[1] == "list":
+				p = plyProperty{countType: fields[2], typ: fields[3], name: fields[4]}
+			case len(fields) == 3:
+				p = plyProperty{typ: fields[1], name: fields[2]}
+			default:
+				return "", nil, fmt.Errorf("invalid property line %q", strings.TrimSpace(line))
+			}
+			for _, typ := range []string{p.typ, p.countType} {
+				if typ != "" && plyTypeSize(typ) == 0 {
+					return "", nil, fmt.Errorf("unknown property type %q", typ)
+				}
+			}
+			e := &elements[len(elements)-1]
+			e.properties = append(e.properties, p)
+		case "end_header":
+			return format, elements, nil
+		}
+	}
+}
+
+// readElement reads every instance of e, keeping the vertices and faces
+func (m *Mesh) readElement(values plyValues, e plyElement) error {
+	colors := e.name == "vertex" && hasProperties(e, "red", "green", "blue")
+	for i := 0; i < e.count; i++ {
+		var position [3]float64
+		var color [3]uint8
+		var polygon []uint32
+		for _, p := range e.properties {
+			if p.countType != "" {
+				n, err := values.next(p.countType)
+				if err != nil {
+					return err
+				}
+				for j := 0; j < int(n); j++ {
+					v, err := values.next(p.typ)
+					if err != nil {
+						return err
+					}
+					if e.name == "face" && (p.name == "vertex_indices" || p.name == "vertex_index") {
+						polygon = append(polygon, uint32(v))
+					}
+				}
+				continue
+			}
+
+			v, err := values.next(p.typ)
+			if err != nil {
+				return err
+			}
+			if e.name != "vertex" {
+				continue
+			}
+			switch p.name {
+			case "x":
+				position[0] = v
+			case "y":
+				position[1] = v
+			case "z":
+				position[2] = v
+			case "red":
+				color[0] = colorByte(v, p.typ)
+			case "green":
+				color[1] = colorByte(v, p.typ)
+			case "blue":
+				color[2] = colorByte(v, p.typ)
+			}
+		}
+
+		switch e.name {
+		case "vertex":
+			m.Positions = append(m.Positions, position)
+			if colors {
+				m.Colors = append(m.Colors, color)
+			}
+		case "face":
+			// Fan triangulation, for the rare polygon with more sides
+			for j := 2; j < len(polygon); j++ {
+				m.Triangles = append(m.Triangles, [3]uint32{polygon[0], polygon[j-1], polygon[j]})
+			}
+		}
+	}
+	return nil
+}
+
+func hasProperties(e plyElement, names ...string) bool {
+	for _, name := range names {
+		found := false
+		for _, p := range e.properties {
+			found = found || p.name == name
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// colorByte converts a color component to 0-255; float colors are 0-1
+func colorByte(v float64, typ string) uint8 {
+	if typ == "float" || typ == "float32" || typ == "double" || typ == "float64" {
+		v *= 255
+	}
+	return uint8(math.Max(0, math.Min(255, math.Round(v))))
+}
+
+// plyTypeSize returns the size in bytes of a PLY scalar type, or 0 for an
+// unknown type
+func plyTypeSize(typ string) int {
+	switch typ {
+	case "char", "uchar", "int8", "uint8":
+		return 1
+	case "short", "ushort", "int16", "uint16":
+		return 2
+	case "int", "uint", "int32", "uint32", "float", "float32":
+		return 4
+	case "double", "float64":
+		return 8
+	}
+	return 0
+}
+
+// plyValues reads the next value of the element data as a number
+type plyValues interface {
+	next(typ string) (float64, error)
+}
+
+// asciiValues reads whitespace-separated values
+type asciiValues struct {
+	r *bufio.Reader
+}
+
+func (a *asciiValues) next(typ string) (float64, error) {
+	var word []byte
+	for {
+		b, err := a.r.ReadByte()
+		if err != nil {
+			if err == io.EOF && len(word) > 0 {
+				break
+			}
+			return 0, fmt.Errorf("truncated data")
+		}
+		if b == ' ' || b == '\t' || b == '\n' || b == '\r' {
+			if len(word) > 0 {
+				break
+			}
+			continue
+		}
+		word = append(word, b)
+	}
+	v, err := strconv.ParseFloat(string(word), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", word)
+	}
+	return v, nil
+}
+
+// binaryValues reads values in the given byte order
+type binaryValues struct {
+	r     *bufio.Reader
+	order binary.ByteOrder
+	buf   [8]byte
+}
+
+func (b *binaryValues) next(typ string) (float64, error) {
+	buf := b.buf[:plyTypeSize(typ)]
+	if _, err := io.ReadFull(b.r, buf); err != nil {
+		return 0, fmt.Errorf("truncated data")
+	}
+	switch typ {
+	case "char", "int8":
+		return float64(int8(buf[0])), nil
+	case "uchar", "uint8":
+		return float64(buf[0]), nil
+	case "short", "int16":
+		return float64(int16(b.order.Uint16(buf))), nil
+	case "ushort", "uint16":
+		return float64(b.order.Uint16(buf)), nil
+	case "int", "int32":
+		return float64(int32(b.order.Uint32(buf))), nil
+	case "uint", "uint32":
+		return float64(b.order.Uint32(buf)), nil
+	case "float", "float32":
+		return float64(math.Float32frombits(b.order.Uint32(buf))), nil
+	default:
+		return math.Float64frombits(b.order.Uint64(buf)), nil
+	}
+}
@@ -0,0 +1,239 @@
+package mesh
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"io"
+	"math"
+)
+
+// View is a direction the mesh is looked at from, in degrees. Azimuth 0
+// looks north from the south and turns clockwise seen from above;
+// elevation 90 looks straight down.
+type View struct {
+	Azimuth   float64
+	Elevation float64
+}
+
+// Standard snapshot views
+var (
+	TopView = View{Azimuth: 0, Elevation: 90}
+	IsoView = View{Azimuth: 45, Elevation: 35}
+)
+
+// Colors of meshes without vertex colors and of the background
+var (
+	meshGray   = [3]uint8{200, 200, 200}
+	background = color.RGBA{30, 30, 36, 255}
+)
+
+// turntableElevation is the elevation the turntable circles the mesh at
+const turntableElevation = 30
+
+// camera projects survey coordinates onto an image: u right, v up and
+// depth towards the viewer
+type camera struct {
+	center             [3]float64
+	sinA, cosA         float64
+	sinE, cosE         float64
+	scale              float64
+	width, height      int
+	offsetU, offsetV   float64
+	lightU, lightV, lz float64
+}
+
+// newCamera looks at the mesh from view. With fit the mesh fills the image;
+// otherwise its bounding sphere does, so the scale doesn't change as the
+// view turns.
+func newCamera(m *Mesh, view View, width, height int, fit bool) *camera {
+	lo, hi := bounds(m.Positions)
+	c := &camera{width: width, height: height}
+	for i := range c.center {
+		c.center[i] = (lo[i] + hi[i]) / 2
+	}
+	a := view.Azimuth * math.Pi / 180
+	e := view.Elevation * math.Pi / 180
+	c.sinA, c.cosA = math.Sin(a), math.Cos(a)
+	c.sinE, c.cosE = math.Sin(e), math.Cos(e)
+
+	// Light from the upper left of the viewer
+	l := math.Sqrt(0.3*0.3 + 0.5*0.5 + 0.8*0.8)
+	c.lightU, c.lightV, c.lz = -0.3/l, 0.5/l, 0.8/l
+
+	var spanU, spanV float64
+	if fit {
+		minU, minV := math.Inf(1), math.Inf(1)
+		maxU, maxV := math.Inf(-1), math.Inf(-1)
+		for _, corner := range corners(lo, hi) {
+			u, v, _ := c.project(corner)
+			minU, maxU = math.Min(minU, u), math.Max(maxU, u)
+			minV, maxV = math.Min(minV, v), math.Max(maxV, v)
+		}
+		spanU, spanV = maxU-minU, maxV-minV
+		c.offsetU, c.offsetV = -(minU+maxU)/2, -(minV+maxV)/2
+	} else {
+		var r float64
+		for i := range lo {
+			r += (hi[i] - lo[i]) * (hi[i] - lo[i])
+		}
+		spanU = math.Sqrt(r)
+		spanV = spanU
+	}
+
+	// A margin of 5% on every side
+	spanU, spanV = math.Max(spanU, 1e-9), math.Max(spanV, 1e-9)
+	c.scale = 0.9 * math.Min(float64(width)/spanU, float64(height)/spanV)
+	return c
+}
+
+// project returns the position of p on the view plane, relative to the
+// center, and its depth
+func (c *camera) project(p [3]float64) (u, v, depth float64) {
+	x, y, z := p[0]-c.center[0], p[1]-c.center[1], p[2]-c.center[2]
+	x1 := x*c.cosA - y*c.sinA
+	y1 := x*c.sinA + y*c.cosA
+	return x1, y1*c.sinE + z*c.cosE, z*c.sinE - y1*c.cosE
+}
+
+// pixel returns the image position of p and its depth
+func (c *camera) pixel(p [3]float64) (px, py, depth float64) {
+	u, v, depth := c.project(p)
+	px = float64(c.width)/2 + (u+c.offsetU)*c.scale
+	py = float64(c.height)/2 - (v+c.offsetV)*c.scale
+	return px, py, depth
+}
+
+// Render draws the mesh from view with flat shading, filling the image
+func Render(m *Mesh, view View, width, height int) (*image.RGBA, error) {
+	if len(m.Triangles) == 0 {
+		return nil, fmt.Errorf("mesh has no faces")
+	}
+	return render(m, newCamera(m, view, width, height, true)), nil
+}
+
+// WriteTurntable writes an animated GIF of frames views circling the mesh
+func WriteTurntable(w io.Writer, m *Mesh, frames, width, height int) error {
+	if len(m.Triangles) == 0 {
+		return fmt.Errorf("mesh has no faces")
+	}
+	anim := &gif.GIF{}
+	pal := color.Palette(palette.Plan9)
+	// Shaded meshes repeat colors a lot, and a palette search per pixel is
+	// most of the work
+	index := make(map[color.RGBA]uint8)
+	for i := 0; i < frames; i++ {
+		view := View{Azimuth: 360 * float64(i) / float64(frames), Elevation: turntableElevation}
+		img := render(m, newCamera(m, view, width, height, false))
+		frame := image.NewPaletted(img.Bounds(), pal)
+		for p := 0; p < len(frame.Pix); p++ {
+			c := color.RGBA{img.Pix[4*p], img.Pix[4*p+1], img.Pix[4*p+2], 255}
+			k, ok := index[c]
+			if !ok {
+				k = uint8(pal.Index(c))
+				index[c] = k
+			}
+			frame.Pix[p] = k
+		}
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 8) // 1/100 s
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// render rasterizes the triangles with a depth buffer
+func render(m *Mesh, c *camera) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, c.width, c.height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{background}, image.Point{}, draw.Src)
+	depth := make([]float64, c.width*c.height)
+	for i := range depth {
+		depth[i] = math.Inf(-1)
+	}
+
+	for _, t := range m.Triangles {
+		var px, py, pz [3]float64
+		var p [3][3]float64
+		for k, index := range t {
+			p[k] = m.Positions[index]
+			px[k], py[k], pz[k] = c.pixel(p[k])
+		}
+
+		// Both sides are lit alike, as mesh orientation isn't guaranteed
+		shade := 0.25 + 0.75*math.Abs(c.facing(p))
+		rgb := meshGray
+		if len(m.Colors) == len(m.Positions) {
+			var sum [3]int
+			for _, index := range t {
+				for k := range sum {
+					sum[k] += int(m.Colors[index][k])
+				}
+			}
+			for k := range rgb {
+				rgb[k] = uint8(sum[k] / 3)
+			}
+		}
+		col := color.RGBA{uint8(float64(rgb[0]) * shade), uint8(float64(rgb[1]) * shade), uint8(float64(rgb[2]) * shade), 255}
+
+		area := (px[1]-px[0])*(py[2]-py[0]) - (px[2]-px[0])*(py[1]-py[0])
+		if area == 0 {
+			continue
+		}
+		minX := max(int(math.Floor(min(px[0], px[1], px[2]))), 0)
+		maxX := min(int(math.Ceil(max(px[0], px[1], px[2]))), c.width-1)
+		minY := max(int(math.Floor(min(py[0], py[1], py[2]))), 0)
+		maxY := min(int(math.Ceil(max(py[0], py[1], py[2]))), c.height-1)
+		for y := minY; y <= maxY; y++ {
+			for x := minX; x <= maxX; x++ {
+				// Barycentric weights of the pixel center
+				fx, fy := float64(x)+0.5, float64(y)+0.5
+				w0 := ((px[1]-fx)*(py[2]-fy) - (px[2]-fx)*(py[1]-fy)) / area
+				w1 := ((px[2]-fx)*(py[0]-fy) - (px[0]-fx)*(py[2]-fy)) / area
+				w2 := 1 - w0 - w1
+				if w0 < 0 || w1 < 0 || w2 < 0 {
+					continue
+				}
+				z := w0*pz[0] + w1*pz[1] + w2*pz[2]
+				if z <= depth[y*c.width+x] {
+					continue
+				}
+				depth[y*c.width+x] = z
+				img.SetRGBA(x, y, col)
+			}
+		}
+	}
+	return img
+}
+
+// facing returns the cosine between the triangle's normal and the light
+func (c *camera) facing(p [3][3]float64) float64 {
+	var q [3][3]float64
+	for k := range p {
+		q[k][0], q[k][1], q[k][2] = c.project(p[k])
+	}
+	a := [3]float64{q[1][0] - q[0][0], q[1][1] - q[0][1], q[1][2] - q[0][2]}
+	b := [3]float64{q[2][0] - q[0][0], q[2][1] - q[0][1], q[2][2] - q[0][2]}
+	n := [3]float64{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+	length := math.Sqrt(n[0]*n[0] + n[1]*n[1] + n[2]*n[2])
+	if length == 0 {
+		return 0
+	}
+	return (n[0]*c.lightU + n[1]*c.lightV + n[2]*c.lz) / length
+}
+
+// corners returns the eight corners of the box from lo to hi
+func corners(lo, hi [3]float64) [][3]float64 {
+	var out [][3]float64
+	for i := 0; i < 8; i++ {
+		p := lo
+		for k := 0; k < 3; k++ {
+			if i&(1<<k) != 0 {
+				p[k] = hi[k]
+			}
+		}
+		out = append(out, p)
+	}
+	return out
+}
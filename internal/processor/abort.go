@@ -0,0 +1,38 @@
+package processor
+
+import "fmt"
+
+// AbortMinFiles is how many files a batch must have finished before its
+// failure rate is checked, so one early failure doesn't abort it
+const AbortMinFiles = 5
+
+// AbortPolicy stops a batch early when its failures show that something
+// is wrong with every file, such as a broken Python environment, rather
+// than grinding through the rest
+type AbortPolicy struct {
+	After int     // Abort after this many files failed in a row (0 = never)
+	Rate  float64 // Abort once more than this percentage of files failed (0 = never)
+}
+
+// CheckAbort reports an abort policy that can't be applied
+func CheckAbort(a AbortPolicy) error {
+	if a.After < 0 {
+		return fmt.Errorf("abort after failures must not be negative")
+	}
+	if a.Rate < 0 || a.Rate > 100 {
+		return fmt.Errorf("abort failure rate must be between 0 and 100")
+	}
+	return nil
+}
+
+// Reason returns why a batch that has finished files, failed of them, the
+// last streak in a row, should be aborted, or "" if it should go on
+func (a AbortPolicy) Reason(files, failed, streak int) string {
+	if a.After > 0 && streak >= a.After {
+		return fmt.Sprintf("%d files failed in a row", streak)
+	}
+	if a.Rate > 0 && files >= AbortMinFiles && float64(failed)*100 > a.Rate*float64(files) {
+		return fmt.Sprintf("%d of %d files failed, more than %g%%", failed, files, a.Rate)
+	}
+	return ""
+}
@@ -0,0 +1,89 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudcompare-automation/internal/humanize"
+	"github.com/cloudcompare-automation/internal/report"
+)
+
+// Acceptance holds the rules a file's outputs must meet to be accepted,
+// checked against what the script measured once the project is saved. A
+// file breaking a rule completes with a warning, or fails with Fail, even
+// though the script itself succeeded. A rule the script measured nothing
+// for, e.g. with a custom pipeline, can't be checked and only warns.
+type Acceptance struct {
+	MinFaces     int     // Fewest faces of the mesh (0 = any)
+	MaxDeviation float64 // Distance 95% of the points must be within of the mesh (0 = any)
+	MinDensity   float64 // Fewest points per square unit of the mesh's footprint (0 = any)
+	Fail         bool    // Fail files breaking a rule instead of warning
+}
+
+// Enabled reports whether any rule is set
+func (a Acceptance) Enabled() bool {
+	return a.MinFaces > 0 || a.MaxDeviation > 0 || a.MinDensity > 0
+}
+
+// String lists the rules, e.g. "at least 100,000 faces, 95% of points
+// within 0.05; failing files"
+func (a Acceptance) String() string {
+	var rules []string
+	if a.MinFaces > 0 {
+		rules = append(rules, "at least "+humanize.Int(int64(a.MinFaces))+" faces")
+	}
+	if a.MaxDeviation > 0 {
+		rules = append(rules, fmt.Sprintf("95%% of points within %g", a.MaxDeviation))
+	}
+	if a.MinDensity > 0 {
+		rules = append(rules, fmt.Sprintf("at least %g points per square unit", a.MinDensity))
+	}
+	s := strings.Join(rules, ", ")
+	if a.Fail {
+		s += "; failing files"
+	}
+	return s
+}
+
+// CheckAcceptance reports rules that can't be applied
+func CheckAcceptance(a Acceptance) error {
+	if a.MinFaces < 0 || a.MaxDeviation < 0 || a.MinDensity < 0 {
+		return fmt.Errorf("acceptance rules must not be negative")
+	}
+	return nil
+}
+
+// Check returns the rules a file breaks, given the faces (negative if not
+// reported), points and mesh quality the script reported, and those it
+// couldn't check
+func (a Acceptance) Check(faces int, points int64, quality *report.MeshQuality) (broken, unchecked []string) {
+	if a.MinFaces > 0 {
+		switch {
+		case faces < 0:
+			unchecked = append(unchecked, "min_faces: no face count was reported")
+		case faces < a.MinFaces:
+			broken = append(broken, fmt.Sprintf("%s faces, fewer than %s", humanize.Int(int64(faces)), humanize.Int(int64(a.MinFaces))))
+		}
+	}
+	if a.MaxDeviation > 0 {
+		switch {
+		case quality == nil || quality.Deviation == nil:
+			unchecked = append(unchecked, "max_deviation: the deviation wasn't measured")
+		case quality.Deviation.P95 > a.MaxDeviation:
+			broken = append(broken, fmt.Sprintf("95%% of points within %.3g of the mesh, more than %g", quality.Deviation.P95, a.MaxDeviation))
+		}
+	}
+	if a.MinDensity > 0 {
+		size := [3]float64{}
+		if quality != nil {
+			size = quality.Size()
+		}
+		switch area := size[0] * size[1]; {
+		case points <= 0 || area <= 0:
+			unchecked = append(unchecked, "min_density: no point count or mesh extent was reported")
+		case float64(points)/area < a.MinDensity:
+			broken = append(broken, fmt.Sprintf("%.3g points per square unit, fewer than %g", float64(points)/area, a.MinDensity))
+		}
+	}
+	return broken, unchecked
+}
@@ -0,0 +1,215 @@
+package processor
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Archive formats for the outputs of each file, for delivery portals that
+// limit the size or number of files
+const (
+	ArchiveNone = "none"
+	ArchiveGzip = "gzip" // The project compressed, scan1.bin.gz
+	ArchiveZip  = "zip"  // The project, mesh and web export in scan1.zip
+)
+
+// ArchiveFormats are the accepted Archive values
+var ArchiveFormats = []string{ArchiveNone, ArchiveGzip, ArchiveZip}
+
+// CheckArchive reports an unknown archive format
+func CheckArchive(format string) error {
+	return checkChoice("archive", format, ArchiveFormats)
+}
+
+func (params Params) archiveEnabled() bool {
+	return params.Archive != "" && params.Archive != ArchiveNone
+}
+
+// archiveStep names the archive step in the pipeline view
+func archiveStep(format string) string {
+	return "Archive (" + format + ")"
+}
+
+// ArchiveCount returns how many files had their outputs archived
+func (r ProcessingResult) ArchiveCount() int {
+	n := 0
+	for _, f := range r.Files {
+		if f.Archive != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// archivePath returns the archive of the project saved at output
+func (params Params) archivePath(output string) string {
+	if params.Archive == ArchiveGzip {
+		return output + ".gz"
+	}
+	return strings.TrimSuffix(output, ".bin") + ".zip"
+}
+
+// archive packs the outputs of a processed file, after the other steps
+// have used them, and removes what it packed. The archive is written under
+// a temporary name and replaces an earlier one only when complete; until
+// then the outputs are left alone. Snapshots stay out, as the HTML report
+// shows them.
+func (p *Processor) archive(f *FileResult) error {
+	dir := filepath.Dir(f.OutputFile)
+	var paths []string
+	if p.params.Archive == ArchiveZip {
+		for _, output := range []string{f.OutputFile, f.MeshFile} {
+			if output != "" {
+				paths = append(paths, output, ProvenancePath(output))
+			}
+		}
+		if f.WebExport != "" {
+			err := filepath.WalkDir(f.WebExport, func(path string, d fs.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					paths = append(paths, path)
+				}
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		}
+	} else {
+		paths = append(paths, f.OutputFile)
+	}
+
+	// Sidecars are optional; everything else must be there
+	var files []string
+	var total int64
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) && strings.HasSuffix(path, ".json") {
+				continue
+			}
+			return err
+		}
+		files = append(files, path)
+		total += info.Size()
+	}
+
+	archive := p.params.archivePath(f.OutputFile)
+	tmp := filepath.Join(dir, "."+filepath.Base(archive)+".partial")
+	defer os.Remove(tmp)
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	progress := p.stepProgress(archiveStep(p.params.Archive), f.InputFile)
+	counter := &archiveCounter{stopped: p.isStopped, progress: func(done int64) {
+		progress(int(done * 100 / max(total, 1)))
+	}}
+
+	if p.params.Archive == ArchiveZip {
+		err = writeZip(out, dir, files, counter)
+	} else {
+		err = writeGzip(out, f.OutputFile, counter)
+	}
+	if err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, archive); err != nil {
+		return err
+	}
+	f.Archive = archive
+
+	for _, path := range files {
+		os.Remove(path)
+	}
+	if f.WebExport != "" && p.params.Archive == ArchiveZip {
+		os.RemoveAll(f.WebExport)
+	}
+	p.sendLog(LogInfo, fmt.Sprintf("Archived: %s (%d file(s))", filepath.Base(archive), len(files)))
+	return nil
+}
+
+// writeZip packs files into a zip archive, named relative to dir
+func writeZip(w io.Writer, dir string, files []string, counter *archiveCounter) error {
+	zw := zip.NewWriter(w)
+	for _, path := range files {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		header.Method = zip.Deflate
+		entry, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if err := copyFile(entry, path, counter); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// writeGzip compresses the file at path
+func writeGzip(w io.Writer, path string, counter *archiveCounter) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	gw := gzip.NewWriter(w)
+	gw.Name = filepath.Base(path)
+	gw.ModTime = info.ModTime()
+	if err := copyFile(gw, path, counter); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// copyFile copies the content of the file at path to w, counting it
+func copyFile(w io.Writer, path string, counter *archiveCounter) error {
+	f, err := os.Open(longPath(path))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	counter.r = f
+	_, err = io.Copy(w, counter)
+	return err
+}
+
+// archiveCounter reads the files being archived, reporting the bytes read
+// so far and failing once the run is stopped
+type archiveCounter struct {
+	r        io.Reader
+	done     int64
+	stopped  func() bool
+	progress func(done int64)
+}
+
+func (c *archiveCounter) Read(b []byte) (int, error) {
+	if c.stopped() {
+		return 0, errStopped
+	}
+	n, err := c.r.Read(b)
+	c.done += int64(n)
+	c.progress(c.done)
+	return n, err
+}
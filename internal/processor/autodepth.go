@@ -0,0 +1,80 @@
+package processor
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudcompare-automation/internal/humanize"
+	"github.com/cloudcompare-automation/internal/las"
+)
+
+// AutoDepth is the octree-depth value that picks the depth for each file
+// from its point spacing
+const AutoDepth = "auto"
+
+// Auto depth limits. The upper one keeps PoissonRecon's memory use within
+// what a workstation has; each extra level needs up to 8x the memory.
+const (
+	minAutoDepth     = 6
+	maxAutoDepth     = 12
+	fallbackDepth    = 11
+	poissonCubeScale = 1.1 // PoissonRecon pads the bounding cube by 10%
+)
+
+// DepthForHeader returns the octree depth whose leaf cells match the
+// average point spacing in the file, clamped to a safe range
+func DepthForHeader(h las.Header) int {
+	spacing := h.Spacing()
+	if spacing <= 0 {
+		return fallbackDepth
+	}
+	depth := int(math.Ceil(math.Log2(h.Extent() * poissonCubeScale / spacing)))
+	if depth < minAutoDepth {
+		return minAutoDepth
+	}
+	if depth > maxAutoDepth {
+		return maxAutoDepth
+	}
+	return depth
+}
+
+// resolveValues returns the parameter values for one file: an automatic
+// octree depth is computed from the file header, and a run from a later
+// stage falls back to all stages when the file has no stage cache yet
+func (p *Processor) resolveValues(file, stageDir string) map[string]string {
+	values := make(map[string]string, len(p.params.Values))
+	for name, value := range p.params.Values {
+		values[name] = value
+	}
+
+	switch values["from-stage"] {
+	case StageAuto:
+		values["from-stage"] = StageAll
+		if hasStageCache(stageDir) {
+			values["from-stage"] = StagePoisson
+			p.sendLog(LogInfo, fmt.Sprintf("Reusing cached normals for %s (same input and parameters)", filepath.Base(file)))
+		}
+	case StagePoisson:
+		if !hasStageCache(stageDir) {
+			values["from-stage"] = StageAll
+			p.sendLog(LogWarning, fmt.Sprintf("No cached normals for %s with these parameters; running all stages", filepath.Base(file)))
+		}
+	}
+
+	if !strings.EqualFold(values["octree-depth"], AutoDepth) {
+		return values
+	}
+
+	h, err := las.ReadHeader(file)
+	if err != nil {
+		values["octree-depth"] = fmt.Sprint(fallbackDepth)
+		p.sendLog(LogWarning, fmt.Sprintf("Auto octree depth: %v; using %d", err, fallbackDepth))
+		return values
+	}
+	depth := DepthForHeader(h)
+	values["octree-depth"] = fmt.Sprint(depth)
+	p.sendLog(LogInfo, fmt.Sprintf("Auto octree depth: %d (%s points, spacing %.3g)", depth, humanize.Int(int64(h.PointCount)), h.Spacing()))
+	return values
+}
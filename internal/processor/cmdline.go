@@ -0,0 +1,102 @@
+package processor
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CommandLines returns the commands a run executes for the pending files,
+// after the environment they need, to run them by hand or attach them to a
+// bug report. On Windows they are batch file lines setting up the conda
+// environment as a run does, elsewhere shell lines. Unlike a run, the
+// project is saved under its final name directly and the stage cache isn't
+// used.
+func (p *Processor) CommandLines() ([]string, error) {
+	if p.scriptPath == "" {
+		if err := p.FindScripts(); err != nil {
+			return nil, err
+		}
+	}
+	files, err := p.ListLASFiles()
+	if err != nil {
+		return nil, err
+	}
+	plan := p.params.Plan(files, time.Now())
+	files, _ = plan.pending(files)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no LAS files to process")
+	}
+
+	python, err := p.params.Python.activate()
+	if err != nil {
+		return nil, err
+	}
+
+	batch := runtime.GOOS == "windows"
+	quote := shellQuote
+	if batch {
+		quote = batchQuote
+	}
+
+	env := p.params.scriptEnv()
+	for key, value := range python.vars {
+		env[key] = value
+	}
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var lines []string
+	for _, key := range keys {
+		if batch {
+			lines = append(lines, fmt.Sprintf(`set "%s=%s"`, key, strings.ReplaceAll(env[key], "%", "%%")))
+		} else {
+			lines = append(lines, fmt.Sprintf("export %s=%s", key, quote(env[key])))
+		}
+	}
+	for _, file := range files {
+		command := []string{quote(python.python), quote(p.scriptPath)}
+		for _, arg := range p.buildArgs(file, plan.Dir(file), "", "", p.resolveValues(file, "")) {
+			command = append(command, quote(arg))
+		}
+		lines = append(lines, strings.Join(command, " "))
+	}
+	return lines, nil
+}
+
+// joinCommand joins args into a command line for display, quoted for the
+// platform's shell
+func joinCommand(args []string) string {
+	quote := shellQuote
+	if runtime.GOOS == "windows" {
+		quote = cmdArg
+	}
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = quote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote quotes s for a POSIX shell where needed
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-+=.,/:@") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// batchQuote quotes s for a Windows batch file where needed; percent
+// signs are doubled so they aren't expanded as variables
+func batchQuote(s string) string {
+	s = strings.ReplaceAll(s, "%", "%%")
+	if s != "" && !strings.ContainsAny(s, " \t&|<>^()\",;=") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
@@ -0,0 +1,77 @@
+package processor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cloudcompare-automation/internal/report"
+)
+
+// CostRates turn a run's CPU and processing time into the cost and energy
+// estimates recorded in its report, for billing compute time back to
+// projects. A zero rate leaves that estimate out.
+type CostRates struct {
+	CPUHour  float64 // Price of one CPU hour
+	Currency string  // e.g. EUR, only used for display
+	Watts    float64 // Average power draw of the machine while processing
+}
+
+// Enabled reports whether any rate is set
+func (c CostRates) Enabled() bool {
+	return c.CPUHour > 0 || c.Watts > 0
+}
+
+// Estimate returns the estimates for cpu CPU time spent over wall
+// processing time, or nil when no rate is set. Energy is estimated from
+// the processing time, as the machine draws power whether or not every
+// core is busy.
+func (c CostRates) Estimate(cpu, wall time.Duration) *report.Estimate {
+	if !c.Enabled() {
+		return nil
+	}
+	return &report.Estimate{
+		CPUHours:  cpu.Hours(),
+		Cost:      cpu.Hours() * c.CPUHour,
+		Currency:  c.Currency,
+		EnergyKWh: wall.Hours() * c.Watts / 1000,
+	}
+}
+
+// CPUTime returns the CPU time spent by the scripts of all files
+func (r ProcessingResult) CPUTime() time.Duration {
+	var total time.Duration
+	for _, f := range r.Files {
+		total += f.CPUTime
+	}
+	return total
+}
+
+// ProcessingTime returns the time spent running the scripts of all files
+func (r ProcessingResult) ProcessingTime() time.Duration {
+	var total time.Duration
+	for _, f := range r.Files {
+		total += f.Duration
+	}
+	return total
+}
+
+// FormatEstimate describes an estimate in one line, e.g. "≈ 0.42 EUR, 0.18 kWh"
+func FormatEstimate(e *report.Estimate) string {
+	if e == nil {
+		return ""
+	}
+	var s string
+	if e.Cost > 0 {
+		s = fmt.Sprintf("%.2f", e.Cost)
+		if e.Currency != "" {
+			s += " " + e.Currency
+		}
+	}
+	if e.EnergyKWh > 0 {
+		if s != "" {
+			s += ", "
+		}
+		s += fmt.Sprintf("%.2f kWh", e.EnergyKWh)
+	}
+	return "≈ " + s
+}
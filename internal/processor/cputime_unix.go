@@ -0,0 +1,20 @@
+//go:build !windows
+
+package processor
+
+import (
+	"os/exec"
+	"time"
+)
+
+// meterCPU starts measuring the CPU time of a started script. The returned
+// function reports it once the script has been waited for; the rusage of
+// the script includes the processes it started and waited for.
+func meterCPU(cmd *exec.Cmd) func() time.Duration {
+	return func() time.Duration {
+		if cmd.ProcessState == nil {
+			return 0
+		}
+		return cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime()
+	}
+}
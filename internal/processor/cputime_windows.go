@@ -0,0 +1,64 @@
+//go:build windows
+
+package processor
+
+import (
+	"os/exec"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// jobAccounting is JOBOBJECT_BASIC_ACCOUNTING_INFORMATION, which the
+// windows package doesn't define
+type jobAccounting struct {
+	TotalUserTime             int64
+	TotalKernelTime           int64
+	ThisPeriodTotalUserTime   int64
+	ThisPeriodTotalKernelTime int64
+	TotalPageFaultCount       uint32
+	TotalProcesses            uint32
+	ActiveProcesses           uint32
+	TotalTerminatedProcesses  uint32
+}
+
+// meterCPU starts measuring the CPU time of a started script. Python is
+// put in a job object whose accounting also covers the processes it starts
+// afterwards, such as a PoissonRecon executable. The returned function
+// reports the time once the script has been waited for; without a job only
+// Python's own time is known.
+func meterCPU(cmd *exec.Cmd) func() time.Duration {
+	own := func() time.Duration {
+		if cmd.ProcessState == nil {
+			return 0
+		}
+		return cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime()
+	}
+
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return own
+	}
+	process, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(cmd.Process.Pid))
+	if err == nil {
+		err = windows.AssignProcessToJobObject(job, process)
+		windows.CloseHandle(process)
+	}
+	if err != nil {
+		windows.CloseHandle(job)
+		return own
+	}
+
+	return func() time.Duration {
+		defer windows.CloseHandle(job)
+		var info jobAccounting
+		err := windows.QueryInformationJobObject(job, windows.JobObjectBasicAccountingInformation,
+			uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info)), nil)
+		if err != nil {
+			return own()
+		}
+		// The times are counted in 100ns units
+		return time.Duration(info.TotalUserTime+info.TotalKernelTime) * 100
+	}
+}
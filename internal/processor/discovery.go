@@ -0,0 +1,140 @@
+package processor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DiscoverLASFiles returns the absolute paths of the LAS files in dir,
+// sorted by name, split into those selected by the include and exclude
+// patterns and those skipped. Patterns are globs matched against the file
+// name, ignoring case, e.g. tile_??.las or *_preview.las. With no include
+// patterns every LAS file is included; exclude patterns win.
+func DiscoverLASFiles(dir string, include, exclude []string) (matched, skipped []string, err error) {
+	if dir == "" {
+		dir = "."
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries, err := os.ReadDir(absDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(strings.ToLower(entry.Name()), ".las") {
+			continue
+		}
+		files = append(files, filepath.Join(absDir, entry.Name()))
+	}
+	matched, skipped = SplitFiles(files, include, exclude)
+	return matched, skipped, nil
+}
+
+// SplitFiles splits files into those selected by the include and exclude
+// patterns and those skipped, as DiscoverLASFiles does
+func SplitFiles(files []string, include, exclude []string) (matched, skipped []string) {
+	for _, path := range files {
+		if selected(filepath.Base(path), include, exclude) {
+			matched = append(matched, path)
+		} else {
+			skipped = append(skipped, path)
+		}
+	}
+	return matched, skipped
+}
+
+// selected reports whether a file name passes the include and exclude
+// patterns
+func selected(name string, include, exclude []string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range exclude {
+		if ok, _ := filepath.Match(strings.ToLower(pattern), name); ok {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, pattern := range include {
+		if ok, _ := filepath.Match(strings.ToLower(pattern), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckPatterns reports the first malformed glob pattern
+func CheckPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if strings.ContainsAny(pattern, `/\`) {
+			return fmt.Errorf("pattern %q must match file names, not paths", pattern)
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// ParseFileList splits a list of file paths separated by semicolons or
+// line breaks, as pasted from a spreadsheet or Explorer's "Copy as path".
+// Quotes around a path are removed and blank entries dropped.
+func ParseFileList(s string) []string {
+	var files []string
+	for _, entry := range strings.FieldsFunc(s, func(r rune) bool { return r == ';' || r == '\n' || r == '\r' }) {
+		entry = strings.Trim(strings.TrimSpace(entry), `"'`)
+		if entry != "" {
+			files = append(files, entry)
+		}
+	}
+	return files
+}
+
+// CheckLASFile reports why path can't be processed as a listed LAS file
+func CheckLASFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("%s does not exist", path)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", path)
+	}
+	if !strings.HasSuffix(strings.ToLower(path), ".las") {
+		return fmt.Errorf("%s is not a LAS file", path)
+	}
+	return nil
+}
+
+// ParsePatterns splits a comma or space separated list of patterns
+func ParsePatterns(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+}
+
+// IsNetworkPath reports whether path is a UNC path, \\server\share\...
+// (or //server/share/...)
+func IsNetworkPath(path string) bool {
+	return strings.HasPrefix(path, `\\`) || strings.HasPrefix(path, "//")
+}
+
+// CheckNetworkPath reports a UNC path without a server or share name,
+// which would otherwise fail with an unhelpful error or a long wait
+func CheckNetworkPath(path string) error {
+	if !IsNetworkPath(path) {
+		return nil
+	}
+	parts := strings.FieldsFunc(path[2:], func(r rune) bool { return r == '\\' || r == '/' })
+	if len(parts) < 2 || strings.HasPrefix(path[2:], `\`) || strings.HasPrefix(path[2:], "/") {
+		return fmt.Errorf(`network path %s needs a server and a share, e.g. \\server\share\survey`, path)
+	}
+	if strings.ContainsAny(parts[0], `<>:"|?*`) {
+		return fmt.Errorf("invalid server name %q in %s", parts[0], path)
+	}
+	return nil
+}
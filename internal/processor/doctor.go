@@ -0,0 +1,106 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Check is the outcome of one of the Doctor checks
+type Check struct {
+	Name   string
+	Level  LogLevel // LogSuccess, LogWarning or LogError
+	Detail string
+}
+
+// Doctor checks that this machine can process with params: that the
+// pipeline script is found, the Python environment can be set up, and
+// Python runs and imports CloudComPy with the PoissonRecon plugin in it.
+// Checks that depend on a failed one are left out.
+func Doctor(ctx context.Context, params Params) []Check {
+	var checks []Check
+	add := func(name string, level LogLevel, detail string) {
+		checks = append(checks, Check{Name: name, Level: level, Detail: detail})
+	}
+
+	p := New(params)
+	if err := p.FindScripts(); err != nil {
+		add("Pipeline script", LogError, err.Error())
+	} else if err := checkScriptVersion(p.ScriptPath()); err != nil && params.Script == "" {
+		add("Pipeline script", LogError, err.Error())
+	} else {
+		add("Pipeline script", LogSuccess, p.ScriptPath())
+	}
+
+	python, err := params.Python.activate()
+	if err != nil {
+		add("Python environment", LogError, err.Error())
+		return checks
+	}
+	if python.prefix != "" {
+		add("Conda environment", LogSuccess, python.prefix)
+	} else {
+		name := params.Python.CondaEnv
+		if name == "" {
+			name = DefaultCondaEnv
+		}
+		add("Conda environment", LogWarning, fmt.Sprintf("%s not found; using %s on PATH", name, python.python))
+	}
+	if python.cloudComPy != "" {
+		add("CloudComPy", LogSuccess, python.cloudComPy)
+	} else {
+		add("CloudComPy", LogWarning, "no installation found; it must be importable in the Python environment")
+	}
+
+	version, err := runPython(ctx, python, "-c", "import sys; print(sys.version.split()[0], sys.executable)")
+	if err != nil {
+		add("Python", LogError, err.Error())
+		return checks
+	}
+	add("Python", LogSuccess, version)
+
+	if _, err := runPython(ctx, python, "-c", "import cloudComPy"); err != nil {
+		add("CloudComPy import", LogError, err.Error())
+		return checks
+	}
+	add("CloudComPy import", LogSuccess, "import cloudComPy works")
+
+	if _, err := runPython(ctx, python, "-c", "import cloudComPy.PoissonRecon"); err != nil {
+		add("PoissonRecon plugin", LogError, err.Error())
+	} else {
+		add("PoissonRecon plugin", LogSuccess, "import cloudComPy.PoissonRecon works")
+	}
+	return checks
+}
+
+// runPython runs Python in the environment and returns the last line of
+// its output, which on failure is usually the error
+func runPython(ctx context.Context, python activation, args ...string) (string, error) {
+	output, err := python.command(ctx, args...).CombinedOutput()
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	if err != nil {
+		if last == "" {
+			return "", fmt.Errorf("%s: %v", python.python, err)
+		}
+		return "", fmt.Errorf("%s", last)
+	}
+	return last, nil
+}
+
+// CheckSetup checks what every file of a run needs, once before a worker
+// claims any: the pipeline script, a recent enough process_las_files.py
+// and the Python environment
+func CheckSetup(params Params) error {
+	p := New(params)
+	if err := p.FindScripts(); err != nil {
+		return err
+	}
+	if params.Script == "" {
+		if err := checkScriptVersion(p.ScriptPath()); err != nil {
+			return err
+		}
+	}
+	_, err := params.Python.activate()
+	return err
+}
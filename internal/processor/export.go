@@ -0,0 +1,73 @@
+package processor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudcompare-automation/internal/mesh"
+)
+
+// Mesh export formats of process_las_files.py. The script exports PLY;
+// glTF formats are converted from it here.
+const (
+	MeshNone = "none"
+	MeshPLY  = "ply"
+	MeshGLB  = "glb"
+	MeshGLTF = "gltf"
+)
+
+// partialMeshPath is where the script exports the mesh of a project saved
+// at partial, next to it
+func partialMeshPath(partial string) string {
+	return strings.TrimSuffix(partial, ".bin") + ".ply"
+}
+
+// exportMesh moves the mesh the script exported at partial into place
+// next to the project at output, converting it to format, and returns the
+// final path
+func exportMesh(partial, output, format string) (string, error) {
+	path := strings.TrimSuffix(output, ".bin") + "." + format
+	if format == MeshPLY {
+		if err := os.Rename(partial, path); err != nil {
+			return "", fmt.Errorf("failed to move mesh into place: %v", err)
+		}
+		return path, nil
+	}
+
+	m, err := mesh.ReadPLY(partial)
+	if err != nil {
+		return "", err
+	}
+	err = writeArtifact(path, func(w io.Writer) error {
+		if format == MeshGLTF {
+			return mesh.WriteGLTF(w, m)
+		}
+		return mesh.WriteGLB(w, m)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to write %s: %v", format, err)
+	}
+	return path, nil
+}
+
+// writeArtifact writes a file next to a project under a temporary name and
+// moves it into place once complete
+func writeArtifact(path string, write func(io.Writer) error) error {
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".partial")
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,45 @@
+package processor
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/cloudcompare-automation/internal/las"
+)
+
+// Rough memory model of a run, from measurements on survey tiles: the
+// cloud with normals and colors, plus PoissonRecon's octree, whose leaf
+// cells cover the surface
+const (
+	cloudBytesPerPoint = 64
+	octreeBytesPerCell = 1024
+)
+
+// VoxelSize returns the edge length of PoissonRecon's leaf cells for a file
+// at the given octree depth, the smallest detail the mesh can resolve
+func VoxelSize(h las.Header, depth int) float64 {
+	return h.Extent() * poissonCubeScale / math.Exp2(float64(depth))
+}
+
+// MemoryEstimate returns the approximate peak memory in bytes needed to
+// process a file at the given octree depth
+func MemoryEstimate(h las.Header, depth int) uint64 {
+	cells := math.Exp2(float64(3 * depth)) // A cloud filling the cube
+	if voxel := VoxelSize(h, depth); voxel > 0 && h.Area() > 0 {
+		cells = math.Min(cells, h.Area()/(voxel*voxel))
+	}
+	return h.PointCount*cloudBytesPerPoint + uint64(cells*octreeBytesPerCell)
+}
+
+// DepthFor returns the octree depth a file is processed at for an
+// octree-depth value, resolving auto from the header like a run does. ok
+// is false for a value that is neither a depth nor auto.
+func DepthFor(h las.Header, value string) (depth int, ok bool) {
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(value, AutoDepth) {
+		return DepthForHeader(h), true
+	}
+	depth, err := strconv.Atoi(value)
+	return depth, err == nil && depth > 0
+}
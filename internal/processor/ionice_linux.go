@@ -0,0 +1,31 @@
+package processor
+
+import "syscall"
+
+// ioprio_set(2) constants
+const (
+	ioprioWhoProcess = 1
+	ioprioWhoPgrp    = 2
+	ioprioClassBE    = 2
+	ioprioClassIdle  = 3
+	ioprioClassShift = 13
+)
+
+// lowerIOPriority moves the process to the idle I/O class, like ionice -c 3
+func lowerIOPriority(pid int) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(pid), ioprioClassIdle<<ioprioClassShift)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// raiseIOPriority moves the process group to the highest best-effort I/O
+// level, like ionice -c 2 -n 0, which needs no privileges
+func raiseIOPriority(pgid int) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoPgrp, uintptr(pgid), ioprioClassBE<<ioprioClassShift)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !windows
+
+package processor
+
+// lowerIOPriority is not supported on this platform; niceness still applies
+func lowerIOPriority(pid int) error {
+	return nil
+}
+
+// raiseIOPriority is not supported on this platform
+func raiseIOPriority(pgid int) error {
+	return nil
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+
+package processor
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// prepareCmd puts the script in its own process group so it can be
+// stopped together with anything it spawns
+func prepareCmd(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killTree kills the script's whole process group
+func killTree(cmd *exec.Cmd) error {
+	if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+//go:build windows
+
+package processor
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// prepareCmd needs no setup on Windows; killTree walks the process tree
+func prepareCmd(cmd *exec.Cmd) {}
+
+// killTree kills Python together with any processes it started; killing
+// only Python would leave those running orphaned
+func killTree(cmd *exec.Cmd) error {
+	kill := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid))
+	if err := kill.Run(); err != nil {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
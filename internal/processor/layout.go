@@ -0,0 +1,116 @@
+package processor
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Output layouts: where in OutputSubdir the outputs of each file go
+const (
+	LayoutFlat    = "flat"     // Processed/scan1.bin, next to each input directory
+	LayoutPerFile = "per-file" // Processed/scan1/scan1.bin, a folder per file
+	LayoutDated   = "dated"    // Processed/2026-03-14_091205/scan1.bin, a folder per run
+	LayoutMirror  = "mirror"   // One Processed for all files, mirroring their directories under it
+)
+
+// OutputLayouts are the accepted OutputLayout values
+var OutputLayouts = []string{LayoutFlat, LayoutPerFile, LayoutDated, LayoutMirror}
+
+// runFolderFormat names the run folders of LayoutDated
+const runFolderFormat = "2006-01-02_150405"
+
+// CheckOutputLayout reports an unknown output layout
+func CheckOutputLayout(layout string) error {
+	return checkChoice("output layout", layout, OutputLayouts)
+}
+
+// OutputPlan places the outputs of a batch's files. The processor, the
+// checks before a run and the Configuration screen's preview all plan
+// with it, so they agree on where a file's project goes.
+type OutputPlan struct {
+	params Params
+	root   string // Common directory of the files, for LayoutMirror
+	run    string // Run folder, for LayoutDated
+}
+
+// Plan places the outputs of files for a run started at started; see
+// Params.RunStarted
+func (params Params) Plan(files []string, started time.Time) OutputPlan {
+	plan := OutputPlan{params: params}
+	switch params.OutputLayout {
+	case LayoutDated:
+		if !params.RunStarted.IsZero() {
+			started = params.RunStarted
+		}
+		plan.run = started.Format(runFolderFormat)
+	case LayoutMirror:
+		plan.root = commonDir(files)
+	}
+	return plan
+}
+
+// Base returns the output directory of file's batch, which the run locks
+// and writes the report to
+func (o OutputPlan) Base(file string) string {
+	if o.root != "" {
+		return filepath.Join(o.root, o.params.OutputSubdir)
+	}
+	return filepath.Join(filepath.Dir(file), o.params.OutputSubdir, o.run)
+}
+
+// Dir returns the directory of file's outputs
+func (o OutputPlan) Dir(file string) string {
+	switch {
+	case o.params.OutputLayout == LayoutPerFile:
+		return filepath.Join(o.Base(file), o.params.outputName(file))
+	case o.root != "":
+		if rel, err := filepath.Rel(o.root, filepath.Dir(file)); err == nil {
+			return filepath.Join(o.Base(file), rel)
+		}
+	}
+	return o.Base(file)
+}
+
+// Output returns the path of the project saved for file
+func (o OutputPlan) Output(file string) string {
+	return filepath.Join(o.Dir(file), o.params.outputName(file)+".bin")
+}
+
+// Bases returns the output directories of files, in order of first use;
+// see Base
+func (o OutputPlan) Bases(files []string) []string {
+	var dirs []string
+	seen := make(map[string]bool)
+	for _, file := range files {
+		dir := o.Base(file)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// commonDir returns the deepest directory holding all files, or "" when
+// they have none in common, e.g. on different drives
+func commonDir(files []string) string {
+	if len(files) == 0 {
+		return ""
+	}
+	common := filepath.Dir(files[0])
+	for _, file := range files[1:] {
+		dir := filepath.Dir(file)
+		for {
+			if rel, err := filepath.Rel(common, dir); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				break
+			}
+			parent := filepath.Dir(common)
+			if parent == common {
+				return ""
+			}
+			common = parent
+		}
+	}
+	return common
+}
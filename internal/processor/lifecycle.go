@@ -0,0 +1,70 @@
+package processor
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// A run ends in finish, which closes the log channel and sends the result
+// exactly once, after the file loop and everything reading the script's
+// output are done. The processes the run starts go through start and wait,
+// so Stop kills only a process that is running and none is started after
+// it.
+
+// errStopped is returned by start once Stop has been called
+var errStopped = errors.New("stopped")
+
+// Hooks are points in a run where tests can step in, to drive a run
+// without CloudComPy and to stop it at a chosen moment. All are optional.
+type Hooks struct {
+	// Command makes the command run for file in place of the Python
+	// script, with the arguments the script would get; the Python
+	// environment isn't activated
+	Command func(file string, args []string) *exec.Cmd
+
+	// AfterFile is called once each file's result is counted, before the
+	// next file starts
+	AfterFile func(FileResult)
+
+	// BeforeResult is called once the log channel is closed, just before
+	// the result is sent
+	BeforeResult func(ProcessingResult)
+}
+
+// SetHooks sets the hooks of the run; call it before Start, after which
+// it changes nothing
+func (p *Processor) SetHooks(hooks Hooks) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running || p.started {
+		return
+	}
+	p.hooks = hooks
+}
+
+// start starts cmd as the process Stop kills. Once Stop has been called it
+// isn't started, and errStopped is returned.
+func (p *Processor) start(cmd *exec.Cmd) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped {
+		return errStopped
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	p.cmd = cmd
+	return nil
+}
+
+// wait waits for cmd to exit, then forgets it, so a later Stop can't kill
+// a process that has taken its PID since
+func (p *Processor) wait(cmd *exec.Cmd) error {
+	err := cmd.Wait()
+	p.mu.Lock()
+	if p.cmd == cmd {
+		p.cmd = nil
+	}
+	p.mu.Unlock()
+	return err
+}
@@ -0,0 +1,272 @@
+package processor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The script is played by this test binary: TestHelperProcess acts as the
+// script when run with CCAUTO_TEST_SCRIPT set to its behaviour.
+func TestHelperProcess(t *testing.T) {
+	switch os.Getenv("CCAUTO_TEST_SCRIPT") {
+	case "":
+		return
+	case "hang":
+		fmt.Println("ready")
+		time.Sleep(time.Minute)
+	case "stream":
+		playStream()
+	default:
+		fmt.Println("done")
+	}
+	os.Exit(0)
+}
+
+// helperCommand runs TestHelperProcess with behaviour as the script
+func helperCommand(behaviour string) func(string, []string) *exec.Cmd {
+	return func(file string, args []string) *exec.Cmd {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+		cmd.Env = append(os.Environ(), "CCAUTO_TEST_SCRIPT="+behaviour)
+		return cmd
+	}
+}
+
+// newTestProcessor makes a processor for a directory of files, with the
+// user directories the run writes to in a temporary directory
+func newTestProcessor(t *testing.T, files int, hooks Hooks) *Processor {
+	t.Helper()
+	home := t.TempDir()
+	for _, env := range []string{"HOME", "XDG_CONFIG_HOME", "XDG_CACHE_HOME", "APPDATA", "LOCALAPPDATA"} {
+		t.Setenv(env, home)
+	}
+
+	dir := t.TempDir()
+	for i := 1; i <= files; i++ {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("scan%d.las", i)), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	script := filepath.Join(dir, "pipeline.py")
+	for _, path := range []string{script, filepath.Join(dir, "process_las_files.py")} {
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := New(Params{InputDir: dir, OutputSubdir: "Processed", Script: script, ScriptDir: dir})
+	p.SetHooks(hooks)
+	return p
+}
+
+// collect reads the log to its close and then the result, failing the test
+// if either takes too long
+func collect(t *testing.T, p *Processor, onEntry func(LogEntry)) ([]LogEntry, ProcessingResult) {
+	t.Helper()
+	timeout := time.After(30 * time.Second)
+	var entries []LogEntry
+	for {
+		select {
+		case entry, ok := <-p.LogChan():
+			if !ok {
+				select {
+				case result := <-p.ResultChan():
+					return entries, result
+				case <-timeout:
+					t.Fatal("no result after the log was closed")
+				}
+			}
+			entries = append(entries, entry)
+			if onEntry != nil {
+				onEntry(entry)
+			}
+		case <-timeout:
+			t.Fatal("log not closed")
+		}
+	}
+}
+
+// assertOneResult checks that no second result follows the first
+func assertOneResult(t *testing.T, p *Processor) {
+	t.Helper()
+	select {
+	case <-p.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Done not closed after the result")
+	}
+	select {
+	case result := <-p.ResultChan():
+		t.Fatalf("second result: %+v", result)
+	default:
+	}
+	if p.IsRunning() {
+		t.Error("still running after the result")
+	}
+}
+
+func TestRunSendsOneResultAfterTheLog(t *testing.T) {
+	p := newTestProcessor(t, 2, Hooks{Command: helperCommand("ok")})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	entries, result := collect(t, p, nil)
+	assertOneResult(t, p)
+
+	if result.SuccessCount != 2 || result.FailedCount != 0 || result.Stopped {
+		t.Errorf("result = %d succeeded, %d failed, stopped %v; want 2, 0, false", result.SuccessCount, result.FailedCount, result.Stopped)
+	}
+	finished := 0
+	for _, entry := range entries {
+		if entry.Outcome != "" {
+			finished++
+		}
+	}
+	if finished != 2 {
+		t.Errorf("got %d Finished entries before the result, want 2", finished)
+	}
+	if err := p.Start(); err == nil {
+		t.Error("second Start succeeded")
+	}
+}
+
+func TestStopDuringFile(t *testing.T) {
+	p := newTestProcessor(t, 2, Hooks{Command: helperCommand("hang")})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	_, result := collect(t, p, func(entry LogEntry) {
+		if strings.Contains(entry.Message, "ready") {
+			p.Stop()
+		}
+	})
+	assertOneResult(t, p)
+
+	if !result.Stopped {
+		t.Error("result not marked stopped")
+	}
+	if len(result.Files) != 0 || result.FailedCount != 0 {
+		t.Errorf("interrupted file counted: %d file(s), %d failed", len(result.Files), result.FailedCount)
+	}
+}
+
+func TestStopBeforeStart(t *testing.T) {
+	p := newTestProcessor(t, 2, Hooks{Command: helperCommand("hang")})
+	p.Stop()
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	_, result := collect(t, p, nil)
+	assertOneResult(t, p)
+
+	if !result.Stopped || len(result.Files) != 0 {
+		t.Errorf("result = stopped %v with %d file(s), want stopped with none", result.Stopped, len(result.Files))
+	}
+}
+
+// Stops arriving as the run completes, from several goroutines, must
+// neither mark the finished run stopped nor send a second result
+func TestStopNearCompletion(t *testing.T) {
+	var p *Processor
+	var wg sync.WaitGroup
+	stop := func() {
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				p.Stop()
+			}()
+		}
+	}
+	last := 0
+	p = newTestProcessor(t, 2, Hooks{
+		Command: helperCommand("ok"),
+		AfterFile: func(FileResult) {
+			if last++; last == 2 {
+				stop()
+			}
+		},
+		BeforeResult: func(ProcessingResult) { stop() },
+	})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	_, result := collect(t, p, nil)
+	assertOneResult(t, p)
+	wg.Wait()
+	p.Stop()
+
+	if result.Stopped || result.SuccessCount != 2 {
+		t.Errorf("result = stopped %v with %d succeeded, want not stopped with 2", result.Stopped, result.SuccessCount)
+	}
+}
+
+// A program embedding the processor calls its methods from its own
+// goroutines while the run's goroutines work; run with -race, this shows
+// they share nothing unguarded, and that the setters leave a started run
+// as it was
+func TestConcurrentAccess(t *testing.T) {
+	p := newTestProcessor(t, 3, Hooks{Command: streamCommand(streamPath(t, "warnings.stdout"), "", 0)})
+	table := filepath.Join(t.TempDir(), "metadata.csv")
+	if err := os.WriteFile(table, []byte("pattern,site\nscan*,harbour\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	params := p.GetParams()
+	params.MetadataLookup = MetadataLookup{CSV: table}
+	params.Prefetch = 1
+	params.StallAfter = time.Millisecond
+	p.SetParams(params)
+	var files int
+	p.Observe(Observer{OnFileDone: func(FileResult) { files++ }})
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	script := p.ScriptPath()
+
+	changed := params
+	changed.OutputSubdir = "Elsewhere"
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				case <-time.After(time.Millisecond):
+				}
+				p.IsRunning()
+				p.GetParams()
+				p.SetParams(changed)
+				p.SetHooks(Hooks{})
+				p.Observe(Observer{OnFileDone: func(FileResult) { t.Error("observer set after Start called") }})
+				p.FindScripts()
+				if got := p.ScriptPath(); got != script {
+					t.Errorf("script changed to %s while running", got)
+				}
+			}
+		}()
+	}
+	_, result := collect(t, p, nil)
+	close(done)
+	wg.Wait()
+	assertOneResult(t, p)
+
+	if result.FailedCount != 0 || files != 3 {
+		t.Errorf("%d file(s) observed, %d failed; want 3 and none", files, result.FailedCount)
+	}
+	if got := p.GetParams().OutputSubdir; got != params.OutputSubdir {
+		t.Errorf("output subdirectory changed to %s after Start", got)
+	}
+	for _, f := range result.Files {
+		if f.Metadata["site"] != "harbour" {
+			t.Errorf("%s: metadata %v, want the looked up site", filepath.Base(f.InputFile), f.Metadata)
+		}
+	}
+}
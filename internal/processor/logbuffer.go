@@ -0,0 +1,153 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Log overflow strategies, for when the log channel is full because its
+// reader has fallen behind
+const (
+	OverflowDropOldest = "drop-oldest" // Drop the oldest entry to make room
+	OverflowBlock      = "block"       // Wait for the reader; processing waits with it
+	OverflowSpill      = "spill"       // Archive the entry to a spill file instead of dropping it
+)
+
+// LogOverflows are the accepted LogOverflow values
+var LogOverflows = []string{OverflowDropOldest, OverflowBlock, OverflowSpill}
+
+// DefaultLogBuffer is the capacity of the log channel when LogBuffer is 0
+const DefaultLogBuffer = 500
+
+// logBuffer returns the capacity of the log channel
+func (params Params) logBuffer() int {
+	if params.LogBuffer > 0 {
+		return params.LogBuffer
+	}
+	return DefaultLogBuffer
+}
+
+// CheckLogOverflow reports an unknown log overflow strategy
+func CheckLogOverflow(strategy string) error {
+	return checkChoice("log overflow", strategy, LogOverflows)
+}
+
+// SpillDir returns the directory of the spill files, named after the
+// batch ID
+func SpillDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "cloudcompare-automation", "spill"), nil
+}
+
+// send queues entry on the log channel, after telling the observer of a
+// step it announces. When the channel is full, the entry is handled as
+// LogOverflow says, and counted if it doesn't reach the channel.
+func (p *Processor) send(entry LogEntry) {
+	p.stepStarted(entry)
+	select {
+	case p.logChan <- entry:
+		return
+	default:
+	}
+
+	switch p.params.LogOverflow {
+	case OverflowBlock:
+		p.logChan <- entry
+	case OverflowSpill:
+		p.spill(entry)
+	default:
+		// Drop the oldest entry and add the new one; with other senders
+		// racing for the room, the new one may not fit either
+		dropped := 0
+		select {
+		case <-p.logChan:
+			dropped++
+		default:
+		}
+		select {
+		case p.logChan <- entry:
+		default:
+			dropped++
+		}
+		p.mu.Lock()
+		p.dropped += dropped
+		p.mu.Unlock()
+	}
+}
+
+// spill appends entry to the batch's spill file as a JSON line, opening it
+// on first use; if that fails the entry is dropped. Spilled entries are
+// archived, not replayed: the reader never gets them, and the entries
+// after them reach the channel again once there is room, so the log read
+// has holes the spill file fills.
+func (p *Processor) spill(entry LogEntry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.spillFile == nil && p.spillErr == nil {
+		p.spillFile, p.spillErr = openSpill(entry.Batch)
+	}
+	if p.spillFile == nil {
+		p.dropped++
+		return
+	}
+	data, _ := json.Marshal(entry)
+	if _, err := p.spillFile.Write(append(data, '\n')); err != nil {
+		p.dropped++
+		return
+	}
+	p.spilled++
+}
+
+// openSpill creates the spill file of a batch
+func openSpill(batch string) (*os.File, error) {
+	dir, err := SpillDir()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if batch == "" {
+		batch = newBatchID()
+	}
+	return os.OpenFile(filepath.Join(dir, batch+".jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+}
+
+// logLosses reports the entries that didn't reach the log channel, on the
+// log and in result, and closes the spill file
+func (p *Processor) logLosses(result *ProcessingResult) {
+	p.mu.Lock()
+	dropped, spilled, spillErr := p.dropped, p.spilled, p.spillErr
+	spillPath := ""
+	if p.spillFile != nil {
+		spillPath = p.spillFile.Name()
+	}
+	p.mu.Unlock()
+
+	// These may not fit either, and spill to the same file
+	if spillErr != nil {
+		p.sendLog(LogWarning, fmt.Sprintf("Failed to create log spill file: %v", spillErr))
+	}
+	if spilled > 0 {
+		p.sendLog(LogWarning, fmt.Sprintf("%d log entries arrived while the log was full and are missing from this log; they were archived to %s", spilled, spillPath))
+	}
+	if dropped > 0 {
+		p.sendLog(LogWarning, fmt.Sprintf("%d log entries were dropped while the log was full; raise log_buffer or set log_overflow", dropped))
+	}
+	result.DroppedLogs = dropped
+	result.SpilledLogs = spilled
+	result.SpillFile = spillPath
+
+	p.mu.Lock()
+	if p.spillFile != nil {
+		p.spillFile.Close()
+	}
+	p.dropped, p.spilled = 0, 0
+	p.spillFile, p.spillErr = nil, nil
+	p.mu.Unlock()
+}
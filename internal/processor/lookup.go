@@ -0,0 +1,244 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetadataLookup fills in the metadata of each file from a project
+// management table, so the site, client or survey date of a batch of
+// mixed files needn't be edited into reports and output names by hand.
+// The table has a pattern column of file name globs and a column per
+// metadata key; the first row whose pattern matches a file's name gives
+// its metadata, over the run's. Empty cells leave the run's value.
+type MetadataLookup struct {
+	CSV     string // Table file
+	Command string // Command printing the table as CSV, e.g. a database export
+
+	// table is the one a run read at its start, which all its files use
+	table *metadataTable
+}
+
+// Enabled reports whether a table is set
+func (l MetadataLookup) Enabled() bool {
+	return l.CSV != "" || l.Command != ""
+}
+
+// Source names the table in the log
+func (l MetadataLookup) Source() string {
+	if l.CSV != "" {
+		return filepath.Base(l.CSV)
+	}
+	return "the lookup command"
+}
+
+// CheckMetadataLookup reports a lookup that can't be used: both a file
+// and a command, or a command that can't be split into arguments
+func CheckMetadataLookup(l MetadataLookup) error {
+	if l.CSV != "" && l.Command != "" {
+		return fmt.Errorf("metadata lookup takes a csv file or a command, not both")
+	}
+	if l.Command != "" {
+		if _, err := expandCommand(l.Command, nil); err != nil {
+			return fmt.Errorf("metadata lookup command: %v", err)
+		}
+	}
+	return nil
+}
+
+// lookupTimeout is how long the lookup command may take
+const lookupTimeout = 2 * time.Minute
+
+// lookupReuse is how long a table printed by the command is reused for
+// checks and previews before the command runs again
+const lookupReuse = time.Minute
+
+// metadataTable is a loaded lookup table
+type metadataTable struct {
+	rows []metadataRow
+}
+
+// metadataRow is the metadata of the files matching pattern
+type metadataRow struct {
+	pattern string
+	values  map[string]string
+}
+
+// match returns the metadata of the first row matching file's name
+func (t *metadataTable) match(file string) (map[string]string, bool) {
+	name := strings.ToLower(filepath.Base(file))
+	for _, row := range t.rows {
+		if ok, _ := filepath.Match(row.pattern, name); ok {
+			return row.values, true
+		}
+	}
+	return nil, false
+}
+
+// lookupCache keeps the last table loaded, so planning outputs names
+// doesn't read it for every file
+var lookupCache struct {
+	sync.Mutex
+	csv     string
+	command string
+	stamp   string // Size and time of the CSV file
+	loaded  time.Time
+	table   *metadataTable
+}
+
+// load returns the table, read again if the file changed or, for the
+// command, if it was printed more than lookupReuse ago; fresh always
+// reads it again
+func (l MetadataLookup) load(fresh bool) (*metadataTable, error) {
+	lookupCache.Lock()
+	defer lookupCache.Unlock()
+
+	stamp := ""
+	if l.CSV != "" {
+		info, err := os.Stat(l.CSV)
+		if err != nil {
+			return nil, err
+		}
+		stamp = fmt.Sprint(info.Size(), info.ModTime().UnixNano())
+	}
+	if !fresh && lookupCache.table != nil && lookupCache.csv == l.CSV && lookupCache.command == l.Command && lookupCache.stamp == stamp &&
+		(l.CSV != "" || time.Since(lookupCache.loaded) < lookupReuse) {
+		return lookupCache.table, nil
+	}
+
+	var data []byte
+	var err error
+	if l.CSV != "" {
+		data, err = os.ReadFile(longPath(l.CSV))
+	} else {
+		data, err = runLookupCommand(l.Command)
+	}
+	if err != nil {
+		return nil, err
+	}
+	table, err := parseMetadataTable(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	lookupCache.csv, lookupCache.command = l.CSV, l.Command
+	lookupCache.stamp, lookupCache.loaded, lookupCache.table = stamp, time.Now(), table
+	return table, nil
+}
+
+// runLookupCommand returns what the lookup command prints
+func runLookupCommand(command string) ([]byte, error) {
+	words, err := expandCommand(command, nil)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, words[0], words[1:]...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// parseMetadataTable reads a lookup table from CSV. The header names the
+// columns; keys are lowercased with spaces as underscores, so "Survey
+// Date" fills {survey_date}.
+func parseMetadataTable(r io.Reader) (*metadataTable, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata table: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("metadata table is empty")
+	}
+
+	header := records[0]
+	patternColumn := -1
+	keys := make([]string, len(header))
+	for i, name := range header {
+		// Spreadsheets save CSV with a byte order mark
+		name = strings.TrimPrefix(strings.TrimSpace(name), "\ufeff")
+		keys[i] = strings.ReplaceAll(strings.ToLower(name), " ", "_")
+		if keys[i] == "pattern" {
+			patternColumn = i
+		}
+	}
+	if patternColumn < 0 {
+		return nil, fmt.Errorf("metadata table has no pattern column")
+	}
+
+	table := &metadataTable{}
+	for n, record := range records[1:] {
+		if patternColumn >= len(record) || strings.TrimSpace(record[patternColumn]) == "" {
+			continue
+		}
+		pattern := strings.ToLower(strings.TrimSpace(record[patternColumn]))
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("metadata table row %d: invalid pattern %q", n+2, pattern)
+		}
+		row := metadataRow{pattern: pattern, values: make(map[string]string)}
+		for i, value := range record {
+			if i == patternColumn || i >= len(keys) || keys[i] == "" {
+				continue
+			}
+			if value = strings.TrimSpace(value); value != "" {
+				row.values[keys[i]] = value
+			}
+		}
+		table.rows = append(table.rows, row)
+	}
+	return table, nil
+}
+
+// lookupMetadata returns the metadata the lookup table has for file. A
+// run uses the table it read at its start; checks and previews a cached
+// one, and without one there is none.
+func (params Params) lookupMetadata(file string) (map[string]string, bool) {
+	lookup := params.MetadataLookup
+	if !lookup.Enabled() {
+		return nil, false
+	}
+	table := lookup.table
+	if table == nil {
+		var err error
+		if table, err = lookup.load(false); err != nil {
+			return nil, false
+		}
+	}
+	return table.match(file)
+}
+
+// fileMetadata returns the metadata of file: the run's, with what the
+// lookup table has for it on top
+func (params Params) fileMetadata(file string) map[string]string {
+	found, _ := params.lookupMetadata(file)
+	if len(found) == 0 {
+		return params.Metadata
+	}
+	metadata := make(map[string]string, len(params.Metadata)+len(found))
+	for key, value := range params.Metadata {
+		metadata[key] = value
+	}
+	for key, value := range found {
+		metadata[key] = value
+	}
+	return metadata
+}
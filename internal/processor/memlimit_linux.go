@@ -0,0 +1,94 @@
+package processor
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// memoryLimits reports whether MemoryLimit can be applied here
+const memoryLimits = true
+
+// memoryPoll is how often the memory of a limited script is measured
+const memoryPoll = 250 * time.Millisecond
+
+// limitMemory caps the resident memory of a started script and the
+// processes it starts, its process group, at limit bytes. A watchdog
+// measures it a few times a second and kills the group once it goes over,
+// like the job object on Windows; the returned function stops the
+// watchdog once the script has been waited for, and reports whether it
+// killed the script.
+func limitMemory(cmd *exec.Cmd, limit uint64) (func() bool, error) {
+	pgid := cmd.Process.Pid
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	exceeded := false
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(memoryPoll)
+		defer ticker.Stop()
+		for {
+			rss, alive := groupMemory(pgid)
+			if !alive {
+				return
+			}
+			if rss > limit {
+				exceeded = true
+				syscall.Kill(-pgid, syscall.SIGKILL)
+				return
+			}
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() bool {
+		close(stop)
+		<-done
+		return exceeded
+	}, nil
+}
+
+// groupMemory sums the resident memory of the processes in process group
+// pgid, and reports whether any is left
+func groupMemory(pgid int) (uint64, bool) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0, false
+	}
+	var total uint64
+	alive := false
+	page := uint64(os.Getpagesize())
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		dir := filepath.Join("/proc", entry.Name())
+		stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+		if err != nil {
+			continue
+		}
+		// After the command name in parentheses: state, ppid, pgrp
+		fields := bytes.Fields(stat[bytes.LastIndexByte(stat, ')')+1:])
+		if len(fields) < 3 || string(fields[2]) != strconv.Itoa(pgid) {
+			continue
+		}
+		alive = true
+		statm, err := os.ReadFile(filepath.Join(dir, "statm"))
+		if err != nil {
+			continue
+		}
+		// Total program size, then resident pages
+		if fields := bytes.Fields(statm); len(fields) > 1 {
+			pages, _ := strconv.ParseUint(string(fields[1]), 10, 64)
+			total += pages * page
+		}
+	}
+	return total, alive
+}
@@ -0,0 +1,16 @@
+//go:build !linux && !windows
+
+package processor
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// memoryLimits reports whether MemoryLimit can be applied here
+const memoryLimits = false
+
+// limitMemory is not supported on this platform
+func limitMemory(cmd *exec.Cmd, limit uint64) (func() bool, error) {
+	return nil, errors.New("memory limits are not supported on this platform")
+}
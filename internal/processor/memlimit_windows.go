@@ -0,0 +1,97 @@
+//go:build windows
+
+package processor
+
+import (
+	"os/exec"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// memoryLimits reports whether MemoryLimit can be applied here
+const memoryLimits = true
+
+// Job object messages, which the windows package doesn't define
+const (
+	jobMsgActiveProcessZero = 4
+	jobMsgJobMemoryLimit    = 10
+)
+
+// jobCompletionPort is JOBOBJECT_ASSOCIATE_COMPLETION_PORT
+type jobCompletionPort struct {
+	CompletionKey  uintptr
+	CompletionPort windows.Handle
+}
+
+// limitMemory caps the memory committed by a started script and the
+// processes it starts afterwards at limit bytes, with a job object of its
+// own. The job is killed when it reaches the cap; the returned function
+// reports whether it did, once the script has been waited for.
+func limitMemory(cmd *exec.Cmd, limit uint64) (func() bool, error) {
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	port, err := windows.CreateIoCompletionPort(windows.InvalidHandle, 0, 0, 1)
+	if err != nil {
+		windows.CloseHandle(job)
+		return nil, err
+	}
+	fail := func(err error) (func() bool, error) {
+		windows.CloseHandle(port)
+		windows.CloseHandle(job)
+		return nil, err
+	}
+
+	info := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{JobMemoryLimit: uintptr(limit)}
+	info.BasicLimitInformation.LimitFlags = windows.JOB_OBJECT_LIMIT_JOB_MEMORY
+	if _, err := windows.SetInformationJobObject(job, windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info))); err != nil {
+		return fail(err)
+	}
+	assoc := jobCompletionPort{CompletionKey: uintptr(job), CompletionPort: port}
+	if _, err := windows.SetInformationJobObject(job, windows.JobObjectAssociateCompletionPortInformation,
+		uintptr(unsafe.Pointer(&assoc)), uint32(unsafe.Sizeof(assoc))); err != nil {
+		return fail(err)
+	}
+	process, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(cmd.Process.Pid))
+	if err == nil {
+		err = windows.AssignProcessToJobObject(job, process)
+		windows.CloseHandle(process)
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	// Allocations beyond the cap only fail; kill the job outright so a
+	// script that survives them doesn't limp on
+	exceeded := false
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			var msg uint32
+			var key uintptr
+			var overlapped *windows.Overlapped
+			if err := windows.GetQueuedCompletionStatus(port, &msg, &key, &overlapped, windows.INFINITE); err != nil {
+				return
+			}
+			switch msg {
+			case jobMsgJobMemoryLimit:
+				exceeded = true
+				windows.TerminateJobObject(job, 1)
+			case jobMsgActiveProcessZero:
+				return
+			}
+		}
+	}()
+
+	return func() bool {
+		// Closing the port ends the watch if the job's end wasn't posted
+		windows.CloseHandle(port)
+		<-done
+		windows.CloseHandle(job)
+		return exceeded
+	}, nil
+}
@@ -0,0 +1,8 @@
+package processor
+
+import "golang.org/x/sys/unix"
+
+// totalMemory returns the installed physical memory in bytes
+func totalMemory() (uint64, error) {
+	return unix.SysctlUint64("hw.memsize")
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !windows
+
+package processor
+
+import "errors"
+
+// totalMemory is not supported on this platform; the memory check is left
+// out
+func totalMemory() (uint64, error) {
+	return 0, errors.New("total memory unknown on this platform")
+}
@@ -0,0 +1,58 @@
+package processor
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// MetadataField is a standard run metadata entry with a form field
+type MetadataField struct {
+	Key   string
+	Label string
+	Short string // Label on narrow terminals
+}
+
+// MetadataFields are the metadata entries offered on the Configuration
+// screen. Other keys can be set in the config file or with --meta.
+var MetadataFields = []MetadataField{
+	{Key: "project", Label: "Project", Short: "Project"},
+	{Key: "client", Label: "Client", Short: "Client"},
+	{Key: "operator", Label: "Operator", Short: "Operator"},
+	{Key: "capture_date", Label: "Capture Date", Short: "Captured"},
+}
+
+var (
+	placeholderPattern = regexp.MustCompile(`\{([a-z0-9_]+)\}`)
+	unsafeNameChars    = regexp.MustCompile(`[<>:"/\\|?*\s]+`)
+)
+
+// outputName returns the output file name (without extension) for a LAS
+// file. With an OutputName template such as "{project}_{name}", {name} is
+// the LAS file name, {params} the ParamsHash of the parameter values and
+// other placeholders are metadata keys, looked up for the file if the
+// lookup table has them.
+func (params Params) outputName(file string) string {
+	stem := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	if params.OutputName == "" {
+		return stem
+	}
+
+	metadata := params.fileMetadata(file)
+	name := placeholderPattern.ReplaceAllStringFunc(params.OutputName, func(placeholder string) string {
+		key := placeholder[1 : len(placeholder)-1]
+		switch key {
+		case "name":
+			return stem
+		case "params":
+			return ParamsHash(params.Values)
+		}
+		return unsafeNameChars.ReplaceAllString(metadata[key], "-")
+	})
+	// Missing metadata leaves separators at the ends
+	name = strings.Trim(name, "_-. ")
+	if name == "" {
+		return stem
+	}
+	return name
+}
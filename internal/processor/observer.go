@@ -0,0 +1,76 @@
+package processor
+
+import "fmt"
+
+// A run can be followed through an Observer as well as the log channel:
+// its callbacks are called as each file starts, as the script and the
+// post-processing commands announce their steps, and as each file ends.
+
+// Step is a step of a file's processing, as announced on the log
+type Step struct {
+	File    string
+	Number  int // Counted from 1
+	Total   int
+	Post    bool   // A post-processing step; these follow the script's
+	Message string // The announcement, e.g. "[2/5] Computing normals..."
+}
+
+// Observer is called as a run progresses. Each callback is optional. They
+// are called one at a time on the run's goroutines, so they should return
+// quickly; they may call Stop.
+type Observer struct {
+	OnFileStart func(index, total int, file string) // index counts from 1
+	OnStep      func(Step)
+	OnFileDone  func(FileResult) // Not called for a file interrupted by Stop
+}
+
+// Observe sets the observer of the run; call it before Start, after which
+// it changes nothing
+func (p *Processor) Observe(o Observer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running || p.started {
+		return
+	}
+	p.observer = o
+}
+
+// fileStarted tells the observer that a file has started
+func (p *Processor) fileStarted(index, total int, file string) {
+	if p.observer.OnFileStart == nil {
+		return
+	}
+	p.observeMu.Lock()
+	defer p.observeMu.Unlock()
+	p.observer.OnFileStart(index, total, file)
+}
+
+// stepStarted tells the observer of the step a log entry announces, if any
+func (p *Processor) stepStarted(entry LogEntry) {
+	if p.observer.OnStep == nil {
+		return
+	}
+	step := Step{File: entry.File, Message: entry.Message}
+	if n, total, ok := ParseStep(entry.Message); ok {
+		step.Number, step.Total = n, total
+	} else if m := postStepPattern.FindStringSubmatch(entry.Message); m != nil {
+		fmt.Sscan(m[1], &step.Number)
+		fmt.Sscan(m[2], &step.Total)
+		step.Post = true
+	} else {
+		return
+	}
+	p.observeMu.Lock()
+	defer p.observeMu.Unlock()
+	p.observer.OnStep(step)
+}
+
+// fileDone tells the observer that a file has ended
+func (p *Processor) fileDone(fileResult FileResult) {
+	if p.observer.OnFileDone == nil {
+		return
+	}
+	p.observeMu.Lock()
+	defer p.observeMu.Unlock()
+	p.observer.OnFileDone(fileResult)
+}
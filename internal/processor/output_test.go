@@ -0,0 +1,167 @@
+package processor
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// readAll runs the output reader on data and returns what it logged
+func readAll(t *testing.T, data []byte) []LogEntry {
+	t.Helper()
+	p := New(Params{LogOverflow: OverflowBlock})
+	out := &fileOutput{meshFaces: -1, last: time.Now()}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.readOutput(bytes.NewReader(data), out)
+	}()
+
+	var entries []LogEntry
+	timeout := time.After(30 * time.Second)
+	for {
+		select {
+		case entry := <-p.LogChan():
+			entries = append(entries, entry)
+		case <-done:
+			for {
+				select {
+				case entry := <-p.LogChan():
+					entries = append(entries, entry)
+				default:
+					return entries
+				}
+			}
+		case <-timeout:
+			t.Fatal("output not read")
+		}
+	}
+}
+
+// addStreams seeds f with the stream fixtures, whole or by line
+func addStreams(f *testing.F, byLine bool) {
+	paths, err := filepath.Glob(filepath.Join(streamDir, "*.std*"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		if !byLine {
+			f.Add(data)
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			f.Add(line)
+		}
+	}
+}
+
+func FuzzParseLine(f *testing.F) {
+	addStreams(f, true)
+	for _, line := range []string{
+		"", "[INFO]", "[]", "[INFO", "[INFO]]] x", "[warning]\tcolors", "[ÉRROR] x",
+		"[ERROR] Failed to save Ø\xc3", "\xff\xfe[INFO] x", "[INFO] \xe2\x82", "[INFO] x ",
+		"=====", "---", "[SUCCESS] ===", "\r\n", "[INFO] a\nb", "10%\r20%\r[INFO] done\r",
+	} {
+		f.Add(line)
+	}
+	f.Fuzz(func(t *testing.T, line string) {
+		level, message, ok := parseLine(line)
+		if !ok {
+			// What the terminal would show of the line
+			shown := strings.TrimSpace(strings.ToValidUTF8(line, "�"))
+			shown = strings.TrimSpace(shown[strings.LastIndexByte(shown, '\r')+1:])
+			if shown != "" && !strings.HasPrefix(shown, "===") && !strings.HasPrefix(shown, "---") {
+				t.Fatalf("%q not logged", line)
+			}
+			return
+		}
+		if !utf8.ValidString(message) {
+			t.Errorf("message %q of %q isn't UTF-8", message, line)
+		}
+		if message != strings.TrimSpace(message) {
+			t.Errorf("message %q of %q isn't trimmed", message, line)
+		}
+		if level == "" {
+			if message == "" {
+				t.Errorf("blank line %q logged", line)
+			}
+			return
+		}
+		for _, r := range level {
+			if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_') {
+				t.Fatalf("level %q of %q", level, line)
+			}
+		}
+	})
+}
+
+func FuzzReadOutput(f *testing.F) {
+	addStreams(f, false)
+	f.Add([]byte("[INFO] no line ending"))
+	f.Add([]byte("\r\r\n\n[INFO]\r"))
+	f.Add([]byte("[WARNING] \xf0\x9f\x9f"))
+	f.Add([]byte{0, 1, 2, 0xff, '\n', 0x1b, '[', '3', '1', 'm'})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// The end marker must be read whatever comes before it
+		const end = "end of output"
+		data = append(data, "\n[SUCCESS] "+end+"\n"...)
+		entries := readAll(t, data)
+		if len(entries) == 0 {
+			t.Fatal("nothing logged")
+		}
+		if last := entries[len(entries)-1]; last.Level != LogSuccess || last.Message != end {
+			t.Fatalf("output read up to %s %q", last.Level, last.Message)
+		}
+		if lines := bytes.Count(data, []byte("\n")) + 1; len(entries) > lines {
+			t.Errorf("%d entries from %d lines", len(entries), lines)
+		}
+		for _, entry := range entries {
+			switch entry.Level {
+			case LogInfo, LogSuccess, LogWarning, LogError:
+			default:
+				t.Errorf("entry %q logged as %s", entry.Message, entry.Level)
+			}
+			if !utf8.ValidString(entry.Message) {
+				t.Errorf("message %q isn't UTF-8", entry.Message)
+			}
+			if strings.ContainsAny(entry.Message, "\r\n") {
+				t.Errorf("message %q spans lines", entry.Message)
+			}
+		}
+	})
+}
+
+func TestReadOutputLongLines(t *testing.T) {
+	long := strings.Repeat("Ø", maxLineLength) // Two bytes a rune, cut inside one
+	var data bytes.Buffer
+	data.WriteString("[INFO] " + long + "\n")
+	data.WriteString(strings.Repeat("x", 3*maxLineLength) + "\r\n")
+	data.WriteString("[SUCCESS] Mesh created with 12 faces")
+
+	entries := readAll(t, data.Bytes())
+	if len(entries) != 3 {
+		t.Fatalf("%d entries, want 3", len(entries))
+	}
+	for _, entry := range entries[:2] {
+		if !strings.HasSuffix(entry.Message, truncatedSuffix) {
+			t.Errorf("long line not marked truncated: …%q", entry.Message[len(entry.Message)-20:])
+		}
+		if len(entry.Message) > maxLineLength+len(truncatedSuffix) {
+			t.Errorf("line of %d bytes kept", len(entry.Message))
+		}
+		if !utf8.ValidString(entry.Message) {
+			t.Error("truncated line isn't UTF-8")
+		}
+	}
+	if last := entries[2]; last.Level != LogSuccess || last.Message != "Mesh created with 12 faces" {
+		t.Errorf("last entry %s %q", last.Level, last.Message)
+	}
+}
@@ -0,0 +1,73 @@
+package processor
+
+import (
+	"runtime"
+	"strings"
+)
+
+// maxPath is the path length from which Windows programs that aren't
+// long-path aware fail; directories are limited to 248 characters so a
+// file name still fits in MAX_PATH (260)
+const maxPath = 248
+
+// longPath returns path in the \\?\ form on Windows if it is too long for
+// programs that aren't long-path aware, such as Python without the
+// LongPathsEnabled policy. Shorter paths and other platforms are left as
+// they are.
+func longPath(path string) string {
+	if runtime.GOOS != "windows" {
+		return path
+	}
+	return extendedPath(path)
+}
+
+// extendedPath converts an absolute Windows path of maxPath characters or
+// more to the \\?\ form, \\?\UNC\server\share\... for network paths.
+// Windows doesn't normalize these, so slashes are turned into backslashes;
+// the path must already be clean. Relative paths are left as they are.
+func extendedPath(path string) string {
+	if len(path) < maxPath || strings.HasPrefix(path, `\\?\`) {
+		return path
+	}
+	path = strings.ReplaceAll(path, "/", `\`)
+	switch {
+	case strings.HasPrefix(path, `\\`):
+		return `\\?\UNC\` + path[2:]
+	case len(path) >= 3 && path[1] == ':' && path[2] == '\\':
+		return `\\?\` + path
+	}
+	return path
+}
+
+// cmdArg quotes s for a command line parsed by cmd.exe and then by the
+// Microsoft C runtime of the program it runs. s is quoted when it holds
+// spaces or anything cmd.exe acts on outside quotes, such as & or
+// parentheses. A quote in s becomes "", which the C runtime reads as a
+// quote inside quotes, and keeps cmd.exe's count of quotes even; the
+// backslashes before it are doubled. Non-ASCII text needs no quoting, as
+// the command line is passed as Unicode.
+func cmdArg(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t&|<>^()%!,;=\"") {
+		return s
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	slashes := 0
+	for _, r := range s {
+		switch r {
+		case '\\':
+			slashes++
+		case '"':
+			b.WriteString(strings.Repeat(`\`, slashes) + `""`)
+			slashes = 0
+			continue
+		default:
+			slashes = 0
+		}
+		b.WriteRune(r)
+	}
+	// Backslashes before the closing quote are doubled too
+	b.WriteString(strings.Repeat(`\`, slashes))
+	b.WriteByte('"')
+	return b.String()
+}
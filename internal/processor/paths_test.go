@@ -0,0 +1,144 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+)
+
+// awkwardArgs are arguments that have broken or could break command
+// lines
+var awkwardArgs = []string{
+	`D:\Surveys\harbour\scan1.las`,
+	`D:\Surveys 2024\harbour scan.las`,
+	`D:\Surveys (old)\scan(1).las`,
+	`\\nas01\Surveys & Scans\scan.las`,
+	`D:\Vermessung\Hafenbecken Süd\Scan Ø2.las`,
+	`D:\測量\港\スキャン.las`,
+	`D:\Surveys\`,
+	`D:\Surveys 2024\`,
+	`D:\Surveys\done!\scan.las`,
+	`operator=J. "Jo" Smith`,
+	`a\"b`,
+	`50%`,
+	`x;y,z`,
+	``,
+}
+
+func TestCmdArgRoundTrip(t *testing.T) {
+	for _, arg := range awkwardArgs {
+		got := parseCRTArgs(cmdArg(arg))
+		if len(got) != 1 || got[0] != arg {
+			t.Errorf("cmdArg(%q) = %s, parsed back as %q", arg, cmdArg(arg), got)
+		}
+	}
+}
+
+func TestExtendedPath(t *testing.T) {
+	deep := strings.Repeat(`level\`, 45) + "scan.las"
+	tests := []struct {
+		path, want string
+	}{
+		{`D:\Surveys\scan.las`, `D:\Surveys\scan.las`},
+		{`D:\` + deep, `\\?\D:\` + deep},
+		{`D:/` + strings.ReplaceAll(deep, `\`, "/"), `\\?\D:\` + deep},
+		{`\\nas01\surveys\` + deep, `\\?\UNC\nas01\surveys\` + deep},
+		{`\\?\D:\` + deep, `\\?\D:\` + deep},
+		{`D:\测量\` + deep, `\\?\D:\测量\` + deep},
+		{deep, deep},
+	}
+	for _, tt := range tests {
+		if got := extendedPath(tt.path); got != tt.want {
+			t.Errorf("extendedPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		arg, want string
+	}{
+		{"/data/scan1.las", "/data/scan1.las"},
+		{"/data/surveys 2024/scan (1).las", "'/data/surveys 2024/scan (1).las'"},
+		{"/data/Hafen Süd/scan.las", "'/data/Hafen Süd/scan.las'"},
+		{"operator=O'Brien", `'operator=O'\''Brien'`},
+		{"", "''"},
+	}
+	for _, tt := range tests {
+		if got := shellQuote(tt.arg); got != tt.want {
+			t.Errorf("shellQuote(%q) = %s, want %s", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestBatchQuote(t *testing.T) {
+	tests := []struct {
+		arg, want string
+	}{
+		{`D:\Surveys\scan1.las`, `D:\Surveys\scan1.las`},
+		{`D:\Surveys (old)\scan 1.las`, `"D:\Surveys (old)\scan 1.las"`},
+		{`D:\R&D\scan.las`, `"D:\R&D\scan.las"`},
+		{`D:\100%\scan.las`, `D:\100%%\scan.las`},
+		{`D:\測量\scan.las`, `D:\測量\scan.las`},
+	}
+	for _, tt := range tests {
+		if got := batchQuote(tt.arg); got != tt.want {
+			t.Errorf("batchQuote(%q) = %s, want %s", tt.arg, got, tt.want)
+		}
+	}
+}
+
+// parseCRTArgs splits a command line as the Microsoft C runtime does for
+// a program's argv, e.g. Python's sys.argv
+func parseCRTArgs(s string) []string {
+	args := []string{}
+	var arg strings.Builder
+	inArg, quoted := false, false
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\':
+			n := 0
+			for i < len(runes) && runes[i] == '\\' {
+				n++
+				i++
+			}
+			if i < len(runes) && runes[i] == '"' {
+				// 2n backslashes and a quote: n backslashes, and the
+				// quote toggles; 2n+1: n backslashes and a literal quote
+				arg.WriteString(strings.Repeat(`\`, n/2))
+				if n%2 == 1 {
+					arg.WriteRune('"')
+				} else {
+					i--
+				}
+			} else {
+				arg.WriteString(strings.Repeat(`\`, n))
+				i--
+			}
+			inArg = true
+		case r == '"':
+			if quoted && i+1 < len(runes) && runes[i+1] == '"' {
+				// "" inside quotes is a literal quote
+				arg.WriteRune('"')
+				i++
+			} else {
+				quoted = !quoted
+			}
+			inArg = true
+		case (r == ' ' || r == '\t') && !quoted:
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args
+}
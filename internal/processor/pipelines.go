@@ -0,0 +1,176 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// pipelineSuffix marks additional pipeline scripts kept next to the
+// default one, e.g. normals_export_pipeline.py
+const pipelineSuffix = "_pipeline.py"
+
+// Pipeline is a processing script that can be selected for a run. Every
+// pipeline takes an input directory or file followed by --<name> <value>
+// for each of its parameters, and prints the same [LEVEL] log lines as
+// process_las_files.py. For the pipeline view, a script announces its
+// steps for each file with "Steps: Load | Save" and then starts each
+// with "[n/total] ...". A step may add how the view shows it, as in
+// "Steps: Loading point cloud; short=Load; icon=📂 | Saving project".
+type Pipeline struct {
+	Name        string
+	Script      string // Absolute path to the Python script
+	Description string
+	Params      []ParamSpec
+}
+
+// DefaultPipeline is the name of the built-in process_las_files.py pipeline
+const DefaultPipeline = "Mesh reconstruction"
+
+// DiscoverPipelines returns the default pipeline followed by any
+// *_pipeline.py scripts found in the same directory, sorted by name, each
+// with the parameter schema shipped next to it. Schema errors are
+// returned together with the pipelines, which then use DefaultSchema.
+func DiscoverPipelines() ([]Pipeline, error) {
+	p := New(DefaultParams())
+	if err := p.FindScripts(); err != nil {
+		return []Pipeline{{Name: DefaultPipeline, Params: DefaultSchema}}, err
+	}
+
+	scripts := []string{p.scriptPath}
+	matches, _ := filepath.Glob(filepath.Join(p.scriptDir, "*"+pipelineSuffix))
+	sort.Strings(matches)
+	scripts = append(scripts, matches...)
+
+	var pipelines []Pipeline
+	var errs []error
+	for i, script := range scripts {
+		schema, err := LoadSchema(script)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		pipeline := Pipeline{Name: pipelineName(script), Script: script, Params: schema}
+		if i == 0 {
+			pipeline.Name = DefaultPipeline
+			pipeline.Description = "Normals, Poisson mesh and CloudCompare project"
+		}
+		pipelines = append(pipelines, pipeline)
+	}
+	return pipelines, errors.Join(errs...)
+}
+
+// Step announcements of pipeline scripts
+var (
+	stepsPattern = regexp.MustCompile(`^Steps: (.+)$`)
+	stepPattern  = regexp.MustCompile(`^\[(\d+)/(\d+)\] `)
+)
+
+// StepLabel is a step a pipeline script announces, as the pipeline view
+// shows it
+type StepLabel struct {
+	Name  string
+	Short string // Name on narrow terminals, e.g. "Normals" (optional)
+	Icon  string // Shown before the name, e.g. "🧭" (optional)
+}
+
+// ParseSteps returns the steps a log message announces. Attributes other
+// than short and icon are ignored, so scripts can announce more for later
+// versions.
+func ParseSteps(message string) ([]StepLabel, bool) {
+	m := stepsPattern.FindStringSubmatch(message)
+	if m == nil {
+		return nil, false
+	}
+	var steps []StepLabel
+	for _, step := range strings.Split(m[1], "|") {
+		fields := strings.Split(step, ";")
+		label := StepLabel{Name: strings.TrimSpace(fields[0])}
+		if label.Name == "" {
+			continue
+		}
+		for _, field := range fields[1:] {
+			key, value, _ := strings.Cut(field, "=")
+			switch strings.TrimSpace(key) {
+			case "short":
+				label.Short = strings.TrimSpace(value)
+			case "icon":
+				label.Icon = strings.TrimSpace(value)
+			}
+		}
+		steps = append(steps, label)
+	}
+	return steps, len(steps) > 0
+}
+
+// ParseStep returns the number of the step a log message starts and the
+// number of steps the script counts
+func ParseStep(message string) (n, total int, ok bool) {
+	m := stepPattern.FindStringSubmatch(message)
+	if m == nil {
+		return 0, 0, false
+	}
+	fmt.Sscan(m[1], &n)
+	fmt.Sscan(m[2], &total)
+	return n, total, n >= 1 && n <= total
+}
+
+// pipelineName derives a display name from a script file name:
+// normals_export_pipeline.py becomes "Normals export"
+func pipelineName(script string) string {
+	name := strings.TrimSuffix(filepath.Base(script), pipelineSuffix)
+	name = strings.ReplaceAll(name, "_", " ")
+	if name == "" {
+		return filepath.Base(script)
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// AddPipeline adds or replaces (by name) a pipeline declared in config.
+// A relative script path is resolved against baseDir.
+func AddPipeline(pipelines []Pipeline, pipeline Pipeline, baseDir string) ([]Pipeline, error) {
+	if pipeline.Name == "" || pipeline.Script == "" {
+		return pipelines, fmt.Errorf("pipeline needs a name and a script")
+	}
+	if !filepath.IsAbs(pipeline.Script) {
+		pipeline.Script = filepath.Join(baseDir, pipeline.Script)
+	}
+	if _, err := os.Stat(pipeline.Script); err != nil {
+		return pipelines, fmt.Errorf("pipeline %q: script not found: %s", pipeline.Name, pipeline.Script)
+	}
+	if pipeline.Params == nil {
+		schema, err := LoadSchema(pipeline.Script)
+		if err != nil {
+			return pipelines, err
+		}
+		pipeline.Params = schema
+	} else {
+		for _, spec := range pipeline.Params {
+			if err := spec.check(); err != nil {
+				return pipelines, fmt.Errorf("pipeline %q: %v", pipeline.Name, err)
+			}
+		}
+	}
+
+	for i := range pipelines {
+		if strings.EqualFold(pipelines[i].Name, pipeline.Name) {
+			pipelines[i] = pipeline
+			return pipelines, nil
+		}
+	}
+	return append(pipelines, pipeline), nil
+}
+
+// FindPipeline looks up a pipeline by name (case-insensitive) or by the
+// file name of its script
+func FindPipeline(pipelines []Pipeline, name string) (Pipeline, bool) {
+	for _, pipeline := range pipelines {
+		if strings.EqualFold(pipeline.Name, name) || strings.EqualFold(filepath.Base(pipeline.Script), name) {
+			return pipeline, true
+		}
+	}
+	return Pipeline{}, false
+}
@@ -0,0 +1,235 @@
+package processor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cloudcompare-automation/internal/report"
+)
+
+// PostCommand is a command run on the artifacts of every successfully
+// processed file, e.g. a conversion for a web viewer
+type PostCommand struct {
+	Name    string // Step name in the pipeline view and report
+	Command string // e.g. "gltf-transform draco {mesh} {mesh%.glb}.draco.glb"
+}
+
+// postStepPattern matches the log line announcing a post-processing step,
+// e.g. "[Post 1/2] glb"
+var postStepPattern = regexp.MustCompile(`^\[Post (\d+)/(\d+)\] `)
+
+// commandPlaceholder matches {key} and {key%suffix}; the latter removes
+// suffix from the end of the value
+var commandPlaceholder = regexp.MustCompile(`\{([a-z]+)(%[^}]*)?\}`)
+
+// ParsePostStep returns the number of the post-processing step a log
+// message announces
+func ParsePostStep(message string) (int, bool) {
+	m := postStepPattern.FindStringSubmatch(message)
+	if m == nil {
+		return 0, false
+	}
+	var n int
+	fmt.Sscan(m[1], &n)
+	return n, true
+}
+
+// CheckPostCommand reports a command template that can't be run: an
+// empty or unbalanced command, or an unknown placeholder
+func CheckPostCommand(c PostCommand) error {
+	if c.Name == "" {
+		return fmt.Errorf("post-processing command without a name")
+	}
+	_, err := expandCommand(c.Command, artifactPaths(FileResult{InputFile: "x.las", OutputFile: "x.bin", MeshFile: "x.glb", WebExport: "x_3dtiles"}))
+	if err != nil {
+		return fmt.Errorf("post-processing command %s: %v", c.Name, err)
+	}
+	return nil
+}
+
+// artifactPaths returns the placeholders available to post-processing
+// commands for a processed file
+func artifactPaths(f FileResult) map[string]string {
+	return map[string]string{
+		"input":  f.InputFile,
+		"output": f.OutputFile,
+		"name":   strings.TrimSuffix(filepath.Base(f.OutputFile), filepath.Ext(f.OutputFile)),
+		"dir":    filepath.Dir(f.OutputFile),
+		"mesh":   f.MeshFile,
+		"web":    f.WebExport,
+	}
+}
+
+// expandCommand splits a command template into arguments and fills in the
+// placeholders. It is split before expanding, so paths with spaces stay one
+// argument and no shell is involved; double quotes group words.
+func expandCommand(template string, paths map[string]string) ([]string, error) {
+	words, err := splitWords(template)
+	if err != nil {
+		return nil, err
+	}
+	if len(words) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	for i, word := range words {
+		var missing string
+		words[i] = commandPlaceholder.ReplaceAllStringFunc(word, func(placeholder string) string {
+			m := commandPlaceholder.FindStringSubmatch(placeholder)
+			value, ok := paths[m[1]]
+			if !ok || value == "" {
+				missing = placeholder
+			}
+			return strings.TrimSuffix(value, strings.TrimPrefix(m[2], "%"))
+		})
+		if missing == "{mesh}" || strings.HasPrefix(missing, "{mesh%") {
+			return nil, fmt.Errorf("%s needs a mesh export format", missing)
+		}
+		if missing == "{web}" || strings.HasPrefix(missing, "{web%") {
+			return nil, fmt.Errorf("%s needs a web export format", missing)
+		}
+		if missing != "" {
+			return nil, fmt.Errorf("unknown placeholder %s", missing)
+		}
+	}
+	return words, nil
+}
+
+// splitWords splits s at spaces outside double quotes
+func splitWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord, quoted := false, false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inWord = true
+		case r == ' ' && !quoted:
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unbalanced quotes")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// ExtraSteps returns the steps run after the script on every successful
+// file: the web export, the post-processing commands, then the archive
+func (params Params) ExtraSteps() []string {
+	var steps []string
+	if params.webExportEnabled() {
+		steps = append(steps, webExportStep(params.WebExport))
+	}
+	for _, c := range params.PostProcess {
+		steps = append(steps, c.Name)
+	}
+	if params.archiveEnabled() {
+		steps = append(steps, archiveStep(params.Archive))
+	}
+	return steps
+}
+
+// postProcess runs the web export, the post-processing commands and the
+// archive on a successfully processed file. A failed step leaves the
+// project in place, so it is a warning on the file rather than a failure.
+func (p *Processor) postProcess(f *FileResult, env []string) {
+	total := len(p.params.ExtraSteps())
+	n := 0
+	if p.params.webExportEnabled() {
+		n++
+		p.sendLog(LogInfo, fmt.Sprintf("[Post %d/%d] %s", n, total, webExportStep(p.params.WebExport)))
+		if err := p.webExport(f); err != nil && !p.isStopped() {
+			warning := fmt.Sprintf("Web export of %s failed: %v", filepath.Base(f.InputFile), err)
+			f.Warnings = append(f.Warnings, warning)
+			p.sendLog(LogWarning, warning)
+		}
+	}
+
+	for _, c := range p.params.PostProcess {
+		if p.isStopped() {
+			return
+		}
+		n++
+		p.sendLog(LogInfo, fmt.Sprintf("[Post %d/%d] %s", n, total, c.Name))
+
+		started := time.Now()
+		step := report.Step{Name: c.Name}
+		err := p.runPostCommand(c, f, env, &step)
+		step.Seconds = time.Since(started).Seconds()
+		if err != nil && !p.isStopped() {
+			step.Error = err.Error()
+			warning := fmt.Sprintf("Post-processing %s failed: %v", c.Name, err)
+			f.Warnings = append(f.Warnings, warning)
+			p.sendLog(LogWarning, warning)
+		}
+		f.PostSteps = append(f.PostSteps, step)
+	}
+
+	if p.params.archiveEnabled() && !p.isStopped() {
+		n++
+		p.sendLog(LogInfo, fmt.Sprintf("[Post %d/%d] %s", n, total, archiveStep(p.params.Archive)))
+		if err := p.archive(f); err != nil && !p.isStopped() {
+			warning := fmt.Sprintf("Archiving %s failed: %v", filepath.Base(f.InputFile), err)
+			f.Warnings = append(f.Warnings, warning)
+			p.sendLog(LogWarning, warning)
+		}
+	}
+}
+
+// runPostCommand runs one post-processing command, logging its output
+func (p *Processor) runPostCommand(c PostCommand, f *FileResult, env []string, step *report.Step) error {
+	args, err := expandCommand(c.Command, artifactPaths(*f))
+	if err != nil {
+		return err
+	}
+	step.Command = joinCommand(args)
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = filepath.Dir(f.OutputFile)
+	cmd.Env = append(os.Environ(), env...)
+	prepareCmd(cmd)
+	output, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stderr = cmd.Stdout
+
+	if err := p.start(cmd); err != nil {
+		if errors.Is(err, errStopped) {
+			return nil
+		}
+		return err
+	}
+	p.logCommandOutput(c.Name, output)
+	return p.wait(cmd)
+}
+
+// logCommandOutput sends each line a post-processing command prints
+func (p *Processor) logCommandOutput(name string, r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			p.sendLog(LogInfo, fmt.Sprintf("[%s] %s", name, line))
+		}
+	}
+}
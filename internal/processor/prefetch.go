@@ -0,0 +1,217 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudcompare-automation/internal/humanize"
+)
+
+// Reading a multi-GB cloud from a network share, or from S3 or SFTP
+// storage mounted as one, can take as long as processing it. With
+// Prefetch, the next files are copied into the run's workspace while the
+// current one is processing, and the script reads the local copy. Only
+// process_las_files.py is given copies: other scripts save their outputs
+// next to their input.
+
+// Transfer is the progress of a file being prefetched
+type Transfer struct {
+	File   string // Input file being copied
+	Copied int64
+	Size   int64
+	Done   bool // Copied or failed; the last entry about the file
+}
+
+// transferUpdate is how often a running transfer reports its progress
+const transferUpdate = time.Second
+
+// IsProgress reports whether the entry only updates a transfer's progress,
+// which a display showing the log as text can leave out
+func (e LogEntry) IsProgress() bool {
+	return e.Transfer != nil && !e.Transfer.Done && e.Transfer.Copied > 0
+}
+
+// fetch is the copy of one file
+type fetch struct {
+	index  int
+	file   string
+	local  string
+	size   int64
+	copied atomic.Int64
+	done   chan struct{}
+	err    error
+}
+
+// prefetcher copies the files of a batch ahead of the one processing
+type prefetcher struct {
+	p       *Processor
+	files   []string
+	dir     string
+	ahead   int
+	fetches map[int]*fetch
+	cancel  chan struct{}
+	wg      sync.WaitGroup
+}
+
+// newPrefetcher copies files ahead into dir
+func (p *Processor) newPrefetcher(files []string, dir string, ahead int) *prefetcher {
+	return &prefetcher{p: p, files: files, dir: dir, ahead: ahead, fetches: make(map[int]*fetch), cancel: make(chan struct{})}
+}
+
+// take returns the local copy of file i, waiting for it to arrive, and
+// starts copying the files after it. Without a copy it returns the file
+// itself.
+func (f *prefetcher) take(i int) string {
+	for next := i + 1; next <= i+f.ahead && next < len(f.files); next++ {
+		if _, ok := f.fetches[next]; !ok {
+			f.start(next)
+		}
+	}
+
+	fe, ok := f.fetches[i]
+	if !ok {
+		return f.files[i]
+	}
+	select {
+	case <-fe.done:
+	case <-f.p.stopping:
+		return f.files[i]
+	}
+	if fe.err != nil {
+		return f.files[i]
+	}
+	return fe.local
+}
+
+// release removes the local copy of file i
+func (f *prefetcher) release(i int) {
+	if fe, ok := f.fetches[i]; ok {
+		<-fe.done
+		os.RemoveAll(filepath.Dir(fe.local))
+		delete(f.fetches, i)
+	}
+}
+
+// close cancels the transfers still running, waits for them and removes
+// the copies
+func (f *prefetcher) close() {
+	close(f.cancel)
+	f.wg.Wait()
+	os.RemoveAll(f.dir)
+}
+
+// start copies file i in the background
+func (f *prefetcher) start(i int) {
+	file := f.files[i]
+	fe := &fetch{
+		index: i,
+		file:  file,
+		// Each copy keeps its name, which the script logs and names the
+		// outputs after
+		local: filepath.Join(f.dir, fmt.Sprint(i+1), filepath.Base(file)),
+		done:  make(chan struct{}),
+	}
+	f.fetches[i] = fe
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		defer close(fe.done)
+		fe.err = f.copy(fe)
+		if fe.err == nil {
+			return
+		}
+		os.RemoveAll(filepath.Dir(fe.local))
+		if !errors.Is(fe.err, errStopped) {
+			f.report(fe, LogWarning, true, fmt.Sprintf("Prefetch of %s failed: %v; it will be read from its directory", filepath.Base(fe.file), fe.err))
+		}
+	}()
+}
+
+// copy copies a file, reporting its progress in the log
+func (f *prefetcher) copy(fe *fetch) error {
+	info, err := os.Stat(fe.file)
+	if err != nil {
+		return err
+	}
+	fe.size = info.Size()
+	if err := os.MkdirAll(filepath.Dir(fe.local), 0o755); err != nil {
+		return err
+	}
+	src, err := os.Open(longPath(fe.file))
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(longPath(fe.local))
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	f.report(fe, LogInfo, false, fmt.Sprintf("Prefetching %s (%s)", filepath.Base(fe.file), humanize.Bytes(uint64(fe.size))))
+	started := time.Now()
+	copied := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(dst, &cancelReader{r: src, cancel: f.cancel, stopping: f.p.stopping, n: &fe.copied})
+		copied <- err
+	}()
+
+	ticker := time.NewTicker(transferUpdate)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-copied:
+			if err != nil {
+				return err
+			}
+			if err := dst.Close(); err != nil {
+				return err
+			}
+			elapsed := time.Since(started)
+			rate := float64(fe.size) / 1e6 / max(elapsed.Seconds(), 0.001)
+			f.report(fe, LogInfo, true, fmt.Sprintf("Prefetched %s (%s in %s, %s MB/s)", filepath.Base(fe.file), humanize.Bytes(uint64(fe.size)), humanize.Duration(elapsed), humanize.Float(rate, 1)))
+			return nil
+		case <-ticker.C:
+			if fe.copied.Load() == 0 {
+				continue
+			}
+			f.report(fe, LogInfo, false, fmt.Sprintf("Prefetching %s: %s of %s", filepath.Base(fe.file), humanize.Bytes(uint64(fe.copied.Load())), humanize.Bytes(uint64(fe.size))))
+		}
+	}
+}
+
+// report logs a transfer's progress, about the file being copied
+func (f *prefetcher) report(fe *fetch, level LogLevel, done bool, message string) {
+	entry := f.p.entry(level, message)
+	entry.FileIndex, entry.File = fe.index+1, fe.file
+	entry.Transfer = &Transfer{File: fe.file, Copied: fe.copied.Load(), Size: fe.size, Done: done}
+	f.p.send(entry)
+}
+
+// cancelReader reads until the prefetch is cancelled or the run stopped,
+// counting what it read
+type cancelReader struct {
+	r        io.Reader
+	cancel   <-chan struct{}
+	stopping <-chan struct{}
+	n        *atomic.Int64
+}
+
+func (c *cancelReader) Read(b []byte) (int, error) {
+	select {
+	case <-c.cancel:
+		return 0, errStopped
+	case <-c.stopping:
+		return 0, errStopped
+	default:
+	}
+	n, err := c.r.Read(b)
+	c.n.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,98 @@
+package processor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudcompare-automation/internal/humanize"
+	"github.com/cloudcompare-automation/internal/las"
+)
+
+// outputBytesFactor is roughly how much disk space a run's outputs take
+// per byte of input: the project holds the cloud with normals and the
+// mesh, next to the exported cloud
+const outputBytesFactor = 2
+
+// Preflight checks the files a run of params would process before it is
+// submitted: that their LAS headers can be read, that there is room on
+// disk for the outputs, and that the largest file fits in memory at the
+// octree depth. Checks the platform can't make are left out.
+func Preflight(params Params, files []string) []Check {
+	var checks []Check
+	add := func(name string, level LogLevel, detail string) {
+		checks = append(checks, Check{Name: name, Level: level, Detail: detail})
+	}
+
+	// Headers, and the input bytes each output directory takes outputs for
+	headers := make(map[string]las.Header)
+	inputBytes := make(map[string]uint64)
+	var dirs []string
+	var points uint64
+	for _, file := range files {
+		h, err := las.ReadHeader(file)
+		if err != nil {
+			add("LAS header", LogError, err.Error())
+			continue
+		}
+		if h.PointCount == 0 {
+			add("LAS header", LogWarning, fmt.Sprintf("%s: no points", file))
+		}
+		headers[file] = h
+		points += h.PointCount
+		if info, err := os.Stat(file); err == nil {
+			dir := filepath.Dir(file)
+			if _, seen := inputBytes[dir]; !seen {
+				dirs = append(dirs, dir)
+			}
+			inputBytes[dir] += uint64(info.Size())
+		}
+	}
+	if len(headers) == len(files) {
+		add("LAS headers", LogSuccess, fmt.Sprintf("%d file(s), %s points", len(files), humanize.Count(int64(points))))
+	}
+
+	// The output directories may not exist yet; the input directories they
+	// are made in are on the same disk
+	for _, dir := range dirs {
+		free, err := diskFree(dir)
+		if err != nil {
+			continue
+		}
+		need := inputBytes[dir] * outputBytesFactor
+		detail := fmt.Sprintf("%s free in %s, about %s needed", humanize.Bytes(free), filepath.Join(dir, params.OutputSubdir), humanize.Bytes(need))
+		if free < need {
+			add("Disk space", LogError, detail)
+		} else {
+			add("Disk space", LogSuccess, detail)
+		}
+	}
+
+	// Files are processed one at a time, so the largest decides
+	value := params.Values["octree-depth"]
+	total, err := totalMemory()
+	if value == "" || err != nil || len(headers) == 0 {
+		return checks
+	}
+	var peak uint64
+	var largest string
+	for file, h := range headers {
+		depth, ok := DepthFor(h, value)
+		if !ok {
+			return checks
+		}
+		if memory := MemoryEstimate(h, depth); memory > peak {
+			peak, largest = memory, file
+		}
+	}
+	detail := fmt.Sprintf("about %s peak for %s, %s installed", humanize.Bytes(peak), filepath.Base(largest), humanize.Bytes(total))
+	switch {
+	case peak > total:
+		add("Memory", LogError, detail)
+	case peak > total/4*3:
+		add("Memory", LogWarning, detail+"; other programs may make it swap")
+	default:
+		add("Memory", LogSuccess, detail)
+	}
+	return checks
+}
@@ -0,0 +1,34 @@
+//go:build !windows
+
+package processor
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// lowPriorityNice is the niceness used for low-priority processing
+const lowPriorityNice = 10
+
+// raiseKeepsCPUPriority reports whether raisePriority leaves the CPU
+// priority alone
+const raiseKeepsCPUPriority = true
+
+// setLowPriority has nothing to do before start on Unix; see lowerPriority
+func setLowPriority(cmd *exec.Cmd) {}
+
+// lowerPriority renices the script's process group and lowers its I/O
+// priority where supported; processes it starts later inherit both
+func lowerPriority(cmd *exec.Cmd) error {
+	if err := syscall.Setpriority(syscall.PRIO_PGRP, cmd.Process.Pid, lowPriorityNice); err != nil {
+		return err
+	}
+	return lowerIOPriority(cmd.Process.Pid)
+}
+
+// raisePriority gives the script's process group the highest normal I/O
+// priority where supported. Niceness is left alone, as lowering it again
+// takes privileges.
+func raisePriority(cmd *exec.Cmd) error {
+	return raiseIOPriority(cmd.Process.Pid)
+}
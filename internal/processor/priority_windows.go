@@ -0,0 +1,64 @@
+//go:build windows
+
+package processor
+
+import (
+	"os/exec"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// BELOW_NORMAL_PRIORITY_CLASS is not exported by the syscall package
+const belowNormalPriorityClass = 0x00004000
+
+// setLowPriority starts the script in the BelowNormal priority class,
+// which Python and anything else it starts inherit
+func setLowPriority(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= belowNormalPriorityClass
+}
+
+// raiseKeepsCPUPriority reports whether raisePriority leaves the CPU
+// priority alone; on Windows the priority class sets both
+const raiseKeepsCPUPriority = false
+
+// lowerPriority has nothing to do after start on Windows; see setLowPriority
+func lowerPriority(cmd *exec.Cmd) error {
+	return nil
+}
+
+// raisePriority moves Python and the processes it started to the
+// AboveNormal priority class, which also raises their I/O priority
+func raisePriority(cmd *exec.Cmd) error {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(snapshot)
+
+	children := make(map[uint32][]uint32)
+	entry := windows.ProcessEntry32{Size: uint32(unsafe.Sizeof(windows.ProcessEntry32{}))}
+	for err = windows.Process32First(snapshot, &entry); err == nil; err = windows.Process32Next(snapshot, &entry) {
+		children[entry.ParentProcessID] = append(children[entry.ParentProcessID], entry.ProcessID)
+	}
+
+	var firstErr error
+	pids := []uint32{uint32(cmd.Process.Pid)}
+	for len(pids) > 0 {
+		pid := pids[0]
+		pids = append(pids[1:], children[pid]...)
+		process, err := windows.OpenProcess(windows.PROCESS_SET_INFORMATION, false, pid)
+		if err == nil {
+			err = windows.SetPriorityClass(process, windows.ABOVE_NORMAL_PRIORITY_CLASS)
+			windows.CloseHandle(process)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -2,15 +2,26 @@ package processor
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
-	"runtime"
+	"sort"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/cloudcompare-automation/internal/history"
+	"github.com/cloudcompare-automation/internal/humanize"
+	"github.com/cloudcompare-automation/internal/lock"
+	"github.com/cloudcompare-automation/internal/report"
+	"github.com/cloudcompare-automation/internal/tiles"
+	"github.com/cloudcompare-automation/internal/update"
+	"github.com/cloudcompare-automation/internal/workspace"
 )
 
 // LogLevel represents the severity of a log message
@@ -27,81 +38,268 @@ const (
 type LogEntry struct {
 	Level   LogLevel
 	Message string
+
+	// Context to tell apart the entries of batches and files logged
+	// together, e.g. by several workers
+	Batch     string `json:",omitempty"` // ID of the batch, see ProcessingResult.BatchID
+	FileIndex int    `json:",omitempty"` // 1-based position of File in the batch, 0 if about the whole batch
+	File      string `json:",omitempty"` // Input file the entry is about
+
+	// Outcome is set on the one entry that ends File, so counting these
+	// counts each file once however much it logged
+	Outcome Outcome `json:",omitempty"`
+
+	// Silent is set on the warnings that the script has printed nothing
+	// for this long, see Params.StallAfter
+	Silent time.Duration `json:",omitempty"`
+
+	// Transfer is set on the entries reporting the copy of File, see
+	// Params.Prefetch
+	Transfer *Transfer `json:",omitempty"`
 }
 
+// Outcome is the final state of a processed file
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeWarning Outcome = "warning" // Completed, but with warnings
+	OutcomeFailed  Outcome = "failed"
+)
+
 // FileResult represents the processing result for a single file
 type FileResult struct {
 	InputFile  string
 	OutputFile string
 	Success    bool
 	Error      string
+	WorkDir    string   // Working directory the script ran in
+	Artifacts  []string // Stray files the script left in WorkDir
+	Warnings   []string // Script warnings and verification problems
+	Duration   time.Duration
+	CPUTime    time.Duration       // CPU time of the script and the processes it started
+	PostSteps  []report.Step       // Post-processing commands run on the outputs
+	MeshFile   string              // Exported mesh (PLY or glTF), if any
+	Snapshots  []string            // Rendered images of the mesh, if any
+	WebExport  string              // Directory of the Potree or 3D Tiles export, if any
+	Archive    string              // Archive the outputs were packed into and removed, if any
+	Metadata   map[string]string   // Metadata looked up for the file, see MetadataLookup
+	Points     int64               // Points in the input cloud, 0 if not reported
+	Faces      int                 // Faces of the mesh, 0 if not reported
+	Crashed    bool                // The script exited with an error it didn't report
+	Quality    *report.MeshQuality // Mesh quality the script measured, if any
+}
+
+// Outcome returns whether the file succeeded, succeeded with warnings, or failed
+func (f FileResult) Outcome() Outcome {
+	switch {
+	case !f.Success:
+		return OutcomeFailed
+	case len(f.Warnings) > 0:
+		return OutcomeWarning
+	default:
+		return OutcomeSuccess
+	}
 }
 
 // ProcessingResult contains the final results of batch processing
 type ProcessingResult struct {
 	TotalFiles   int
-	SuccessCount int
+	SuccessCount int // Includes the files completed with warnings
+	WarningCount int // Successful files that completed with warnings
 	FailedCount  int
 	OutputDir    string
 	Completed    bool
+	Stopped      bool   // Processing was stopped before all files were done
+	Aborted      string // Why the abort policy ended the batch early, see Params.Abort
+	Files        []FileResult
+	ReportPath   string // Run report written next to the outputs
+	Skipped      int    // Files left out as already processed, see SkipExisting
+	BatchID      string // ID on the log entries of the run
+	SetupError   string // Why the run failed before processing any file, e.g. no Python environment
+
+	// Log entries that didn't fit in the log channel, see LogOverflow
+	DroppedLogs int    // Lost
+	SpilledLogs int    // Written to SpillFile instead
+	SpillFile   string // Spill file of the run, if any
 }
 
 // Params holds all configuration parameters for processing
 type Params struct {
-	InputDir       string
-	OutputSubdir   string
-	KNN            int
-	OctreeDepth    int
-	SamplesPerNode float64
-	PointWeight    float64
-	BoundaryType   int
+	InputDir     string
+	InputFile    string // Process only this file instead of all of InputDir
+	OutputSubdir string
+	Values       map[string]string // Pipeline parameters by name, see ParamSpec
+	Env          map[string]string // Extra environment for the script (threads, GPU)
+	Script       string            // Pipeline script to run instead of process_las_files.py
+	Threads      int               // Cap on CloudComPy's worker threads (0 = no cap)
+	LowPriority  bool              // Run the script at low CPU and I/O priority
+	Metadata     map[string]string // Run metadata (project, client...) for the report and project
+	OutputName   string            // Output file name template, e.g. "{project}_{name}"
+	Labels       []string          // Run labels for the history and report, e.g. delivery-v2
+	SharedOutput bool              // The output directory is shared by queue workers, which lock it themselves
+	Include      []string          // File name patterns to process, e.g. tile_??.las (default: all)
+	Exclude      []string          // File name patterns to skip, e.g. *_preview.las
+	Rates        CostRates         // Rates for the report's cost and energy estimates
+	PostProcess  []PostCommand     // Commands run on the outputs of every successful file
+
+	// WebExport converts every processed cloud for browser viewing:
+	// potree or 3dtiles (default: none)
+	WebExport       string
+	PotreeConverter string        // PotreeConverter executable (default: found on PATH)
+	TilesOrigin     *tiles.Origin // Places 3D Tiles on the globe
+
+	// Archive packs the outputs of every processed file once its other
+	// steps are done: gzip or zip (default: none)
+	Archive string
+
+	// MetadataLookup fills in metadata for each file from a project
+	// management table
+	MetadataLookup MetadataLookup
+
+	// OutputLayout places the outputs in OutputSubdir: flat, per-file,
+	// dated or mirror (default: flat); see OutputPlan. RunStarted names
+	// the run folder of the dated layout instead of the batch's start, so
+	// the batches of a worker share one.
+	OutputLayout string
+	RunStarted   time.Time
+
+	// Deterministic runs the script single-threaded, so processing the
+	// same input again gives identical outputs
+	Deterministic bool
+
+	// BoostSave raises the script's I/O priority when it starts saving the
+	// project and has it flush the file to disk, so the write isn't
+	// starved by other disk activity
+	BoostSave bool
+
+	// SkipExisting leaves out files whose project is already in the
+	// output directory, to resume a batch without redoing it
+	SkipExisting bool
+
+	// Files lists the LAS files to process instead of those of InputDir,
+	// for files scattered across directories. Each file's project is saved
+	// in OutputSubdir next to it.
+	Files []string
+
+	// ScriptDir is the directory of process_las_files.py, for programs
+	// embedding the processor; when empty it is searched for around the
+	// working directory and the executable
+	ScriptDir string
+
+	// Python is the conda environment and CloudComPy installation the
+	// script runs with on Windows
+	Python PythonEnv
+
+	// StallAfter is how long the script may print nothing before it is
+	// warned about as possibly stalled, and again after each further
+	// period (0 = DefaultStallAfter)
+	StallAfter time.Duration
+
+	// Abort ends the batch early when too many files fail
+	Abort AbortPolicy
+
+	// Acceptance holds the rules a file's outputs must meet, checked
+	// against what the script measured
+	Acceptance Acceptance
+
+	// CrashRecovery waits CrashCooldown (0 = DefaultCrashCooldown) after
+	// the script crashed, then sets up and checks the Python environment
+	// again before the next file
+	CrashRecovery bool
+	CrashCooldown time.Duration
+
+	// MemoryLimit caps the memory of the script and the processes it
+	// starts, in bytes (0 = no cap); see limitMemory
+	MemoryLimit uint64
+
+	// Prefetch copies this many of the next files into the workspace
+	// while the current one is processing, for inputs on slow network
+	// storage (0 = none); see prefetcher
+	Prefetch int
+
+	// LogBuffer is the capacity of the log channel (0 = DefaultLogBuffer),
+	// and LogOverflow what happens to entries arriving while it is full
+	// (default: OverflowDropOldest)
+	LogBuffer   int
+	LogOverflow string
 }
 
+// DefaultStallAfter is the silence after which a script is warned about;
+// Poisson reconstruction of a large cloud runs for minutes without output
+const DefaultStallAfter = 10 * time.Minute
+
 // DefaultParams returns the default processing parameters
 func DefaultParams() Params {
 	return Params{
-		InputDir:       ".",
-		OutputSubdir:   "Processed",
-		KNN:            6,
-		OctreeDepth:    11,
-		SamplesPerNode: 1.5,
-		PointWeight:    2.0,
-		BoundaryType:   2,
+		InputDir:     ".",
+		OutputSubdir: "Processed",
+		Values:       Defaults(DefaultSchema),
 	}
 }
 
-// Processor handles the execution of the CloudComPy processing script
+// Processor handles the execution of the CloudComPy processing script.
+// Its methods may be called from any goroutine. What a run works from, the
+// parameters, script, hooks and observer, is set before Start and is then
+// read by the run's goroutines without the lock; the setters change
+// nothing once Start has been called. The state the run changes as it
+// goes is guarded by mu.
 type Processor struct {
 	params     Params
 	scriptPath string
-	batPath    string
 	scriptDir  string
+	python     activation // Environment of the running batch
+	plan       OutputPlan // Where the outputs of the running batch go
 
 	// Channels for communication
 	logChan    chan LogEntry
 	resultChan chan ProcessingResult
+	done       chan struct{} // Closed once the result is sent
+	stopping   chan struct{} // Closed by the first Stop
+	finishOnce sync.Once
+	hooks      Hooks
+	observer   Observer
+	observeMu  sync.Mutex // Calls the observer one at a time
 
 	// State
-	running      bool
-	mu           sync.Mutex
-	cmd          *exec.Cmd
-	successCount int
-	failedCount  int
+	running bool
+	started bool // A processor runs once
+	stopped bool
+	mu      sync.Mutex
+	cmd     *exec.Cmd   // The process Stop kills, while it runs
+	schema  []ParamSpec // Schema of the default script, for stage cache keys
+
+	// Log entries that didn't fit in the log channel, see send
+	dropped   int
+	spilled   int
+	spillFile *os.File
+	spillErr  error
+
+	// Context stamped on log entries
+	batch     string
+	fileIndex int
+	file      string
 }
 
 // New creates a new Processor instance
 func New(params Params) *Processor {
 	return &Processor{
 		params:     params,
-		logChan:    make(chan LogEntry, 500),
+		logChan:    make(chan LogEntry, params.logBuffer()),
 		resultChan: make(chan ProcessingResult, 1),
+		done:       make(chan struct{}),
+		stopping:   make(chan struct{}),
 	}
 }
 
-// SetParams updates the processing parameters
+// SetParams updates the processing parameters; once Start has been
+// called they are the run's and it changes nothing
 func (p *Processor) SetParams(params Params) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	if p.running || p.started {
+		return
+	}
 	p.params = params
 }
 
@@ -112,16 +310,24 @@ func (p *Processor) GetParams() Params {
 	return p.params
 }
 
-// LogChan returns the channel for receiving log entries
+// LogChan returns the channel for receiving log entries. It is closed
+// after the run's last entry, just before the result is sent, so a reader
+// that has read it to the end has every entry of the run.
 func (p *Processor) LogChan() <-chan LogEntry {
 	return p.logChan
 }
 
-// ResultChan returns the channel for receiving the final result
+// ResultChan returns the channel for receiving the final result, sent
+// exactly once per run
 func (p *Processor) ResultChan() <-chan ProcessingResult {
 	return p.resultChan
 }
 
+// Done returns a channel closed once the result has been sent
+func (p *Processor) Done() <-chan struct{} {
+	return p.done
+}
+
 // IsRunning returns whether the processor is currently running
 func (p *Processor) IsRunning() bool {
 	p.mu.Lock()
@@ -129,8 +335,35 @@ func (p *Processor) IsRunning() bool {
 	return p.running
 }
 
-// FindScripts locates the Python script and batch file
+// ScriptPath returns the resolved path of the pipeline script, if found
+func (p *Processor) ScriptPath() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.scriptPath
+}
+
+// MinScriptVersion is the oldest process_las_files.py that takes the
+// flags this build passes it, such as --save-as and --stage-dir
+const MinScriptVersion = "0.2.0"
+
+// checkScriptVersion refuses a process_las_files.py older than
+// MinScriptVersion, which would reject its arguments on every file
+func checkScriptVersion(path string) error {
+	version, err := update.ScriptVersion(path)
+	if err != nil {
+		return fmt.Errorf("%v; process_las_files.py %s or later is needed", err, MinScriptVersion)
+	}
+	if update.IsNewer(MinScriptVersion, version) {
+		return fmt.Errorf("process_las_files.py %s is too old, %s or later is needed; update the pipeline script", version, MinScriptVersion)
+	}
+	return nil
+}
+
+// FindScripts locates the Python script and batch file; once Start has
+// been called the run keeps the script it found
 func (p *Processor) FindScripts() error {
+	params := p.GetParams()
+
 	// Get the executable's directory
 	execPath, err := os.Executable()
 	if err != nil {
@@ -149,166 +382,577 @@ func (p *Processor) FindScripts() error {
 		filepath.Join(execDir, "..", ".."),
 		filepath.Join(cwd, ".."),
 	}
+	if params.ScriptDir != "" {
+		searchPaths = []string{params.ScriptDir}
+	}
 
 	// Look for the Python script
+	var scriptPath, scriptDir string
 	for _, basePath := range searchPaths {
-		scriptPath := filepath.Join(basePath, "process_las_files.py")
-		if _, err := os.Stat(scriptPath); err == nil {
-			p.scriptPath, _ = filepath.Abs(scriptPath)
-			p.scriptDir = filepath.Dir(p.scriptPath)
+		path := filepath.Join(basePath, "process_las_files.py")
+		if _, err := os.Stat(path); err == nil {
+			scriptPath, _ = filepath.Abs(path)
+			scriptDir = filepath.Dir(scriptPath)
 			break
 		}
 	}
 
-	// Look for the batch file (Windows only)
-	if runtime.GOOS == "windows" {
-		for _, basePath := range searchPaths {
-			batPath := filepath.Join(basePath, "run_cloudcompy.bat")
-			if _, err := os.Stat(batPath); err == nil {
-				p.batPath, _ = filepath.Abs(batPath)
-				break
-			}
-		}
+	if scriptPath == "" {
+		return fmt.Errorf("could not find process_las_files.py")
 	}
 
-	if p.scriptPath == "" {
-		return fmt.Errorf("could not find process_las_files.py")
+	// A selected pipeline replaces the default script; the environment
+	// stays the same
+	if params.Script != "" {
+		script, _ := filepath.Abs(params.Script)
+		if _, err := os.Stat(script); err != nil {
+			return fmt.Errorf("pipeline script not found: %s", params.Script)
+		}
+		scriptPath = script
 	}
 
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.started {
+		p.scriptPath, p.scriptDir = scriptPath, scriptDir
+	}
 	return nil
 }
 
-// CountLASFiles counts the number of LAS files in the input directory
+// CountLASFiles counts the LAS files that will be processed, leaving out
+// those SkipExisting skips
 func (p *Processor) CountLASFiles() (int, error) {
-	inputDir := p.params.InputDir
-	if inputDir == "" {
-		inputDir = "."
-	}
-
-	// Make sure it's absolute
-	absDir, err := filepath.Abs(inputDir)
+	files, err := p.ListLASFiles()
 	if err != nil {
 		return 0, err
 	}
+	pending, _ := p.params.PendingFiles(files)
+	return len(pending), nil
+}
 
-	count := 0
-	entries, err := os.ReadDir(absDir)
-	if err != nil {
-		return 0, err
-	}
+// PendingFiles returns the files that still need processing and how many
+// were left out because their output exists; without SkipExisting that
+// is all of them
+func (params Params) PendingFiles(files []string) ([]string, int) {
+	return params.Plan(files, time.Now()).pending(files)
+}
 
-	for _, entry := range entries {
-		if entry.IsDir() {
+// pending returns the files of the plan that still need processing, see
+// PendingFiles
+func (o OutputPlan) pending(files []string) ([]string, int) {
+	if !o.params.SkipExisting {
+		return files, 0
+	}
+	var pending []string
+	for _, file := range files {
+		if o.exists(file) {
 			continue
 		}
-		name := strings.ToLower(entry.Name())
-		if strings.HasSuffix(name, ".las") {
-			count++
+		pending = append(pending, file)
+	}
+	return pending, len(files) - len(pending)
+}
+
+// exists reports whether the project of file is saved, or packed in its
+// archive
+func (o OutputPlan) exists(file string) bool {
+	output := o.Output(file)
+	if _, err := os.Stat(output); err == nil {
+		return true
+	}
+	if o.params.archiveEnabled() {
+		if _, err := os.Stat(o.params.archivePath(output)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// OutputDirs returns the output directories of files, in order of first
+// use; files from several directories have one each, unless the layout
+// mirrors them under one
+func (params Params) OutputDirs(files []string) []string {
+	return params.Plan(files, time.Now()).Bases(files)
+}
+
+// ListLASFiles returns the absolute paths of the LAS files that will be
+// processed: InputFile, the listed Files in their order, or the files of
+// InputDir selected by the Include and Exclude patterns, sorted by name
+func (p *Processor) ListLASFiles() ([]string, error) {
+	if p.params.InputFile != "" {
+		absFile, err := filepath.Abs(p.params.InputFile)
+		if err != nil {
+			return nil, err
+		}
+		return []string{absFile}, nil
+	}
+	if len(p.params.Files) > 0 {
+		var files []string
+		seen := make(map[string]bool)
+		for _, file := range p.params.Files {
+			absFile, err := filepath.Abs(file)
+			if err != nil {
+				return nil, err
+			}
+			if !seen[absFile] {
+				seen[absFile] = true
+				files = append(files, absFile)
+			}
 		}
+		return files, nil
 	}
 
-	return count, nil
+	files, _, err := DiscoverLASFiles(p.params.InputDir, p.params.Include, p.params.Exclude)
+	return files, err
 }
 
-// Start begins the processing in a goroutine
+// Start begins the processing in a goroutine. A processor runs once; a
+// Start that failed can be tried again.
 func (p *Processor) Start() error {
 	p.mu.Lock()
 	if p.running {
 		p.mu.Unlock()
 		return fmt.Errorf("processor is already running")
 	}
+	if p.started {
+		p.mu.Unlock()
+		return fmt.Errorf("processor has already run")
+	}
 	p.running = true
-	p.successCount = 0
-	p.failedCount = 0
+	found := p.scriptPath != ""
 	p.mu.Unlock()
 
 	// Find scripts if not already found
-	if p.scriptPath == "" {
-		if err := p.FindScripts(); err != nil {
-			p.sendLog(LogError, err.Error())
-			p.mu.Lock()
-			p.running = false
-			p.mu.Unlock()
-			return err
-		}
+	var err error
+	if !found {
+		err = p.FindScripts()
+	}
+	if err == nil && p.GetParams().Script == "" {
+		err = checkScriptVersion(p.ScriptPath())
+	}
+	if err != nil {
+		p.sendLog(LogError, err.Error())
+		p.mu.Lock()
+		p.running = false
+		p.mu.Unlock()
+		return err
 	}
 
-	go p.run()
+	p.mu.Lock()
+	p.started = true
+	p.mu.Unlock()
+	go func() { p.finish(p.run()) }()
 	return nil
 }
 
-// Stop attempts to stop the running process
+// Stop stops the running script along with any processes it started.
+// The result is still sent, marked Stopped if files were left, with the
+// counts so far. Stop can be called any number of times and from any
+// goroutine: before Start the run stops at once, and once the last file
+// is done it changes nothing.
 func (p *Processor) Stop() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	if p.cmd != nil && p.cmd.Process != nil {
-		p.cmd.Process.Kill()
+	if !p.stopped {
+		close(p.stopping)
 	}
-	p.running = false
+	p.stopped = true
+	if p.cmd != nil {
+		killTree(p.cmd)
+	}
+}
+
+// setupFailed logs why the run couldn't start on its files and returns
+// its result, one failure without any file's
+func (p *Processor) setupFailed(batch, message string) ProcessingResult {
+	p.sendLog(LogError, message)
+	return ProcessingResult{Completed: true, FailedCount: 1, TotalFiles: 1, BatchID: batch, SetupError: message}
 }
 
-func (p *Processor) run() {
-	defer func() {
+// run processes the files and returns the result; the locks and workspace
+// it holds are released before finish sends it
+func (p *Processor) run() ProcessingResult {
+	batch := newBatchID()
+	p.setContext(batch, 0, "")
+
+	files, err := p.ListLASFiles()
+	if err != nil || len(files) == 0 {
+		if err == nil {
+			err = fmt.Errorf("no LAS files found")
+		}
+		return p.setupFailed(batch, err.Error())
+	}
+	// The table is read once for the run, before the output names are
+	// planned; a run without it would name and report every file wrongly
+	if lookup := p.params.MetadataLookup; lookup.Enabled() {
+		table, err := lookup.load(true)
+		if err != nil {
+			return p.setupFailed(batch, fmt.Sprintf("Metadata lookup failed: %v", err))
+		}
+		p.sendLog(LogInfo, fmt.Sprintf("Metadata lookup: %d row(s) from %s", len(table.rows), lookup.Source()))
 		p.mu.Lock()
-		p.running = false
+		p.params.MetadataLookup.table = table
 		p.mu.Unlock()
-	}()
+	}
 
-	// Get absolute input directory
-	inputDir := p.params.InputDir
-	if inputDir == "" || inputDir == "." {
-		inputDir, _ = os.Getwd()
+	// Planned before leaving any out, as mirroring depends on all files
+	plan := p.params.Plan(files, time.Now())
+	p.plan = plan
+	files, existing := plan.pending(files)
+	if existing > 0 {
+		p.sendLog(LogInfo, fmt.Sprintf("Skipping %d file(s) already processed", existing))
+	}
+	if len(files) == 0 {
+		return ProcessingResult{Completed: true, Skipped: existing, BatchID: batch}
 	}
-	absInputDir, _ := filepath.Abs(inputDir)
 
-	p.sendLog(LogInfo, fmt.Sprintf("Input: %s", absInputDir))
+	// Keep other instances out of the output directories for the run; the
+	// report goes to the first
+	outputDirs := plan.Bases(files)
+	outputDir := outputDirs[0]
+	if !p.params.SharedOutput {
+		for _, dir := range outputDirs {
+			l, err := lock.Acquire(dir)
+			if err != nil {
+				return p.setupFailed(batch, err.Error())
+			}
+			defer l.Release()
+		}
+	}
 
-	// Build command arguments for the Python script
-	args := p.buildArgs(absInputDir)
+	input, _ := filepath.Abs(p.params.InputDir)
+	switch {
+	case p.params.InputFile != "":
+		input = files[0]
+	case len(p.params.Files) > 0:
+		input = fmt.Sprintf("%d listed file(s)", len(files))
+	}
+	p.sendLog(LogInfo, fmt.Sprintf("Input: %s", input))
+
+	// A test's Command hook runs without the Python environment
+	if p.hooks.Command == nil {
+		p.python, err = p.params.Python.activate()
+		if err != nil {
+			return p.setupFailed(batch, err.Error())
+		}
+	}
+	if p.python.prefix != "" {
+		p.sendLog(LogInfo, fmt.Sprintf("Conda environment: %s", p.python.prefix))
+	}
+	if p.python.cloudComPy != "" {
+		p.sendLog(LogInfo, fmt.Sprintf("CloudComPy: %s", p.python.cloudComPy))
+	}
+	p.sendLog(LogInfo, fmt.Sprintf("Running: %s %s", p.python.python, p.scriptPath))
 
-	var cmd *exec.Cmd
+	// Log the per-job environment once, in a stable order
+	env := p.params.scriptEnv()
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	envList := make([]string, 0, len(keys))
+	for _, key := range keys {
+		envList = append(envList, key+"="+env[key])
+		p.sendLog(LogInfo, fmt.Sprintf("Environment: %s=%s", key, env[key]))
+	}
+	if p.params.LowPriority {
+		p.sendLog(LogInfo, "Running at low priority")
+	}
+	if p.params.BoostSave {
+		p.sendLog(LogInfo, "Boosting I/O priority for the save step")
+	}
+	if limit := p.params.MemoryLimit; limit > 0 {
+		if memoryLimits {
+			p.sendLog(LogInfo, fmt.Sprintf("Memory limited to %s per file", humanize.Bytes(limit)))
+		} else {
+			p.sendLog(LogWarning, "Memory limits are not supported on this platform; running without one")
+		}
+	}
 
-	if runtime.GOOS == "windows" && p.batPath != "" {
-		// On Windows, use the batch file wrapper
-		// The batch file handles conda activation and environment setup
-		allArgs := append([]string{"/c", p.batPath}, args...)
-		cmd = exec.Command("cmd", allArgs...)
-		p.sendLog(LogInfo, "Starting CloudComPy processing...")
+	if p.params.Script == "" {
+		p.schema, _ = LoadSchema(p.scriptPath)
+	}
+
+	// Each file runs in its own working directory in the workspace
+	ws, err := workspace.NewRun()
+	if err != nil {
+		p.sendLog(LogWarning, fmt.Sprintf("Running without a workspace: %v", err))
 	} else {
-		// Direct Python execution (requires CloudComPy in PATH)
-		allArgs := append([]string{p.scriptPath}, args...)
-		cmd = exec.Command("python", allArgs...)
-		p.sendLog(LogInfo, fmt.Sprintf("Running: python %s", p.scriptPath))
+		defer ws.Close()
 	}
 
-	// Set environment
-	cmd.Env = os.Environ()
+	var prefetch *prefetcher
+	if p.params.Prefetch > 0 {
+		switch {
+		case p.params.Script != "":
+			p.sendLog(LogWarning, "Prefetching only works with process_las_files.py; reading the files from their directory")
+		case ws == nil:
+			p.sendLog(LogWarning, "Prefetching needs the workspace; reading the files from their directory")
+		default:
+			prefetch = p.newPrefetcher(files, filepath.Join(ws.Dir, "prefetch"), p.params.Prefetch)
+			defer prefetch.close()
+			p.sendLog(LogInfo, fmt.Sprintf("Prefetching up to %d file(s) ahead", p.params.Prefetch))
+		}
+	}
 
-	p.mu.Lock()
-	p.cmd = cmd
-	p.mu.Unlock()
+	started := time.Now()
+	result := ProcessingResult{
+		Completed:  true,
+		TotalFiles: len(files),
+		OutputDir:  outputDir,
+		Skipped:    existing,
+		BatchID:    batch,
+	}
+	streak := 0 // Files failed in a row
+	for i, file := range files {
+		if p.isStopped() {
+			break
+		}
+		p.setContext(batch, i+1, file)
+		p.fileStarted(i+1, len(files), file)
+
+		dir := ""
+		if ws != nil {
+			if dir, err = ws.FileDir(file); err != nil {
+				p.sendLog(LogWarning, err.Error())
+			}
+		}
+
+		// The script reads the file's local copy, if it was prefetched
+		input := file
+		if prefetch != nil {
+			input = prefetch.take(i)
+		}
+		fileResult := p.runFile(file, input, envList, dir)
+		if prefetch != nil {
+			prefetch.release(i)
+		}
+		if p.isStopped() && !fileResult.Success {
+			// Interrupted mid-file: neither a success nor a failure
+			break
+		}
+		if fileResult.Success && len(p.params.ExtraSteps()) > 0 {
+			p.postProcess(&fileResult, envList)
+		}
+		result.Files = append(result.Files, fileResult)
+		p.sendFileDone(fileResult)
+		switch fileResult.Outcome() {
+		case OutcomeSuccess:
+			result.SuccessCount++
+		case OutcomeWarning:
+			result.SuccessCount++
+			result.WarningCount++
+		default:
+			result.FailedCount++
+		}
+		if p.hooks.AfterFile != nil {
+			p.hooks.AfterFile(fileResult)
+		}
+
+		if fileResult.Outcome() == OutcomeFailed {
+			streak++
+		} else {
+			streak = 0
+		}
+		if left := len(files) - i - 1; left > 0 {
+			reason := p.params.Abort.Reason(len(result.Files), result.FailedCount, streak)
+			if reason == "" && fileResult.Crashed && p.params.CrashRecovery && !p.isStopped() {
+				if err := p.recoverEnvironment(); err != nil {
+					reason = fmt.Sprintf("environment broken after a crash: %v", err)
+				}
+			}
+			if reason != "" {
+				p.sendLog(LogError, fmt.Sprintf("Batch aborted: %s; %d file(s) left unprocessed", reason, left))
+				result.Aborted = reason
+				break
+			}
+		}
+	}
+
+	p.setContext(batch, 0, "")
+
+	// A stopped run keeps its partial counts; the kill is not a failure.
+	// A Stop that came after the last file changes nothing.
+	if p.isStopped() && len(result.Files) < len(files) {
+		p.sendLog(LogWarning, fmt.Sprintf("Processing stopped after %d file(s)", len(result.Files)))
+		result.Stopped = true
+	}
+
+	if len(result.Files) > 0 {
+		r := p.buildReport(result, input, started)
+		if r.Estimate != nil {
+			p.sendLog(LogInfo, fmt.Sprintf("CPU time %s, %s", humanize.Duration(result.CPUTime()), FormatEstimate(r.Estimate)))
+		}
+		path, err := report.Write(r)
+		if path != "" {
+			result.ReportPath = path
+			p.sendLog(LogInfo, fmt.Sprintf("Report: %s", path))
+		}
+		if err != nil {
+			p.sendLog(LogWarning, err.Error())
+		}
+		if _, err := history.Record(history.FromReport(r, result.ReportPath)); err != nil {
+			p.sendLog(LogWarning, err.Error())
+		}
+	}
+
+	return result
+}
+
+// buildReport describes a finished run for the report file
+func (p *Processor) buildReport(result ProcessingResult, input string, started time.Time) report.Report {
+	r := report.Report{
+		Pipeline:      p.scriptPath,
+		Input:         input,
+		OutputDir:     result.OutputDir,
+		Params:        p.params.Values,
+		Metadata:      p.params.Metadata,
+		Labels:        p.params.Labels,
+		StartedAt:     started,
+		FinishedAt:    time.Now(),
+		Total:         result.TotalFiles,
+		Succeeded:     result.SuccessCount - result.WarningCount,
+		Warned:        result.WarningCount,
+		Failed:        result.FailedCount,
+		Stopped:       result.Stopped,
+		Aborted:       result.Aborted,
+		CPUSeconds:    result.CPUTime().Seconds(),
+		Estimate:      p.params.Rates.Estimate(result.CPUTime(), result.ProcessingTime()),
+		Threads:       p.params.threadCap(),
+		Deterministic: p.params.Deterministic,
+	}
+	for _, f := range result.Files {
+		r.Files = append(r.Files, report.File{
+			Input:      f.InputFile,
+			Output:     f.OutputFile,
+			Outcome:    string(f.Outcome()),
+			Error:      f.Error,
+			Warnings:   f.Warnings,
+			Seconds:    f.Duration.Seconds(),
+			CPUSeconds: f.CPUTime.Seconds(),
+			Artifacts:  f.Artifacts,
+			Points:     f.Points,
+			Mesh:       f.MeshFile,
+			Snapshots:  f.Snapshots,
+			Web:        f.WebExport,
+			Archive:    f.Archive,
+			Metadata:   f.Metadata,
+			Quality:    f.Quality,
+			Faces:      f.Faces,
+			PostSteps:  f.PostSteps,
+		})
+	}
+	return r
+}
+
+// runFile runs the script on a single file, read from input, with dir as
+// its working directory, and captures any files the script leaves behind
+// there
+func (p *Processor) runFile(file, input string, env []string, dir string) (fileResult FileResult) {
+	fileResult = FileResult{
+		InputFile:  file,
+		OutputFile: p.plan.Output(file),
+		WorkDir:    dir,
+	}
+	started := time.Now()
+	defer func() { fileResult.Duration = time.Since(started) }()
+
+	// A file missing from the lookup table is named and reported with the
+	// run's metadata only, which the operator should know about
+	missing := ""
+	if p.params.MetadataLookup.Enabled() {
+		var found bool
+		if fileResult.Metadata, found = p.params.lookupMetadata(file); !found {
+			missing = fmt.Sprintf("No metadata found for %s in %s", filepath.Base(file), p.params.MetadataLookup.Source())
+			p.sendLog(LogWarning, missing)
+		}
+	}
+
+	// Intermediate results of process_las_files.py are kept per file so a
+	// later run can start from them
+	stageDir := ""
+	if p.params.Script == "" {
+		var err error
+		if stageDir, err = p.stageDir(file, p.schema); err != nil {
+			p.sendLog(LogWarning, fmt.Sprintf("Running without a stage cache: %v", err))
+		}
+	}
+
+	// process_las_files.py saves to a temporary name, renamed into place
+	// below once verified; a cancelled or failed run leaves nothing behind
+	partial := ""
+	if p.params.Script == "" {
+		partial = partialPath(fileResult.OutputFile)
+		os.Remove(partial)
+		defer os.Remove(partial)
+		os.Remove(partialMeshPath(partial))
+		defer os.Remove(partialMeshPath(partial))
+	}
+
+	// Build command arguments for the Python script
+	values := p.resolveValues(file, stageDir)
+	args := p.buildArgs(input, filepath.Dir(fileResult.OutputFile), stageDir, partial, values)
+
+	// Python runs directly in the environment activate built; see pyenv.go
+	cmd := p.python.command(context.Background(), append([]string{p.scriptPath}, args...)...)
+	if p.hooks.Command != nil {
+		cmd = p.hooks.Command(file, args)
+	}
+	cmd.Dir = dir
+	cmd.Env = append(cmd.Env, env...)
+	// Python writes to pipes in the ANSI code page on Windows, which
+	// garbles or fails on file names outside it
+	cmd.Env = append(cmd.Env, "PYTHONIOENCODING=utf-8")
+	prepareCmd(cmd)
+	if p.params.LowPriority {
+		setLowPriority(cmd)
+	}
+
+	if p.isStopped() {
+		return fileResult
+	}
+	out := &fileOutput{meshFaces: -1, last: time.Now()}
 
 	// Create pipes for stdout and stderr
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		p.sendLog(LogError, fmt.Sprintf("Failed to create stdout pipe: %v", err))
-		p.sendResult(ProcessingResult{Completed: true, FailedCount: 1})
-		return
+		fileResult.Error = fmt.Sprintf("Failed to create stdout pipe: %v", err)
+		p.sendLog(LogError, fileResult.Error)
+		return fileResult
 	}
 
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		p.sendLog(LogError, fmt.Sprintf("Failed to create stderr pipe: %v", err))
-		p.sendResult(ProcessingResult{Completed: true, FailedCount: 1})
-		return
+		fileResult.Error = fmt.Sprintf("Failed to create stderr pipe: %v", err)
+		p.sendLog(LogError, fileResult.Error)
+		return fileResult
 	}
 
-	// Start the command
-	if err := cmd.Start(); err != nil {
-		p.sendLog(LogError, fmt.Sprintf("Failed to start process: %v", err))
-		p.sendResult(ProcessingResult{Completed: true, FailedCount: 1})
-		return
+	// Start the command, unless the run was stopped
+	if err := p.start(cmd); err != nil {
+		if errors.Is(err, errStopped) {
+			return fileResult
+		}
+		fileResult.Error = fmt.Sprintf("Failed to start process: %v", err)
+		p.sendLog(LogError, fileResult.Error)
+		return fileResult
+	}
+
+	if p.params.LowPriority {
+		if err := lowerPriority(cmd); err != nil {
+			p.sendLog(LogWarning, fmt.Sprintf("Failed to lower process priority: %v", err))
+		}
+	}
+	cpuTime := meterCPU(cmd)
+	overLimit := func() bool { return false }
+	if limit := p.params.MemoryLimit; limit > 0 && memoryLimits {
+		if exceeded, err := limitMemory(cmd, limit); err != nil {
+			p.sendLog(LogWarning, fmt.Sprintf("Failed to limit memory: %v", err))
+		} else {
+			overLimit = exceeded
+		}
 	}
 
 	// Read output in separate goroutines
@@ -317,173 +961,527 @@ func (p *Processor) run() {
 
 	go func() {
 		defer wg.Done()
-		p.readOutput(stdout)
+		p.readOutput(stdout, out)
 	}()
 
 	go func() {
 		defer wg.Done()
-		p.readOutput(stderr)
+		p.readOutput(stderr, out)
+	}()
+
+	// Warn while the script is silent, until its output is read
+	silence := make(chan struct{})
+	watched := make(chan struct{})
+	go func() {
+		defer close(watched)
+		p.watchOutput(file, out, silence)
 	}()
 
-	// Wait for output reading to complete (this ensures all logs are captured)
+	// Wait for output reading to complete (this ensures all logs are
+	// captured), and for the watch, which may be sending a warning
 	wg.Wait()
+	close(silence)
+	<-watched
 
 	// Wait for command to finish
-	exitErr := cmd.Wait()
+	exitErr := p.wait(cmd)
+	fileResult.CPUTime = cpuTime()
+
+	// The file succeeded if the script said so, or exited cleanly without
+	// reporting an error
+	stopped := p.isStopped()
+	fileResult.Error = out.lastError
+	faces := out.meshFaces
+	fileResult.Points = out.points
+	fileResult.Quality = out.quality
+	fileResult.Faces = max(faces, 0)
+	fileResult.Warnings = out.warnings
+	if missing != "" {
+		fileResult.Warnings = append(fileResult.Warnings, missing)
+	}
 
-	// Small delay to ensure all logs are processed
-	// (the channel should have all messages by now)
+	fileResult.Success = out.reported || (exitErr == nil && !out.errored)
+	if overLimit() {
+		fileResult.Success = false
+		fileResult.Error = fmt.Sprintf("Killed at the memory limit of %s", humanize.Bytes(p.params.MemoryLimit))
+		p.sendLog(LogError, fileResult.Error)
+	} else if exitErr != nil && !out.errored && !stopped {
+		fileResult.Crashed = !out.reported
+		fileResult.Error = fmt.Sprintf("Process exited with error: %v", exitErr)
+		p.sendLog(LogError, fileResult.Error)
+	}
 
-	// Determine result based on tracked success/fail counts
-	p.mu.Lock()
-	successCount := p.successCount
-	failedCount := p.failedCount
-	p.mu.Unlock()
+	// The script has reported success while writing an empty project, so
+	// check the output before it gets its final name; only
+	// process_las_files.py's outputs are known
+	if fileResult.Success && partial != "" {
+		warnings, err := promoteOutput(partial, fileResult.OutputFile, faces)
+		for _, warning := range warnings {
+			fileResult.Warnings = append(fileResult.Warnings, warning)
+			p.sendLog(LogWarning, fmt.Sprintf("Verification failed for %s: %s", filepath.Base(file), warning))
+		}
+		if err != nil {
+			fileResult.Success = false
+			fileResult.Error = fmt.Sprintf("Output of %s not saved: %v", filepath.Base(file), err)
+			p.sendLog(LogError, fileResult.Error)
+		}
+	}
 
-	result := ProcessingResult{
-		Completed:    true,
-		SuccessCount: successCount,
-		FailedCount:  failedCount,
-		TotalFiles:   successCount + failedCount,
+	// A saved project can still fall short of the acceptance rules
+	if fileResult.Success && p.params.Acceptance.Enabled() {
+		broken, unchecked := p.params.Acceptance.Check(faces, fileResult.Points, fileResult.Quality)
+		for _, rule := range unchecked {
+			warning := fmt.Sprintf("Acceptance of %s not checked: %s", filepath.Base(file), rule)
+			fileResult.Warnings = append(fileResult.Warnings, warning)
+			p.sendLog(LogWarning, warning)
+		}
+		if len(broken) > 0 {
+			reason := fmt.Sprintf("%s not accepted: %s", filepath.Base(file), strings.Join(broken, "; "))
+			if p.params.Acceptance.Fail {
+				fileResult.Success = false
+				fileResult.Error = reason
+				p.sendLog(LogError, reason)
+			} else {
+				fileResult.Warnings = append(fileResult.Warnings, reason)
+				p.sendLog(LogWarning, reason)
+			}
+		}
 	}
 
-	// If we have no counts but exit was clean, assume success
-	if exitErr == nil && successCount == 0 && failedCount == 0 {
-		// Check if we just didn't track properly, look at exit code
-		result.SuccessCount = 1
-		result.TotalFiles = 1
+	// The project is saved, so failed snapshots or a failed mesh export
+	// are only a warning. Snapshots come first, as a PLY export moves the
+	// mesh they are rendered from.
+	if mode := values["snapshots"]; fileResult.Success && partial != "" && mode != "" && mode != SnapshotNone {
+		paths, err := renderSnapshots(partialMeshPath(partial), fileResult.OutputFile, mode)
+		fileResult.Snapshots = paths
+		if err != nil {
+			warning := fmt.Sprintf("Snapshots of %s failed: %v", filepath.Base(file), err)
+			fileResult.Warnings = append(fileResult.Warnings, warning)
+			p.sendLog(LogWarning, warning)
+		} else {
+			p.sendLog(LogInfo, fmt.Sprintf("Snapshots rendered: %d image(s)", len(paths)))
+		}
+	}
+	if format := values["mesh-format"]; fileResult.Success && partial != "" && format != "" && format != MeshNone {
+		path, err := exportMesh(partialMeshPath(partial), fileResult.OutputFile, format)
+		if err != nil {
+			warning := fmt.Sprintf("Mesh export of %s failed: %v", filepath.Base(file), err)
+			fileResult.Warnings = append(fileResult.Warnings, warning)
+			p.sendLog(LogWarning, warning)
+		} else {
+			fileResult.MeshFile = path
+			p.sendLog(LogInfo, fmt.Sprintf("Mesh exported: %s", filepath.Base(path)))
+		}
 	}
 
-	// If exit error and no tracked failures, mark as failed
-	if exitErr != nil && failedCount == 0 {
-		p.sendLog(LogError, fmt.Sprintf("Process exited with error: %v", exitErr))
-		result.FailedCount = 1
-		if result.TotalFiles == 0 {
-			result.TotalFiles = 1
+	// Sidecars trace the outputs back to their settings; without one the
+	// outputs are still good, so a failure is only logged
+	if fileResult.Success {
+		// The local copy is hashed instead of reading the file again
+		prov, err := p.provenance(input, values, started)
+		prov.Input = file
+		if err == nil {
+			err = writeProvenance(prov, fileResult.OutputFile, fileResult.MeshFile)
+		}
+		if err != nil {
+			p.sendLog(LogWarning, fmt.Sprintf("Provenance of %s not written: %v", filepath.Base(file), err))
 		}
 	}
 
-	p.sendResult(result)
+	// Keep the stage cache just used and drop older ones
+	if stageDir != "" {
+		workspace.TouchStage(stageDir)
+		if err := workspace.PruneStages(file, keepStages); err != nil {
+			p.sendLog(LogWarning, fmt.Sprintf("Failed to prune stage cache: %v", err))
+		}
+	}
+
+	// Anything left in the working directory belongs to this file
+	if dir != "" {
+		artifacts, err := workspace.Collect(dir)
+		if err != nil {
+			p.sendLog(LogWarning, fmt.Sprintf("Failed to inspect working directory: %v", err))
+		}
+		if len(artifacts) > 0 {
+			fileResult.Artifacts = artifacts
+			p.sendLog(LogWarning, fmt.Sprintf("Captured %d stray file(s) from %s in %s", len(artifacts), filepath.Base(file), dir))
+		}
+	}
+
+	return fileResult
 }
 
-func (p *Processor) buildArgs(absInputDir string) []string {
-	args := []string{}
+// isStopped reports whether Stop has been called
+func (p *Processor) isStopped() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stopped
+}
 
-	// Input directory (always first positional argument)
-	args = append(args, absInputDir)
+// threadEnvVars are the thread-pool sizes honoured by CloudComPy and the
+// numeric libraries it loads
+var threadEnvVars = []string{"OMP_NUM_THREADS", "MKL_NUM_THREADS", "OPENBLAS_NUM_THREADS"}
 
-	// Output subdirectory
-	if p.params.OutputSubdir != "" && p.params.OutputSubdir != "Processed" {
-		args = append(args, "--output-dir", p.params.OutputSubdir)
+// threadCap returns the cap on the script's worker threads: one in
+// deterministic mode, as parallel sums depend on how threads interleave
+func (params Params) threadCap() int {
+	if params.Deterministic {
+		return 1
 	}
+	return params.Threads
+}
 
-	// KNN parameter
-	if p.params.KNN != 6 && p.params.KNN > 0 {
-		args = append(args, "--knn", fmt.Sprintf("%d", p.params.KNN))
+// scriptEnv returns the extra environment for the script: Env plus the
+// thread cap, without overriding variables set explicitly in Env
+func (params Params) scriptEnv() map[string]string {
+	env := make(map[string]string, len(params.Env)+len(threadEnvVars)+1)
+	if threads := params.threadCap(); threads > 0 {
+		for _, key := range threadEnvVars {
+			env[key] = fmt.Sprint(threads)
+		}
 	}
-
-	// Octree depth
-	if p.params.OctreeDepth != 11 && p.params.OctreeDepth > 0 {
-		args = append(args, "--octree-depth", fmt.Sprintf("%d", p.params.OctreeDepth))
+	if params.Deterministic {
+		env["PYTHONHASHSEED"] = "0"
+	}
+	for key, value := range params.Env {
+		env[key] = value
 	}
+	return env
+}
+
+func (p *Processor) buildArgs(input, outputDir, stageDir, partial string, values map[string]string) []string {
+	args := []string{}
 
-	// Samples per node
-	if p.params.SamplesPerNode != 1.5 && p.params.SamplesPerNode > 0 {
-		args = append(args, "--samples-per-node", fmt.Sprintf("%.1f", p.params.SamplesPerNode))
+	// Input directory or file (always first positional argument)
+	args = append(args, longPath(input))
+
+	// Output subdirectory next to the input, or the full path where the
+	// output layout or a prefetched input put it elsewhere
+	if outputDir != "" && outputDir != filepath.Join(filepath.Dir(input), p.params.OutputSubdir) {
+		args = append(args, "--output-dir", longPath(outputDir))
+	} else if p.params.OutputSubdir != "" && p.params.OutputSubdir != "Processed" {
+		args = append(args, "--output-dir", p.params.OutputSubdir)
 	}
 
-	// Point weight
-	if p.params.PointWeight != 2.0 && p.params.PointWeight > 0 {
-		args = append(args, "--point-weight", fmt.Sprintf("%.1f", p.params.PointWeight))
+	// Output name, metadata and the stage cache are flags of
+	// process_las_files.py only
+	if p.params.Script == "" {
+		if partial != "" {
+			args = append(args, "--save-as", longPath(partial))
+		}
+		if stageDir != "" {
+			args = append(args, "--stage-dir", longPath(stageDir))
+		}
+		if p.params.BoostSave {
+			args = append(args, "--sync-save")
+		}
+		if p.params.Acceptance.MaxDeviation > 0 {
+			args = append(args, "--deviation")
+		}
+		if name := p.params.outputName(input); name != strings.TrimSuffix(filepath.Base(input), filepath.Ext(input)) {
+			args = append(args, "--output-name", name)
+		}
+		metadata := p.params.fileMetadata(input)
+		keys := make([]string, 0, len(metadata))
+		for key := range metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if metadata[key] != "" {
+				args = append(args, "--meta", key+"="+metadata[key])
+			}
+		}
 	}
 
-	// Boundary type
-	if p.params.BoundaryType != 2 && p.params.BoundaryType >= 0 && p.params.BoundaryType <= 2 {
-		args = append(args, "--boundary-type", fmt.Sprintf("%d", p.params.BoundaryType))
+	// Pipeline parameters, in a stable order
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, "--"+name, values[name])
 	}
 
 	return args
 }
 
-func (p *Processor) readOutput(reader io.Reader) {
-	scanner := bufio.NewScanner(reader)
+// fileOutput is what the script reported about one file. Each run of the
+// script has its own, shared by the readers of its stdout and stderr.
+type fileOutput struct {
+	mu        sync.Mutex
+	reported  bool                // The script said the file was processed
+	errored   bool                // The script logged a failure
+	lastError string              // The last failure it logged
+	meshFaces int                 // Faces reported, -1 if none
+	points    int64               // Points reported
+	quality   *report.MeshQuality // Mesh quality reported
+	warnings  []string            // Warnings it logged
+	last      time.Time           // When the script last printed anything
+}
 
-	// Increase buffer size for long lines
-	buf := make([]byte, 0, 64*1024)
-	scanner.Buffer(buf, 1024*1024)
+// touch notes that the script printed a line
+func (o *fileOutput) touch() {
+	o.mu.Lock()
+	o.last = time.Now()
+	o.mu.Unlock()
+}
 
-	// Regex patterns for parsing output
-	levelRegex := regexp.MustCompile(`^\[(\w+)\]\s*(.*)$`)
+// silent returns how long the script has printed nothing
+func (o *fileOutput) silent() time.Duration {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return time.Since(o.last)
+}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		line = strings.TrimSpace(line)
+// record notes what a line of the script's output says about the file
+func (o *fileOutput) record(level LogLevel, message string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if level == LogSuccess && strings.Contains(message, "Successfully processed:") {
+		o.reported = true
+	}
+	if faces, ok := ParseFaces(message); ok {
+		o.meshFaces = faces
+	}
+	if points, ok := ParsePoints(message); ok {
+		o.points = points
+	}
+	if quality, ok := ParseMeshQuality(message); ok {
+		o.quality = &quality
+	}
+	if level == LogWarning {
+		o.warnings = append(o.warnings, message)
+	}
+	if level == LogError && (strings.Contains(message, "Failed to") || strings.Contains(message, "failed")) {
+		o.errored = true
+		o.lastError = message
+	}
+}
 
-		if line == "" {
+// maxLineLength is the longest line of the script's output kept; the rest
+// of a longer line is dropped
+const maxLineLength = 1024 * 1024
+
+// truncatedSuffix ends a line cut to maxLineLength
+const truncatedSuffix = " … (truncated)"
+
+// levelRegex matches a line with a level prefix, e.g. "[INFO] Loading"
+var levelRegex = regexp.MustCompile(`^\[(\w+)\]\s*(.*)$`)
+
+// readOutput logs the lines the script prints to reader until it is
+// closed. Whatever the script prints, the pipe is read to its end, so a
+// script isn't left blocked writing to it.
+func (p *Processor) readOutput(reader io.Reader, out *fileOutput) {
+	r := bufio.NewReaderSize(reader, 64*1024)
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return
+		}
+		out.touch()
+
+		level, message, ok := parseLine(line)
+		switch {
+		case !ok:
+			continue
+		case level == "":
+			// No level prefix, treat as info
+			p.sendLog(LogInfo, message)
 			continue
 		}
 
-		// Skip separator lines
-		if strings.HasPrefix(line, "===") || strings.HasPrefix(line, "---") {
+		out.record(level, message)
+		switch level {
+		case LogSuccess, LogError, LogWarning, LogInfo:
+			p.sendLog(level, message)
+		default:
+			p.sendLog(LogInfo, message)
+		}
+		if p.params.BoostSave && saveStepPattern.MatchString(message) {
+			p.boostSave()
+		}
+	}
+}
+
+// readLine returns the next line of r, without its line ending, cut to
+// maxLineLength. The last line needn't end in one; io.EOF follows it.
+func readLine(r *bufio.Reader) (string, error) {
+	var line []byte
+	truncated := false
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if room := maxLineLength - len(line); len(chunk) > room {
+			chunk, truncated = chunk[:room], true
+		}
+		line = append(line, chunk...)
+		switch {
+		case err == bufio.ErrBufferFull:
 			continue
+		case err != nil && len(line) == 0:
+			return "", err
+		}
+		if truncated {
+			return string(line) + truncatedSuffix, nil
 		}
+		return strings.TrimRight(string(line), "\r\n"), nil
+	}
+}
 
-		// Parse the log level from the line
-		matches := levelRegex.FindStringSubmatch(line)
-		if matches != nil {
-			level := LogLevel(strings.ToUpper(matches[1]))
-			message := matches[2]
+// parseLine splits a line of the script's output into its level, upper
+// case, and message. The level is empty for a line without a level
+// prefix; ok is false for blank and separator lines, which aren't logged.
+// Bytes that aren't UTF-8 are replaced, so the message is safe to show
+// and to save as JSON, and of a line redrawn with carriage returns, as
+// progress bars are, only what the terminal would show is kept.
+func parseLine(line string) (level LogLevel, message string, ok bool) {
+	line = strings.TrimSpace(strings.ToValidUTF8(line, "\uFFFD"))
+	if i := strings.LastIndexByte(line, '\r'); i >= 0 {
+		line = strings.TrimSpace(line[i+1:])
+	}
+	if line == "" || strings.HasPrefix(line, "===") || strings.HasPrefix(line, "---") {
+		return "", "", false
+	}
+	if matches := levelRegex.FindStringSubmatch(line); matches != nil {
+		return LogLevel(strings.ToUpper(matches[1])), strings.TrimSpace(matches[2]), true
+	}
+	return "", line, true
+}
 
-			// Track success/failure for files
-			if level == LogSuccess && strings.Contains(message, "Successfully processed:") {
-				p.mu.Lock()
-				p.successCount++
-				p.mu.Unlock()
-			}
-			if level == LogError && (strings.Contains(message, "Failed to") || strings.Contains(message, "failed")) {
-				p.mu.Lock()
-				p.failedCount++
-				p.mu.Unlock()
-			}
+// saveStepPattern matches the step process_las_files.py saves the project in
+var saveStepPattern = regexp.MustCompile(`^\[\d+/\d+\] Saving`)
 
-			switch level {
-			case LogSuccess, LogError, LogWarning, LogInfo:
-				p.sendLog(level, message)
-			default:
-				p.sendLog(LogInfo, message)
-			}
-		} else {
-			// No level prefix, treat as info
-			p.sendLog(LogInfo, line)
-		}
+// boostSave raises the priority of the running script for its save step;
+// a failure only costs speed
+func (p *Processor) boostSave() {
+	p.mu.Lock()
+	cmd := p.cmd
+	p.mu.Unlock()
+	if cmd == nil || cmd.Process == nil {
+		return
 	}
+	// Raising the priority class would undo low priority for the save
+	if p.params.LowPriority && !raiseKeepsCPUPriority {
+		p.sendLog(LogInfo, "Kept low priority for saving")
+		return
+	}
+	if err := raisePriority(cmd); err != nil {
+		p.sendLog(LogWarning, fmt.Sprintf("Failed to raise priority for saving: %v", err))
+		return
+	}
+	p.sendLog(LogInfo, "Raised I/O priority for saving")
+}
+
+// newBatchID returns an ID for a run, unique on this machine
+func newBatchID() string {
+	return fmt.Sprintf("%s-%d", time.Now().Format("20060102-150405.000"), os.Getpid())
+}
+
+// setContext sets the batch and file stamped on the following log entries
+func (p *Processor) setContext(batch string, fileIndex int, file string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.batch, p.fileIndex, p.file = batch, fileIndex, file
 }
 
 func (p *Processor) sendLog(level LogLevel, message string) {
-	select {
-	case p.logChan <- LogEntry{Level: level, Message: message}:
-	default:
-		// Channel full, drop oldest and add new
+	p.send(p.entry(level, message))
+}
+
+// entry makes a log entry stamped with the current batch and file
+func (p *Processor) entry(level LogLevel, message string) LogEntry {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return LogEntry{Level: level, Message: message, Batch: p.batch, FileIndex: p.fileIndex, File: p.file}
+}
+
+// watchOutput warns each time the script running on file has printed
+// nothing for another StallAfter, until done is closed. The spinner keeps
+// turning regardless, so this is the only sign of a hung script.
+func (p *Processor) watchOutput(file string, out *fileOutput, done <-chan struct{}) {
+	after := p.params.StallAfter
+	if after <= 0 {
+		after = DefaultStallAfter
+	}
+	ticker := time.NewTicker(after / 10)
+	defer ticker.Stop()
+	warned := 0 // Periods of the current silence warned about
+	for {
 		select {
-		case <-p.logChan:
-		default:
+		case <-done:
+			return
+		case <-ticker.C:
 		}
-		select {
-		case p.logChan <- LogEntry{Level: level, Message: message}:
-		default:
+		silent := out.silent()
+		if silent < after {
+			warned = 0
+			continue
+		}
+		if periods := int(silent / after); periods > warned {
+			warned = periods
+			entry := p.entry(LogWarning, fmt.Sprintf("No output from %s for %s; the script may be stalled", filepath.Base(file), humanize.Duration(silent)))
+			entry.Silent = silent
+			p.send(entry)
 		}
 	}
 }
 
-func (p *Processor) sendResult(result ProcessingResult) {
-	select {
-	case p.resultChan <- result:
-	default:
+// sendFileDone sends the entry that ends the current file, with its outcome
+func (p *Processor) sendFileDone(fileResult FileResult) {
+	outcome := fileResult.Outcome()
+	level := LogSuccess
+	switch outcome {
+	case OutcomeWarning:
+		level = LogWarning
+	case OutcomeFailed:
+		level = LogError
 	}
+	entry := p.entry(level, fmt.Sprintf("Finished: %s (%s)", filepath.Base(fileResult.InputFile), outcome))
+	entry.Outcome = outcome
+	p.send(entry)
+	p.fileDone(fileResult)
+}
+
+// finish ends the run, once: the losses are logged, the log channel is
+// closed and the result sent, in that order
+func (p *Processor) finish(result ProcessingResult) {
+	p.finishOnce.Do(func() {
+		p.logLosses(&result)
+		p.mu.Lock()
+		p.running = false
+		p.mu.Unlock()
+
+		close(p.logChan)
+		if p.hooks.BeforeResult != nil {
+			p.hooks.BeforeResult(result)
+		}
+		p.resultChan <- result
+		close(p.done)
+	})
 }
 
 // ValidateInputDir checks if the input directory exists and contains LAS files
 func (p *Processor) ValidateInputDir() error {
+	if p.params.InputFile != "" {
+		info, err := os.Stat(p.params.InputFile)
+		if err != nil {
+			return fmt.Errorf("input file does not exist: %s", p.params.InputFile)
+		}
+		if info.IsDir() || !strings.HasSuffix(strings.ToLower(p.params.InputFile), ".las") {
+			return fmt.Errorf("input file is not a LAS file: %s", p.params.InputFile)
+		}
+		return nil
+	}
+	if len(p.params.Files) > 0 {
+		for _, file := range p.params.Files {
+			if err := CheckLASFile(file); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	inputDir := p.params.InputDir
 	if inputDir == "" {
 		inputDir = "."
@@ -503,12 +1501,12 @@ func (p *Processor) ValidateInputDir() error {
 		return fmt.Errorf("input path is not a directory: %s", absDir)
 	}
 
-	count, err := p.CountLASFiles()
+	files, err := p.ListLASFiles()
 	if err != nil {
 		return fmt.Errorf("failed to read directory: %v", err)
 	}
 
-	if count == 0 {
+	if len(files) == 0 {
 		return fmt.Errorf("no LAS files found in: %s", absDir)
 	}
 
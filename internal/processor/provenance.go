@@ -0,0 +1,106 @@
+package processor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/cloudcompare-automation/internal/report"
+	"github.com/cloudcompare-automation/internal/update"
+)
+
+// Every saved project and exported mesh gets a sidecar, scan1.bin.json
+// next to scan1.bin, recording what produced it, so an output found on disk
+// months later can be traced to its settings without the run's report.
+
+// Provenance is the content of an output's sidecar
+type Provenance struct {
+	Output        string            `json:"output"`
+	Input         string            `json:"input"`
+	InputSize     int64             `json:"input_size"`
+	InputSHA256   string            `json:"input_sha256,omitempty"`
+	Pipeline      string            `json:"pipeline"`
+	ScriptVersion string            `json:"script_version,omitempty"` // The script's __version__
+	ScriptSHA256  string            `json:"script_sha256,omitempty"`
+	Params        map[string]string `json:"params"`      // As passed to the script for this file
+	ParamsHash    string            `json:"params_hash"` // Of the parameters as set, see ParamsHash
+	Metadata      map[string]string `json:"metadata,omitempty"`
+	Deterministic bool              `json:"deterministic,omitempty"`
+	StartedAt     time.Time         `json:"started_at"`
+	FinishedAt    time.Time         `json:"finished_at"`
+}
+
+// ProvenancePath returns the sidecar of an output file
+func ProvenancePath(output string) string {
+	return output + ".json"
+}
+
+// ParamsHash returns a short hash of parameter values, the {params}
+// placeholder of output names: outputs with the same hash were made with
+// the same settings
+func ParamsHash(values map[string]string) string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%s=%s\n", name, values[name])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:8]
+}
+
+// provenance describes how the outputs of file were made with values,
+// from started until now
+func (p *Processor) provenance(file string, values map[string]string, started time.Time) (Provenance, error) {
+	prov := Provenance{
+		Input:         file,
+		Pipeline:      p.scriptPath,
+		Params:        values,
+		ParamsHash:    ParamsHash(p.params.Values),
+		Metadata:      p.params.fileMetadata(file),
+		Deterministic: p.params.Deterministic,
+		StartedAt:     started,
+		FinishedAt:    time.Now(),
+	}
+
+	if sum, err := report.FileSHA256(p.scriptPath); err == nil {
+		prov.ScriptSHA256 = sum
+		prov.ScriptVersion, _ = update.ScriptVersion(p.scriptPath)
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return prov, err
+	}
+	prov.InputSize = info.Size()
+	prov.InputSHA256, err = report.FileSHA256(file)
+	return prov, err
+}
+
+// writeProvenance writes the sidecar of each output that exists
+func writeProvenance(prov Provenance, outputs ...string) error {
+	for _, output := range outputs {
+		if output == "" {
+			continue
+		}
+		if _, err := os.Stat(output); err != nil {
+			continue
+		}
+		prov.Output = output
+		data, err := json.MarshalIndent(prov, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(ProvenancePath(output), data, 0o644); err != nil {
+			return fmt.Errorf("failed to write provenance: %v", err)
+		}
+	}
+	return nil
+}
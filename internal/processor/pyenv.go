@@ -0,0 +1,466 @@
+package processor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// The processing script needs the CloudComPy311 conda environment with
+// CloudComPy's libraries on the paths. Rather than have a shell activate
+// it through conda and CloudComPy's own scripts for every file, the
+// environment is built here once per run and Python started directly, so
+// the script's output and exit status reach the processor unchanged and
+// stopping the run stops Python itself.
+
+const (
+	// DefaultCondaEnv is the conda environment setup_cloudcompy.bat creates
+	DefaultCondaEnv = "CloudComPy311"
+	// DefaultCloudComPy is where the CloudComPy binaries are extracted on
+	// Windows
+	DefaultCloudComPy = `C:\bin\CloudComPy311`
+)
+
+// PythonEnv selects the Python environment the processing script runs in
+type PythonEnv struct {
+	CondaEnv    string // Conda environment name (default: CloudComPy311)
+	CondaPrefix string // Conda environment directory; found from CondaEnv when empty
+	CloudComPy  string // CloudComPy installation directory; see cloudComPyDirs for the default
+}
+
+// activation is a Python environment ready to run scripts in
+type activation struct {
+	python     string            // Python executable
+	vars       map[string]string // Variables to set on top of the inherited environment
+	prefix     string            // Conda environment directory, "" for the python on PATH
+	cloudComPy string            // CloudComPy installation directory, "" if none was found
+}
+
+// environ returns the variables of a in a stable order, as KEY=value
+func (a activation) environ() []string {
+	keys := make([]string, 0, len(a.vars))
+	for key := range a.vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	env := make([]string, 0, len(keys))
+	for _, key := range keys {
+		env = append(env, key+"="+a.vars[key])
+	}
+	return env
+}
+
+// command prepares Python to run with args in the environment
+func (a activation) command(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, a.python, args...)
+	cmd.Env = append(os.Environ(), a.environ()...)
+	return cmd
+}
+
+// activate prepares the environment e selects. On Windows the conda
+// environment and CloudComPy installation must exist, as
+// setup_cloudcompy.bat and the installation steps make them. Elsewhere
+// CloudComPy may be installed in other ways, so without a conda
+// environment configured or found the python on PATH is used, with the
+// CloudComPy installation if one is found.
+func (e PythonEnv) activate() (activation, error) {
+	name := e.CondaEnv
+	if name == "" {
+		name = DefaultCondaEnv
+	}
+	windows := runtime.GOOS == "windows"
+
+	prefix := e.CondaPrefix
+	if prefix == "" {
+		prefix = findCondaEnv(name)
+	}
+	if prefix == "" && (windows || e.CondaEnv != "") {
+		return activation{}, fmt.Errorf("conda environment %s not found; run setup_cloudcompy.bat, or set python.conda_prefix in the configuration file", name)
+	}
+	if prefix != "" {
+		if _, err := os.Stat(condaPython(prefix)); err != nil {
+			return activation{}, fmt.Errorf("no Python in conda environment %s", prefix)
+		}
+	}
+
+	cloudComPy := e.CloudComPy
+	if cloudComPy == "" {
+		for _, dir := range cloudComPyDirs() {
+			if isCloudComPy(dir) {
+				cloudComPy = dir
+				break
+			}
+		}
+	}
+	if cloudComPy == "" && windows {
+		cloudComPy = DefaultCloudComPy
+	}
+	if cloudComPy != "" && !isCloudComPy(cloudComPy) {
+		return activation{}, fmt.Errorf("CloudComPy not found at %s; extract it there, or set python.cloudcompy in the configuration file", cloudComPy)
+	}
+
+	if windows {
+		return windowsActivation(name, prefix, cloudComPy)
+	}
+	return unixActivation(name, prefix, cloudComPy)
+}
+
+// condaPython returns the Python executable of a conda environment
+func condaPython(prefix string) string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(prefix, "python.exe")
+	}
+	return filepath.Join(prefix, "bin", "python")
+}
+
+// cloudComPyDirs lists the usual places of a CloudComPy installation
+func cloudComPyDirs() []string {
+	home, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "windows":
+		return []string{DefaultCloudComPy}
+	case "darwin":
+		return []string{
+			filepath.Join(home, "CloudComPy311"),
+			filepath.Join(home, "Applications", "CloudComPy311"),
+			"/Applications/CloudComPy311",
+			"/opt/CloudComPy311",
+		}
+	}
+	return []string{
+		filepath.Join(home, "CloudComPy311"),
+		"/opt/CloudComPy311",
+		"/usr/local/CloudComPy311",
+	}
+}
+
+// isCloudComPy reports whether dir looks like a CloudComPy installation:
+// envCloudComPy.bat on Windows, a cloudComPy package elsewhere
+func isCloudComPy(dir string) bool {
+	if runtime.GOOS == "windows" {
+		_, err := os.Stat(filepath.Join(dir, "envCloudComPy.bat"))
+		return err == nil
+	}
+	return findPackageDir(dir) != ""
+}
+
+// findCondaEnv returns the directory of the conda environment name: the
+// active one, one conda has recorded in ~/.conda/environments.txt, or one
+// in the envs directory of a conda installation in a usual place. It
+// returns "" if none has a Python.
+func findCondaEnv(name string) string {
+	var candidates []string
+	if strings.EqualFold(os.Getenv("CONDA_DEFAULT_ENV"), name) {
+		candidates = append(candidates, os.Getenv("CONDA_PREFIX"))
+	}
+
+	home, _ := os.UserHomeDir()
+	if f, err := os.Open(filepath.Join(home, ".conda", "environments.txt")); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if dir := strings.TrimSpace(scanner.Text()); strings.EqualFold(filepath.Base(dir), name) {
+				candidates = append(candidates, dir)
+			}
+		}
+		f.Close()
+	}
+
+	for _, root := range condaRoots(home) {
+		candidates = append(candidates, filepath.Join(root, "envs", name))
+	}
+	candidates = append(candidates, filepath.Join(home, ".conda", "envs", name))
+
+	for _, dir := range candidates {
+		if dir == "" {
+			continue
+		}
+		if _, err := os.Stat(condaPython(dir)); err == nil {
+			return dir
+		}
+	}
+	return ""
+}
+
+// condaRoots lists the usual places of conda installations
+func condaRoots(home string) []string {
+	var roots []string
+	if exe := os.Getenv("CONDA_EXE"); exe != "" {
+		// CONDA_EXE is <root>\Scripts\conda.exe or <root>/bin/conda
+		roots = append(roots, filepath.Dir(filepath.Dir(exe)))
+	}
+
+	bases := []string{home, "/opt", "/usr/local"}
+	switch runtime.GOOS {
+	case "windows":
+		bases = []string{home, os.Getenv("LOCALAPPDATA"), os.Getenv("ProgramData"), `C:\`}
+	case "darwin":
+		// Homebrew casks
+		for _, brew := range []string{"/opt/homebrew", "/usr/local"} {
+			for _, cask := range []string{"miniconda", "miniforge", "anaconda"} {
+				roots = append(roots, filepath.Join(brew, "Caskroom", cask, "base"))
+			}
+		}
+	default:
+		// Container images
+		roots = append(roots, "/opt/conda")
+	}
+	for _, base := range bases {
+		for _, dist := range []string{"miniconda3", "anaconda3", "miniforge3", "mambaforge"} {
+			if base != "" {
+				roots = append(roots, filepath.Join(base, dist))
+			}
+		}
+	}
+	return roots
+}
+
+// windowsActivation builds what conda activate and envCloudComPy.bat
+// would set up: the conda environment's directories first on PATH, the
+// variables of its activate.d scripts, then those of envCloudComPy.bat
+func windowsActivation(name, prefix, cloudComPy string) (activation, error) {
+	vars := map[string]string{
+		"CONDA_PREFIX":      prefix,
+		"CONDA_DEFAULT_ENV": name,
+		"PATH": joinPathList(
+			prefix,
+			filepath.Join(prefix, "Library", "mingw-w64", "bin"),
+			filepath.Join(prefix, "Library", "usr", "bin"),
+			filepath.Join(prefix, "Library", "bin"),
+			filepath.Join(prefix, "Scripts"),
+			filepath.Join(prefix, "bin"),
+			os.Getenv("PATH"),
+		),
+	}
+
+	scripts, _ := filepath.Glob(filepath.Join(prefix, "etc", "conda", "activate.d", "*.bat"))
+	sort.Strings(scripts)
+	for _, script := range scripts {
+		if err := applyBatchSets(vars, script); err != nil {
+			return activation{}, err
+		}
+	}
+	if err := applyBatchSets(vars, filepath.Join(cloudComPy, "envCloudComPy.bat")); err != nil {
+		return activation{}, err
+	}
+	return activation{python: condaPython(prefix), vars: vars, prefix: prefix, cloudComPy: cloudComPy}, nil
+}
+
+// unixActivation builds what conda activate and CloudComPy's condaCloud.sh
+// would set up on Linux and macOS: the conda environment's bin first on
+// PATH with the variables of its activate.d scripts, and the directory of
+// the cloudComPy package on the Python and library paths. Without a conda
+// environment, the python on PATH is used.
+func unixActivation(name, prefix, cloudComPy string) (activation, error) {
+	a := activation{python: "python", vars: map[string]string{}, prefix: prefix, cloudComPy: cloudComPy}
+	if _, err := exec.LookPath("python"); err != nil {
+		// Many distributions only have python3
+		if _, err := exec.LookPath("python3"); err == nil {
+			a.python = "python3"
+		}
+	}
+
+	libraryPath := "LD_LIBRARY_PATH"
+	if runtime.GOOS == "darwin" {
+		libraryPath = "DYLD_LIBRARY_PATH"
+	}
+	var libs []string
+
+	if prefix != "" {
+		a.python = condaPython(prefix)
+		a.vars["CONDA_PREFIX"] = prefix
+		a.vars["CONDA_DEFAULT_ENV"] = name
+		a.vars["PATH"] = joinPathList(filepath.Join(prefix, "bin"), os.Getenv("PATH"))
+		scripts, _ := filepath.Glob(filepath.Join(prefix, "etc", "conda", "activate.d", "*.sh"))
+		sort.Strings(scripts)
+		for _, script := range scripts {
+			if err := applyShellExports(a.vars, script); err != nil {
+				return activation{}, err
+			}
+		}
+		libs = append(libs, filepath.Join(prefix, "lib"))
+	}
+
+	if cloudComPy != "" {
+		pkg := findPackageDir(cloudComPy)
+		pythonPath := []string{pkg}
+		if tests := filepath.Join(cloudComPy, "doc", "PythonAPI_test"); isDir(tests) {
+			pythonPath = append(pythonPath, tests)
+		}
+		a.vars["PYTHONPATH"] = joinPathList(append(pythonPath, lookupVar(a.vars, "PYTHONPATH"))...)
+		libs = append([]string{pkg, filepath.Join(pkg, "plugins")}, libs...)
+	}
+	if len(libs) > 0 {
+		a.vars[libraryPath] = joinPathList(append(libs, lookupVar(a.vars, libraryPath))...)
+	}
+	return a, nil
+}
+
+// findPackageDir returns the directory holding the cloudComPy package in
+// a CloudComPy installation, e.g. lib/cloudcompare on Linux or
+// CloudCompare.app/Contents/Frameworks on macOS, or "" if there is none
+func findPackageDir(root string) string {
+	found := ""
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if rel, _ := filepath.Rel(root, path); strings.Count(rel, string(filepath.Separator)) >= 5 {
+			return filepath.SkipDir
+		}
+		if d.Name() == "cloudComPy" {
+			if _, err := os.Stat(filepath.Join(path, "__init__.py")); err == nil {
+				found = filepath.Dir(path)
+				return filepath.SkipAll
+			}
+		}
+		return nil
+	})
+	return found
+}
+
+// joinPathList joins directories into a PATH-style list, leaving out
+// empty entries
+func joinPathList(dirs ...string) string {
+	var list []string
+	for _, dir := range dirs {
+		if dir != "" {
+			list = append(list, dir)
+		}
+	}
+	return strings.Join(list, string(os.PathListSeparator))
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// batchSet matches a set command of a batch file, with or without quotes
+var batchSet = regexp.MustCompile(`(?i)^@?set\s+("?)([A-Za-z_][A-Za-z0-9_.]*)=(.*)$`)
+
+// batchVar matches %NAME% and %~dp0 in a batch file line
+var batchVar = regexp.MustCompile(`%~dp0|%%|%([^%]+)%`)
+
+// applyBatchSets applies the set commands of the batch file at path to
+// vars, as running it would. Only unconditional commands outside
+// parenthesized blocks are applied; activation scripts use blocks to save
+// and restore previous values, which don't matter for a fresh process.
+// Variables are expanded from vars, then from the inherited environment.
+func applyBatchSets(vars map[string]string, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dir := filepath.Dir(path) + string(filepath.Separator)
+	depth := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, ")") {
+			depth--
+		}
+		if strings.HasSuffix(line, "(") {
+			depth++
+			continue
+		}
+		m := batchSet.FindStringSubmatch(line)
+		if depth > 0 || m == nil || strings.HasPrefix(strings.ToUpper(m[2]), "_CONDA_") {
+			continue
+		}
+		value := m[3]
+		if m[1] == `"` {
+			value = value[:max(strings.LastIndex(value, `"`), 0)]
+		}
+		value = batchVar.ReplaceAllStringFunc(value, func(ref string) string {
+			switch ref {
+			case "%~dp0":
+				return dir
+			case "%%":
+				return "%"
+			}
+			return lookupVar(vars, ref[1:len(ref)-1])
+		})
+		vars[strings.ToUpper(m[2])] = value
+	}
+	return scanner.Err()
+}
+
+// shellExport matches an export command of a shell script
+var shellExport = regexp.MustCompile(`^export\s+([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
+
+// blockEnd is the keyword closing a shell block
+var blockEnd = map[string]string{"if": "fi", "case": "esac", "for": "done", "while": "done"}
+
+// shellVar matches $NAME and ${NAME} in a shell script line
+var shellVar = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// applyShellExports applies the export commands of the shell script at
+// path to vars, as sourcing it would, under the same rules as
+// applyBatchSets: only unconditional commands outside if blocks and
+// functions, with variables expanded from vars, then the environment
+func applyShellExports(vars map[string]string, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	depth := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		fields := strings.Fields(line)
+		if len(fields) > 0 {
+			switch fields[0] {
+			case "if", "case", "for", "while":
+				// Unless closed on the same line
+				if closer := blockEnd[fields[0]]; fields[len(fields)-1] != closer {
+					depth++
+				}
+			case "fi", "esac", "done", "}":
+				depth--
+			}
+		}
+		if strings.HasSuffix(line, "{") {
+			depth++
+		}
+		m := shellExport.FindStringSubmatch(line)
+		if depth > 0 || m == nil || strings.HasPrefix(m[1], "CONDA_BACKUP_") || strings.HasPrefix(m[1], "_CONDA_") {
+			continue
+		}
+		value := m[2]
+		if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			vars[m[1]] = value[1 : len(value)-1]
+			continue
+		}
+		value = strings.Trim(value, `"`)
+		vars[m[1]] = shellVar.ReplaceAllStringFunc(value, func(ref string) string {
+			return lookupVar(vars, strings.Trim(ref, "${}"))
+		})
+	}
+	return scanner.Err()
+}
+
+// lookupVar returns the variable name of vars, or else of the inherited
+// environment. On Windows, names are upper-cased in vars as their case
+// doesn't matter there.
+func lookupVar(vars map[string]string, name string) string {
+	key := name
+	if runtime.GOOS == "windows" {
+		key = strings.ToUpper(name)
+	}
+	if value, ok := vars[key]; ok {
+		return value
+	}
+	return os.Getenv(name)
+}
@@ -0,0 +1,96 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cloudcompare-automation/internal/humanize"
+)
+
+// A crashed CloudComPy run sometimes leaves the machine in a state where
+// the next ones crash too, e.g. a GPU driver still resetting or a lock
+// file left in the conda environment. With CrashRecovery, the batch waits
+// after a crash, sets the Python environment up again from scratch and
+// checks that CloudComPy still imports before going on with the next file.
+
+// DefaultCrashCooldown is the wait after a crash when CrashCooldown is 0
+const DefaultCrashCooldown = 30 * time.Second
+
+// crashCooldown returns how long to wait after a crash
+func (params Params) crashCooldown() time.Duration {
+	if params.CrashCooldown > 0 {
+		return params.CrashCooldown
+	}
+	return DefaultCrashCooldown
+}
+
+// checkEnvironment checks that Python starts in the environment and
+// imports CloudComPy, the part of Doctor a crash can break
+func checkEnvironment(ctx context.Context, python activation) error {
+	if _, err := runPython(ctx, python, "-c", "import cloudComPy"); err != nil {
+		return fmt.Errorf("CloudComPy doesn't import: %v", err)
+	}
+	return nil
+}
+
+// RecoverFromCrash waits the cool-down after the script crashed, then sets
+// the Python environment up again and checks that CloudComPy imports.
+// Cancelling ctx cuts it short without an error.
+func (params Params) RecoverFromCrash(ctx context.Context) error {
+	_, err := params.recoverFromCrash(ctx, true)
+	return err
+}
+
+// recoverFromCrash waits the cool-down and, with check, returns the
+// environment set up again once it is checked
+func (params Params) recoverFromCrash(ctx context.Context, check bool) (activation, error) {
+	select {
+	case <-ctx.Done():
+		return activation{}, nil
+	case <-time.After(params.crashCooldown()):
+	}
+	if !check {
+		return activation{}, nil
+	}
+
+	python, err := params.Python.activate()
+	if err != nil {
+		return activation{}, err
+	}
+	if err := checkEnvironment(ctx, python); err != nil && ctx.Err() == nil {
+		return activation{}, err
+	}
+	return python, nil
+}
+
+// recoverEnvironment prepares the environment for the next file after a
+// crash. It returns why the batch can't go on, or nil, also when Stop cut
+// the wait short.
+func (p *Processor) recoverEnvironment() error {
+	ctx, cancel := p.stopContext()
+	defer cancel()
+
+	p.sendLog(LogWarning, fmt.Sprintf("The script crashed; waiting %s before the next file", humanize.Duration(p.params.crashCooldown())))
+	// A test's Command hook runs without the Python environment
+	python, err := p.params.recoverFromCrash(ctx, p.hooks.Command == nil)
+	if err != nil || ctx.Err() != nil || p.hooks.Command != nil {
+		return err
+	}
+	p.python = python
+	p.sendLog(LogInfo, "Python environment set up again; CloudComPy imports")
+	return nil
+}
+
+// stopContext returns a context cancelled by Stop
+func (p *Processor) stopContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-p.stopping:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
@@ -0,0 +1,119 @@
+package processor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cloudcompare-automation/internal/history"
+	"github.com/cloudcompare-automation/internal/report"
+)
+
+// A run recorded in the history can be looked at again, from its report,
+// and repeated with its settings, from the command line and from the
+// Welcome screen.
+
+// RerunParams returns the settings to repeat a recorded run with: its
+// input, output subdirectory, pipeline, parameter values and labels, and
+// the metadata and listed files from its report. The rest comes from base.
+func RerunParams(base Params, pipelines []Pipeline, run history.Entry) (Params, error) {
+	params := base
+	params.InputFile, params.Files = "", nil
+
+	pipeline, ok := FindPipeline(pipelines, filepath.Base(run.Pipeline))
+	if !ok {
+		return params, fmt.Errorf("pipeline %s is no longer available", run.Pipeline)
+	}
+	params.Script = ""
+	if pipeline.Name != DefaultPipeline {
+		params.Script = pipeline.Script
+	}
+	params.Values = Defaults(pipeline.Params)
+	for name, value := range run.Params {
+		params.Values[name] = value
+	}
+	params.Labels = append([]string(nil), run.Labels...)
+
+	var r report.Report
+	if run.Report != "" {
+		r, _ = report.Load(run.Report)
+	}
+	params.Metadata = make(map[string]string, len(base.Metadata)+len(r.Metadata))
+	for key, value := range base.Metadata {
+		params.Metadata[key] = value
+	}
+	for key, value := range r.Metadata {
+		params.Metadata[key] = value
+	}
+
+	// The input was a directory, a single file, or a list of files that
+	// only the report has
+	dir := run.Input
+	if info, err := os.Stat(run.Input); err == nil {
+		if info.IsDir() {
+			params.InputDir = run.Input
+		} else {
+			params.InputFile = run.Input
+			dir = filepath.Dir(run.Input)
+		}
+	} else if len(r.Files) > 0 && !filepath.IsAbs(run.Input) {
+		for _, f := range r.Files {
+			params.Files = append(params.Files, f.Input)
+		}
+		dir = filepath.Dir(params.Files[0])
+	} else {
+		return params, fmt.Errorf("input %s no longer exists", run.Input)
+	}
+	if rel, err := filepath.Rel(dir, run.OutputDir); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+		params.OutputSubdir = rel
+	}
+	return params, nil
+}
+
+// ResultFromReport rebuilds the result of a recorded run, to show it
+// again: from its report when it can be read, or else from the counts the
+// history keeps
+func ResultFromReport(run history.Entry) ProcessingResult {
+	result := ProcessingResult{
+		TotalFiles:   run.Total,
+		SuccessCount: run.Succeeded + run.Warned,
+		WarningCount: run.Warned,
+		FailedCount:  run.Failed,
+		OutputDir:    run.OutputDir,
+		Completed:    true,
+		Stopped:      run.Stopped,
+		Aborted:      run.Aborted,
+	}
+	if run.Report == "" {
+		return result
+	}
+	r, err := report.Load(run.Report)
+	if err != nil {
+		return result
+	}
+	result.ReportPath = run.Report
+	for _, f := range r.Files {
+		result.Files = append(result.Files, FileResult{
+			InputFile:  f.Input,
+			OutputFile: f.Output,
+			Success:    f.Outcome != string(OutcomeFailed),
+			Error:      f.Error,
+			Artifacts:  f.Artifacts,
+			Warnings:   f.Warnings,
+			Duration:   time.Duration(f.Seconds * float64(time.Second)),
+			CPUTime:    time.Duration(f.CPUSeconds * float64(time.Second)),
+			PostSteps:  f.PostSteps,
+			MeshFile:   f.Mesh,
+			Snapshots:  f.Snapshots,
+			WebExport:  f.Web,
+			Archive:    f.Archive,
+			Metadata:   f.Metadata,
+			Points:     f.Points,
+			Faces:      f.Faces,
+			Quality:    f.Quality,
+		})
+	}
+	return result
+}
@@ -0,0 +1,184 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cloudcompare-automation"
+)
+
+// ParamType is the value type of a pipeline parameter
+type ParamType string
+
+const (
+	ParamInt    ParamType = "int"
+	ParamFloat  ParamType = "float"
+	ParamString ParamType = "string"
+)
+
+// ParamSpec describes one pipeline parameter. It is passed to the script
+// as --<Name> <value>, and the TUI form and worker flags are generated
+// from it.
+type ParamSpec struct {
+	Name    string    `yaml:"name" json:"name"`
+	Label   string    `yaml:"label" json:"label"`
+	Short   string    `yaml:"short,omitempty" json:"short,omitempty"` // Label on narrow terminals
+	Type    ParamType `yaml:"type" json:"type"`
+	Default string    `yaml:"default" json:"default"`
+	Min     *float64  `yaml:"min,omitempty" json:"min,omitempty"`
+	Max     *float64  `yaml:"max,omitempty" json:"max,omitempty"`
+	Help    string    `yaml:"help,omitempty" json:"help,omitempty"`
+
+	// Keywords are words accepted besides numbers, e.g. auto. For a
+	// string parameter they are the only accepted values.
+	Keywords []string `yaml:"keywords,omitempty" json:"keywords,omitempty"`
+
+	// Stage names the cached intermediate result the parameter affects
+	// (StageNormals); changing it invalidates that cache
+	Stage string `yaml:"stage,omitempty" json:"stage,omitempty"`
+}
+
+// schemaSuffix names the schema file shipped next to a pipeline script:
+// process_las_files.py is described by process_las_files.params.yaml
+const schemaSuffix = ".params.yaml"
+
+// DefaultSchema describes the parameters of process_las_files.py, parsed
+// from the copy of its schema file built into the binary. It is used for
+// scripts that don't ship a schema file.
+var DefaultSchema = mustParseSchema(cloudcompare.ParamsSchema)
+
+func mustParseSchema(data []byte) []ParamSpec {
+	schema, err := parseSchema(data)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in schema: %v", err))
+	}
+	return schema
+}
+
+// LoadSchema reads the schema file next to script. It returns
+// DefaultSchema when the script has none.
+func LoadSchema(script string) ([]ParamSpec, error) {
+	path := strings.TrimSuffix(script, ".py") + schemaSuffix
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return DefaultSchema, nil
+		}
+		return DefaultSchema, err
+	}
+
+	schema, err := parseSchema(data)
+	if err != nil {
+		return DefaultSchema, fmt.Errorf("invalid schema %s: %v", path, err)
+	}
+	return schema, nil
+}
+
+// parseSchema decodes and checks the contents of a schema file
+func parseSchema(data []byte) ([]ParamSpec, error) {
+	var schema []ParamSpec
+	if err := yaml.Unmarshal(data, &schema); err != nil {
+		return nil, err
+	}
+	for _, spec := range schema {
+		if err := spec.check(); err != nil {
+			return nil, err
+		}
+	}
+	return schema, nil
+}
+
+// check reports problems in the spec itself
+func (s ParamSpec) check() error {
+	if s.Name == "" {
+		return fmt.Errorf("parameter without a name")
+	}
+	switch s.Type {
+	case ParamInt, ParamFloat, ParamString:
+	default:
+		return fmt.Errorf("parameter %s: unknown type %q", s.Name, s.Type)
+	}
+	if _, err := s.Validate(s.Default); err != nil {
+		return fmt.Errorf("parameter %s: default %v", s.Name, err)
+	}
+	return nil
+}
+
+// DisplayLabel returns the form label, falling back to the name
+func (s ParamSpec) DisplayLabel(narrow bool) string {
+	if narrow && s.Short != "" {
+		return s.Short
+	}
+	if s.Label != "" {
+		return s.Label
+	}
+	return s.Name
+}
+
+// Validate checks value against the spec and returns it normalized;
+// an empty value means the default
+func (s ParamSpec) Validate(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		value = s.Default
+	}
+	for _, keyword := range s.Keywords {
+		if strings.EqualFold(value, keyword) {
+			return keyword, nil
+		}
+	}
+	if s.Type == ParamString && len(s.Keywords) > 0 {
+		return "", fmt.Errorf("%q must be one of %s", value, strings.Join(s.Keywords, ", "))
+	}
+
+	var number float64
+	switch s.Type {
+	case ParamInt:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a whole number", value)
+		}
+		number = float64(n)
+	case ParamFloat:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a number", value)
+		}
+		number = f
+	default:
+		return value, nil
+	}
+
+	if s.Min != nil && number < *s.Min {
+		return "", fmt.Errorf("%s must be at least %g", value, *s.Min)
+	}
+	if s.Max != nil && number > *s.Max {
+		return "", fmt.Errorf("%s must be at most %g", value, *s.Max)
+	}
+	return value, nil
+}
+
+// Defaults returns the default value of every parameter in schema
+func Defaults(schema []ParamSpec) map[string]string {
+	values := make(map[string]string, len(schema))
+	for _, spec := range schema {
+		values[spec.Name] = spec.Default
+	}
+	return values
+}
+
+// checkChoice reports a value that isn't one of choices, naming what it
+// sets, e.g. "archive must be one of none, gzip, zip"
+func checkChoice(what, value string, choices []string) error {
+	for _, choice := range choices {
+		if value == choice {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s must be one of %s", what, strings.Join(choices, ", "))
+}
@@ -0,0 +1,76 @@
+package processor
+
+import (
+	"image/png"
+	"io"
+	"strings"
+
+	"github.com/cloudcompare-automation/internal/mesh"
+)
+
+// Snapshot modes of process_las_files.py: views renders a top and an
+// isometric image of the mesh, turntable adds a rotating GIF. The script
+// exports the mesh as PLY and the images are rendered from it here.
+const (
+	SnapshotNone      = "none"
+	SnapshotViews     = "views"
+	SnapshotTurntable = "turntable"
+)
+
+// Snapshot image sizes in pixels
+const (
+	snapshotWidth   = 1200
+	snapshotHeight  = 900
+	turntableWidth  = 480
+	turntableHeight = 360
+	turntableFrames = 36
+)
+
+// renderSnapshots renders the mesh the script exported at partial into
+// images next to the project at output, e.g. scan1_top.png, and returns
+// their paths
+func renderSnapshots(partial, output, mode string) ([]string, error) {
+	m, err := mesh.ReadPLY(partial)
+	if err != nil {
+		return nil, err
+	}
+	stem := strings.TrimSuffix(output, ".bin")
+
+	var paths []string
+	views := []struct {
+		name string
+		view mesh.View
+	}{{"top", mesh.TopView}, {"iso", mesh.IsoView}}
+	for _, v := range views {
+		img, err := mesh.Render(m, v.view, snapshotWidth, snapshotHeight)
+		if err != nil {
+			return paths, err
+		}
+		path := stem + "_" + v.name + ".png"
+		if err := writeArtifact(path, func(w io.Writer) error { return png.Encode(w, img) }); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+
+	if mode == SnapshotTurntable {
+		path := stem + "_turntable.gif"
+		err := writeArtifact(path, func(w io.Writer) error {
+			return mesh.WriteTurntable(w, m, turntableFrames, turntableWidth, turntableHeight)
+		})
+		if err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// SnapshotCount returns the number of snapshots rendered in the run
+func (r ProcessingResult) SnapshotCount() int {
+	n := 0
+	for _, f := range r.Files {
+		n += len(f.Snapshots)
+	}
+	return n
+}
@@ -0,0 +1,71 @@
+package processor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/cloudcompare-automation/internal/workspace"
+)
+
+// Stages the default pipeline can start from (the from-stage parameter)
+const (
+	StageAuto    = "auto"    // Reuse the cached normals when they match, else run all
+	StageAll     = "all"     // Run all stages, refreshing the cache
+	StagePoisson = "poisson" // Reuse the cloud with normals, rerun Poisson and save
+)
+
+// StageNormals is the cached cloud with normals and DIP fields; parameters
+// marked with this stage in the schema are part of its cache key
+const StageNormals = "normals"
+
+// stageCloud is the file the script keeps the normals stage in
+const stageCloud = "normals.bin"
+
+// keepStages is how many normals caches are kept per file, e.g. for
+// comparing two KNN values
+const keepStages = 2
+
+// hasStageCache reports whether an earlier run left a cloud with normals
+// in stageDir
+func hasStageCache(stageDir string) bool {
+	if stageDir == "" {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(stageDir, stageCloud))
+	return err == nil && info.Size() > 0
+}
+
+// stageDir returns the normals cache directory for a file. Its key is a
+// hash of the input file's identity (path, size and modification time)
+// and of the values of the parameters the normals depend on, so a cache
+// is reused only when nothing upstream has changed.
+func (p *Processor) stageDir(file string, schema []ParamSpec) (string, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return "", err
+	}
+
+	var upstream []string
+	for _, spec := range schema {
+		if spec.Stage == StageNormals {
+			upstream = append(upstream, spec.Name+"="+p.params.Values[spec.Name])
+		}
+	}
+	sort.Strings(upstream)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\n%d\n", info.Size(), info.ModTime().UnixNano())
+	for _, value := range upstream {
+		fmt.Fprintln(h, value)
+	}
+	// Normals from a multi-threaded run can differ in the last bits, so
+	// deterministic runs keep their own
+	if p.params.Deterministic {
+		fmt.Fprintln(h, "deterministic")
+	}
+	return workspace.StageDir(file, hex.EncodeToString(h.Sum(nil))[:16])
+}
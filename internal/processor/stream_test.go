@@ -0,0 +1,217 @@
+package processor
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// The output streams in testdata/streams are synthetic fixtures, written
+// after the output of process_las_files.py and of the CloudComPy and
+// Python failures it passes on, with some lines it would never print to
+// try the parser. NAME.stdout and NAME.stderr are played by the helper
+// process as the script, and what the processor made of them, the log
+// entries of the file and its result, is compared with NAME.golden. After
+// a deliberate change to the parsing, write the golden files again with
+//
+//	go test ./internal/processor -run TestOutputStreams -update
+//
+// and review their diff.
+
+var updateGolden = flag.Bool("update", false, "write the golden files of the output stream tests")
+
+// streamDir holds the stream fixtures and their golden files
+const streamDir = "testdata/streams"
+
+// playStream copies the streams named in the environment to stdout and
+// stderr, and exits with the code given
+func playStream() {
+	for env, out := range map[string]io.Writer{"CCAUTO_TEST_STDOUT": os.Stdout, "CCAUTO_TEST_STDERR": os.Stderr} {
+		if path := os.Getenv(env); path != "" {
+			f, err := os.Open(path)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
+			}
+			io.Copy(out, f)
+			f.Close()
+		}
+	}
+	code, _ := strconv.Atoi(os.Getenv("CCAUTO_TEST_EXIT"))
+	os.Exit(code)
+}
+
+// streamCommand runs TestHelperProcess playing the stream files stdout and
+// stderr, either of which may be empty, and exiting with code
+func streamCommand(stdout, stderr string, code int) func(string, []string) *exec.Cmd {
+	return func(file string, args []string) *exec.Cmd {
+		cmd := helperCommand("stream")(file, args)
+		cmd.Env = append(cmd.Env,
+			"CCAUTO_TEST_STDOUT="+stdout,
+			"CCAUTO_TEST_STDERR="+stderr,
+			"CCAUTO_TEST_EXIT="+strconv.Itoa(code))
+		return cmd
+	}
+}
+
+// hugeLine is the length of the line the huge_line stream is made around,
+// over the longest line kept
+const hugeLine = 2 * maxLineLength
+
+// writeHugeLine writes the huge_line stream, a run that logs a line too
+// long to keep in the repository, and returns its path
+func writeHugeLine(t *testing.T) string {
+	t.Helper()
+	var b bytes.Buffer
+	b.WriteString("[INFO] Processing: scan1.las\n")
+	b.WriteString("[SUCCESS] Loaded 4,096 points\n")
+	b.WriteString("[WARNING] Invalid classification values: ")
+	for b.Len() < hugeLine {
+		b.WriteString("65535,")
+	}
+	b.WriteString("65535\n")
+	b.WriteString("[SUCCESS] Mesh created with 8,190 faces\n")
+	b.WriteString("[SUCCESS] Successfully processed: scan1.las\n")
+	path := filepath.Join(t.TempDir(), "huge_line.stdout")
+	if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOutputStreams(t *testing.T) {
+	tests := []struct {
+		name    string
+		stdout  bool // NAME.stdout is played
+		stderr  bool // NAME.stderr is played
+		code    int
+		outcome Outcome
+	}{
+		{name: "success", stdout: true, outcome: OutcomeSuccess},
+		{name: "failure", stdout: true, code: 1, outcome: OutcomeFailed},
+		{name: "warnings", stdout: true, outcome: OutcomeWarning},
+		{name: "unicode", stdout: true, outcome: OutcomeWarning},
+		{name: "crash", stderr: true, code: 1, outcome: OutcomeFailed},
+		{name: "huge_line", outcome: OutcomeWarning},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr string
+			if tt.stdout {
+				stdout = streamPath(t, tt.name+".stdout")
+			}
+			if tt.stderr {
+				stderr = streamPath(t, tt.name+".stderr")
+			}
+			if tt.name == "huge_line" {
+				stdout = writeHugeLine(t)
+			}
+
+			p := newTestProcessor(t, 1, Hooks{Command: streamCommand(stdout, stderr, tt.code)})
+			if err := p.Start(); err != nil {
+				t.Fatal(err)
+			}
+			entries, result := collect(t, p, nil)
+			if len(result.Files) != 1 {
+				t.Fatalf("%d file results, want 1", len(result.Files))
+			}
+			if got := result.Files[0].Outcome(); got != tt.outcome {
+				t.Errorf("outcome %s, want %s", got, tt.outcome)
+			}
+
+			got := renderStream(entries, result.Files[0], p.params.InputDir)
+			golden := filepath.Join(streamDir, tt.name+".golden")
+			if *updateGolden {
+				if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run with -update to write it)", err)
+			}
+			if got != string(want) {
+				t.Errorf("output of %s differs from %s:\n%s", tt.name, golden, diffLines(string(want), got))
+			}
+		})
+	}
+}
+
+// streamPath returns the absolute path of a stream fixture, as the
+// script runs in a directory of its own
+func streamPath(t *testing.T, name string) string {
+	t.Helper()
+	path, err := filepath.Abs(filepath.Join(streamDir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// renderStream writes out the log entries of the file and its result,
+// with the input directory as $DIR and lines too long to compare by eye
+// cut short
+func renderStream(entries []LogEntry, f FileResult, dir string) string {
+	var b strings.Builder
+	clean := func(s string) string {
+		s = strings.ReplaceAll(s, dir, "$DIR")
+		if r := []rune(s); len(r) > 240 {
+			s = fmt.Sprintf("%s … %s (%d bytes)", string(r[:160]), string(r[len(r)-40:]), len(s))
+		}
+		return s
+	}
+	for _, e := range entries {
+		if e.FileIndex == 0 || e.Transfer != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "%-7s %s\n", e.Level, clean(e.Message))
+	}
+
+	fmt.Fprintf(&b, "\noutcome: %s\n", f.Outcome())
+	if f.Error != "" {
+		fmt.Fprintf(&b, "error: %s\n", clean(f.Error))
+	}
+	if f.Crashed {
+		b.WriteString("crashed\n")
+	}
+	for _, w := range f.Warnings {
+		fmt.Fprintf(&b, "warning: %s\n", clean(w))
+	}
+	if f.Points > 0 {
+		fmt.Fprintf(&b, "points: %d\n", f.Points)
+	}
+	if f.Faces > 0 {
+		fmt.Fprintf(&b, "faces: %d\n", f.Faces)
+	}
+	if f.Quality != nil {
+		fmt.Fprintf(&b, "quality: %s\n", f.Quality)
+	}
+	return b.String()
+}
+
+// diffLines lists the lines of want and got that differ, by line number
+func diffLines(want, got string) string {
+	w, g := strings.Split(want, "\n"), strings.Split(got, "\n")
+	var b strings.Builder
+	for i := 0; i < len(w) || i < len(g); i++ {
+		var wl, gl string
+		if i < len(w) {
+			wl = w[i]
+		}
+		if i < len(g) {
+			gl = g[i]
+		}
+		if wl != gl {
+			fmt.Fprintf(&b, "%d:\n  want %q\n  got  %q\n", i+1, wl, gl)
+		}
+	}
+	return b.String()
+}
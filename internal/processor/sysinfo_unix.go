@@ -0,0 +1,14 @@
+//go:build !windows
+
+package processor
+
+import "golang.org/x/sys/unix"
+
+// diskFree returns the bytes available to this user on the disk of path
+func diskFree(path string) (uint64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
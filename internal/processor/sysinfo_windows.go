@@ -0,0 +1,46 @@
+//go:build windows
+
+package processor
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// diskFree returns the bytes available to this user on the disk of path
+func diskFree(path string) (uint64, error) {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var free, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(name, &free, &total, &totalFree); err != nil {
+		return 0, err
+	}
+	return free, nil
+}
+
+// memoryStatus is MEMORYSTATUSEX, which the windows package doesn't define
+type memoryStatus struct {
+	Length               uint32
+	MemoryLoad           uint32
+	TotalPhys            uint64
+	AvailPhys            uint64
+	TotalPageFile        uint64
+	AvailPageFile        uint64
+	TotalVirtual         uint64
+	AvailVirtual         uint64
+	AvailExtendedVirtual uint64
+}
+
+var globalMemoryStatusEx = windows.NewLazySystemDLL("kernel32.dll").NewProc("GlobalMemoryStatusEx")
+
+// totalMemory returns the installed physical memory in bytes
+func totalMemory() (uint64, error) {
+	status := memoryStatus{Length: uint32(unsafe.Sizeof(memoryStatus{}))}
+	if ok, _, err := globalMemoryStatusEx.Call(uintptr(unsafe.Pointer(&status))); ok == 0 {
+		return 0, err
+	}
+	return status.TotalPhys, nil
+}
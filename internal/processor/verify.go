@@ -0,0 +1,162 @@
+package processor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cloudcompare-automation/internal/report"
+)
+
+// binMagic starts every CloudCompare BIN (v2) file
+const binMagic = "CCB"
+
+// facesPattern matches the script's mesh size lines, e.g.
+// "Mesh created with 1,234 faces" and "Mesh trimmed to 1,000 faces"
+var facesPattern = regexp.MustCompile(`^Mesh (?:created with|trimmed to) ([\d,]+) faces`)
+
+// pointsPattern matches the script's cloud size line, e.g.
+// "Loaded 1,234,567 points"
+var pointsPattern = regexp.MustCompile(`^Loaded ([\d,]+) points`)
+
+// qualityPrefix starts the script's mesh quality line, e.g. "Mesh
+// quality: watertight=no boundary_edges=120 non_manifold_edges=0
+// degenerate_triangles=3 bbox_min=0.000,0.000,0.000 bbox_max=12.5,8,3.2",
+// ending in "c2m_mean=0.0123 c2m_p95=0.0456" when the deviation was
+// measured
+const qualityPrefix = "Mesh quality: "
+
+// ParseMeshQuality returns the metrics of a mesh quality log line. Fields
+// it doesn't know are ignored, so the script can report more.
+func ParseMeshQuality(message string) (report.MeshQuality, bool) {
+	var q report.MeshQuality
+	fields, ok := strings.CutPrefix(message, qualityPrefix)
+	if !ok {
+		return q, false
+	}
+	found := false
+	for _, field := range strings.Fields(fields) {
+		key, value, _ := strings.Cut(field, "=")
+		var err error
+		switch key {
+		case "watertight":
+			q.Watertight = value == "yes"
+		case "boundary_edges":
+			q.BoundaryEdges, err = strconv.Atoi(value)
+		case "non_manifold_edges":
+			q.NonManifoldEdges, err = strconv.Atoi(value)
+		case "degenerate_triangles":
+			q.DegenerateTriangles, err = strconv.Atoi(value)
+		case "bbox_min":
+			q.BBoxMin, err = parseVector(value)
+		case "bbox_max":
+			q.BBoxMax, err = parseVector(value)
+		case "c2m_mean", "c2m_p95":
+			if q.Deviation == nil {
+				q.Deviation = &report.Deviation{}
+			}
+			if key == "c2m_mean" {
+				q.Deviation.Mean, err = strconv.ParseFloat(value, 64)
+			} else {
+				q.Deviation.P95, err = strconv.ParseFloat(value, 64)
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return report.MeshQuality{}, false
+		}
+		found = true
+	}
+	return q, found
+}
+
+// parseVector reads x,y,z
+func parseVector(value string) ([3]float64, error) {
+	var v [3]float64
+	parts := strings.Split(value, ",")
+	if len(parts) != 3 {
+		return v, fmt.Errorf("expected x,y,z")
+	}
+	for i, part := range parts {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return v, err
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+// ParseFaces returns the face count from a mesh size log line
+func ParseFaces(message string) (int, bool) {
+	m := facesPattern.FindStringSubmatch(message)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.ReplaceAll(m[1], ",", ""))
+	return n, err == nil
+}
+
+// ParsePoints returns the point count from a cloud size log line, for
+// the report and statistics
+func ParsePoints(message string) (int64, bool) {
+	m := pointsPattern.FindStringSubmatch(message)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(strings.ReplaceAll(m[1], ",", ""), 10, 64)
+	return n, err == nil
+}
+
+// checkProject checks that the script left a plausible project at path:
+// present, non-empty and a CloudCompare BIN file
+func checkProject(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("output missing")
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		return fmt.Errorf("output is empty")
+	}
+
+	magic := make([]byte, len(binMagic))
+	if _, err := io.ReadFull(f, magic); err != nil || string(magic) != binMagic {
+		return fmt.Errorf("output is not a CloudCompare BIN file")
+	}
+	return nil
+}
+
+// partialPath is where the script saves a project before it is verified
+// and renamed to path. It is a hidden file in the same directory, so the
+// rename is atomic and a half-written project never has the final name.
+func partialPath(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), ".bin")
+	return filepath.Join(filepath.Dir(path), "."+name+".partial.bin")
+}
+
+// promoteOutput verifies the project the script saved at partial and
+// renames it to path. A project without faces is still promoted, with a
+// warning; faces is negative when the script didn't report it.
+func promoteOutput(partial, path string, faces int) (warnings []string, err error) {
+	if err := checkProject(partial); err != nil {
+		return nil, err
+	}
+	if faces == 0 {
+		warnings = append(warnings, "mesh has no faces")
+	}
+	if err := os.Rename(partial, path); err != nil {
+		return warnings, fmt.Errorf("failed to move output into place: %v", err)
+	}
+	return warnings, nil
+}
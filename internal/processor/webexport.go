@@ -0,0 +1,161 @@
+package processor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/cloudcompare-automation/internal/tiles"
+)
+
+// Web export formats for viewing processed clouds in a browser
+const (
+	WebNone    = "none"
+	WebPotree  = "potree"
+	Web3DTiles = "3dtiles"
+)
+
+// WebExportFormats are the accepted WebExport values
+var WebExportFormats = []string{WebNone, WebPotree, Web3DTiles}
+
+// progressPattern matches the progress lines of a long step, e.g.
+// "[Progress 40%] 3D Tiles"
+var progressPattern = regexp.MustCompile(`^\[Progress (\d+)%\]`)
+
+// potreePercent matches PotreeConverter's progress, e.g. "[ 34%, 00:00:05]"
+var potreePercent = regexp.MustCompile(`\[\s*(\d+)%`)
+
+// CheckWebExport reports an unknown web export format
+func CheckWebExport(format string) error {
+	return checkChoice("web export", format, WebExportFormats)
+}
+
+// stepProgress returns a function logging the progress of a long step on
+// file, e.g. "[Progress 40%] 3D Tiles of scan1.las"; every 5% is enough
+// to move the progress bar
+func (p *Processor) stepProgress(step, file string) func(percent int) {
+	name := filepath.Base(file)
+	last := -1
+	return func(percent int) {
+		if percent/5 > last/5 {
+			last = percent
+			p.sendLog(LogInfo, fmt.Sprintf("[Progress %d%%] %s of %s", percent, step, name))
+		}
+	}
+}
+
+// ParseProgress returns the percentage a progress log line reports
+func ParseProgress(message string) (int, bool) {
+	m := progressPattern.FindStringSubmatch(message)
+	if m == nil {
+		return 0, false
+	}
+	var percent int
+	fmt.Sscan(m[1], &percent)
+	return percent, true
+}
+
+func (params Params) webExportEnabled() bool {
+	return params.WebExport != "" && params.WebExport != WebNone
+}
+
+// webExportStep names the web export step in the pipeline view
+func webExportStep(format string) string {
+	if format == WebPotree {
+		return "Web export (Potree)"
+	}
+	return "Web export (3D Tiles)"
+}
+
+// webExport converts the input cloud of a processed file for browser
+// viewing, into a directory next to its project, e.g. scan1_3dtiles. The
+// processing doesn't move the points, so the input is converted. The
+// export is built under a temporary name and replaces an earlier one only
+// when complete.
+func (p *Processor) webExport(f *FileResult) error {
+	dir := strings.TrimSuffix(f.OutputFile, ".bin") + "_" + p.params.WebExport
+	tmp := filepath.Join(filepath.Dir(dir), "."+filepath.Base(dir)+".partial")
+	os.RemoveAll(tmp)
+	defer os.RemoveAll(tmp)
+
+	progress := p.stepProgress(webExportStep(p.params.WebExport), f.InputFile)
+
+	var err error
+	if p.params.WebExport == WebPotree {
+		err = p.runPotree(f.InputFile, tmp, progress)
+	} else {
+		err = tiles.Write(f.InputFile, tmp, tiles.Options{
+			Origin:   p.params.TilesOrigin,
+			Progress: func(done float64) { progress(int(done * 100)) },
+			Stopped:  p.isStopped,
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if p.isStopped() {
+		return fmt.Errorf("stopped")
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to replace the earlier export: %v", err)
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return fmt.Errorf("failed to move export into place: %v", err)
+	}
+	f.WebExport = dir
+	p.sendLog(LogInfo, fmt.Sprintf("Web export: %s", filepath.Base(dir)))
+	return nil
+}
+
+// runPotree runs PotreeConverter on input, reporting its progress. Its
+// last lines are kept for the error when it fails.
+func (p *Processor) runPotree(input, dir string, progress func(int)) error {
+	converter := p.params.PotreeConverter
+	if converter == "" {
+		converter = "PotreeConverter"
+	}
+	cmd := exec.Command(converter, input, "-o", dir)
+	prepareCmd(cmd)
+	output, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stderr = cmd.Stdout
+
+	if err := p.start(cmd); err != nil {
+		if errors.Is(err, errStopped) {
+			return nil
+		}
+		return fmt.Errorf("failed to start PotreeConverter: %v", err)
+	}
+	var tail []string
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if m := potreePercent.FindStringSubmatch(line); m != nil {
+			var percent int
+			fmt.Sscan(m[1], &percent)
+			progress(percent)
+			continue
+		}
+		if line != "" {
+			tail = append(tail, line)
+			if len(tail) > 3 {
+				tail = tail[1:]
+			}
+		}
+	}
+	if err := p.wait(cmd); err != nil {
+		if len(tail) > 0 {
+			return fmt.Errorf("PotreeConverter %v: %s", err, strings.Join(tail, " | "))
+		}
+		return fmt.Errorf("PotreeConverter %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package profile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// On a shared lab machine every student runs as the same account, so the
+// form, the last run, the history and the settings would be one state
+// they keep overwriting for each other. A profile keeps that state in a
+// directory of its own; without one the shared state is used as before.
+// The configuration file of a profile is applied over the shared one, so
+// a lab can set up CloudComPy once and each student keep their own
+// preferences.
+
+// EnvVar names the active profile. Set exports it, so the background
+// sessions and workers a run starts use the same profile.
+const EnvVar = "CCAUTO_PROFILE"
+
+// MaxName is the longest profile name accepted
+const MaxName = 64
+
+// Check reports a name that can't be used as a profile's directory
+func Check(name string) error {
+	if name == "" || len(name) > MaxName {
+		return fmt.Errorf("profile name must be 1 to %d characters", MaxName)
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		case r == '.' && name[0] != '.':
+		default:
+			return fmt.Errorf("profile name %q may only hold letters, digits, '-', '_' and '.', and not start with '.'", name)
+		}
+	}
+	return nil
+}
+
+// Set makes name the active profile; "" selects the shared state
+func Set(name string) error {
+	if name == "" {
+		return os.Unsetenv(EnvVar)
+	}
+	if err := Check(name); err != nil {
+		return err
+	}
+	return os.Setenv(EnvVar, name)
+}
+
+// Active returns the name of the active profile, "" for none
+func Active() string {
+	return os.Getenv(EnvVar)
+}
+
+// Base returns the directory of the shared state and settings
+func Base() (string, error) {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "cloudcompare-automation"), nil
+}
+
+// Dir returns the directory of the active profile's state, Base when
+// there is none
+func Dir() (string, error) {
+	if Active() == "" {
+		return Base()
+	}
+	return DirOf(Active())
+}
+
+// DirOf returns the directory of the profile name
+func DirOf(name string) (string, error) {
+	if err := Check(name); err != nil {
+		return "", err
+	}
+	base, err := Base()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "profiles", name), nil
+}
+
+// Create makes the directory of the profile name
+func Create(name string) error {
+	dir, err := DirOf(name)
+	if err != nil {
+		return err
+	}
+	return os.MkdirAll(dir, 0o755)
+}
+
+// List returns the names of the profiles on this machine, sorted
+func List() ([]string, error) {
+	base, err := Base()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(base, "profiles"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && Check(entry.Name()) == nil {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
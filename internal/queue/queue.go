@@ -0,0 +1,392 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// StateDirName is the directory inside the shared input directory that
+// holds lease and completion markers
+const StateDirName = ".ccqueue"
+
+// DefaultLeaseTTL is how long a lease may go unrefreshed before another
+// worker considers its holder dead and reclaims the file
+const DefaultLeaseTTL = 10 * time.Minute
+
+// LeaseInfo is the content of a lease or completion marker file
+type LeaseInfo struct {
+	Worker    string    `json:"worker"`
+	Host      string    `json:"host"`
+	PID       int       `json:"pid"`
+	ClaimedAt time.Time `json:"claimed_at"`
+	Success   bool      `json:"success,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+}
+
+// Queue coordinates several workers processing the files of one shared
+// directory. Each file is claimed by atomically creating a lease file,
+// so no two workers process the same file.
+type Queue struct {
+	Dir      string
+	StateDir string
+	WorkerID string
+	TTL      time.Duration
+}
+
+// Open prepares the queue state directory inside dir
+func Open(dir, workerID string, ttl time.Duration) (*Queue, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if ttl <= 0 {
+		ttl = DefaultLeaseTTL
+	}
+
+	stateDir := filepath.Join(absDir, StateDirName)
+	if err := os.MkdirAll(stateDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create queue state directory: %v", err)
+	}
+
+	return &Queue{
+		Dir:      absDir,
+		StateDir: stateDir,
+		WorkerID: workerID,
+		TTL:      ttl,
+	}, nil
+}
+
+// DefaultWorkerID identifies this process as host-pid
+func DefaultWorkerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+func (q *Queue) leasePath(file string) string {
+	return filepath.Join(q.StateDir, filepath.Base(file)+".lease")
+}
+
+func (q *Queue) donePath(file string) string {
+	return filepath.Join(q.StateDir, filepath.Base(file)+".done")
+}
+
+func (q *Queue) failedPath(file string) string {
+	return filepath.Join(q.StateDir, filepath.Base(file)+".failed")
+}
+
+func (q *Queue) cancelledPath(file string) string {
+	return filepath.Join(q.StateDir, filepath.Base(file)+".cancelled")
+}
+
+// IsFinished reports whether any worker has completed the file, or it was
+// cancelled
+func (q *Queue) IsFinished(file string) bool {
+	return fileExists(q.donePath(file)) || fileExists(q.failedPath(file)) || fileExists(q.cancelledPath(file))
+}
+
+// Claim leases the highest-priority unfinished, unclaimed file from files.
+// It returns nil without error when every file is finished or leased, or
+// the queue is paused.
+func (q *Queue) Claim(files []string) (*Lease, error) {
+	if q.Paused() {
+		return nil, nil
+	}
+	for _, file := range q.Order(files) {
+		if q.IsFinished(file) {
+			continue
+		}
+
+		path := q.leasePath(file)
+		q.reclaim(path)
+
+		lease, err := q.tryLease(file, path)
+		if err != nil {
+			return nil, err
+		}
+		if lease != nil {
+			return lease, nil
+		}
+	}
+	return nil, nil
+}
+
+// tryLease creates the lease file exclusively; losing the race is not an error
+func (q *Queue) tryLease(file, path string) (*Lease, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to create lease: %v", err)
+	}
+
+	host, _ := os.Hostname()
+	info := LeaseInfo{
+		Worker:    q.WorkerID,
+		Host:      host,
+		PID:       os.Getpid(),
+		ClaimedAt: time.Now(),
+	}
+	err = json.NewEncoder(f).Encode(info)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to write lease: %v", err)
+	}
+
+	// Another worker may have finished the file between our check and the lease
+	if q.IsFinished(file) {
+		os.Remove(path)
+		return nil, nil
+	}
+
+	lease := &Lease{
+		File:  file,
+		Info:  info,
+		queue: q,
+		path:  path,
+		stop:  make(chan struct{}),
+	}
+	go lease.refresh()
+	return lease, nil
+}
+
+// isStale reports whether a lease has not been refreshed within the TTL
+func (q *Queue) isStale(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return time.Since(info.ModTime()) > q.TTL
+}
+
+// readLease reads the content of a lease file
+func readLease(path string) (LeaseInfo, error) {
+	var info LeaseInfo
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, &info)
+	}
+	return info, err
+}
+
+// reclaim removes a stale lease. Renaming is atomic, so when several
+// workers race to reclaim the same lease only one of them moves it aside;
+// but another may have reclaimed it and leased the file again since it
+// was seen stale, so the lease moved aside is checked again and put back
+// unless it is still the stale one.
+func (q *Queue) reclaim(path string) {
+	if !q.isStale(path) {
+		return
+	}
+	stale, err := readLease(path)
+	if err != nil {
+		return
+	}
+	aside := fmt.Sprintf("%s.stale-%s", path, q.WorkerID)
+	if err := os.Rename(path, aside); err != nil {
+		return
+	}
+	if info, err := readLease(aside); err == nil && q.isStale(aside) &&
+		info.Worker == stale.Worker && info.ClaimedAt.Equal(stale.ClaimedAt) {
+		os.Remove(aside)
+		return
+	}
+	// A fresh lease goes back, unless yet another worker leased the file
+	// in the meantime; link fails rather than replacing its lease
+	err = os.Link(aside, path)
+	if err != nil && !errors.Is(err, os.ErrExist) && !fileExists(path) {
+		// Hard links aren't supported everywhere
+		os.Rename(aside, path)
+	}
+	os.Remove(aside)
+}
+
+// State is the queue state of a single file
+type State string
+
+const (
+	StatePending   State = "pending"
+	StateLeased    State = "leased"
+	StateDone      State = "done"
+	StateFailed    State = "failed"
+	StateCancelled State = "cancelled"
+)
+
+// State returns the current queue state of a file
+func (q *Queue) State(file string) State {
+	switch {
+	case fileExists(q.donePath(file)):
+		return StateDone
+	case fileExists(q.failedPath(file)):
+		return StateFailed
+	case fileExists(q.leasePath(file)) && !q.isStale(q.leasePath(file)):
+		return StateLeased
+	case fileExists(q.cancelledPath(file)):
+		return StateCancelled
+	default:
+		return StatePending
+	}
+}
+
+// Status counts the files in each queue state
+func (q *Queue) Status(files []string) map[State]int {
+	counts := make(map[State]int)
+	for _, file := range files {
+		counts[q.State(file)]++
+	}
+	return counts
+}
+
+// Cancel keeps workers from claiming a file. A file being processed is
+// finished; one already finished is left alone, and false is returned.
+func (q *Queue) Cancel(file string) (bool, error) {
+	if fileExists(q.donePath(file)) || fileExists(q.failedPath(file)) {
+		return false, nil
+	}
+	host, _ := os.Hostname()
+	data, err := json.Marshal(LeaseInfo{Worker: q.WorkerID, Host: host, PID: os.Getpid(), ClaimedAt: time.Now()})
+	if err != nil {
+		return false, err
+	}
+	if err := os.WriteFile(q.cancelledPath(file), data, 0o644); err != nil {
+		return false, fmt.Errorf("failed to write cancellation marker: %v", err)
+	}
+	return true, nil
+}
+
+// Requeue returns a cancelled or failed file to the queue, reporting
+// whether it was either
+func (q *Queue) Requeue(file string) (bool, error) {
+	requeued := false
+	for _, path := range []string{q.cancelledPath(file), q.failedPath(file)} {
+		err := os.Remove(path)
+		if err == nil {
+			requeued = true
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return requeued, err
+		}
+	}
+	return requeued, nil
+}
+
+// pausedFile names the file in the state directory that pauses the queue
+const pausedFile = "paused"
+
+// Paused reports whether the queue is paused: workers claim no new files
+// until it is resumed
+func (q *Queue) Paused() bool {
+	return fileExists(filepath.Join(q.StateDir, pausedFile))
+}
+
+// SetPaused pauses or resumes the queue for all its workers
+func (q *Queue) SetPaused(paused bool) error {
+	path := filepath.Join(q.StateDir, pausedFile)
+	if !paused {
+		err := os.Remove(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	return os.WriteFile(path, []byte(time.Now().Format(time.RFC3339)+"\n"), 0o644)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// ErrLeaseLost is returned when a lease was reclaimed by another worker,
+// after this one left it unrefreshed for longer than the TTL
+var ErrLeaseLost = errors.New("lease was reclaimed by another worker")
+
+// Lease is a claim on a single file held by this worker
+type Lease struct {
+	File string
+	Info LeaseInfo
+
+	queue *Queue
+	path  string
+	stop  chan struct{}
+	once  sync.Once
+}
+
+// owned reports whether the lease file is still this lease, and not the
+// lease of a worker that reclaimed the file
+func (l *Lease) owned() bool {
+	info, err := readLease(l.path)
+	return err == nil && info.Worker == l.Info.Worker && info.ClaimedAt.Equal(l.Info.ClaimedAt)
+}
+
+// remove deletes the lease file if it is still this lease
+func (l *Lease) remove() error {
+	if !l.owned() {
+		return fmt.Errorf("%s: %w", filepath.Base(l.File), ErrLeaseLost)
+	}
+	return os.Remove(l.path)
+}
+
+// refresh touches the lease periodically so other workers see it as alive,
+// until it is dropped or found reclaimed
+func (l *Lease) refresh() {
+	ticker := time.NewTicker(l.queue.TTL / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			if !l.owned() {
+				return
+			}
+			now := time.Now()
+			os.Chtimes(l.path, now, now)
+		}
+	}
+}
+
+// Complete records the outcome for the file and drops the lease
+func (l *Lease) Complete(success bool, detail string) error {
+	l.stopRefresh()
+
+	info := l.Info
+	info.Success = success
+	info.Detail = detail
+
+	marker := l.queue.failedPath(l.File)
+	if success {
+		marker = l.queue.donePath(l.File)
+	}
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(marker, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write completion marker: %v", err)
+	}
+	return l.remove()
+}
+
+// Release drops the lease without recording an outcome, returning the file
+// to the queue (used when a worker is cancelled mid-file)
+func (l *Lease) Release() error {
+	l.stopRefresh()
+	return l.remove()
+}
+
+func (l *Lease) stopRefresh() {
+	l.once.Do(func() { close(l.stop) })
+}
@@ -0,0 +1,177 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Window is a daily time range during which workers may start new files.
+// A window may wrap midnight ("19:00-07:00"); the zero Window is always open.
+type Window struct {
+	Start time.Duration // Offset from midnight
+	End   time.Duration // Offset from midnight
+}
+
+// ParseWindow parses a "HH:MM-HH:MM" range; an empty string means always open
+func ParseWindow(s string) (Window, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Window{}, nil
+	}
+
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return Window{}, fmt.Errorf("invalid window %q: expected HH:MM-HH:MM", s)
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid window %q: %v", s, err)
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return Window{}, fmt.Errorf("invalid window %q: %v", s, err)
+	}
+	if start == end {
+		return Window{}, fmt.Errorf("invalid window %q: start equals end", s)
+	}
+	return Window{Start: start, End: end}, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("bad time %q", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// IsAlways reports whether the window places no restriction
+func (w Window) IsAlways() bool {
+	return w.Start == w.End
+}
+
+// Contains reports whether t falls inside the window
+func (w Window) Contains(t time.Time) bool {
+	if w.IsAlways() {
+		return true
+	}
+	offset := sinceMidnight(t)
+	if w.Start < w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	// Wraps midnight
+	return offset >= w.Start || offset < w.End
+}
+
+// NextStart returns t if the window is open, otherwise when it next opens
+func (w Window) NextStart(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+	// By the clock, as a day with a DST change is longer or shorter
+	hour, minute := int(w.Start/time.Hour), int(w.Start%time.Hour/time.Minute)
+	start := time.Date(t.Year(), t.Month(), t.Day(), hour, minute, 0, 0, t.Location())
+	if !start.After(t) {
+		start = time.Date(t.Year(), t.Month(), t.Day()+1, hour, minute, 0, 0, t.Location())
+	}
+	return start
+}
+
+// String formats the window as "HH:MM-HH:MM"
+func (w Window) String() string {
+	if w.IsAlways() {
+		return "always"
+	}
+	return formatClock(w.Start) + "-" + formatClock(w.End)
+}
+
+func formatClock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour +
+		time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second
+}
+
+// windowFile names the file in the state directory holding the queue's
+// processing window, shared by all its workers
+const windowFile = "window"
+
+// Window returns the processing window stored with the queue; without one
+// the queue is always open
+func (q *Queue) Window() (Window, error) {
+	data, err := os.ReadFile(filepath.Join(q.StateDir, windowFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Window{}, nil
+		}
+		return Window{}, err
+	}
+	return ParseWindow(string(data))
+}
+
+// SetWindow stores the processing window for all workers of the queue;
+// an always-open window removes it
+func (q *Queue) SetWindow(w Window) error {
+	path := filepath.Join(q.StateDir, windowFile)
+	if w.IsAlways() {
+		err := os.Remove(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	return os.WriteFile(path, []byte(w.String()+"\n"), 0o644)
+}
+
+func (q *Queue) priorityPath(file string) string {
+	return filepath.Join(q.StateDir, filepath.Base(file)+".priority")
+}
+
+// Priority returns the priority assigned to a file (default 0)
+func (q *Queue) Priority(file string) int {
+	data, err := os.ReadFile(q.priorityPath(file))
+	if err != nil {
+		return 0
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// SetPriority assigns a priority to a file; higher priorities are claimed first
+func (q *Queue) SetPriority(file string, priority int) error {
+	if priority == 0 {
+		err := os.Remove(q.priorityPath(file))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	return os.WriteFile(q.priorityPath(file), []byte(strconv.Itoa(priority)+"\n"), 0o644)
+}
+
+// Order sorts files by descending priority, keeping name order within a priority
+func (q *Queue) Order(files []string) []string {
+	ordered := make([]string, len(files))
+	copy(ordered, files)
+
+	priorities := make(map[string]int, len(files))
+	for _, file := range files {
+		priorities[file] = q.Priority(file)
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return priorities[ordered[i]] > priorities[ordered[j]]
+	})
+	return ordered
+}
@@ -0,0 +1,73 @@
+package queue
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DoneSuffix marks a file as completely copied: scan.las is ready as soon
+// as scan.las.done (or scan.done) exists, without waiting for it to settle
+const DoneSuffix = ".done"
+
+// Settler holds back files that may still be being copied into the queue
+// directory. A file is ready once its size has not changed for Period, or
+// once its .done marker exists. Copy tools often preserve the source's
+// modification time, so the size is watched across polls instead.
+type Settler struct {
+	Period time.Duration // 0 means every file is ready at once
+
+	seen map[string]observation
+}
+
+// observation is a file's size and when it was first seen at that size
+type observation struct {
+	size  int64
+	since time.Time
+}
+
+// NewSettler creates a Settler that waits for files to be stable for period
+func NewSettler(period time.Duration) *Settler {
+	return &Settler{Period: period, seen: make(map[string]observation)}
+}
+
+// Ready returns the files that can be claimed and the ones still settling
+func (s *Settler) Ready(files []string, now time.Time) (ready, settling []string) {
+	if s.Period <= 0 {
+		return files, nil
+	}
+
+	for _, file := range files {
+		if hasDoneMarker(file) {
+			ready = append(ready, file)
+			continue
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		obs, ok := s.seen[file]
+		if !ok || obs.size != info.Size() {
+			obs = observation{size: info.Size(), since: now}
+			s.seen[file] = obs
+		}
+		if now.Sub(obs.since) >= s.Period {
+			ready = append(ready, file)
+		} else {
+			settling = append(settling, file)
+		}
+	}
+	return ready, settling
+}
+
+// hasDoneMarker reports whether file has a scan.las.done or scan.done marker
+func hasDoneMarker(file string) bool {
+	for _, marker := range []string{file + DoneSuffix, strings.TrimSuffix(file, filepath.Ext(file)) + DoneSuffix} {
+		if _, err := os.Stat(marker); err == nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ChecksumsPath returns the checksum file of the report at path, which
+// lists the SHA-256 of the run's outputs in the format of sha256sum, so a
+// client can check a delivery with sha256sum -c
+func ChecksumsPath(path string) string {
+	return strings.TrimSuffix(path, ".json") + ".sha256"
+}
+
+// WriteChecksums writes the checksum file of the report at path, with the
+// outputs named relative to its output directory, and returns how many
+// files it lists. Outputs no longer there, such as those packed into an
+// archive, are left out.
+func WriteChecksums(path string) (int, error) {
+	r, err := Load(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var files []string
+	for _, f := range r.Files {
+		files = append(files, f.Output, f.Mesh, f.Archive)
+		files = append(files, f.Snapshots...)
+		if f.Web != "" {
+			filepath.WalkDir(f.Web, func(path string, d fs.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					files = append(files, path)
+				}
+				return nil
+			})
+		}
+	}
+
+	var lines []string
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		sum, err := FileSHA256(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		name := file
+		if rel, err := filepath.Rel(r.OutputDir, file); err == nil {
+			name = rel
+		}
+		lines = append(lines, sum+"  "+filepath.ToSlash(name))
+	}
+
+	data := strings.Join(lines, "\n")
+	if len(lines) > 0 {
+		data += "\n"
+	}
+	if err := os.WriteFile(ChecksumsPath(path), []byte(data), 0o644); err != nil {
+		return 0, fmt.Errorf("failed to write checksums: %v", err)
+	}
+	return len(lines), nil
+}
+
+// FileSHA256 returns the SHA-256 of the file at path, hex encoded
+func FileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,228 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/cloudcompare-automation/internal/humanize"
+)
+
+// A comparison sets two runs of the same files side by side, processed
+// with different parameters, e.g. octree depths 11 and 12, to decide which
+// to deliver. Each run keeps its outputs and report; the comparison only
+// refers to them.
+
+// Comparison is an A/B comparison of two runs
+type Comparison struct {
+	CreatedAt   time.Time      `json:"created_at"`
+	Input       string         `json:"input"`
+	Differences []string       `json:"differences"` // Parameters set differently, e.g. "octree-depth: 11 → 12"
+	A           ComparedRun    `json:"a"`
+	B           ComparedRun    `json:"b"`
+	Files       []ComparedFile `json:"files"`
+}
+
+// ComparedRun is one side of a comparison
+type ComparedRun struct {
+	Report     string            `json:"report"` // Path of the run's JSON report
+	OutputDir  string            `json:"output_dir"`
+	Params     map[string]string `json:"params"`
+	Seconds    float64           `json:"seconds"`
+	CPUSeconds float64           `json:"cpu_seconds"`
+	Succeeded  int               `json:"succeeded"`
+	Warned     int               `json:"warned"`
+	Failed     int               `json:"failed"`
+}
+
+// ComparedFile is a file as each run processed it; a side is nil when its
+// run didn't get to the file
+type ComparedFile struct {
+	Input string          `json:"input"`
+	A     *ComparedOutput `json:"a,omitempty"`
+	B     *ComparedOutput `json:"b,omitempty"`
+}
+
+// ComparedOutput is a file's entry in a run's report, with the size of its
+// project
+type ComparedOutput struct {
+	File
+	Bytes int64 `json:"bytes,omitempty"`
+}
+
+// Compare sets the runs that wrote the reports at pathA and pathB side by
+// side
+func Compare(pathA, pathB string) (Comparison, error) {
+	a, err := Load(pathA)
+	if err != nil {
+		return Comparison{}, err
+	}
+	b, err := Load(pathB)
+	if err != nil {
+		return Comparison{}, err
+	}
+
+	c := Comparison{
+		CreatedAt: time.Now(),
+		Input:     a.Input,
+		A:         comparedRun(a, pathA),
+		B:         comparedRun(b, pathB),
+	}
+
+	keys := make(map[string]bool)
+	for key := range a.Params {
+		keys[key] = true
+	}
+	for key := range b.Params {
+		keys[key] = true
+	}
+	for key := range keys {
+		if a.Params[key] != b.Params[key] {
+			c.Differences = append(c.Differences, fmt.Sprintf("%s: %s → %s", key, orNone(a.Params[key]), orNone(b.Params[key])))
+		}
+	}
+	sort.Strings(c.Differences)
+
+	// Files in the order of run A, then any only B got to
+	index := make(map[string]int)
+	for _, f := range a.Files {
+		index[f.Input] = len(c.Files)
+		c.Files = append(c.Files, ComparedFile{Input: f.Input, A: comparedOutput(f)})
+	}
+	for _, f := range b.Files {
+		i, ok := index[f.Input]
+		if !ok {
+			i = len(c.Files)
+			c.Files = append(c.Files, ComparedFile{Input: f.Input})
+		}
+		c.Files[i].B = comparedOutput(f)
+	}
+	return c, nil
+}
+
+// comparedRun summarises the run of report r, saved at path
+func comparedRun(r Report, path string) ComparedRun {
+	return ComparedRun{
+		Report:     path,
+		OutputDir:  r.OutputDir,
+		Params:     r.Params,
+		Seconds:    r.FinishedAt.Sub(r.StartedAt).Seconds(),
+		CPUSeconds: r.CPUSeconds,
+		Succeeded:  r.Succeeded,
+		Warned:     r.Warned,
+		Failed:     r.Failed,
+	}
+}
+
+// comparedOutput returns the entry f with the size of its project, if it
+// is still there
+func comparedOutput(f File) *ComparedOutput {
+	out := &ComparedOutput{File: f}
+	if info, err := os.Stat(f.Output); err == nil && f.Outcome != "failed" {
+		out.Bytes = info.Size()
+	}
+	return out
+}
+
+// orNone shows an unset parameter
+func orNone(value string) string {
+	if value == "" {
+		return "(unset)"
+	}
+	return value
+}
+
+// WriteComparison saves the comparison in dir as JSON and as HTML, and
+// returns the path of the HTML file
+func WriteComparison(c Comparison, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create comparison directory: %v", err)
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("comparison-%s.json", c.CreatedAt.Format("20060102-150405")))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write comparison: %v", err)
+	}
+
+	// The reports are linked relative to the page, like snapshots
+	html := HTMLPath(path)
+	for _, run := range []*ComparedRun{&c.A, &c.B} {
+		report := HTMLPath(run.Report)
+		if rel, err := filepath.Rel(dir, report); err == nil {
+			report = rel
+		}
+		run.Report = filepath.ToSlash(report)
+	}
+	out, err := os.Create(html)
+	if err != nil {
+		return "", fmt.Errorf("failed to write HTML comparison: %v", err)
+	}
+	err = comparisonTemplate.Execute(out, c)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write HTML comparison: %v", err)
+	}
+	return html, nil
+}
+
+// comparisonTemplate lays out a comparison with a row per file and the
+// figures of each run next to each other
+var comparisonTemplate = template.Must(template.New("comparison").Funcs(template.FuncMap{
+	"duration": func(seconds float64) string {
+		return humanize.Duration(time.Duration(seconds * float64(time.Second)))
+	},
+	"count": func(n int) string { return humanize.Int(int64(n)) },
+	"bytes": func(n int64) string { return humanize.Bytes(uint64(n)) },
+	"base":  filepath.Base,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>A/B comparison – {{.CreatedAt.Format "2006-01-02 15:04"}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; }
+td, th { padding: 0.2em 1em 0.2em 0; text-align: left; vertical-align: top; }
+.files td, .files th { border-top: 1px solid #ccc; }
+.b { background: #f3f4f6; }
+.success { color: #15803d; } .warning { color: #b45309; } .failed { color: #b91c1c; }
+</style>
+</head>
+<body>
+<h1>A/B comparison</h1>
+<table>
+<tr><th>Input</th><td>{{.Input}}</td></tr>
+{{- range .Differences}}
+<tr><th>Changed</th><td>{{.}}</td></tr>
+{{- end}}
+{{- with .A}}
+<tr><th>A</th><td><a href="{{.Report}}">{{.OutputDir}}</a>: {{.Succeeded}} succeeded, {{.Warned}} with warnings, {{.Failed}} failed in {{duration .Seconds}}, {{duration .CPUSeconds}} CPU</td></tr>
+{{- end}}
+{{- with .B}}
+<tr><th>B</th><td><a href="{{.Report}}">{{.OutputDir}}</a>: {{.Succeeded}} succeeded, {{.Warned}} with warnings, {{.Failed}} failed in {{duration .Seconds}}, {{duration .CPUSeconds}} CPU</td></tr>
+{{- end}}
+</table>
+<h2>Files</h2>
+<table class="files">
+<tr><th>File</th><th>Run</th><th>Outcome</th><th>Duration</th><th>Faces</th><th>Project</th><th>Mesh</th><th>Note</th></tr>
+{{- range .Files}}
+<tr><td rowspan="2">{{base .Input}}</td><td>A</td>{{template "side" .A}}</tr>
+<tr class="b"><td>B</td>{{template "side" .B}}</tr>
+{{- end}}
+</table>
+</body>
+</html>
+{{define "side"}}
+{{- if .}}<td class="{{.Outcome}}">{{.Outcome}}</td><td>{{duration .Seconds}}</td><td>{{if .Faces}}{{count .Faces}}{{end}}</td><td>{{if .Bytes}}{{bytes .Bytes}}{{end}}</td><td>{{with .Quality}}{{.}}{{end}}</td><td>{{if .Error}}{{.Error}}{{else}}{{range .Warnings}}{{.}} {{end}}{{end}}</td>
+{{- else}}<td colspan="6">not processed</td>{{end}}
+{{- end}}
+`))
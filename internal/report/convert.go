@@ -0,0 +1,213 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudcompare-automation/internal/humanize"
+)
+
+// Formats a report can be converted to, by name, with their file name
+// extensions
+var Formats = map[string]string{
+	"html": ".html",
+	"csv":  ".csv",
+	"md":   ".md",
+}
+
+// Regenerate writes the HTML version of the report at path again, e.g.
+// after the layout changed, and returns its path
+func Regenerate(path string) (string, error) {
+	r, err := Load(path)
+	if err != nil {
+		return "", err
+	}
+	return HTMLPath(path), writeHTML(r, path)
+}
+
+// Convert saves the report at path in another format to out
+func Convert(path, format, out string) error {
+	r, err := Load(path)
+	if err != nil {
+		return err
+	}
+	if _, ok := Formats[format]; !ok {
+		return fmt.Errorf("unknown report format: %s", format)
+	}
+	if format == "html" {
+		return saveHTML(r, out)
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return fmt.Errorf("failed to write report: %v", err)
+	}
+	if format == "csv" {
+		err = writeCSV(f, r)
+	} else {
+		err = writeMarkdown(f, r)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write report: %v", err)
+	}
+	return nil
+}
+
+// writeCSV writes a row per file, for spreadsheets, with a column for
+// each key of the metadata looked up for the files
+func writeCSV(w io.Writer, r Report) error {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, f := range r.Files {
+		for key := range f.Metadata {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	out := csv.NewWriter(w)
+	out.Write(append([]string{"input", "output", "outcome", "seconds", "cpu_seconds", "points", "watertight", "boundary_edges", "non_manifold_edges", "degenerate_triangles", "bbox_min", "bbox_max", "c2m_mean", "c2m_p95", "error", "warnings", "note", "reviewed_by", "reviewed_at"}, keys...))
+	for _, f := range r.Files {
+		var review Review
+		if f.Review != nil {
+			review = *f.Review
+		}
+		reviewedBy, reviewedAt := "", ""
+		if review.Reviewed {
+			reviewedBy = review.ReviewedBy
+			if review.ReviewedAt != nil {
+				reviewedAt = review.ReviewedAt.Format(time.RFC3339)
+			}
+		}
+		row := []string{
+			f.Input,
+			f.Output,
+			f.Outcome,
+			strconv.FormatFloat(f.Seconds, 'f', 1, 64),
+			strconv.FormatFloat(f.CPUSeconds, 'f', 1, 64),
+			strconv.FormatInt(f.Points, 10),
+		}
+		row = append(row, qualityColumns(f.Quality)...)
+		row = append(row,
+			f.Error,
+			strings.Join(f.Warnings, "; "),
+			review.Note,
+			reviewedBy,
+			reviewedAt,
+		)
+		for _, key := range keys {
+			row = append(row, f.Metadata[key])
+		}
+		out.Write(row)
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// qualityColumns returns the mesh quality columns of the CSV, empty when
+// the script didn't measure it
+func qualityColumns(q *MeshQuality) []string {
+	if q == nil {
+		return make([]string, 8)
+	}
+	vector := func(v [3]float64) string {
+		return fmt.Sprintf("%g %g %g", v[0], v[1], v[2])
+	}
+	mean, p95 := "", ""
+	if q.Deviation != nil {
+		mean = strconv.FormatFloat(q.Deviation.Mean, 'g', -1, 64)
+		p95 = strconv.FormatFloat(q.Deviation.P95, 'g', -1, 64)
+	}
+	return []string{
+		strconv.FormatBool(q.Watertight),
+		strconv.Itoa(q.BoundaryEdges),
+		strconv.Itoa(q.NonManifoldEdges),
+		strconv.Itoa(q.DegenerateTriangles),
+		vector(q.BBoxMin),
+		vector(q.BBoxMax),
+		mean,
+		p95,
+	}
+}
+
+// writeMarkdown writes the run and a table of its files, for tickets and
+// wikis
+func writeMarkdown(w io.Writer, r Report) error {
+	duration := func(seconds float64) string {
+		return humanize.Duration(time.Duration(seconds * float64(time.Second)))
+	}
+	cell := func(s string) string {
+		return strings.ReplaceAll(strings.ReplaceAll(s, "|", `\|`), "\n", " ")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", filepath.Base(r.Pipeline))
+	fmt.Fprintf(&b, "| | |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Input | %s |\n", cell(r.Input))
+	fmt.Fprintf(&b, "| Started | %s |\n", r.StartedAt.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "| Finished | %s |\n", r.FinishedAt.Format("2006-01-02 15:04:05"))
+	files := fmt.Sprintf("%d: %d succeeded, %d with warnings, %d failed", r.Total, r.Succeeded, r.Warned, r.Failed)
+	if r.Stopped {
+		files += ", stopped"
+	}
+	if r.Aborted != "" {
+		files += ", aborted: " + r.Aborted
+	}
+	fmt.Fprintf(&b, "| Files | %s |\n", cell(files))
+	if len(r.Labels) > 0 {
+		fmt.Fprintf(&b, "| Labels | %s |\n", cell(strings.Join(r.Labels, ", ")))
+	}
+	for _, values := range []map[string]string{r.Metadata, r.Params} {
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(&b, "| %s | %s |\n", cell(key), cell(values[key]))
+		}
+	}
+
+	fmt.Fprintf(&b, "\n| File | Outcome | Duration | Points | Mesh | Note | Review |\n|---|---|---|---|---|---|---|\n")
+	for _, f := range r.Files {
+		points := ""
+		if f.Points > 0 {
+			points = humanize.Count(f.Points)
+		}
+		mesh := ""
+		if f.Quality != nil {
+			mesh = f.Quality.String()
+		}
+		note := f.Error
+		if note == "" {
+			note = strings.Join(f.Warnings, "; ")
+		}
+		var review []string
+		if f.Review != nil && f.Review.Reviewed {
+			signoff := "Reviewed"
+			if f.Review.ReviewedBy != "" {
+				signoff += " by " + f.Review.ReviewedBy
+			}
+			review = append(review, signoff)
+		}
+		if f.Review != nil && f.Review.Note != "" {
+			review = append(review, f.Review.Note)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %s |\n", cell(filepath.Base(f.Input)), f.Outcome, duration(f.Seconds), points, cell(mesh), cell(note), cell(strings.Join(review, ": ")))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
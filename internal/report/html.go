@@ -0,0 +1,135 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cloudcompare-automation/internal/humanize"
+)
+
+// htmlTemplate lays out a report for reading in a browser, with the
+// snapshots of every file for a quick visual check
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"duration": func(seconds float64) string {
+		return humanize.Duration(time.Duration(seconds * float64(time.Second)))
+	},
+	"count": humanize.Count,
+	"base":  filepath.Base,
+	"join":  strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Pipeline}} – {{.StartedAt.Format "2006-01-02 15:04"}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; }
+td, th { padding: 0.2em 1em 0.2em 0; text-align: left; vertical-align: top; }
+.file { border-top: 1px solid #ccc; padding: 1em 0; }
+.success { color: #15803d; } .warning { color: #b45309; } .failed { color: #b91c1c; }
+.note { font-style: italic; } .reviewed { color: #1d4ed8; }
+.snapshots img { max-width: 32%; margin-right: 1%; border: 1px solid #ccc; }
+</style>
+</head>
+<body>
+<h1>{{.Pipeline}}</h1>
+<table>
+<tr><th>Input</th><td>{{.Input}}</td></tr>
+<tr><th>Started</th><td>{{.StartedAt.Format "2006-01-02 15:04:05"}}</td></tr>
+<tr><th>Finished</th><td>{{.FinishedAt.Format "2006-01-02 15:04:05"}}</td></tr>
+{{- if .Deterministic}}
+<tr><th>Threads</th><td>1, deterministic</td></tr>
+{{- else if .Threads}}
+<tr><th>Threads</th><td>{{.Threads}}</td></tr>
+{{- end}}
+<tr><th>Files</th><td>{{.Total}}: {{.Succeeded}} succeeded, {{.Warned}} with warnings, {{.Failed}} failed{{if .Stopped}}, stopped{{end}}{{if .Aborted}}, aborted: {{.Aborted}}{{end}}</td></tr>
+{{- if .Labels}}
+<tr><th>Labels</th><td>{{join .Labels ", "}}</td></tr>
+{{- end}}
+{{- range $key, $value := .Metadata}}
+<tr><th>{{$key}}</th><td>{{$value}}</td></tr>
+{{- end}}
+{{- range $key, $value := .Params}}
+<tr><th>{{$key}}</th><td>{{$value}}</td></tr>
+{{- end}}
+</table>
+{{range .Files}}
+<div class="file">
+<h2>{{base .Input}} <span class="{{.Outcome}}">{{.Outcome}}</span></h2>
+<p>{{base .Output}}{{with .Archive}} in {{base .}}{{end}}, {{duration .Seconds}}{{if .Points}}, {{count .Points}} points{{end}}</p>
+{{- with .Metadata}}
+<p>{{range $key, $value := .}}{{$key}}: {{$value}}. {{end}}</p>
+{{- end}}
+{{- with .Quality}}
+<p>Mesh: {{.}}</p>
+{{- end}}
+{{- if .Error}}
+<p class="failed">{{.Error}}</p>
+{{- end}}
+{{- range .Warnings}}
+<p class="warning">{{.}}</p>
+{{- end}}
+{{- with .Review}}
+{{- if .Note}}
+<p class="note">Note: {{.Note}}</p>
+{{- end}}
+{{- if .Reviewed}}
+<p class="reviewed">Reviewed{{if .ReviewedBy}} by {{.ReviewedBy}}{{end}}{{with .ReviewedAt}} on {{.Format "2006-01-02 15:04"}}{{end}}</p>
+{{- end}}
+{{- end}}
+{{- if .Snapshots}}
+<div class="snapshots">
+{{- range .Snapshots}}
+<a href="{{.}}"><img src="{{.}}" alt="{{base .}}"></a>
+{{- end}}
+</div>
+{{- end}}
+</div>
+{{end}}
+</body>
+</html>
+`))
+
+// HTMLPath returns the path of the HTML version of the report at path
+func HTMLPath(path string) string {
+	return strings.TrimSuffix(path, ".json") + ".html"
+}
+
+// writeHTML saves the report as HTML next to its JSON file at path
+func writeHTML(r Report, path string) error {
+	return saveHTML(r, HTMLPath(path))
+}
+
+// saveHTML saves the report as HTML at path. Snapshots are linked
+// relative to it, so the output directory can be moved as a whole.
+func saveHTML(r Report, path string) error {
+	files := make([]File, len(r.Files))
+	for i, f := range r.Files {
+		f.Snapshots = nil
+		for _, snapshot := range r.Files[i].Snapshots {
+			if rel, err := filepath.Rel(filepath.Dir(path), snapshot); err == nil {
+				snapshot = rel
+			}
+			f.Snapshots = append(f.Snapshots, filepath.ToSlash(snapshot))
+		}
+		files[i] = f
+	}
+	r.Files = files
+
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to write HTML report: %v", err)
+	}
+	err = htmlTemplate.Execute(out, r)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write HTML report: %v", err)
+	}
+	return nil
+}
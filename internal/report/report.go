@@ -0,0 +1,220 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Report records one processing run next to its outputs, so a delivery
+// can be traced back to the parameters and people that produced it
+type Report struct {
+	Pipeline   string            `json:"pipeline"`
+	Input      string            `json:"input"` // Input directory, or the file for single-file runs
+	OutputDir  string            `json:"output_dir"`
+	Params     map[string]string `json:"params"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	Labels     []string          `json:"labels,omitempty"` // e.g. delivery-v2, experiment-depth13
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+	Total      int               `json:"total"`
+	Succeeded  int               `json:"succeeded"` // Without warnings
+	Warned     int               `json:"warned"`    // Completed with warnings
+	Failed     int               `json:"failed"`
+	Stopped    bool              `json:"stopped,omitempty"`
+	Aborted    string            `json:"aborted,omitempty"`
+	CPUSeconds float64           `json:"cpu_seconds"` // CPU time of the scripts, including the processes they started
+	Estimate   *Estimate         `json:"estimate,omitempty"`
+	Files      []File            `json:"files"`
+
+	// Threads is the cap on the script's worker threads, 0 for none;
+	// deterministic runs are single-threaded to be reproducible
+	Threads       int  `json:"threads,omitempty"`
+	Deterministic bool `json:"deterministic,omitempty"`
+}
+
+// Estimate is the cost and energy of a run, estimated from the configured
+// rates
+type Estimate struct {
+	CPUHours  float64 `json:"cpu_hours"`
+	Cost      float64 `json:"cost,omitempty"` // CPU hours at the rate per CPU hour
+	Currency  string  `json:"currency,omitempty"`
+	EnergyKWh float64 `json:"energy_kwh,omitempty"` // Processing time at the machine's power draw
+}
+
+// File is the outcome of one input file
+type File struct {
+	Input      string            `json:"input"`
+	Output     string            `json:"output"`
+	Outcome    string            `json:"outcome"` // success, warning or failed
+	Error      string            `json:"error,omitempty"`
+	Warnings   []string          `json:"warnings,omitempty"`
+	Seconds    float64           `json:"seconds"`
+	CPUSeconds float64           `json:"cpu_seconds"`
+	Points     int64             `json:"points,omitempty"` // Points in the input cloud
+	Faces      int               `json:"faces,omitempty"`  // Faces of the mesh
+	Artifacts  []string          `json:"artifacts,omitempty"`
+	Mesh       string            `json:"mesh,omitempty"`      // Exported mesh, if any
+	Snapshots  []string          `json:"snapshots,omitempty"` // Rendered images of the mesh
+	Web        string            `json:"web,omitempty"`       // Potree or 3D Tiles export directory, if any
+	Archive    string            `json:"archive,omitempty"`   // Archive the outputs were packed into, if any
+	Metadata   map[string]string `json:"metadata,omitempty"`  // Looked up for the file, over the run's
+	PostSteps  []Step            `json:"post_process,omitempty"`
+	Review     *Review           `json:"review,omitempty"`
+	Quality    *MeshQuality      `json:"mesh_quality,omitempty"` // As measured by the script
+}
+
+// MeshQuality is what the script measured of a file's mesh, for
+// automated acceptance of deliverables
+type MeshQuality struct {
+	Watertight          bool       `json:"watertight"`
+	BoundaryEdges       int        `json:"boundary_edges"`     // Edges of a single triangle
+	NonManifoldEdges    int        `json:"non_manifold_edges"` // Edges of more than two triangles
+	DegenerateTriangles int        `json:"degenerate_triangles"`
+	BBoxMin             [3]float64 `json:"bbox_min"`
+	BBoxMax             [3]float64 `json:"bbox_max"`
+	Deviation           *Deviation `json:"deviation,omitempty"` // If measured, see Acceptance
+}
+
+// Deviation is how far the points of the cloud are from its mesh
+type Deviation struct {
+	Mean float64 `json:"mean"`
+	P95  float64 `json:"p95"` // 95% of the points are closer than this
+}
+
+// Size returns the extent of the bounding box
+func (q MeshQuality) Size() [3]float64 {
+	return [3]float64{q.BBoxMax[0] - q.BBoxMin[0], q.BBoxMax[1] - q.BBoxMin[1], q.BBoxMax[2] - q.BBoxMin[2]}
+}
+
+// String summarises the quality, e.g. "open, 120 boundary edges, 3
+// degenerate triangles, 12.5 × 8.0 × 3.2"
+func (q MeshQuality) String() string {
+	parts := []string{"watertight"}
+	if !q.Watertight {
+		parts[0] = "open"
+	}
+	if q.BoundaryEdges > 0 {
+		parts = append(parts, fmt.Sprintf("%d boundary edges", q.BoundaryEdges))
+	}
+	if q.NonManifoldEdges > 0 {
+		parts = append(parts, fmt.Sprintf("%d non-manifold edges", q.NonManifoldEdges))
+	}
+	if q.DegenerateTriangles > 0 {
+		parts = append(parts, fmt.Sprintf("%d degenerate triangles", q.DegenerateTriangles))
+	}
+	size := q.Size()
+	parts = append(parts, fmt.Sprintf("%.1f × %.1f × %.1f", size[0], size[1], size[2]))
+	if q.Deviation != nil {
+		parts = append(parts, fmt.Sprintf("95%% of points within %.3g", q.Deviation.P95))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Review is what an operator noted about a file's outputs after the run,
+// and whether they signed them off, for QA of deliverables
+type Review struct {
+	Note       string     `json:"note,omitempty"`
+	Reviewed   bool       `json:"reviewed,omitempty"`
+	ReviewedBy string     `json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+}
+
+// IsZero reports whether the review holds neither a note nor a sign-off
+func (r Review) IsZero() bool {
+	return r.Note == "" && !r.Reviewed
+}
+
+// Step is a post-processing command run on a file's outputs
+type Step struct {
+	Name    string  `json:"name"`
+	Command string  `json:"command,omitempty"` // As run, with the paths filled in
+	Error   string  `json:"error,omitempty"`
+	Seconds float64 `json:"seconds"`
+}
+
+// Dir is the subdirectory of the output directory reports are written to
+const Dir = "reports"
+
+// Write saves the report in the reports directory under its output
+// directory, as JSON and as HTML, and returns the path of the JSON file
+func Write(r Report) (string, error) {
+	dir := filepath.Join(r.OutputDir, Dir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create report directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	// The pid keeps reports of workers sharing an output directory apart
+	name := fmt.Sprintf("report-%s-%d.json", r.StartedAt.Format("20060102-150405.000"), os.Getpid())
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write report: %v", err)
+	}
+	return path, writeHTML(r, path)
+}
+
+// Load reads the report at path
+func Load(path string) (Report, error) {
+	var r Report
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return r, fmt.Errorf("failed to read report: %v", err)
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return r, fmt.Errorf("invalid report %s: %v", path, err)
+	}
+	return r, nil
+}
+
+// SetLabels replaces the labels of the report at path, for runs labelled
+// after they finished
+func SetLabels(path string, labels []string) error {
+	r, err := Load(path)
+	if err != nil {
+		return err
+	}
+
+	r.Labels = labels
+	return save(r, path)
+}
+
+// SetReview replaces the review of the file processed from input in the
+// report at path; a zero review removes it
+func SetReview(path, input string, review Review) error {
+	r, err := Load(path)
+	if err != nil {
+		return err
+	}
+
+	for i := range r.Files {
+		if r.Files[i].Input != input {
+			continue
+		}
+		r.Files[i].Review = nil
+		if !review.IsZero() {
+			r.Files[i].Review = &review
+		}
+		return save(r, path)
+	}
+	return fmt.Errorf("%s is not in report %s", input, path)
+}
+
+// save writes a changed report over its JSON and HTML files
+func save(r Report, path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %v", err)
+	}
+	return writeHTML(r, path)
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package session
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detach starts the process in its own session so closing the terminal
+// (SIGHUP to the foreground process group) does not stop it
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
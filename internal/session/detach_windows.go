@@ -0,0 +1,20 @@
+//go:build windows
+
+package session
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// DETACHED_PROCESS is not exported by the syscall package
+const detachedProcess = 0x00000008
+
+// detach starts the process without a console and outside our process
+// group, so closing the terminal window does not stop it
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | detachedProcess,
+		HideWindow:    true,
+	}
+}
@@ -0,0 +1,459 @@
+package session
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cloudcompare-automation/internal/processor"
+)
+
+// A session is a batch running in a detached background process. The
+// process appends every log entry and the final result to a journal, so
+// the TUI can exit (or be killed with its terminal) and a fresh instance
+// can attach later and replay the journal to continue displaying progress.
+
+const (
+	stateFile   = "state.json"
+	journalFile = "journal.jsonl"
+	cancelFile  = "cancel"
+	outputFile  = "session.log"
+
+	// heartbeatInterval is how often a running session touches its state file
+	heartbeatInterval = 5 * time.Second
+	// staleAfter is how long without a heartbeat before a session is presumed dead
+	staleAfter = 30 * time.Second
+)
+
+// State describes a background session
+type State struct {
+	ID         string           `json:"id"`
+	PID        int              `json:"pid"`
+	Params     processor.Params `json:"params"`
+	FilesTotal int              `json:"files_total"`
+	StartedAt  time.Time        `json:"started_at"`
+	Finished   bool             `json:"finished"`
+
+	// Dir is the session directory (not stored)
+	Dir string `json:"-"`
+}
+
+// event is a single journal line
+type event struct {
+	Type    string                      `json:"type"` // "log" or "result"
+	Time    time.Time                   `json:"time"`
+	Level   processor.LogLevel          `json:"level,omitempty"`
+	Message string                      `json:"message,omitempty"`
+	Result  *processor.ProcessingResult `json:"result,omitempty"`
+
+	// Context of log events, see processor.LogEntry
+	Batch     string            `json:"batch,omitempty"`
+	FileIndex int               `json:"file_index,omitempty"`
+	File      string            `json:"file,omitempty"`
+	Outcome   processor.Outcome `json:"outcome,omitempty"`
+	Silent    time.Duration     `json:"silent,omitempty"`
+
+	Transfer *processor.Transfer `json:"transfer,omitempty"`
+}
+
+// logEvent journals a log entry
+func logEvent(entry processor.LogEntry) event {
+	return event{Type: "log", Time: time.Now(), Level: entry.Level, Message: entry.Message,
+		Batch: entry.Batch, FileIndex: entry.FileIndex, File: entry.File, Outcome: entry.Outcome, Silent: entry.Silent,
+		Transfer: entry.Transfer}
+}
+
+// Root returns the directory holding all session directories
+func Root() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "cloudcompare-automation", "sessions"), nil
+}
+
+// createDir creates a new session directory under root named id, or id
+// with a counter when a session of this process started in the same second
+func createDir(root, id string) (string, string, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return "", "", err
+	}
+	for n := 1; ; n++ {
+		name := id
+		if n > 1 {
+			name = fmt.Sprintf("%s-%d", id, n)
+		}
+		dir := filepath.Join(root, name)
+		err := os.Mkdir(dir, 0o755)
+		if err == nil {
+			return name, dir, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return "", "", err
+		}
+	}
+}
+
+// Start creates a session for params, launches it in a detached background
+// process and returns a follower attached to its journal
+func Start(params processor.Params, filesTotal int) (*Follower, error) {
+	root, err := Root()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	state := State{
+		Params:     params,
+		FilesTotal: filesTotal,
+		StartedAt:  now,
+	}
+	state.ID, state.Dir, err = createDir(root, fmt.Sprintf("%s-%d", now.Format("20060102-150405"), os.Getpid()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %v", err)
+	}
+	if err := writeState(state); err != nil {
+		return nil, err
+	}
+
+	if err := spawn(state.Dir); err != nil {
+		return nil, err
+	}
+	return Attach(state.Dir)
+}
+
+// spawn re-executes this binary as "session run DIR", detached from the terminal
+func spawn(dir string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate executable: %v", err)
+	}
+
+	out, err := os.Create(filepath.Join(dir, outputFile))
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	cmd := exec.Command(exe, "session", "run", dir)
+	cmd.Dir, _ = os.Getwd()
+	cmd.Stdout = out
+	cmd.Stderr = out
+	detach(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start background session: %v", err)
+	}
+	// The session outlives us; don't wait for it
+	return cmd.Process.Release()
+}
+
+// Run executes the session in dir. It is the body of the background
+// process started by Start and returns once processing has finished.
+// Cancelling ctx stops processing; the partial result is still journaled.
+func Run(ctx context.Context, dir string) error {
+	state, err := readState(dir)
+	if err != nil {
+		return err
+	}
+	state.PID = os.Getpid()
+	if err := writeState(state); err != nil {
+		return err
+	}
+
+	journal, err := os.OpenFile(filepath.Join(dir, journalFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %v", err)
+	}
+	defer journal.Close()
+	enc := json.NewEncoder(journal)
+
+	proc := processor.New(state.Params)
+	if err := proc.Start(); err != nil {
+		result := processor.ProcessingResult{Completed: true, FailedCount: 1, TotalFiles: state.FilesTotal}
+		enc.Encode(event{Type: "log", Time: time.Now(), Level: processor.LogError, Message: err.Error()})
+		enc.Encode(event{Type: "result", Time: time.Now(), Result: &result})
+		return finish(state)
+	}
+
+	// Heartbeat and cancellation watch
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+		poll := time.NewTicker(500 * time.Millisecond)
+		defer poll.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ctx.Done():
+				proc.Stop()
+				return
+			case <-heartbeat.C:
+				now := time.Now()
+				os.Chtimes(filepath.Join(dir, stateFile), now, now)
+			case <-poll.C:
+				if _, err := os.Stat(filepath.Join(dir, cancelFile)); err == nil {
+					proc.Stop()
+				}
+			}
+		}
+	}()
+
+	// The log channel is closed after the last entry, just before the
+	// result
+	for entry := range proc.LogChan() {
+		enc.Encode(logEvent(entry))
+	}
+	result := <-proc.ResultChan()
+	enc.Encode(event{Type: "result", Time: time.Now(), Result: &result})
+	return finish(state)
+}
+
+func finish(state State) error {
+	state.Finished = true
+	return writeState(state)
+}
+
+// Running returns the sessions that are still alive, newest first
+func Running() ([]State, error) {
+	return list(func(state State) bool { return !state.Finished && isAlive(state.Dir) })
+}
+
+// Ended returns the sessions that finished or died, newest first, whose
+// directories only keep their journal for replay
+func Ended() ([]State, error) {
+	return list(func(state State) bool { return state.Finished || !isAlive(state.Dir) })
+}
+
+// list returns the sessions keep selects, newest first
+func list(keep func(State) bool) ([]State, error) {
+	root, err := Root()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var states []State
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		state, err := readState(filepath.Join(root, entry.Name()))
+		if err != nil || !keep(state) {
+			continue
+		}
+		states = append(states, state)
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].StartedAt.After(states[j].StartedAt)
+	})
+	return states, nil
+}
+
+// isAlive reports whether the session in dir has sent a recent heartbeat
+func isAlive(dir string) bool {
+	info, err := os.Stat(filepath.Join(dir, stateFile))
+	if err != nil {
+		return false
+	}
+	return time.Since(info.ModTime()) < staleAfter
+}
+
+func readState(dir string) (State, error) {
+	var state State
+	data, err := os.ReadFile(filepath.Join(dir, stateFile))
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("invalid session state: %v", err)
+	}
+	state.Dir = dir
+	return state, nil
+}
+
+func writeState(state State) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write then rename so a reader never sees a partial state file
+	tmp := filepath.Join(state.Dir, stateFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write session state: %v", err)
+	}
+	return os.Rename(tmp, filepath.Join(state.Dir, stateFile))
+}
+
+// Follower replays and tails a session journal, exposing it through the
+// same channels as a Processor
+type Follower struct {
+	State State
+
+	logChan    chan processor.LogEntry
+	resultChan chan processor.ProcessingResult
+	done       chan struct{}
+	once       sync.Once
+}
+
+// Attach follows the session in dir from the beginning of its journal
+func Attach(dir string) (*Follower, error) {
+	state, err := readState(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Follower{
+		State:      state,
+		logChan:    make(chan processor.LogEntry, 500),
+		resultChan: make(chan processor.ProcessingResult, 1),
+		done:       make(chan struct{}),
+	}
+	go f.tail()
+	return f, nil
+}
+
+// LogChan returns the channel for receiving log entries
+func (f *Follower) LogChan() <-chan processor.LogEntry {
+	return f.logChan
+}
+
+// ResultChan returns the channel for receiving the final result
+func (f *Follower) ResultChan() <-chan processor.ProcessingResult {
+	return f.resultChan
+}
+
+// Stop asks the background session to cancel processing
+func (f *Follower) Stop() {
+	os.WriteFile(filepath.Join(f.State.Dir, cancelFile), nil, 0o644)
+}
+
+// Detach stops following the journal and leaves the session running
+func (f *Follower) Detach() {
+	f.once.Do(func() { close(f.done) })
+}
+
+func (f *Follower) tail() {
+	path := filepath.Join(f.State.Dir, journalFile)
+
+	// The background process may not have created the journal yet
+	var file *os.File
+	for file == nil {
+		var err error
+		if file, err = os.Open(path); err == nil {
+			break
+		}
+		if !f.wait() {
+			return
+		}
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var partial []byte
+	for {
+		line, err := reader.ReadBytes('\n')
+		partial = append(partial, line...)
+		if err == io.EOF {
+			if !isAlive(f.State.Dir) {
+				f.lost()
+				return
+			}
+			if !f.wait() {
+				return
+			}
+			continue
+		}
+		if err != nil {
+			f.lost()
+			return
+		}
+
+		var ev event
+		if json.Unmarshal(partial, &ev) != nil {
+			partial = partial[:0]
+			continue
+		}
+		partial = partial[:0]
+
+		switch ev.Type {
+		case "log":
+			select {
+			case f.logChan <- processor.LogEntry{Level: ev.Level, Message: ev.Message, Batch: ev.Batch, FileIndex: ev.FileIndex, File: ev.File, Outcome: ev.Outcome, Silent: ev.Silent, Transfer: ev.Transfer}:
+			case <-f.done:
+				return
+			}
+		case "result":
+			if ev.Result != nil {
+				f.resultChan <- *ev.Result
+			}
+			return
+		}
+	}
+}
+
+// Result reads the final result of a finished session from its journal;
+// path is the journal or the session directory
+func Result(path string) (processor.ProcessingResult, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, journalFile)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return processor.ProcessingResult{}, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<24)
+	for scanner.Scan() {
+		var ev event
+		if json.Unmarshal(scanner.Bytes(), &ev) == nil && ev.Type == "result" && ev.Result != nil {
+			return *ev.Result, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return processor.ProcessingResult{}, err
+	}
+	return processor.ProcessingResult{}, fmt.Errorf("%s has no result; the session hasn't finished", path)
+}
+
+// wait pauses between journal polls; it returns false once detached
+func (f *Follower) wait() bool {
+	select {
+	case <-f.done:
+		return false
+	case <-time.After(200 * time.Millisecond):
+		return true
+	}
+}
+
+// lost reports a session that died without writing a result
+func (f *Follower) lost() {
+	select {
+	case f.logChan <- processor.LogEntry{Level: processor.LogError, Message: "Background session stopped unexpectedly"}:
+	case <-f.done:
+		return
+	}
+	f.resultChan <- processor.ProcessingResult{Completed: true, FailedCount: 1, TotalFiles: f.State.FilesTotal}
+}
@@ -0,0 +1,328 @@
+// Package tiles writes LAS point clouds as Cesium 3D Tiles (point cloud
+// tiles with a tileset.json), for viewing surveys in a browser.
+package tiles
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudcompare-automation/internal/las"
+)
+
+// maxTilePoints is the number of points in one tile; a node with more
+// keeps a sample of this size and splits the rest among four children
+const maxTilePoints = 50000
+
+// maxLevel stops splitting nodes of coincident points
+const maxLevel = 20
+
+// Origin places the survey on the globe: the center of the cloud is put at
+// this WGS84 position, with X east, Y north and Z up. Without it the tiles
+// are in the survey's own coordinates, relative to the center of the cloud.
+type Origin struct {
+	Latitude  float64 `yaml:"latitude" json:"latitude"`
+	Longitude float64 `yaml:"longitude" json:"longitude"`
+	Height    float64 `yaml:"height,omitempty" json:"height,omitempty"`
+}
+
+// Options configure Write
+type Options struct {
+	Origin *Origin
+
+	// Progress is called with the share of the work done, 0-1
+	Progress func(done float64)
+
+	// Stopped is checked between tiles; Write gives up when it returns true
+	Stopped func() bool
+}
+
+// ErrStopped is returned by Write when Options.Stopped asked it to stop
+var ErrStopped = errors.New("stopped")
+
+// point is a point relative to the center of the cloud; float32 keeps
+// millimeters over kilometers and halves the memory of large clouds
+type point struct {
+	pos   [3]float32
+	color [3]uint16
+}
+
+// box is an axis-aligned bounding box
+type box struct {
+	min, max [3]float64
+}
+
+// tile is a node of tileset.json
+type tile struct {
+	BoundingVolume map[string][]float64 `json:"boundingVolume"`
+	GeometricError float64              `json:"geometricError"`
+	Refine         string               `json:"refine,omitempty"`
+	Transform      []float64            `json:"transform,omitempty"`
+	Content        map[string]string    `json:"content,omitempty"`
+	Children       []*tile              `json:"children,omitempty"`
+}
+
+// writer builds the tiles of one cloud
+type writer struct {
+	dir        string
+	colorShift int // Turns the LAS colors into 8 bits
+	total      int
+	written    int
+	progress   func(float64)
+	stopped    func() bool
+}
+
+// Write converts the LAS file input into a tileset in dir, which must not
+// exist yet
+func Write(input, dir string, opts Options) error {
+	progress := opts.Progress
+	if progress == nil {
+		progress = func(float64) {}
+	}
+
+	h, err := las.ReadHeader(input)
+	if err != nil {
+		return err
+	}
+	if h.PointCount == 0 {
+		return fmt.Errorf("%s has no points", filepath.Base(input))
+	}
+	center := [3]float64{(h.MinX + h.MaxX) / 2, (h.MinY + h.MaxY) / 2, (h.MinZ + h.MaxZ) / 2}
+
+	// Reading is the first half of the work
+	points := make([]point, 0, h.PointCount)
+	var maxColor uint16
+	_, err = las.ReadPoints(input, 1, func(p las.Point) {
+		points = append(points, point{
+			pos:   [3]float32{float32(p.X - center[0]), float32(p.Y - center[1]), float32(p.Z - center[2])},
+			color: [3]uint16{p.R, p.G, p.B},
+		})
+		maxColor = max16(maxColor, p.R, p.G, p.B)
+		if len(points)%1000000 == 0 {
+			progress(0.5 * float64(len(points)) / float64(h.PointCount))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "tiles"), 0o755); err != nil {
+		return err
+	}
+	w := &writer{dir: dir, total: len(points), progress: func(done float64) { progress(0.5 + 0.5*done) }, stopped: opts.Stopped}
+	if w.stopped == nil {
+		w.stopped = func() bool { return false }
+	}
+	// Colors are 16-bit by the standard, but some writers store 8-bit values
+	if maxColor > 255 {
+		w.colorShift = 8
+	}
+	root, err := w.build(points, "r", 0)
+	if err != nil {
+		return err
+	}
+	if opts.Origin != nil {
+		root.Transform = enuToECEF(*opts.Origin)
+	}
+
+	tileset := map[string]any{
+		"asset":          map[string]string{"version": "1.0", "generator": "cloudcompare-automation"},
+		"geometricError": 2 * root.GeometricError,
+		"root":           root,
+	}
+	data, err := json.MarshalIndent(tileset, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "tileset.json"), data, 0o644)
+}
+
+// build writes the tile of a node holding points and its children. With
+// additive refinement each point is stored once: the node keeps an even
+// sample and its children the rest.
+func (w *writer) build(points []point, id string, level int) (*tile, error) {
+	if w.stopped() {
+		return nil, ErrStopped
+	}
+	bounds := boundsOf(points)
+	t := &tile{
+		BoundingVolume: map[string][]float64{"box": bounds.orientedBox()},
+		Refine:         "ADD",
+		Content:        map[string]string{"uri": "tiles/" + id + ".pnts"},
+	}
+
+	content := points
+	var rest []point
+	if len(points) > maxTilePoints && level < maxLevel {
+		// Move every k-th point to the front as the sample
+		k := len(points) / maxTilePoints
+		n := 0
+		for i := 0; i < len(points) && n < maxTilePoints; i += k {
+			points[n], points[i] = points[i], points[n]
+			n++
+		}
+		content, rest = points[:n], points[n:]
+
+		// The error of showing only the sample is about its point spacing
+		area := math.Max((bounds.max[0]-bounds.min[0])*(bounds.max[1]-bounds.min[1]), 1e-6)
+		t.GeometricError = math.Sqrt(area / float64(n))
+	}
+
+	if err := w.writePnts(filepath.Join(w.dir, "tiles", id+".pnts"), content); err != nil {
+		return nil, err
+	}
+	w.written += len(content)
+	w.progress(float64(w.written) / float64(w.total))
+
+	for i, quadrant := range splitQuadrants(rest, bounds) {
+		if len(quadrant) == 0 {
+			continue
+		}
+		child, err := w.build(quadrant, fmt.Sprintf("%s%d", id, i), level+1)
+		if err != nil {
+			return nil, err
+		}
+		t.Children = append(t.Children, child)
+	}
+	return t, nil
+}
+
+// splitQuadrants partitions points in place by the horizontal center of
+// bounds
+func splitQuadrants(points []point, bounds box) [4][]point {
+	cx := float32((bounds.min[0] + bounds.max[0]) / 2)
+	cy := float32((bounds.min[1] + bounds.max[1]) / 2)
+	west, east := partition(points, func(p point) bool { return p.pos[0] < cx })
+	sw, nw := partition(west, func(p point) bool { return p.pos[1] < cy })
+	se, ne := partition(east, func(p point) bool { return p.pos[1] < cy })
+	return [4][]point{sw, se, nw, ne}
+}
+
+// partition moves the points matching in to the front
+func partition(points []point, in func(point) bool) (matching, others []point) {
+	n := 0
+	for i := range points {
+		if in(points[i]) {
+			points[n], points[i] = points[i], points[n]
+			n++
+		}
+	}
+	return points[:n], points[n:]
+}
+
+func boundsOf(points []point) box {
+	b := box{min: [3]float64{math.Inf(1), math.Inf(1), math.Inf(1)}, max: [3]float64{math.Inf(-1), math.Inf(-1), math.Inf(-1)}}
+	for _, p := range points {
+		for i, v := range p.pos {
+			b.min[i] = math.Min(b.min[i], float64(v))
+			b.max[i] = math.Max(b.max[i], float64(v))
+		}
+	}
+	return b
+}
+
+// orientedBox returns the box as a 3D Tiles box: its center followed by
+// the half-length axes
+func (b box) orientedBox() []float64 {
+	var c, h [3]float64
+	for i := range c {
+		c[i] = (b.min[i] + b.max[i]) / 2
+		// A flat or single-point tile still needs some volume
+		h[i] = math.Max((b.max[i]-b.min[i])/2, 0.001)
+	}
+	return []float64{c[0], c[1], c[2], h[0], 0, 0, 0, h[1], 0, 0, 0, h[2]}
+}
+
+// writePnts writes points as a point cloud tile, with positions relative
+// to the tile's center (RTC_CENTER) and 8-bit RGB colors
+func (w *writer) writePnts(path string, points []point) error {
+	b := boundsOf(points)
+	var center [3]float64
+	for i := range center {
+		center[i] = (b.min[i] + b.max[i]) / 2
+	}
+
+	n := len(points)
+	feature, err := json.Marshal(map[string]any{
+		"POINTS_LENGTH": n,
+		"RTC_CENTER":    center,
+		"POSITION":      map[string]int{"byteOffset": 0},
+		"RGB":           map[string]int{"byteOffset": 12 * n},
+	})
+	if err != nil {
+		return err
+	}
+	// The binary body must start at a multiple of 8 bytes
+	for (28+len(feature))%8 != 0 {
+		feature = append(feature, ' ')
+	}
+
+	body := make([]byte, 0, 15*n+8)
+	le := binary.LittleEndian
+	for _, p := range points {
+		for i, v := range p.pos {
+			body = le.AppendUint32(body, math.Float32bits(v-float32(center[i])))
+		}
+	}
+	for _, p := range points {
+		for _, c := range p.color {
+			body = append(body, uint8(c>>w.colorShift))
+		}
+	}
+	for len(body)%8 != 0 {
+		body = append(body, 0)
+	}
+
+	header := make([]byte, 0, 28)
+	header = append(header, "pnts"...)
+	for _, v := range []int{1, 28 + len(feature) + len(body), len(feature), len(body), 0, 0} {
+		header = le.AppendUint32(header, uint32(v))
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	for _, part := range [][]byte{header, feature, body} {
+		if _, err := f.Write(part); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// enuToECEF returns the column-major transform from a local east-north-up
+// frame at origin to Earth-centered coordinates, on the WGS84 ellipsoid
+func enuToECEF(o Origin) []float64 {
+	const a = 6378137.0
+	const f = 1 / 298.257223563
+	e2 := f * (2 - f)
+	lat, lon := o.Latitude*math.Pi/180, o.Longitude*math.Pi/180
+	sinLat, cosLat := math.Sin(lat), math.Cos(lat)
+	sinLon, cosLon := math.Sin(lon), math.Cos(lon)
+	n := a / math.Sqrt(1-e2*sinLat*sinLat)
+
+	x := (n + o.Height) * cosLat * cosLon
+	y := (n + o.Height) * cosLat * sinLon
+	z := (n*(1-e2) + o.Height) * sinLat
+	return []float64{
+		-sinLon, cosLon, 0, 0,
+		-sinLat * cosLon, -sinLat * sinLon, cosLat, 0,
+		cosLat * cosLon, cosLat * sinLon, sinLat, 0,
+		x, y, z, 1,
+	}
+}
+
+func max16(m uint16, values ...uint16) uint16 {
+	for _, v := range values {
+		if v > m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,146 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudcompare-automation/internal/humanize"
+	"github.com/cloudcompare-automation/internal/processor"
+)
+
+// In accessible mode the TUI runs inline, without colors, animations or
+// decorative glyphs, and instead of redrawing the progress it prints each
+// change of state as a line of its own, for terminal screen readers to
+// read out as it comes.
+
+// plainIcons spell out the outcomes in accessible mode
+var plainIcons = Icons{
+	Success: "OK", Error: "FAILED", Warning: "WARNING", Info: "INFO",
+}
+
+// decorative reports whether r only decorates: borders, blocks, spinners,
+// emoji and the like. Arrows stay, as the key help names keys with them.
+func decorative(r rune) bool {
+	switch {
+	case r >= 0x2500 && r <= 0x25FF: // Box drawing, blocks, geometric shapes
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // Symbols and dingbats
+		return true
+	case r >= 0x2800 && r <= 0x28FF: // Braille spinners
+		return true
+	case r >= 0x23E9 && r <= 0x23FA: // Media controls such as ⏸
+		return true
+	case r >= 0x1F000 && r <= 0x1FAFF: // Emoji
+		return true
+	case r == 0xFE0F || r == 0x200D: // Emoji presentation and joiners
+		return true
+	}
+	return false
+}
+
+// plainView drops the decorative glyphs from a rendered view
+func plainView(view string) string {
+	return mapView(view, func(r rune) rune {
+		if decorative(r) {
+			return -1
+		}
+		return r
+	})
+}
+
+// mapView maps the runes of a rendered view, dropping those mapped to -1
+// and the lines that held nothing else
+func mapView(view string, mapping func(rune) rune) string {
+	var lines []string
+	for _, line := range strings.Split(view, "\n") {
+		mapped := strings.TrimRight(strings.Map(mapping, line), " ")
+		if mapped == "" && strings.TrimSpace(line) != "" {
+			continue
+		}
+		lines = append(lines, mapped)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// announcement returns the line accessible mode prints for a log entry
+// once the model has taken it in, or "" for an entry that doesn't change
+// the state. New files and steps, finished files, warnings and errors do.
+func (m Model) announcement(entry processor.LogEntry) string {
+	message := entry.Message
+	switch {
+	case entry.Outcome != "":
+		return message
+	case entry.Level == processor.LogError:
+		return "Error: " + message
+	case entry.Level == processor.LogWarning:
+		return "Warning: " + message
+	case strings.HasPrefix(message, "Processing:"):
+		return fmt.Sprintf("File %d of %d: %s", m.fileNumber(), m.filesTotal, filepath.Base(m.currentFile))
+	}
+	if n, ok := processor.ParsePostStep(message); ok {
+		return fmt.Sprintf("Post-processing step %d: %s", n, stepText(message))
+	}
+	if n, total, ok := processor.ParseStep(message); ok {
+		return fmt.Sprintf("Step %d of %d: %s", n, total, stepText(message))
+	}
+	return ""
+}
+
+// fileNumber returns the number of the current file in the batch
+func (m Model) fileNumber() int {
+	n := len(m.fileOutcomes)
+	if _, finished := m.fileOutcomes[m.currentFile]; !finished {
+		n++
+	}
+	return n
+}
+
+// stepText returns what a step announcement such as "[2/5] Computing
+// normals..." says the step does
+func stepText(message string) string {
+	if _, text, ok := strings.Cut(message, "] "); ok {
+		message = text
+	}
+	return strings.TrimSuffix(strings.TrimSpace(message), "...")
+}
+
+// doneAnnouncement sums up a finished batch in a line
+func (m Model) doneAnnouncement() string {
+	r := m.result
+	if r.Stopped {
+		return fmt.Sprintf("Processing stopped after %s: %d of %d files done", humanize.Duration(m.elapsedTime), r.SuccessCount, r.TotalFiles)
+	}
+	if r.Aborted != "" {
+		return fmt.Sprintf("Processing aborted after %s, as %s: %d of %d files succeeded", humanize.Duration(m.elapsedTime), r.Aborted, r.SuccessCount, r.TotalFiles)
+	}
+	text := fmt.Sprintf("Processing finished in %s: %d of %d files succeeded", humanize.Duration(m.elapsedTime), r.SuccessCount, r.TotalFiles)
+	if r.WarningCount > 0 {
+		text += fmt.Sprintf(", %d with warnings", r.WarningCount)
+	}
+	if r.FailedCount > 0 {
+		text += fmt.Sprintf(", %d failed", r.FailedCount)
+	}
+	return text
+}
+
+// viewProcessingAccessible renders the processing screen in accessible
+// mode: what is being processed, without a spinner or a clock, so it only
+// changes along with the announcements
+func (m Model) viewProcessingAccessible() string {
+	status := "Starting"
+	if m.currentFile != "" {
+		status = fmt.Sprintf("Processing file %d of %d: %s", m.fileNumber(), m.filesTotal, filepath.Base(m.currentFile))
+	}
+	lines := []string{status}
+	if m.currentStep != "" {
+		lines = append(lines, "Step: "+stepText(m.currentStep))
+	}
+	if !m.stalledSince.IsZero() {
+		lines = append(lines, "Possibly stalled")
+	}
+	if len(m.transfers) > 0 {
+		lines = append(lines, fmt.Sprintf("Prefetching %d file(s)", len(m.transfers)))
+	}
+	return strings.Join(append(lines, "Keys: "+m.processingKeysPlain()), "\n")
+}
@@ -0,0 +1,63 @@
+package tui
+
+import (
+	"strings"
+)
+
+// In corporate mode the TUI keeps to what any terminal shows the same and
+// what suits a client's site: plain ASCII, the muted palette unless
+// another is chosen, no animations and no celebrations.
+
+// asciiDots spell out the punctuation that has an ASCII equivalent of
+// more than one character
+var asciiDots = strings.NewReplacer("…", "...", "—", "-", "–", "-")
+
+// asciiRune returns the ASCII stand-in for a glyph, or -1 for one that
+// only decorates and has none. Letters outside ASCII stay, as file names
+// may hold them.
+func asciiRune(r rune) rune {
+	switch {
+	case r < 0x80:
+		return r
+	case strings.ContainsRune("─━═┄┅┈┉╌╍╴╶╸╺", r):
+		return '-'
+	case strings.ContainsRune("│┃║┆┇┊┋╎╏╵╷╹╻", r):
+		return '|'
+	case r >= 0x2500 && r <= 0x257F: // Corners, tees and crossings
+		return '+'
+	case strings.ContainsRune("█▓▇▆▅▄▃▂▁▌▐", r):
+		return '#'
+	case strings.ContainsRune("░▒", r):
+		return '.'
+	case strings.ContainsRune("▶►▸→", r):
+		return '>'
+	case strings.ContainsRune("◀◄◂←", r):
+		return '<'
+	case strings.ContainsRune("▲↑", r):
+		return '^'
+	case strings.ContainsRune("▼↓", r):
+		return 'v'
+	case strings.ContainsRune("○◯◌", r):
+		return 'o'
+	case strings.ContainsRune("●◉•", r):
+		return '*'
+	case r == '·':
+		return '.'
+	case r == '≈':
+		return '~'
+	case r == '↗' || r == '↙':
+		return '/'
+	case r == '↖' || r == '↘':
+		return '\\'
+	case r >= 0x2800 && r <= 0x28FF: // Braille spinners
+		return '*'
+	case decorative(r) || r == 0x2139: // ℹ
+		return -1
+	}
+	return r
+}
+
+// asciiView turns a rendered view into plain ASCII
+func asciiView(view string) string {
+	return mapView(asciiDots.Replace(view), asciiRune)
+}
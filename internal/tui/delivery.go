@@ -0,0 +1,232 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/cloudcompare-automation/internal/history"
+	"github.com/cloudcompare-automation/internal/processor"
+	"github.com/cloudcompare-automation/internal/report"
+)
+
+// The delivery checklist follows the handover procedure: before a run is
+// marked delivered in the history, the operator goes through what a
+// delivery needs and ticks every item. Items found unmet can still be
+// ticked, and are recorded as waived.
+
+// deliveryItem is a line of the delivery checklist
+type deliveryItem struct {
+	label  string
+	met    bool
+	detail string // Why it isn't met, or what was found
+}
+
+// errNotTicked is shown when the run is marked delivered before every
+// item was ticked
+var errNotTicked = errors.New("tick every item to mark the run delivered")
+
+// deliveredMsg reports the outcome of marking the run delivered
+type deliveredMsg struct {
+	delivery history.Delivery
+	err      error
+}
+
+// checksumsWrittenMsg reports the outcome of writing the checksum file
+type checksumsWrittenMsg struct {
+	err error
+}
+
+// markDelivered records the delivery of the run that wrote reportPath
+func markDelivered(reportPath string, d history.Delivery) tea.Cmd {
+	return func() tea.Msg {
+		_, err := history.SetDelivered(reportPath, d)
+		return deliveredMsg{delivery: d, err: err}
+	}
+}
+
+// writeChecksums writes the checksum file of the run's outputs
+func writeChecksums(reportPath string) tea.Cmd {
+	return func() tea.Msg {
+		_, err := report.WriteChecksums(reportPath)
+		return checksumsWrittenMsg{err: err}
+	}
+}
+
+// deliveryChecks goes through the checklist for the finished run
+func (m Model) deliveryChecks() []deliveryItem {
+	r := m.result
+	exists := func(path string) bool {
+		_, err := os.Stat(path)
+		return path != "" && err == nil
+	}
+
+	succeeded := deliveryItem{label: "All files succeeded", met: r.FailedCount == 0 && !r.Stopped && r.Aborted == ""}
+	switch {
+	case r.Aborted != "":
+		succeeded.detail = "aborted: " + r.Aborted
+	case r.Stopped:
+		succeeded.detail = "stopped before all files were done"
+	case r.FailedCount > 0:
+		succeeded.detail = fmt.Sprintf("%d file(s) failed", r.FailedCount)
+	}
+
+	qa := deliveryItem{label: "QA checks passed", met: r.WarningCount == 0}
+	if r.WarningCount > 0 {
+		qa.detail = fmt.Sprintf("%d file(s) with warnings", r.WarningCount)
+	}
+
+	reported := deliveryItem{label: "Report generated", met: exists(r.ReportPath) && exists(report.HTMLPath(r.ReportPath))}
+	if reported.met {
+		reported.detail = filepath.Base(report.HTMLPath(r.ReportPath))
+	} else {
+		reported.detail = "no report was written"
+	}
+
+	checksums := deliveryItem{label: "Checksums written"}
+	if r.ReportPath != "" {
+		checksums.met = exists(report.ChecksumsPath(r.ReportPath))
+	}
+	if checksums.met {
+		checksums.detail = filepath.Base(report.ChecksumsPath(r.ReportPath))
+	} else if r.ReportPath != "" {
+		checksums.detail = "press c to write them"
+	}
+
+	archived := deliveryItem{label: "Outputs archived"}
+	done, total := 0, 0
+	for _, f := range r.Files {
+		if f.Success {
+			total++
+			if f.Archive != "" {
+				done++
+			}
+		}
+	}
+	archived.met = total > 0 && done == total
+	switch {
+	case m.params.Archive == "" || m.params.Archive == processor.ArchiveNone:
+		archived.detail = "archiving is off"
+	case !archived.met:
+		archived.detail = fmt.Sprintf("%d of %d archived", done, total)
+	}
+
+	return []deliveryItem{succeeded, qa, reported, checksums, archived}
+}
+
+// openDelivery switches to the delivery checklist of the finished run
+func (m Model) openDelivery() Model {
+	m.screen = ScreenDelivery
+	m.delivery = m.deliveryChecks()
+	m.deliveryTicked = make([]bool, len(m.delivery))
+	m.deliveryCursor = 0
+	m.deliveryErr = nil
+	return m
+}
+
+func (m Model) updateDelivery(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		m.deliveryCursor = max(m.deliveryCursor-1, 0)
+	case "down", "j":
+		m.deliveryCursor = min(m.deliveryCursor+1, len(m.delivery)-1)
+	case " ", "x":
+		if m.delivered == nil {
+			m.deliveryTicked[m.deliveryCursor] = !m.deliveryTicked[m.deliveryCursor]
+			m.deliveryErr = nil
+		}
+	case "c":
+		if m.result.ReportPath != "" && m.delivered == nil {
+			return m, writeChecksums(m.result.ReportPath)
+		}
+	case "enter":
+		if m.delivered != nil {
+			break
+		}
+		d := history.Delivery{At: time.Now(), By: m.reviewer()}
+		for i, item := range m.delivery {
+			if !m.deliveryTicked[i] {
+				m.deliveryErr = errNotTicked
+				return m, nil
+			}
+			if !item.met {
+				d.Waived = append(d.Waived, item.label)
+			}
+		}
+		return m, markDelivered(m.result.ReportPath, d)
+	case "esc":
+		m.screen = ScreenResults
+	}
+	return m, nil
+}
+
+// deliveryDone handles the outcome of marking the run delivered, or of
+// writing its checksums, which checks the checklist again
+func (m Model) deliveryDone(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case deliveredMsg:
+		m.deliveryErr = msg.err
+		if msg.err == nil {
+			m.delivered = &msg.delivery
+		}
+	case checksumsWrittenMsg:
+		m.deliveryErr = msg.err
+		ticked := m.deliveryTicked
+		m.delivery = m.deliveryChecks()
+		m.deliveryTicked = ticked
+	}
+	return m, nil
+}
+
+// viewDelivery renders the delivery checklist
+func (m Model) viewDelivery() string {
+	s := m.styles
+	icons := s.Icons
+
+	parts := []string{s.HeaderTitle.Render("📦 Delivery Checklist"), ""}
+	for i, item := range m.delivery {
+		box := "[ ]"
+		if m.deliveryTicked[i] || m.delivered != nil {
+			box = "[x]"
+		}
+		status := s.StatusSuccess.Render(icons.Success)
+		if !item.met {
+			status = s.StatusWarning.Render(icons.Warning)
+		}
+		line := fmt.Sprintf("%s %s %s", box, status, item.label)
+		if item.detail != "" {
+			line += s.TextMuted.Render(" — " + truncate(item.detail, max(m.width-lipgloss.Width(line)-8, 10)))
+		}
+		if i == m.deliveryCursor && m.delivered == nil {
+			line = s.ListItemSelected.Render(line)
+		} else {
+			line = s.ListItem.Render(line)
+		}
+		parts = append(parts, line)
+	}
+	parts = append(parts, "")
+
+	switch {
+	case m.delivered != nil:
+		parts = append(parts, s.StatusSuccess.Render(icons.Success+" "+truncate("Marked delivered "+m.delivered.String(), m.width-8)))
+	case m.deliveryErr != nil:
+		parts = append(parts, s.StatusError.Render("⚠ "+truncate(m.deliveryErr.Error(), m.width-10)))
+	default:
+		parts = append(parts, s.TextMuted.Render("Tick each item once checked; unmet items ticked are recorded as waived."))
+	}
+
+	keys := s.RenderKeyHelp("esc", "back")
+	if m.delivered == nil {
+		keys = s.RenderKeyHelp("↑↓", "select") + " " +
+			s.RenderKeyHelp("space", "tick") + " " +
+			s.RenderKeyHelp("c", "write checksums") + " " +
+			s.RenderKeyHelp("enter", "mark delivered") + " " + keys
+	}
+	parts = append(parts, "", s.Footer.Render(keys))
+	return lipgloss.JoinVertical(lipgloss.Left, parts...)
+}
@@ -0,0 +1,124 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/cloudcompare-automation/internal/descriptor"
+	"github.com/cloudcompare-automation/internal/processor"
+)
+
+// importCheckedMsg carries what differs from the imported run
+type importCheckedMsg struct {
+	diffs []string
+}
+
+// descriptorSavedMsg reports exporting a run descriptor
+type descriptorSavedMsg struct {
+	path  string
+	files int
+	err   error
+}
+
+// applyParams fills the Configuration form from params, e.g. those of an
+// imported run, so starting processes them as they are
+func (m Model) applyParams(params processor.Params) Model {
+	// The Python environment is this machine's
+	params.Python = m.params.Python
+	m.params = params
+	m.inputs[FocusInputDir].SetValue(params.InputDir)
+	m.inputs[FocusOutputSubdir].SetValue(params.OutputSubdir)
+	m.inputs[FocusInclude].SetValue(strings.Join(params.Include, ", "))
+	m.inputs[FocusExclude].SetValue(strings.Join(params.Exclude, ", "))
+	m.inputs[FocusWebExport].SetValue(params.WebExport)
+	for i, field := range processor.MetadataFields {
+		m.inputs[FocusMetadata+FocusedField(i)].SetValue(params.Metadata[field.Key])
+	}
+	m.inputs[FocusLabels].SetValue(strings.Join(params.Labels, ", "))
+
+	idx := 0
+	for i, pipeline := range m.pipelines {
+		if i > 0 && pipeline.Script == params.Script {
+			idx = i
+		}
+	}
+	m = m.selectPipeline(idx)
+	for i, spec := range m.schema() {
+		m.inputs[int(FocusParams)+i].SetValue(params.Values[spec.Name])
+	}
+	m.focusedField = FocusInputDir
+	m.fileList.SetValue(strings.Join(params.Files, "\n"))
+
+	// The settings are complete as they are, so a project configuration of
+	// their directory isn't applied over them
+	m.projectDir = params.InputDir
+	m.projectPath = ""
+	m.projectOverrides = nil
+	m.projectErr = nil
+	return m
+}
+
+// checkImport compares the imported run with the files, pipeline script
+// and version that would process it now
+func checkImport(d descriptor.Descriptor, version string) tea.Cmd {
+	return func() tea.Msg {
+		p := processor.New(d.Params)
+		if err := p.FindScripts(); err != nil {
+			return importCheckedMsg{diffs: []string{err.Error()}}
+		}
+		return importCheckedMsg{diffs: d.Differences(p.ScriptPath(), version)}
+	}
+}
+
+// exportDescriptor saves a descriptor of the run the form sets up in the
+// first output directory, listing the files in the background
+func (m Model) exportDescriptor() (tea.Model, tea.Cmd) {
+	params, err := m.formParams()
+	if err != nil {
+		m.err = err
+		return m, nil
+	}
+	if len(params.Files) == 0 && !m.dirUsable(params.InputDir) {
+		m.err = fmt.Errorf("network path %s hasn't been reached yet", params.InputDir)
+		return m, nil
+	}
+	m.err = nil
+	version := m.version
+	return m, func() tea.Msg {
+		d, err := descriptor.Describe(processor.New(params), version)
+		if err != nil {
+			return descriptorSavedMsg{err: err}
+		}
+		dir := d.Params.OutputDirs(d.Params.Files)[0]
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return descriptorSavedMsg{err: err}
+		}
+		path := filepath.Join(dir, "run-"+time.Now().Format("20060102-150405")+descriptor.Extension)
+		return descriptorSavedMsg{path: path, files: len(d.Files), err: d.Save(path)}
+	}
+}
+
+// importLines describes the imported run and what differs from it for
+// the summary panel
+func (m Model) importLines(width int) []string {
+	s := m.styles
+	if m.imported == nil {
+		return nil
+	}
+	lines := []string{"", s.Text.Render(fmt.Sprintf("↻ Repeating run of %s", m.imported.CreatedAt.Format("2006-01-02 15:04")))}
+	if !m.importChecked {
+		return append(lines, s.TextMuted.Render("  Comparing with the original..."))
+	}
+	if len(m.importDiffs) == 0 {
+		return append(lines, s.TextSuccess.Render("  Same files, script and version"))
+	}
+	for _, diff := range m.importDiffs {
+		lines = append(lines, s.StatusWarning.Render("  ⚠ "+truncate(diff, width-4)))
+	}
+	return lines
+}
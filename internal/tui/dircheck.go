@@ -0,0 +1,93 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/cloudcompare-automation/internal/processor"
+)
+
+// dirCheckTimeout is how long a network directory may take to answer
+// before it counts as unreachable, e.g. a share whose server is down
+const dirCheckTimeout = 5 * time.Second
+
+// dirCheckedMsg reports whether a network directory could be reached
+type dirCheckedMsg struct {
+	path string
+	err  error
+}
+
+// checkDir looks a directory up, giving up after dirCheckTimeout. A lookup
+// on a dead share can't be cancelled, so it is left to finish on its own.
+func checkDir(path string) tea.Cmd {
+	return func() tea.Msg {
+		done := make(chan error, 1)
+		go func() {
+			info, err := os.Stat(path)
+			if err == nil && !info.IsDir() {
+				err = fmt.Errorf("not a directory: %s", path)
+			}
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			return dirCheckedMsg{path: path, err: err}
+		case <-time.After(dirCheckTimeout):
+			return dirCheckedMsg{path: path, err: fmt.Errorf("%s did not respond within %s", path, dirCheckTimeout)}
+		}
+	}
+}
+
+// inputDir returns the directory the Configuration screen selects
+func (m Model) inputDir() string {
+	if dir := m.inputs[FocusInputDir].Value(); dir != "" {
+		return dir
+	}
+	return m.selectedDir
+}
+
+// dirUsable reports whether dir can be read without risking a freeze:
+// local directories always, network ones once found reachable
+func (m Model) dirUsable(dir string) bool {
+	return !processor.IsNetworkPath(dir) || dir == m.dirReachable
+}
+
+// refreshDirCheck checks a network input directory once it has been
+// typed in and the Input Dir field is left
+func refreshDirCheck(model tea.Model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	m, ok := model.(Model)
+	if !ok || m.screen != ScreenParams || m.focusedField == FocusInputDir {
+		return model, cmd
+	}
+	dir := m.inputDir()
+	if m.dirUsable(dir) || dir == m.dirChecking || dir == m.dirFailed {
+		return model, cmd
+	}
+	if processor.CheckNetworkPath(dir) != nil {
+		return model, cmd
+	}
+	m.dirChecking = dir
+	return m, tea.Batch(cmd, checkDir(dir), m.spinner.Tick)
+}
+
+// dirCheckLine describes the state of a network input directory in at
+// most width columns for the summary panel, or returns "" for a local or
+// reachable one
+func (m Model) dirCheckLine(dir string, width int) string {
+	s := m.styles
+	switch {
+	case m.dirUsable(dir):
+		return ""
+	case processor.CheckNetworkPath(dir) != nil:
+		return s.StatusWarning.Render(truncate("⚠ "+processor.CheckNetworkPath(dir).Error(), width))
+	case dir == m.dirChecking:
+		return s.StatusInfo.Render(m.spinner.View() + " Checking network path...")
+	case dir == m.dirFailed && m.dirCheckErr != nil:
+		return s.StatusError.Render(truncate("⚠ "+m.dirCheckErr.Error(), width))
+	default:
+		return s.TextMuted.Render("Network path, checked when you leave the field")
+	}
+}
@@ -0,0 +1,172 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/cloudcompare-automation/internal/processor"
+)
+
+// listedFile is an entry of the Files screen and why it can't be
+// processed, if it can't
+type listedFile struct {
+	path string
+	err  error
+}
+
+// filesCheckedMsg carries the checked entries of a file list
+type filesCheckedMsg struct {
+	text    string
+	entries []listedFile
+}
+
+// newFileList creates the Files screen's editor, one path per line
+func newFileList() textarea.Model {
+	ta := textarea.New()
+	ta.Placeholder = `D:\Surveys\north\tile_01.las`
+	ta.ShowLineNumbers = false
+	ta.CharLimit = 0
+	ta.MaxHeight = 0
+	return ta
+}
+
+// checkFiles checks the entries of a file list in the background, as
+// paths on a network share can take a while to reach
+func checkFiles(text string) tea.Cmd {
+	return func() tea.Msg {
+		var entries []listedFile
+		for _, path := range processor.ParseFileList(text) {
+			entries = append(entries, listedFile{path: path, err: processor.CheckLASFile(path)})
+		}
+		return filesCheckedMsg{text: text, entries: entries}
+	}
+}
+
+// listedFiles returns the paths of the Files screen; none means the input
+// directory is processed
+func (m Model) listedFiles() []string {
+	return processor.ParseFileList(m.fileList.Value())
+}
+
+// filesChecked reports whether the entries of the file list are checked
+// as they are now
+func (m Model) filesChecked() bool {
+	return m.fileChecks.text == m.fileList.Value()
+}
+
+// invalidFiles counts the checked entries that can't be processed
+func (m Model) invalidFiles() int {
+	n := 0
+	for _, entry := range m.fileChecks.entries {
+		if entry.err != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// openFiles switches to the Files screen
+func (m Model) openFiles() (tea.Model, tea.Cmd) {
+	m.screen = ScreenFiles
+	m.err = nil
+	return m, m.fileList.Focus()
+}
+
+func (m Model) updateFiles(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.fileList.Blur()
+		m.screen = ScreenParams
+		return m, nil
+	case "ctrl+x":
+		m.fileList.Reset()
+	case "ctrl+z":
+		m = m.undoForm()
+	case "ctrl+v":
+		if text, err := clipboard.ReadAll(); err == nil {
+			m.fileList.InsertString(text)
+		}
+	default:
+		var cmd tea.Cmd
+		m.fileList, cmd = m.fileList.Update(msg)
+		if m.filesChecked() {
+			return m, cmd
+		}
+		return m, tea.Batch(cmd, checkFiles(m.fileList.Value()))
+	}
+	return m, checkFiles(m.fileList.Value())
+}
+
+// viewFiles renders the file list editor with the checked entries below
+func (m Model) viewFiles() string {
+	s := m.styles
+
+	var parts []string
+	parts = append(parts, s.HeaderTitle.Render("📄 Files"), "",
+		s.TextMuted.Render("Paste or type LAS file paths, one per line or separated by ;"),
+		s.TextMuted.Render("Listed files are processed instead of the input directory"), "")
+
+	m.fileList.SetWidth(max(20, m.width-6))
+	m.fileList.SetHeight(max(3, m.height/3))
+	parts = append(parts, m.fileList.View(), "")
+
+	entries := m.fileChecks.entries
+	switch {
+	case len(m.listedFiles()) == 0:
+		parts = append(parts, s.TextMuted.Render("No files listed"))
+	case !m.filesChecked():
+		parts = append(parts, s.TextMuted.Render("Checking files..."))
+	default:
+		count := fmt.Sprintf("%d file(s) listed", len(entries))
+		if n := m.invalidFiles(); n > 0 {
+			parts = append(parts, s.StatusWarning.Render(fmt.Sprintf("%s, %d can't be processed", count, n)))
+		} else {
+			parts = append(parts, s.TextSuccess.Render(count))
+		}
+
+		// Problems first, as they need fixing before a run
+		rows := max(1, m.height-m.height/3-14)
+		shown := 0
+		for _, bad := range []bool{true, false} {
+			for _, entry := range entries {
+				if (entry.err != nil) != bad || shown == rows {
+					continue
+				}
+				shown++
+				if entry.err != nil {
+					parts = append(parts, s.StatusError.Render(s.Icons.Error+" "+truncate(entry.err.Error(), m.width-6)))
+				} else {
+					parts = append(parts, s.TextSuccess.Render(s.Icons.Success+" ")+s.Text.Render(truncateLeft(entry.path, m.width-6)))
+				}
+			}
+		}
+		if more := len(entries) - shown; more > 0 {
+			parts = append(parts, s.TextMuted.Render(fmt.Sprintf("  ... %d more", more)))
+		}
+	}
+
+	footer := s.Footer.Render(
+		s.RenderKeyHelp("ctrl+v", "paste") + " " +
+			s.RenderKeyHelp("ctrl+x", "clear") + " " +
+			s.RenderKeyHelp("ctrl+z", "undo") + " " +
+			s.RenderKeyHelp("esc", "back"),
+	)
+	parts = append(parts, "", footer)
+
+	return lipgloss.JoinVertical(lipgloss.Left, parts...)
+}
+
+// filesSummary describes the listed files for the Configuration screen
+func (m Model) filesSummary() string {
+	files := m.listedFiles()
+	dirs := make(map[string]bool)
+	for _, file := range files {
+		dirs[filepath.Dir(file)] = true
+	}
+	return fmt.Sprintf("📄 %d listed file(s) in %d folder(s), instead of the input directory", len(files), len(dirs))
+}
@@ -0,0 +1,89 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Escape sequences that turn the terminal's focus reports on and off. With
+// them on, terminals that support it (xterm and most others, tmux with
+// focus-events on) send CSI I on gaining focus and CSI O on losing it.
+const (
+	FocusReportsOn  = "\x1b[?1004h"
+	FocusReportsOff = "\x1b[?1004l"
+)
+
+// focusReport reports whether msg is a focus report and if so whether the
+// terminal has focus. This Bubble Tea version has no focus messages and
+// passes the reports on as unknown CSI sequences, recognised here by the
+// way they print.
+func focusReport(msg tea.Msg) (focused, ok bool) {
+	report, isReport := msg.(fmt.Stringer)
+	if !isReport {
+		return false, false
+	}
+	switch report.String() {
+	case "?CSI[73]?": // CSI I
+		return true, true
+	case "?CSI[79]?": // CSI O
+		return false, true
+	}
+	return false, false
+}
+
+// Animation frame intervals: the dashboard's waves and particles, and the
+// step spinner that is all the compact and log layouts animate
+const (
+	dashboardFrame = 80 * time.Millisecond
+	spinnerFrame   = 240 * time.Millisecond
+)
+
+// animInterval returns how often the processing screen animates, or 0 when
+// nothing animated is on screen: in inline and corporate mode, and while
+// the terminal doesn't have focus
+func (m Model) animInterval() time.Duration {
+	switch {
+	case m.inline || m.corporate || m.unfocused:
+		return 0
+	case m.celebrating || !m.rawLog && m.height >= compactHeight:
+		return dashboardFrame
+	default:
+		return spinnerFrame
+	}
+}
+
+// nextAnimTick schedules the next animation frame, or lets the animation
+// stop when there is nothing to animate
+func (m Model) nextAnimTick() (Model, tea.Cmd) {
+	interval := m.animInterval()
+	m.animating = m.processing && interval > 0
+	if !m.animating {
+		return m, nil
+	}
+	return m, tea.Tick(interval, func(t time.Time) tea.Msg {
+		return AnimTickMsg(t)
+	})
+}
+
+// setFocus pauses the animations and the spinner while the terminal
+// doesn't have focus, and restarts them when it gets it back
+func (m Model) setFocus(focused bool) (Model, tea.Cmd) {
+	m.unfocused = !focused
+	if !focused {
+		return m, nil
+	}
+	var cmds []tea.Cmd
+	if m.processing || m.dirChecking != "" || m.preparing {
+		// If the spinner hadn't stopped yet, the tick still on its way
+		// and this one share a tag, and the spinner drops the second
+		cmds = append(cmds, m.spinner.Tick)
+	}
+	if !m.animating {
+		var cmd tea.Cmd
+		m, cmd = m.nextAnimTick()
+		cmds = append(cmds, cmd)
+	}
+	return m, tea.Batch(cmds...)
+}
@@ -0,0 +1,196 @@
+package tui
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/cloudcompare-automation/internal/processor"
+	"github.com/cloudcompare-automation/internal/profile"
+)
+
+// formState is what was typed into the Configuration and Files screens,
+// with the layout chosen for the processing screen, saved as it changes
+// and restored at the next start, so a crash or an accidental quit doesn't
+// lose it. Values are kept as typed, invalid ones included.
+type formState struct {
+	Dir      string            `json:"dir,omitempty"`      // Input directory
+	Pipeline string            `json:"pipeline,omitempty"` // Script of the selected pipeline; empty for the default
+	Fields   map[string]string `json:"fields,omitempty"`   // Field values by formKey
+	Files    string            `json:"files,omitempty"`    // Text of the Files screen
+	Layout   string            `json:"layout,omitempty"`   // Processing screen layout: log, or empty for the dashboard
+}
+
+// formSaveMsg is due a second after the form last changed; only the
+// latest one saves
+type formSaveMsg struct {
+	seq int
+}
+
+// formStateDelay is how long the form must be left alone to be saved
+const formStateDelay = time.Second
+
+// formStatePath returns the file the form is saved in, in the active
+// profile's directory
+func formStatePath() (string, error) {
+	dir, err := profile.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "form.json"), nil
+}
+
+// loadFormState reads the saved form; a missing or unreadable file gives
+// an empty one
+func loadFormState() formState {
+	var state formState
+	path, err := formStatePath()
+	if err != nil {
+		return state
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return state
+	}
+	json.Unmarshal(data, &state)
+	return state
+}
+
+// save writes the form state, replacing the file in one step so a crash
+// while writing leaves the previous one
+func (state formState) save() error {
+	path, err := formStatePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// formKey names a fixed form field or pipeline parameter in the saved
+// state
+func (m Model) formKey(field FocusedField) string {
+	switch {
+	case field == FocusInputDir:
+		return "input_dir"
+	case field == FocusOutputSubdir:
+		return "output_subdir"
+	case field == FocusInclude:
+		return "include"
+	case field == FocusExclude:
+		return "exclude"
+	case field == FocusWebExport:
+		return "web_export"
+	case field == FocusLabels:
+		return "labels"
+	case field >= FocusMetadata && field < FocusLabels:
+		return "metadata." + processor.MetadataFields[field-FocusMetadata].Key
+	}
+	return "param." + m.schema()[field-FocusParams].Name
+}
+
+// formState returns what the form holds now
+func (m Model) formState() formState {
+	state := formState{
+		Dir:    m.selectedDir,
+		Fields: make(map[string]string),
+		Files:  m.fileList.Value(),
+	}
+	if m.rawLog {
+		state.Layout = "log"
+	}
+	if dir := m.inputs[FocusInputDir].Value(); dir != "" {
+		state.Dir = dir
+	}
+	if m.pipelineIdx > 0 && m.pipelineIdx < len(m.pipelines) {
+		state.Pipeline = m.pipelines[m.pipelineIdx].Script
+	}
+	for i := range m.inputs {
+		if value := m.inputs[i].Value(); value != "" {
+			state.Fields[m.formKey(FocusedField(i))] = value
+		}
+	}
+	return state
+}
+
+// restoreForm fills the form from a saved state. Fields left empty keep
+// what the config file prefilled them with; a pipeline that is gone
+// leaves the default one selected.
+func (m Model) restoreForm(state formState) Model {
+	if state.Dir != "" {
+		m.selectedDir = state.Dir
+		m.currentDir = state.Dir
+	}
+	for i, pipeline := range m.pipelines {
+		if i > 0 && state.Pipeline != "" && pipeline.Script == state.Pipeline {
+			m = m.selectPipeline(i)
+		}
+	}
+	for i := range m.inputs {
+		if value, ok := state.Fields[m.formKey(FocusedField(i))]; ok {
+			m.inputs[i].SetValue(value)
+		}
+	}
+	m.fileList.SetValue(state.Files)
+	m.rawLog = state.Layout == "log"
+	m.focusedField = FocusInputDir
+	m.formSaved = state
+	return m
+}
+
+// refreshFormSave schedules saving the form after a key press changed it
+func refreshFormSave(model tea.Model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	m, ok := model.(Model)
+	if !ok || !m.autosave {
+		return model, cmd
+	}
+	state := m.formState()
+	if state.equal(m.formSaved) {
+		return model, cmd
+	}
+	m.formSaved = state
+	m.formSeq++
+	seq := m.formSeq
+	return m, tea.Batch(cmd, tea.Tick(formStateDelay, func(time.Time) tea.Msg {
+		return formSaveMsg{seq: seq}
+	}))
+}
+
+// equal reports whether two states hold the same form
+func (state formState) equal(other formState) bool {
+	if state.Dir != other.Dir || state.Pipeline != other.Pipeline || state.Files != other.Files || state.Layout != other.Layout ||
+		len(state.Fields) != len(other.Fields) {
+		return false
+	}
+	for key, value := range state.Fields {
+		if other.Fields[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// saveForm writes the latest form state in the background
+func (m Model) saveForm(msg formSaveMsg) tea.Cmd {
+	if msg.seq != m.formSeq {
+		return nil
+	}
+	state := m.formSaved
+	return func() tea.Msg {
+		state.save()
+		return nil
+	}
+}
@@ -0,0 +1,274 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/cloudcompare-automation/internal/history"
+)
+
+// historyLoadedMsg carries the recorded runs for the history screen
+type historyLoadedMsg struct {
+	runs []history.Entry
+	err  error
+}
+
+// historyLabelledMsg reports the outcome of relabelling a run
+type historyLabelledMsg struct {
+	run history.Entry
+	err error
+}
+
+// loadHistory reads the recorded runs
+func loadHistory() tea.Msg {
+	runs, err := history.List()
+	return historyLoadedMsg{runs: runs, err: err}
+}
+
+// saveLabels relabels a run in the history and its report
+func saveLabels(run history.Entry, labels []string) tea.Cmd {
+	return func() tea.Msg {
+		run, err := history.SetLabels(run, labels)
+		return historyLabelledMsg{run: run, err: err}
+	}
+}
+
+// newHistoryInput creates the filter or label input of the history screen
+func newHistoryInput(placeholder string) textinput.Model {
+	input := textinput.New()
+	input.Placeholder = placeholder
+	input.CharLimit = 128
+	input.Width = 30
+	return input
+}
+
+// openHistory switches to the history screen and loads the runs
+func (m Model) openHistory() (tea.Model, tea.Cmd) {
+	m.screen = ScreenHistory
+	m.historyCursor = 0
+	m.err = nil
+	return m, loadHistory
+}
+
+// visibleRuns returns the runs matching the label filter
+func (m Model) visibleRuns() []history.Entry {
+	var runs []history.Entry
+	for _, run := range m.runs {
+		if run.Matches(m.historyFilter.Value()) {
+			runs = append(runs, run)
+		}
+	}
+	return runs
+}
+
+// historyTyping reports whether the filter or label input has the keyboard
+func (m Model) historyTyping() bool {
+	return m.screen == ScreenHistory && (m.historyFilter.Focused() || m.historyLabels.Focused())
+}
+
+func (m Model) updateHistory(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	runs := m.visibleRuns()
+
+	// Editing the labels of the selected run
+	if m.historyLabels.Focused() {
+		switch msg.String() {
+		case "enter":
+			m.historyLabels.Blur()
+			if m.historyCursor < len(runs) {
+				return m, saveLabels(runs[m.historyCursor], history.ParseLabels(m.historyLabels.Value()))
+			}
+			return m, nil
+		case "esc":
+			m.historyLabels.Blur()
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.historyLabels, cmd = m.historyLabels.Update(msg)
+		return m, cmd
+	}
+
+	// Typing a label filter; the list follows as it changes
+	if m.historyFilter.Focused() {
+		switch msg.String() {
+		case "enter":
+			m.historyFilter.Blur()
+			return m, nil
+		case "esc":
+			m.historyFilter.Blur()
+			m.historyFilter.SetValue("")
+			m.historyCursor = 0
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.historyFilter, cmd = m.historyFilter.Update(msg)
+		m.historyCursor = 0
+		return m, cmd
+	}
+
+	switch msg.String() {
+	case "up", "k":
+		if m.historyCursor > 0 {
+			m.historyCursor--
+		}
+	case "down", "j":
+		if m.historyCursor < len(runs)-1 {
+			m.historyCursor++
+		}
+	case "/", "f":
+		return m, m.historyFilter.Focus()
+	case "l":
+		if m.historyCursor < len(runs) {
+			m.historyLabels.SetValue(strings.Join(runs[m.historyCursor].Labels, ", "))
+			m.historyLabels.CursorEnd()
+			return m, m.historyLabels.Focus()
+		}
+	case "r":
+		return m, loadHistory
+	case "s":
+		return m.openStats()
+	case "esc":
+		if m.historyFilter.Value() != "" {
+			m.historyFilter.SetValue("")
+			m.historyCursor = 0
+			return m, nil
+		}
+		m.screen = ScreenWelcome
+		return m, loadRecent
+	}
+	return m, nil
+}
+
+// viewHistory renders the list of earlier runs
+func (m Model) viewHistory() string {
+	s := m.styles
+
+	header := s.HeaderTitle.Render("🕘 History")
+
+	var parts []string
+	parts = append(parts, header)
+	if m.err != nil {
+		parts = append(parts, s.StatusError.Render("⚠ "+truncate(m.err.Error(), m.width-10)))
+	}
+
+	// Filter line, shown while typing or when a filter is set
+	if m.historyFilter.Focused() || m.historyFilter.Value() != "" {
+		parts = append(parts, s.FormLabel.Render("Label filter: ")+m.historyFilter.View())
+	}
+	parts = append(parts, "")
+
+	runs := m.visibleRuns()
+	detailHeight := 5
+	maxVisible := max(m.height-10-detailHeight, 3)
+
+	// Keep the cursor in view
+	first := 0
+	if m.historyCursor >= maxVisible {
+		first = m.historyCursor - maxVisible + 1
+	}
+
+	var rows []string
+	for i := first; i < len(runs) && i < first+maxVisible; i++ {
+		row := historyRow(runs[i], m.width-4)
+		if i == m.historyCursor {
+			rows = append(rows, s.SelectedItem.Render("▶ "+row))
+		} else {
+			rows = append(rows, s.Text.Render("  "+row))
+		}
+	}
+	switch {
+	case len(m.runs) == 0:
+		rows = append(rows, s.TextMuted.Render("  No runs recorded yet"))
+	case len(runs) == 0:
+		rows = append(rows, s.TextMuted.Render("  No runs with a matching label"))
+	case len(runs) > maxVisible:
+		rows = append(rows, s.TextMuted.Render(fmt.Sprintf("  [%d-%d of %d]", first+1, min(first+maxVisible, len(runs)), len(runs))))
+	}
+	parts = append(parts, lipgloss.JoinVertical(lipgloss.Left, rows...))
+
+	// Details of the selected run
+	if m.historyCursor < len(runs) {
+		run := runs[m.historyCursor]
+		parts = append(parts, "")
+		if m.historyLabels.Focused() {
+			parts = append(parts, s.FormLabel.Render("Labels: ")+m.historyLabels.View())
+		} else if len(run.Labels) > 0 {
+			parts = append(parts, s.StatusInfo.Render("Labels: "+strings.Join(run.Labels, ", ")))
+		}
+		if run.Delivered != nil {
+			parts = append(parts, s.StatusSuccess.Render(truncate("Delivered "+run.Delivered.String(), m.width-4)))
+		}
+		parts = append(parts,
+			s.TextMuted.Render(truncateLeft("Input: "+run.Input, m.width-4)),
+			s.TextMuted.Render(truncate("Params: "+formatParams(run.Params), m.width-4)),
+		)
+		if run.Report != "" {
+			parts = append(parts, s.TextMuted.Render(truncateLeft("Report: "+run.Report, m.width-4)))
+		}
+	}
+
+	var keys string
+	switch {
+	case m.historyLabels.Focused():
+		keys = s.RenderKeyHelp("enter", "save") + " " + s.RenderKeyHelp("esc", "cancel")
+	case m.historyFilter.Focused():
+		keys = s.RenderKeyHelp("enter", "apply") + " " + s.RenderKeyHelp("esc", "clear")
+	default:
+		keys = s.RenderKeyHelp("↑↓", "nav") + " " +
+			s.RenderKeyHelp("/", "filter") + " " +
+			s.RenderKeyHelp("l", "labels") + " " +
+			s.RenderKeyHelp("r", "refresh") + " " +
+			s.RenderKeyHelp("s", "stats") + " " +
+			s.RenderKeyHelp("esc", "back")
+	}
+	parts = append(parts, "", s.Footer.Render(keys))
+
+	return lipgloss.JoinVertical(lipgloss.Left, parts...)
+}
+
+// historyRow summarizes a run on one line
+func historyRow(run history.Entry, width int) string {
+	counts := fmt.Sprintf("%d ok", run.Succeeded)
+	if run.Warned > 0 {
+		counts += fmt.Sprintf(", %d warn", run.Warned)
+	}
+	if run.Failed > 0 {
+		counts += fmt.Sprintf(", %d failed", run.Failed)
+	}
+	if run.Stopped {
+		counts += ", stopped"
+	}
+	if run.Aborted != "" {
+		counts += ", aborted"
+	}
+
+	if run.Delivered != nil {
+		counts += ", delivered"
+	}
+
+	row := fmt.Sprintf("%s  %-20s  %s", run.StartedAt.Format("2006-01-02 15:04"), truncate(filepath.Base(run.Input), 20), counts)
+	if len(run.Labels) > 0 {
+		row += "  [" + strings.Join(run.Labels, ", ") + "]"
+	}
+	return truncate(row, width-2)
+}
+
+// formatParams lists parameter values in name order
+func formatParams(params map[string]string) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + params[name]
+	}
+	return strings.Join(pairs, " ")
+}
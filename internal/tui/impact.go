@@ -0,0 +1,97 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/cloudcompare-automation/internal/history"
+	"github.com/cloudcompare-automation/internal/humanize"
+	"github.com/cloudcompare-automation/internal/processor"
+)
+
+// calibrationLoadedMsg carries the processing speed measured in the history
+type calibrationLoadedMsg struct {
+	calibration history.Calibration
+}
+
+// loadCalibration measures the processing speed of the recorded runs for
+// the runtime estimate. An unreadable history just leaves it out.
+func loadCalibration() tea.Msg {
+	runs, _ := history.List()
+	return calibrationLoadedMsg{calibration: history.Calibrate(runs)}
+}
+
+// impactLines estimates what the octree depth in the form means for the
+// selected files: the detail the mesh resolves, the peak memory (files are
+// processed one at a time) and the runtime, from the LAS headers and the
+// speed of earlier runs
+func (m Model) impactLines() []string {
+	s := m.styles
+	value := m.paramValue("octree-depth")
+	if value == "" || len(m.tiles) == 0 {
+		return nil
+	}
+
+	var minVoxel, maxVoxel float64
+	var peak uint64
+	var runtime time.Duration
+	timed, extrapolated := true, false
+	for _, tile := range m.tiles {
+		depth, ok := processor.DepthFor(tile.Header, value)
+		if !ok {
+			return nil
+		}
+		voxel := processor.VoxelSize(tile.Header, depth)
+		if minVoxel == 0 || voxel < minVoxel {
+			minVoxel = voxel
+		}
+		if voxel > maxVoxel {
+			maxVoxel = voxel
+		}
+		if memory := processor.MemoryEstimate(tile.Header, depth); memory > peak {
+			peak = memory
+		}
+		d, from, ok := m.calibration.Estimate(tile.Header.PointCount, depth)
+		timed = timed && ok
+		extrapolated = extrapolated || from != depth
+		runtime += d
+	}
+
+	voxel := formatLength(maxVoxel)
+	if formatLength(minVoxel) != voxel {
+		voxel = formatLength(minVoxel) + "–" + voxel
+	}
+	lines := []string{
+		s.Text.Render("Impact:"),
+		s.TextMuted.Render(" Voxel:   " + voxel),
+		s.TextMuted.Render(" Memory:  ≈ " + humanize.Bytes(peak) + " peak"),
+	}
+	if !timed {
+		return append(lines, s.TextMuted.Render(" Runtime: no earlier runs to go by"))
+	}
+	runs := 0
+	for _, speed := range m.calibration {
+		runs += speed.Runs
+	}
+	basis := fmt.Sprintf("%d earlier run(s)", runs)
+	if extrapolated {
+		basis += ", other depths"
+	}
+	return append(lines,
+		s.TextMuted.Render(" Runtime: ≈ "+humanize.Duration(runtime)),
+		s.TextMuted.Render("          "+basis))
+}
+
+// formatLength formats a length in meters, e.g. 4.2 cm or 1.5 m
+func formatLength(meters float64) string {
+	switch {
+	case meters >= 1:
+		return fmt.Sprintf("%.1f m", meters)
+	case meters >= 0.01:
+		return fmt.Sprintf("%.1f cm", meters*100)
+	default:
+		return fmt.Sprintf("%.1f mm", meters*1000)
+	}
+}
@@ -0,0 +1,91 @@
+package tui
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cloudcompare-automation/internal/las"
+	"github.com/cloudcompare-automation/internal/processor"
+)
+
+// cachedHeader is the header of a LAS file as of its size and
+// modification time
+type cachedHeader struct {
+	size    int64
+	modTime time.Time
+	header  las.Header
+}
+
+// lasCache keeps the headers of the LAS files the Configuration screen has
+// shown, so editing the patterns or coming back to a directory doesn't read
+// the headers of a large or remote directory again. Directories are listed
+// as a run lists them, every time; a header is read again once its file's
+// size or modification time changes.
+type lasCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedHeader
+}
+
+func newLASCache() *lasCache {
+	return &lasCache{entries: make(map[string]cachedHeader)}
+}
+
+// list returns the absolute paths of the LAS files in dir, sorted by name
+func (c *lasCache) list(dir string) ([]string, error) {
+	files, _, err := processor.DiscoverLASFiles(dir, nil, nil)
+	return files, err
+}
+
+// headers returns the headers of the LAS files in dir, by path, reading
+// those not cached yet. Files whose header can't be read are left out.
+// A nil cache reads every header.
+func (c *lasCache) headers(dir string) (map[string]las.Header, error) {
+	files, err := c.list(dir)
+	if err != nil {
+		return nil, err
+	}
+	headers := make(map[string]las.Header, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		if h, ok := c.lookup(file, info); ok {
+			headers[file] = h
+			continue
+		}
+		// Read without the lock, so counting doesn't wait for headers
+		h, err := las.ReadHeader(file)
+		if err != nil {
+			continue
+		}
+		c.store(file, info, h)
+		headers[file] = h
+	}
+	return headers, nil
+}
+
+// lookup returns the cached header of file if it hasn't changed since
+func (c *lasCache) lookup(file string, info os.FileInfo) (las.Header, bool) {
+	if c == nil {
+		return las.Header{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[file]
+	if !ok || entry.size != info.Size() || !entry.modTime.Equal(info.ModTime()) {
+		return las.Header{}, false
+	}
+	return entry.header, true
+}
+
+// store caches the header of file as of info
+func (c *lasCache) store(file string, info os.FileInfo, h las.Header) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[file] = cachedHeader{size: info.Size(), modTime: info.ModTime(), header: h}
+}
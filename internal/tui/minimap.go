@@ -0,0 +1,228 @@
+package tui
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/cloudcompare-automation/internal/las"
+	"github.com/cloudcompare-automation/internal/processor"
+)
+
+// tileBounds is the horizontal bounding box of one LAS file
+type tileBounds struct {
+	Name                   string
+	MinX, MaxX, MinY, MaxY float64
+	Header                 las.Header // For the impact estimates
+}
+
+func (t tileBounds) size() float64    { return math.Max(t.MaxX-t.MinX, t.MaxY-t.MinY) }
+func (t tileBounds) centerX() float64 { return (t.MinX + t.MaxX) / 2 }
+func (t tileBounds) centerY() float64 { return (t.MinY + t.MaxY) / 2 }
+
+// boundsLoadedMsg carries the tile bounds read from the LAS headers of a
+// selection of files
+type boundsLoadedMsg struct {
+	key   string
+	tiles []tileBounds
+}
+
+// loadBounds reads the LAS headers of the files in dir selected by the
+// include and exclude patterns. Files whose header can't be read are left
+// out of the map.
+func loadBounds(cache *lasCache, key, dir string, include, exclude []string) tea.Cmd {
+	return func() tea.Msg {
+		headers, _ := cache.headers(dir)
+		var files []string
+		for file := range headers {
+			files = append(files, file)
+		}
+		sort.Strings(files)
+		files, _ = processor.SplitFiles(files, include, exclude)
+		var tiles []tileBounds
+		for _, file := range files {
+			h := headers[file]
+			tiles = append(tiles, tileBounds{
+				Name: filepath.Base(file),
+				MinX: h.MinX, MaxX: h.MaxX,
+				MinY: h.MinY, MaxY: h.MaxY,
+				Header: h,
+			})
+		}
+		return boundsLoadedMsg{key: key, tiles: tiles}
+	}
+}
+
+// refreshBounds starts loading the tile bounds when the Configuration
+// screen shows a directory or patterns they weren't loaded for
+func refreshBounds(model tea.Model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	m, ok := model.(Model)
+	if !ok || m.screen != ScreenParams {
+		return model, cmd
+	}
+	dir := m.inputDir()
+	if !m.dirUsable(dir) {
+		return model, cmd
+	}
+	include := m.inputs[FocusInclude].Value()
+	exclude := m.inputs[FocusExclude].Value()
+	key := strings.Join([]string{dir, include, exclude}, "\x00")
+	if key == m.boundsKey {
+		return model, cmd
+	}
+	m.boundsKey = key
+	m.tiles = nil
+	return m, tea.Batch(cmd, loadBounds(m.lasFiles, key, dir, processor.ParsePatterns(include), processor.ParsePatterns(exclude)))
+}
+
+// tilePoints returns the point count of the tiles from their headers
+func (m Model) tilePoints() uint64 {
+	var points uint64
+	for _, t := range m.tiles {
+		points += t.Header.PointCount
+	}
+	return points
+}
+
+// splitOutliers separates tiles lying far from the rest, typically a file
+// in the wrong coordinate system, which would squash the map into a corner
+func splitOutliers(tiles []tileBounds) (inliers, outliers []tileBounds) {
+	if len(tiles) < 3 {
+		return tiles, nil
+	}
+	median := func(value func(tileBounds) float64) float64 {
+		values := make([]float64, len(tiles))
+		for i, t := range tiles {
+			values[i] = value(t)
+		}
+		sort.Float64s(values)
+		return values[len(values)/2]
+	}
+	cx, cy := median(tileBounds.centerX), median(tileBounds.centerY)
+	size := median(tileBounds.size)
+
+	// A regular grid of n tiles spans about sqrt(n) tiles each way
+	reach := size * (2*math.Sqrt(float64(len(tiles))) + 10)
+	for _, t := range tiles {
+		if math.Abs(t.centerX()-cx) > reach || math.Abs(t.centerY()-cy) > reach {
+			outliers = append(outliers, t)
+		} else {
+			inliers = append(inliers, t)
+		}
+	}
+	return inliers, outliers
+}
+
+// countOverlaps counts tile pairs whose overlap covers more than 1% of the
+// smaller tile; neighbouring tiles that only share an edge don't count
+func countOverlaps(tiles []tileBounds) int {
+	count := 0
+	for i := range tiles {
+		for j := i + 1; j < len(tiles); j++ {
+			a, b := tiles[i], tiles[j]
+			w := math.Min(a.MaxX, b.MaxX) - math.Max(a.MinX, b.MinX)
+			h := math.Min(a.MaxY, b.MaxY) - math.Max(a.MinY, b.MinY)
+			if w <= 0 || h <= 0 {
+				continue
+			}
+			smaller := math.Min((a.MaxX-a.MinX)*(a.MaxY-a.MinY), (b.MaxX-b.MinX)*(b.MaxY-b.MinY))
+			if w*h > smaller*0.01 {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// renderMinimap draws a top-down map of the tiles, north up: ▒ is covered
+// by one tile, █ by overlapping tiles and · is a gap inside the survey
+func renderMinimap(tiles []tileBounds, width, height int) []string {
+	if len(tiles) == 0 || width < 4 || height < 2 {
+		return nil
+	}
+
+	minX, maxX := tiles[0].MinX, tiles[0].MaxX
+	minY, maxY := tiles[0].MinY, tiles[0].MaxY
+	for _, t := range tiles[1:] {
+		minX, maxX = math.Min(minX, t.MinX), math.Max(maxX, t.MaxX)
+		minY, maxY = math.Min(minY, t.MinY), math.Max(maxY, t.MaxY)
+	}
+
+	// Terminal cells are about twice as tall as wide, so a row covers
+	// twice the distance of a column
+	spanX, spanY := math.Max(maxX-minX, 1e-9), math.Max(maxY-minY, 1e-9)
+	cell := math.Max(spanX/float64(width), spanY/float64(height)/2)
+	cols := max(1, int(math.Ceil(spanX/cell)))
+	rows := max(1, int(math.Ceil(spanY/(cell*2))))
+	cols, rows = min(cols, width), min(rows, height)
+
+	coverage := make([][]int, rows)
+	for r := range coverage {
+		coverage[r] = make([]int, cols)
+	}
+	// A tile covers the cells whose centre it contains, so tiles sharing an
+	// edge don't show as overlapping; a tile smaller than a cell marks the
+	// cell its own centre falls in
+	for _, t := range tiles {
+		marked := false
+		for r := clampCell(int((maxY-t.MaxY)/(cell*2)), rows); r <= clampCell(int((maxY-t.MinY)/(cell*2)), rows); r++ {
+			y := maxY - (float64(r)+0.5)*cell*2
+			for c := clampCell(int((t.MinX-minX)/cell), cols); c <= clampCell(int((t.MaxX-minX)/cell), cols); c++ {
+				x := minX + (float64(c)+0.5)*cell
+				if x >= t.MinX && x < t.MaxX && y > t.MinY && y <= t.MaxY {
+					coverage[r][c]++
+					marked = true
+				}
+			}
+		}
+		if !marked {
+			r := clampCell(int((maxY-t.centerY())/(cell*2)), rows)
+			c := clampCell(int((t.centerX()-minX)/cell), cols)
+			coverage[r][c]++
+		}
+	}
+
+	lines := make([]string, rows)
+	for r, row := range coverage {
+		var b strings.Builder
+		for _, n := range row {
+			switch {
+			case n == 0:
+				b.WriteRune('·')
+			case n == 1:
+				b.WriteRune('▒')
+			default:
+				b.WriteRune('█')
+			}
+		}
+		lines[r] = b.String()
+	}
+	return lines
+}
+
+// clampCell keeps a cell index inside [0, n)
+func clampCell(i, n int) int {
+	if i < 0 {
+		return 0
+	}
+	if i >= n {
+		return n - 1
+	}
+	return i
+}
+
+// minimapSummary describes outliers and overlaps below the map
+func minimapSummary(outliers []tileBounds, overlaps int) []string {
+	var lines []string
+	for _, t := range outliers {
+		lines = append(lines, fmt.Sprintf("⚠ %s is far from the other tiles", t.Name))
+	}
+	if overlaps > 0 {
+		lines = append(lines, fmt.Sprintf("%d overlapping tile pair(s)", overlaps))
+	}
+	return lines
+}
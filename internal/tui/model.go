@@ -6,15 +6,24 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 
+	"github.com/cloudcompare-automation/internal/descriptor"
+	"github.com/cloudcompare-automation/internal/history"
+	"github.com/cloudcompare-automation/internal/humanize"
+	"github.com/cloudcompare-automation/internal/lock"
 	"github.com/cloudcompare-automation/internal/processor"
+	"github.com/cloudcompare-automation/internal/report"
+	"github.com/cloudcompare-automation/internal/session"
 )
 
 // Step-specific spinner frames for visual variety
@@ -34,6 +43,120 @@ var (
 	sparkles = []string{"✨", "⭐", "💫", "✨"}
 )
 
+// defaultSteps are the steps of process_las_files.py, shown until a
+// script announces its own, as it does them
+var defaultSteps = []processor.StepLabel{
+	{Name: "Loading point cloud", Short: "Load", Icon: "📂"},
+	{Name: "Computing normals", Short: "Normals", Icon: "🧭"},
+	{Name: "Converting to DIP", Short: "DIP", Icon: "🔄"},
+	{Name: "Poisson reconstruction", Short: "Poisson", Icon: "🔺"},
+	{Name: "Saving project", Short: "Save", Icon: "💾"},
+}
+
+// stepKind gives steps whose name contains keyword a spinner, and the
+// time they usually take for steps that don't report their progress
+type stepKind struct {
+	keyword  string
+	frames   []string
+	expected float64 // Seconds
+}
+
+// stepKinds are matched in order against lowercase step names
+var stepKinds = []stepKind{
+	{"load", loadingFrames, 5},
+	{"normal", normalFrames, 60},
+	{"dip", dipFrames, 2},
+	{"poisson", meshFrames, 300}, // Poisson takes long
+	{"mesh", meshFrames, 300},
+	{"sav", saveFrames, 10},
+}
+
+// kindOfStep returns the kind of the named step
+func kindOfStep(name string) stepKind {
+	name = strings.ToLower(name)
+	for _, kind := range stepKinds {
+		if strings.Contains(name, kind.keyword) {
+			return kind
+		}
+	}
+	return stepKind{frames: pulseFrames, expected: 30}
+}
+
+// shortStepName returns the name of a step for the compact layout: the
+// one the script gave, or the first word
+func shortStepName(step processor.StepLabel) string {
+	if step.Short != "" {
+		return step.Short
+	}
+	if first, _, ok := strings.Cut(step.Name, " "); ok {
+		return first
+	}
+	return step.Name
+}
+
+// stepLabel returns the name of a step for the full layout, after its
+// icon; icons wider than two cells would break the layout and are left
+// out
+func stepLabel(step processor.StepLabel) string {
+	if w := lipgloss.Width(step.Icon); w > 0 && w <= 2 {
+		return step.Icon + " " + step.Name
+	}
+	return step.Name
+}
+
+// scriptSteps returns the steps of the running script: those it
+// announced, or the default pipeline's
+func (m Model) scriptSteps() []processor.StepLabel {
+	if len(m.steps) > 0 {
+		return m.steps
+	}
+	return defaultSteps
+}
+
+// countSteps adjusts the script's steps to the number its step lines
+// count, for scripts that don't announce them
+func (m Model) countSteps(total int) Model {
+	steps := m.scriptSteps()
+	if len(steps) == total {
+		return m
+	}
+	m.steps = make([]processor.StepLabel, total)
+	for i := range m.steps {
+		if i < len(steps) {
+			m.steps[i] = steps[i]
+		} else {
+			m.steps[i] = processor.StepLabel{Name: fmt.Sprintf("Step %d", i+1)}
+		}
+	}
+	return m
+}
+
+// pipelineSteps returns the steps shown in the pipeline view: the
+// script's steps followed by the web export and post-processing commands
+func (m Model) pipelineSteps() []processor.StepLabel {
+	steps := append([]processor.StepLabel(nil), m.scriptSteps()...)
+	for _, name := range m.params.ExtraSteps() {
+		steps = append(steps, processor.StepLabel{Name: name})
+	}
+	return steps
+}
+
+// currentStepKind returns the kind of the step in progress
+func (m Model) currentStepKind() stepKind {
+	steps := m.pipelineSteps()
+	if m.currentStepNum < 1 || m.currentStepNum > len(steps) {
+		return kindOfStep("")
+	}
+	return kindOfStep(steps[m.currentStepNum-1].Name)
+}
+
+// Terminal size thresholds
+const (
+	minWidth      = 60 // Below this the layouts overlap
+	minHeight     = 16
+	compactHeight = 30 // Below this the processing screen uses the compact layout
+)
+
 // Screen represents the current view in the TUI
 type Screen int
 
@@ -43,6 +166,11 @@ const (
 	ScreenParams
 	ScreenProcessing
 	ScreenResults
+	ScreenHistory
+	ScreenStats
+	ScreenPreview
+	ScreenFiles
+	ScreenDelivery
 )
 
 // FocusedField represents which form field is currently focused
@@ -51,20 +179,76 @@ type FocusedField int
 const (
 	FocusInputDir FocusedField = iota
 	FocusOutputSubdir
-	FocusKNN
-	FocusOctreeDepth
-	FocusSamplesPerNode
-	FocusPointWeight
-	FocusBoundaryType
-	FocusStartButton
-	FocusFieldCount
+	FocusInclude
+	FocusExclude
+	FocusWebExport
+	FocusMetadata // First run metadata field, in processor.MetadataFields order
 )
 
+// FocusLabels is the run labels field, after the metadata fields
+var FocusLabels = FocusMetadata + FocusedField(len(processor.MetadataFields))
+
+// FocusParams is the first pipeline parameter; the others follow in schema order
+var FocusParams = FocusLabels + 1
+
+// The pipeline selector and start button follow the parameter inputs, so
+// their positions depend on the selected pipeline's schema: see
+// pipelineField and startField.
+
+// Options configures how the TUI is presented
+type Options struct {
+	// Inline renders without the alternate screen: log lines are printed
+	// into the terminal scrollback and the live view is kept compact
+	Inline bool
+
+	// Params are the initial processing parameters, including settings
+	// from the config file that have no form field (environment, priority)
+	Params processor.Params
+
+	// RestoreForm fills the form as it was left when the TUI last ran,
+	// and saves it as it changes
+	RestoreForm bool
+
+	// Pipelines are the selectable pipeline scripts; the first is the default
+	Pipelines []processor.Pipeline
+
+	// Version is the version of the binary, recorded in run descriptors
+	Version string
+
+	// Descriptor is a run to repeat: the Configuration screen opens with
+	// its settings and files
+	Descriptor *descriptor.Descriptor
+
+	// Palette names the colors and icons, see NewStyles
+	Palette string
+
+	// Accessible runs inline without colors, animations or decorative
+	// glyphs, printing changes of state as lines for screen readers
+	Accessible bool
+
+	// Corporate renders plain ASCII without animations or celebrations
+	Corporate bool
+
+	// DeliveryChecklist opens the delivery checklist after every batch,
+	// see delivery.go
+	DeliveryChecklist bool
+
+	// Observe only watches background sessions, see observe.go; with
+	// ObserveSession it waits for that one instead of any
+	Observe        bool
+	ObserveSession string
+}
+
 // Model represents the main application state
 type Model struct {
 	// Current screen
 	screen Screen
 
+	// Presentation options
+	inline     bool
+	accessible bool
+	corporate  bool
+
 	// Styling
 	styles Styles
 
@@ -74,10 +258,13 @@ type Model struct {
 
 	// File browser state
 	currentDir   string
-	entries      []os.DirEntry
+	entries      []dirEntry
+	entriesDir   string // Directory entries were read from; currentDir while loading
 	cursor       int
 	selectedDir  string
 	browseScroll int
+	browseFilter textinput.Model // Shows only directories containing its text
+	showHidden   bool            // Lists dot directories too
 
 	// Form inputs
 	inputs       []textinput.Model
@@ -86,8 +273,54 @@ type Model struct {
 	// Parameters
 	params processor.Params
 
+	// Selectable pipeline scripts and the chosen one
+	pipelines   []processor.Pipeline
+	pipelineIdx int
+
+	// Tile bounds of the selected input files for the minimap
+	tiles     []tileBounds
+	boundsKey string
+
+	// LAS files the Configuration screen selects, counted in the background
+	selection    selection
+	selectionKey string
+
+	// LAS files and headers of the directories shown, shared by the
+	// background loaders
+	lasFiles *lasCache
+
+	// Files listed on the Files screen to process instead of the input
+	// directory, and their checks
+	fileList   textarea.Model
+	fileChecks filesCheckedMsg
+
+	// Confirmation shown on the Configuration screen until the next key
+	notice string
+
+	// Version of the binary, and the imported run being repeated with
+	// what differs from it
+	version       string
+	imported      *descriptor.Descriptor
+	importDiffs   []string
+	importChecked bool
+
+	// Input checks of a run about to start, done in the background
+	preparing bool
+
+	// Preview of the selected files: the files, the shown one and its
+	// sampled points
+	previewFiles []string
+	previewIdx   int
+	previewIso   bool
+	preview      cloudPreview
+	previewErr   error
+
+	// Processing speed of earlier runs for the runtime estimate
+	calibration history.Calibration
+
 	// Processing state
 	processor   *processor.Processor
+	source      logSource
 	processing  bool
 	logs        []processor.LogEntry
 	logScroll   int
@@ -101,6 +334,9 @@ type Model struct {
 	meshFaces   string
 	filesTotal  int
 	filesDone   int
+	fileOutcomes map[string]processor.Outcome // Outcome of each finished file, by path
+	stalledSince time.Time // Since when the script has printed nothing, if warned about
+	transfers    []processor.Transfer // Prefetches in progress, see transferLine
 	startTime   time.Time
 	elapsedTime time.Duration
 
@@ -108,18 +344,147 @@ type Model struct {
 	animFrame    int
 	animTick     int
 	particlePos  int
-	completedSteps []bool
+	steps        []processor.StepLabel // Steps the script announced, see scriptSteps
 	stepStartTime time.Time
+	stepPercent   int // Progress the current step reports, or -1
 	celebrating  bool
 	celebrateFrame int
+	animating    bool // An animation frame is scheduled, see nextAnimTick
+	unfocused    bool // The terminal reported losing focus
+
+	// Whether the processing screen shows only the log, kept for later
+	// runs and saved with the form
+	rawLog bool
 
 	// Results
 	result processor.ProcessingResult
 
+	// Results screen file table: its order, page, the failed-only filter,
+	// and whether the log is shown instead
+	resultsOrder      resultOrder
+	resultsPage       int
+	resultsFailedOnly bool
+	resultsShowLog    bool
+
+	// File reviews on the results screen: the selected file, the note
+	// input, the notes and sign-offs by input file, and why the last one
+	// wasn't saved
+	resultsCursor int
+	reviewNote    textinput.Model
+	reviews       map[string]report.Review
+	reviewErr     error
+
+	// Network input directory checks: the path being checked, the last one
+	// found reachable, the last one that wasn't and why, and whether to
+	// start processing once the check succeeds
+	dirChecking     string
+	dirReachable    string
+	dirFailed       string
+	dirCheckErr     error
+	startAfterCheck bool
+
+	// Project configuration: the input directory it was last looked up
+	// for, the file in effect, the form values it replaced and the
+	// parameters before it, and why it couldn't be read
+	projectDir       string
+	projectPath      string
+	projectOverrides []projectOverride
+	projectBefore    processor.Params
+	projectErr       error
+
+	// History screen: recorded runs, the selected one, the label filter
+	// and the label editor
+	runs          []history.Entry
+	historyCursor int
+	historyFilter textinput.Model
+	historyLabels textinput.Model
+
+	// A batch left running in the background by an earlier instance
+	runningSession *session.State
+
+	// The delivery checklist of the finished run, see delivery.go
+	deliveryChecklist bool
+	delivery          []deliveryItem
+	deliveryTicked    []bool
+	deliveryCursor    int
+	deliveryErr       error
+	delivered         *history.Delivery
+
+	// Observer mode, the session it waits for, and the one it followed last
+	observer  bool
+	observeID string
+	observed  string
+
+	// The parameters of the last run started, and whether the Welcome
+	// screen asks to run them again
+	lastRun         *processor.Params
+	quickRunConfirm bool
+
+	// The latest recorded runs listed on the Welcome screen, the one
+	// selected, and the settings of the one it asks to run again, see
+	// recent.go
+	recentRuns   []history.Entry
+	recentCursor int
+	recentErr    error
+	relaunch     *processor.Params
+
+	// Whether the form is saved as it changes, the form as last saved or
+	// scheduled to be, and the number of the latest change, see
+	// refreshFormSave
+	autosave  bool
+	formSaved formState
+	formSeq   int
+
+	// Form states ctrl+z goes back to, newest last, and the field being
+	// typed into, whose keys are undone together
+	undo       []formState
+	undoTyping string
+
+	// The form as the Configuration screen was opened with, and whether
+	// Esc asked to confirm dropping the changes since
+	formOpened     formState
+	confirmDiscard bool
+
 	// Error message
 	err error
 }
 
+// logSource is where the processing screen reads progress from: a
+// background session journal, or an in-process processor as a fallback
+type logSource interface {
+	LogChan() <-chan processor.LogEntry
+	ResultChan() <-chan processor.ProcessingResult
+	Stop()
+}
+
+// Shutdown is called after the program exits. A background session keeps
+// running; in-process processing is stopped so no orphaned Python is left.
+func (m Model) Shutdown() {
+	if m.formSeq > 0 {
+		m.formSaved.save()
+	}
+	switch source := m.source.(type) {
+	case *session.Follower:
+		source.Detach()
+	case *processor.Processor:
+		if source.IsRunning() {
+			source.Stop()
+		}
+	}
+}
+
+// detachable reports whether processing runs in a background session that
+// can be left running when the TUI exits
+func (m Model) detachable() bool {
+	_, ok := m.source.(*session.Follower)
+	return ok && m.processing
+}
+
+// runningSessionMsg reports a background session found at startup
+type runningSessionMsg struct {
+	state *session.State
+}
+
 // LogMsg is sent when a new log entry is received
 type LogMsg processor.LogEntry
 
@@ -135,12 +500,15 @@ type PollLogsMsg struct{}
 // AnimTickMsg triggers animation updates
 type AnimTickMsg time.Time
 
-// New creates a new Model with default settings
-func New() Model {
-	styles := DefaultStyles()
+// New creates a new Model with the given options
+func New(opts Options) Model {
+	styles := NewStyles(opts.Palette)
+	if opts.Accessible || opts.Corporate {
+		styles.Icons = plainIcons
+	}
 
 	// Initialize text inputs
-	inputs := make([]textinput.Model, FocusFieldCount-1) // -1 because button isn't an input
+	inputs := make([]textinput.Model, FocusParams)
 
 	// Input directory
 	inputs[FocusInputDir] = textinput.New()
@@ -154,86 +522,171 @@ func New() Model {
 	inputs[FocusOutputSubdir].CharLimit = 256
 	inputs[FocusOutputSubdir].Width = 20
 
-	// KNN
-	inputs[FocusKNN] = textinput.New()
-	inputs[FocusKNN].Placeholder = "6"
-	inputs[FocusKNN].CharLimit = 4
-	inputs[FocusKNN].Width = 10
-
-	// Octree depth
-	inputs[FocusOctreeDepth] = textinput.New()
-	inputs[FocusOctreeDepth].Placeholder = "11"
-	inputs[FocusOctreeDepth].CharLimit = 4
-	inputs[FocusOctreeDepth].Width = 10
-
-	// Samples per node
-	inputs[FocusSamplesPerNode] = textinput.New()
-	inputs[FocusSamplesPerNode].Placeholder = "1.5"
-	inputs[FocusSamplesPerNode].CharLimit = 6
-	inputs[FocusSamplesPerNode].Width = 10
-
-	// Point weight
-	inputs[FocusPointWeight] = textinput.New()
-	inputs[FocusPointWeight].Placeholder = "2.0"
-	inputs[FocusPointWeight].CharLimit = 6
-	inputs[FocusPointWeight].Width = 10
-
-	// Boundary type
-	inputs[FocusBoundaryType] = textinput.New()
-	inputs[FocusBoundaryType].Placeholder = "2"
-	inputs[FocusBoundaryType].CharLimit = 1
-	inputs[FocusBoundaryType].Width = 10
+	// File name patterns, prefilled from the config file
+	inputs[FocusInclude] = textinput.New()
+	inputs[FocusInclude].Placeholder = "all LAS files"
+	inputs[FocusInclude].CharLimit = 256
+	inputs[FocusInclude].Width = 20
+	inputs[FocusInclude].SetValue(strings.Join(opts.Params.Include, ", "))
+
+	inputs[FocusExclude] = textinput.New()
+	inputs[FocusExclude].Placeholder = "e.g. *_preview.las"
+	inputs[FocusExclude].CharLimit = 256
+	inputs[FocusExclude].Width = 20
+	inputs[FocusExclude].SetValue(strings.Join(opts.Params.Exclude, ", "))
+
+	inputs[FocusWebExport] = textinput.New()
+	inputs[FocusWebExport].Placeholder = "none, potree or 3dtiles"
+	inputs[FocusWebExport].CharLimit = 16
+	inputs[FocusWebExport].Width = 20
+	inputs[FocusWebExport].SetValue(opts.Params.WebExport)
+
+	// Run metadata, prefilled from the config file
+	for i, field := range processor.MetadataFields {
+		input := textinput.New()
+		input.Placeholder = "optional"
+		input.CharLimit = 128
+		input.Width = 20
+		input.SetValue(opts.Params.Metadata[field.Key])
+		inputs[FocusMetadata+FocusedField(i)] = input
+	}
+
+	// Run labels, comma separated
+	inputs[FocusLabels] = textinput.New()
+	inputs[FocusLabels].Placeholder = "e.g. delivery-v2"
+	inputs[FocusLabels].CharLimit = 128
+	inputs[FocusLabels].Width = 20
+	inputs[FocusLabels].SetValue(strings.Join(opts.Params.Labels, ", "))
+
+	// Pipeline parameters, from the default pipeline's schema
+	schema := processor.DefaultSchema
+	if len(opts.Pipelines) > 0 {
+		schema = opts.Pipelines[0].Params
+	}
+	inputs = append(inputs, newParamInputs(schema)...)
 
 	// Get current directory
 	cwd, _ := os.Getwd()
 
-	// Initialize progress bar
-	prog := progress.New(progress.WithDefaultGradient())
+	// Initialize progress bar; a gradient blended from 16 colors comes
+	// out striped, so those get a solid bar, as does corporate mode
+	fill := progress.WithDefaultGradient()
+	if lipgloss.ColorProfile() == termenv.ANSI || opts.Corporate {
+		fill = progress.WithSolidFill(string(styles.Colors.Primary))
+	}
+	prog := progress.New(fill, progress.WithColorProfile(lipgloss.ColorProfile()))
 
 	// Initialize spinner
 	spin := spinner.New()
 	spin.Spinner = spinner.Dot
+	if opts.Corporate {
+		spin.Spinner = spinner.Line
+	}
 	spin.Style = styles.Spinner
 
-	return Model{
+	m := Model{
 		screen:       ScreenWelcome,
+		inline:       opts.Inline || opts.Accessible,
+		accessible:   opts.Accessible,
+		corporate:    opts.Corporate,
 		styles:       styles,
 		currentDir:   cwd,
 		selectedDir:  cwd,
 		inputs:       inputs,
 		focusedField: FocusInputDir,
-		params:       processor.DefaultParams(),
+		params:       opts.Params,
+		pipelines:    opts.Pipelines,
+		browseFilter: newHistoryInput("name"),
+		historyFilter: newHistoryInput("label"),
+		historyLabels: newHistoryInput("comma separated"),
+		reviewNote:    newReviewInput(),
+		lasFiles:     newLASCache(),
+		fileList:     newFileList(),
 		maxLogs:      500,
 		logs:         make([]processor.LogEntry, 0),
 		progress:     prog,
 		spinner:      spin,
 		width:        80,
 		height:       24,
-		completedSteps: make([]bool, 5),
+		version:      opts.Version,
+		deliveryChecklist: opts.DeliveryChecklist,
+		observer:     opts.Observe,
+		observeID:    opts.ObserveSession,
 	}
+
+	// An imported run opens on the Configuration screen with its settings;
+	// otherwise the form is as it was left
+	if opts.Descriptor != nil {
+		m = m.applyParams(opts.Descriptor.Params)
+		m.imported = opts.Descriptor
+		m = m.openForm()
+		m.inputs[FocusInputDir].Focus()
+	} else if opts.RestoreForm {
+		m = m.restoreForm(loadFormState())
+	}
+	m.autosave = opts.RestoreForm
+	m.lastRun = loadLastRun()
+	return m
 }
 
 // Init implements tea.Model
 func (m Model) Init() tea.Cmd {
+	checkSessions := findRunningSession
+	if m.observer {
+		checkSessions = findObservedSession(m.observeID)
+	}
 	return tea.Batch(
 		m.spinner.Tick,
 		m.loadDirectory(m.currentDir),
+		checkSessions,
+		loadCalibration,
+		loadRecent,
+		m.startupChecks(),
 	)
 }
 
+// startupChecks compares an imported run with the original and checks
+// its or the restored form's listed files
+func (m Model) startupChecks() tea.Cmd {
+	if m.imported == nil {
+		if m.fileList.Value() != "" {
+			return checkFiles(m.fileList.Value())
+		}
+		return nil
+	}
+	return tea.Batch(checkImport(*m.imported, m.version), checkFiles(m.fileList.Value()))
+}
+
+// findRunningSession looks for a batch left running by an earlier instance
+func findRunningSession() tea.Msg {
+	running, err := session.Running()
+	if err != nil || len(running) == 0 {
+		return runningSessionMsg{}
+	}
+	return runningSessionMsg{state: &running[0]}
+}
+
 // Update implements tea.Model
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 
+	if focused, ok := focusReport(msg); ok {
+		return m.setFocus(focused)
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.observer {
+			return m.updateObserver(msg)
+		}
+
 		// Global key handlers
 		switch msg.String() {
 		case "ctrl+c":
 			if m.processing {
 				// Cancel processing
-				if m.processor != nil {
-					m.processor.Stop()
+				if m.source != nil {
+					m.source.Stop()
 				}
 				m.processing = false
 				m.elapsedTime = time.Since(m.startTime)
@@ -242,36 +695,59 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Quit
 
 		case "q":
-			if !m.processing && m.screen != ScreenParams {
+			if !m.processing && m.screen != ScreenParams && m.screen != ScreenFiles && !m.historyTyping() && !m.browseFilter.Focused() && !m.reviewTyping() {
 				return m, tea.Quit
 			}
 
 		case "esc":
 			switch m.screen {
 			case ScreenFileBrowser:
+				// Esc clears a filter before it leaves the browser
+				if m.browseFilter.Focused() || m.browseFilter.Value() != "" {
+					break
+				}
 				m.screen = ScreenParams
 				return m, nil
 			case ScreenParams:
-				m.screen = ScreenWelcome
-				return m, nil
+				return refreshFormSave(refreshParams(m.leaveForm(), nil))
 			case ScreenResults:
+				// Esc cancels a note before it leaves the results
+				if m.reviewTyping() {
+					break
+				}
 				m.screen = ScreenWelcome
-				return m, nil
+				return m, loadRecent
 			}
 		}
 
 		// Screen-specific key handlers
 		switch m.screen {
 		case ScreenWelcome:
-			return m.updateWelcome(msg)
+			return refreshFormSave(refreshParams(m.updateWelcome(msg)))
 		case ScreenFileBrowser:
-			return m.updateFileBrowser(msg)
+			before := m.formState()
+			model, cmd := m.updateFileBrowser(msg)
+			return refreshFormSave(refreshParams(m.recordUndo(before, msg, model), cmd))
 		case ScreenParams:
-			return m.updateParams(msg)
+			before := m.formState()
+			model, cmd := m.updateParams(msg)
+			return refreshFormSave(refreshParams(m.recordUndo(before, msg, model), cmd))
 		case ScreenProcessing:
-			return m.updateProcessing(msg)
+			return refreshFormSave(m.updateProcessing(msg))
 		case ScreenResults:
 			return m.updateResults(msg)
+		case ScreenHistory:
+			return m.updateHistory(msg)
+		case ScreenStats:
+			return m.updateStats(msg)
+		case ScreenPreview:
+			return m.updatePreview(msg)
+		case ScreenFiles:
+			before := m.formState()
+			model, cmd := m.updateFiles(msg)
+			return refreshFormSave(m.recordUndo(before, msg, model), cmd)
+		case ScreenDelivery:
+			return m.updateDelivery(msg)
 		}
 
 	case tea.WindowSizeMsg:
@@ -281,7 +757,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case spinner.TickMsg:
-		if m.processing {
+		if (m.processing || m.dirChecking != "" || m.preparing) && !m.unfocused {
 			var cmd tea.Cmd
 			m.spinner, cmd = m.spinner.Update(msg)
 			return m, cmd
@@ -303,10 +779,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			}
 
-			return m, tea.Tick(time.Millisecond*80, func(t time.Time) tea.Msg {
-				return AnimTickMsg(t)
-			})
+			return m.nextAnimTick()
 		}
+		m.animating = false
 		return m, nil
 
 	case progress.FrameMsg:
@@ -315,6 +790,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, cmd
 
 	case LogMsg:
+		m = m.noteTransfer(processor.LogEntry(msg))
+		if processor.LogEntry(msg).IsProgress() {
+			return m, nil
+		}
 		m.logs = append(m.logs, processor.LogEntry(msg))
 		if len(m.logs) > m.maxLogs {
 			m.logs = m.logs[1:]
@@ -327,15 +806,16 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.currentFile = strings.TrimPrefix(msg.Message, "Processing: ")
 			m.currentFile = strings.TrimSpace(m.currentFile)
 		}
-		if msg.Level == processor.LogSuccess && strings.Contains(msg.Message, "Successfully processed:") {
-			m.filesDone++
+		if msg.Outcome != "" {
+			m = m.fileFinished(processor.LogEntry(msg))
 		}
+		m = m.noteSilence(processor.LogEntry(msg))
 
 		return m, nil
 
 	case PollLogsMsg:
-		// Poll for logs from processor
-		if m.processor == nil {
+		// Poll for logs from the session or processor
+		if m.source == nil {
 			return m, nil
 		}
 
@@ -343,10 +823,15 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Drain all available logs
 		for {
 			select {
-			case log, ok := <-m.processor.LogChan():
+			case log, ok := <-m.source.LogChan():
 				if !ok {
-					// Channel closed
-					return m, nil
+					// Closed after the last entry; the result follows
+					goto done
+				}
+				// Transfer progress moves the secondary bar only
+				m = m.noteTransfer(log)
+				if log.IsProgress() {
+					continue
 				}
 				newLogs = append(newLogs, log)
 			default:
@@ -355,6 +840,14 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 	done:
+		// Inline mode prints each log line into the scrollback, and
+		// accessible mode the changes they make, below
+		if m.inline && !m.accessible {
+			for _, log := range newLogs {
+				cmds = append(cmds, tea.Println(m.styles.RenderLogEntry(string(log.Level), log.Message)))
+			}
+		}
+
 		// Process all new logs
 		for _, log := range newLogs {
 			m.logs = append(m.logs, log)
@@ -362,6 +855,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.logs = m.logs[1:]
 			}
 			m.logScroll = len(m.logs) - 1
+			m = m.noteSilence(log)
 
 			// Track current file
 			if strings.Contains(log.Message, "Processing:") {
@@ -373,55 +867,65 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.meshFaces = ""
 			}
 
-			// Track current step [1/5], [2/5], etc.
-			if strings.Contains(log.Message, "[") && strings.Contains(log.Message, "/5]") {
-				// Extract step info like "[1/5] Loading point cloud..."
+			// The script announces its steps for each file, then starts
+			// each with e.g. "[1/5] Loading point cloud..."; post-processing
+			// steps follow the script's
+			if steps, ok := processor.ParseSteps(log.Message); ok {
+				m.steps = steps
+			} else if n, ok := processor.ParsePostStep(log.Message); ok {
 				m.currentStep = log.Message
 				m.stepStartTime = time.Now()
+				m.stepPercent = -1
+				m.currentStepNum = len(m.scriptSteps()) + n
+			} else if n, total, ok := processor.ParseStep(log.Message); ok {
+				m = m.countSteps(total)
+				m.currentStep = log.Message
+				m.stepStartTime = time.Now()
+				m.stepPercent = -1
+				m.currentStepNum = n
+			}
 
-				// Parse step number
-				if strings.Contains(log.Message, "[1/5]") {
-					m.currentStepNum = 1
-				} else if strings.Contains(log.Message, "[2/5]") {
-					m.currentStepNum = 2
-					m.completedSteps[0] = true
-				} else if strings.Contains(log.Message, "[3/5]") {
-					m.currentStepNum = 3
-					m.completedSteps[1] = true
-				} else if strings.Contains(log.Message, "[4/5]") {
-					m.currentStepNum = 4
-					m.completedSteps[2] = true
-				} else if strings.Contains(log.Message, "[5/5]") {
-					m.currentStepNum = 5
-					m.completedSteps[3] = true
-				}
+			// Steps that report their progress replace the estimate
+			if percent, ok := processor.ParseProgress(log.Message); ok {
+				m.stepPercent = percent
 			}
 
 			// Track point count
-			if strings.Contains(log.Message, "Loaded") && strings.Contains(log.Message, "points") {
-				m.pointCount = log.Message
+			if points, ok := processor.ParsePoints(log.Message); ok {
+				m.pointCount = fmt.Sprintf("Loaded %s points", humanize.Count(points))
 			}
 
 			// Track mesh faces
-			if strings.Contains(log.Message, "Mesh created with") {
-				m.meshFaces = log.Message
+			if faces, ok := processor.ParseFaces(log.Message); ok {
+				verb := "created with"
+				if strings.HasPrefix(log.Message, "Mesh trimmed") {
+					verb = "trimmed to"
+				}
+				m.meshFaces = fmt.Sprintf("Mesh %s %s faces", verb, humanize.Count(int64(faces)))
 			}
 
-			if log.Level == processor.LogSuccess && strings.Contains(log.Message, "Successfully processed:") {
-				m.filesDone++
-				m.completedSteps[4] = true
-				m.celebrating = true
-				m.celebrateFrame = 0
+			if log.Outcome != "" {
+				m = m.fileFinished(log)
+				if log.Outcome != processor.OutcomeFailed && !m.corporate {
+					m.celebrating = true
+					m.celebrateFrame = 0
+				}
+			}
+
+			if m.accessible {
+				if line := m.announcement(log); line != "" {
+					cmds = append(cmds, tea.Println(line))
+				}
 			}
 		}
 
 		// Keep polling if still processing
 		if m.processing {
-			return m, tea.Tick(time.Millisecond*50, func(t time.Time) tea.Msg {
+			cmds = append(cmds, tea.Tick(time.Millisecond*50, func(t time.Time) tea.Msg {
 				return PollLogsMsg{}
-			})
+			}))
 		}
-		return m, nil
+		return m, tea.Sequence(cmds...)
 
 	case ProcessingDoneMsg:
 		m.processing = false
@@ -429,16 +933,27 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.result = processor.ProcessingResult(msg)
 
 		// Final drain of logs
-		if m.processor != nil {
+		if m.source != nil {
 			for {
 				select {
-				case log, ok := <-m.processor.LogChan():
+				case log, ok := <-m.source.LogChan():
 					if !ok {
 						goto finaldone
 					}
+					if log.IsProgress() {
+						continue
+					}
 					m.logs = append(m.logs, log)
-					if log.Level == processor.LogSuccess && strings.Contains(log.Message, "Successfully processed:") {
-						m.filesDone++
+					if m.inline && !m.accessible {
+						cmds = append(cmds, tea.Println(m.styles.RenderLogEntry(string(log.Level), log.Message)))
+					}
+					if log.Outcome != "" {
+						m = m.fileFinished(log)
+					}
+					if m.accessible {
+						if line := m.announcement(log); line != "" {
+							cmds = append(cmds, tea.Println(line))
+						}
 					}
 				default:
 					goto finaldone
@@ -454,9 +969,22 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.result.TotalFiles > 0 {
 			m.filesTotal = m.result.TotalFiles
 		}
+		if m.accessible {
+			cmds = append(cmds, tea.Println(m.doneAnnouncement()))
+		}
 
 		m.screen = ScreenResults
-		return m, nil
+		m.resultsPage = 0
+		m.resultsCursor = 0
+		m.resultsFailedOnly = false
+		m.resultsShowLog = false
+		m.reviews = nil
+		m.reviewErr = nil
+		m.delivered = nil
+		if m.deliveryChecklist && !m.observer && m.result.ReportPath != "" {
+			m = m.openDelivery()
+		}
+		return m, tea.Sequence(cmds...)
 
 	case TickMsg:
 		if m.processing {
@@ -467,7 +995,148 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case runningSessionMsg:
+		if m.observer {
+			return m.observe(msg.state)
+		}
+		m.runningSession = msg.state
+		return m, nil
+
+	case formSaveMsg:
+		return m, m.saveForm(msg)
+
+	case calibrationLoadedMsg:
+		m.calibration = msg.calibration
+		return m, nil
+
+	case recentLoadedMsg:
+		return m.recentLoaded(msg), nil
+
+	case recentOpenedMsg:
+		return m.recentOpened(msg), nil
+
+	case relaunchMsg:
+		return m.relaunched(msg), nil
+
+	case historyLoadedMsg:
+		m.runs = msg.runs
+		m.err = msg.err
+		m.historyCursor = min(m.historyCursor, max(len(m.visibleRuns())-1, 0))
+		return m, nil
+
+	case projectLoadedMsg:
+		return m.projectLoaded(msg)
+
+	case deliveredMsg, checksumsWrittenMsg:
+		return m.deliveryDone(msg)
+
+	case reviewSavedMsg:
+		m.reviewErr = msg.err
+		return m, nil
+
+	case historyLabelledMsg:
+		m.err = msg.err
+		for i := range m.runs {
+			if m.runs[i].ID == msg.run.ID {
+				m.runs[i] = msg.run
+			}
+		}
+		return m, nil
+
+	case previewFilesMsg:
+		return m.previewFilesListed(msg)
+
+	case previewLoadedMsg:
+		// Ignore a file the preview has moved on from
+		if m.screen == ScreenPreview && len(m.previewFiles) > 0 && msg.preview.path == m.previewFiles[m.previewIdx] {
+			m.preview = msg.preview
+			m.previewErr = msg.err
+		}
+		return m, nil
+
+	case dirCheckedMsg:
+		if msg.path != m.dirChecking {
+			return m, nil
+		}
+		m.dirChecking = ""
+		start := m.startAfterCheck
+		m.startAfterCheck = false
+		if msg.err != nil {
+			m.dirFailed, m.dirCheckErr = msg.path, msg.err
+			if start {
+				m.err = msg.err
+			}
+			return m, nil
+		}
+		m.dirReachable = msg.path
+		if start {
+			return m.startProcessing()
+		}
+		return refreshParams(m, nil)
+
+	case importCheckedMsg:
+		m.importDiffs = msg.diffs
+		m.importChecked = true
+		return m, nil
+
+	case descriptorSavedMsg:
+		if msg.err != nil {
+			m.err = fmt.Errorf("failed to export the run: %v", msg.err)
+		} else {
+			m.notice = fmt.Sprintf("Saved run descriptor of %d file(s): %s", msg.files, msg.path)
+		}
+		return m, nil
+
+	case commandsCopiedMsg:
+		if msg.err != nil {
+			m.err = fmt.Errorf("failed to copy the command lines: %v", msg.err)
+		} else {
+			m.notice = fmt.Sprintf("Copied the command lines for %d file(s) to the clipboard", msg.files)
+		}
+		return m, nil
+
+	case filesCheckedMsg:
+		// Ignore checks of a list edited since
+		if msg.text == m.fileList.Value() {
+			m.fileChecks = msg
+		}
+		return m, nil
+
+	case selectionLoadedMsg:
+		// Ignore counts for a selection that is no longer shown
+		if msg.selection.key == m.selectionKey {
+			m.selection = msg.selection
+		}
+		return m, nil
+
+	case runPreparedMsg:
+		// The user may have left the screen meanwhile
+		if !m.preparing {
+			return m, nil
+		}
+		m.preparing = false
+		if m.screen != ScreenParams {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		return m.launchRun(msg.count)
+
+	case boundsLoadedMsg:
+		// Ignore results for a selection that is no longer shown
+		if msg.key == m.boundsKey {
+			m.tiles = msg.tiles
+		}
+		return m, nil
+
 	case directoryLoadedMsg:
+		// Ignore a directory the browser has moved on from
+		if msg.path != m.currentDir {
+			return m, nil
+		}
+		m.entriesDir = msg.path
 		m.entries = msg.entries
 		m.cursor = 0
 		m.browseScroll = 0
@@ -482,6 +1151,22 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 // View implements tea.Model
 func (m Model) View() string {
+	switch {
+	case m.accessible:
+		return plainView(m.view())
+	case m.corporate:
+		return asciiView(m.view())
+	}
+	return m.view()
+}
+
+// view renders the current screen
+func (m Model) view() string {
+	// Below the minimum size every layout overlaps; say so instead
+	if m.width < minWidth || (m.height < minHeight && !m.inline) {
+		return m.viewTooSmall()
+	}
+
 	switch m.screen {
 	case ScreenWelcome:
 		return m.viewWelcome()
@@ -493,6 +1178,16 @@ func (m Model) View() string {
 		return m.viewProcessing()
 	case ScreenResults:
 		return m.viewResults()
+	case ScreenHistory:
+		return m.viewHistory()
+	case ScreenStats:
+		return m.viewStats()
+	case ScreenPreview:
+		return m.viewPreview()
+	case ScreenFiles:
+		return m.viewFiles()
+	case ScreenDelivery:
+		return m.viewDelivery()
 	default:
 		return "Unknown screen"
 	}
@@ -501,90 +1196,276 @@ func (m Model) View() string {
 // Screen update functions
 
 func (m Model) updateWelcome(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// Confirming a quick run; any other key cancels it
+	if m.quickRunConfirm {
+		m.quickRunConfirm = false
+		switch msg.String() {
+		case "enter", "r", "y":
+			return m.quickRun()
+		}
+		return m, nil
+	}
+
+	// Confirming a recorded run to run again, likewise
+	if m.relaunch != nil {
+		params := *m.relaunch
+		m.relaunch = nil
+		switch msg.String() {
+		case "enter", "l", "y":
+			return m.runAgain(params)
+		}
+		return m, nil
+	}
+
 	switch msg.String() {
+	case "up", "k":
+		m.recentCursor = max(m.recentCursor-1, 0)
+
+	case "down", "j":
+		m.recentCursor = min(m.recentCursor+1, max(len(m.recentRuns)-1, 0))
+
+	case "o":
+		if m.recentCursor < len(m.recentRuns) && !m.observer {
+			return m, openRecent(m.recentRuns[m.recentCursor], m.params, m.recentPipelines())
+		}
+
+	case "l":
+		if m.recentCursor < len(m.recentRuns) && !m.observer {
+			m.recentErr = nil
+			return m, relaunchRecent(m.recentRuns[m.recentCursor], m.params, m.recentPipelines())
+		}
+
 	case "enter", " ":
-		m.screen = ScreenParams
 		m.inputs[FocusInputDir].SetValue(m.selectedDir)
+		m = m.openForm()
 		m.inputs[FocusInputDir].Focus()
 		return m, textinput.Blink
+
+	case "a":
+		if m.runningSession != nil {
+			return m.attachSession(*m.runningSession)
+		}
+
+	case "r":
+		if m.lastRun != nil {
+			m.quickRunConfirm = true
+		}
+
+	case "h":
+		return m.openHistory()
+
+	case "s":
+		return m.openStats()
 	}
 	return m, nil
 }
 
 func (m Model) updateFileBrowser(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	maxVisible := m.height - 10
-	if maxVisible < 3 {
-		maxVisible = 3
+	entries := m.visibleEntries()
+
+	// Typing a filter; the listing follows as it changes, and the arrows
+	// and enter still work on it
+	if m.browseFilter.Focused() {
+		switch msg.String() {
+		case "up", "down":
+		case "enter":
+			m.browseFilter.Blur()
+			if len(entries) > 0 && m.cursor < len(entries) {
+				return m.openEntry(entries[m.cursor])
+			}
+			return m, nil
+		case "esc":
+			m.browseFilter.Blur()
+			m.browseFilter.SetValue("")
+			return m.browseTo(0), nil
+		default:
+			var cmd tea.Cmd
+			m.browseFilter, cmd = m.browseFilter.Update(msg)
+			return m.browseTo(0), cmd
+		}
 	}
 
-	switch msg.String() {
+	switch key := msg.String(); key {
 	case "up", "k":
 		if m.cursor > 0 {
-			m.cursor--
-			if m.cursor < m.browseScroll {
-				m.browseScroll = m.cursor
-			}
+			m = m.browseTo(m.cursor - 1)
 		}
 
 	case "down", "j":
-		if m.cursor < len(m.entries)-1 {
-			m.cursor++
-			if m.cursor >= m.browseScroll+maxVisible {
-				m.browseScroll = m.cursor - maxVisible + 1
-			}
+		if m.cursor < len(entries)-1 {
+			m = m.browseTo(m.cursor + 1)
 		}
 
 	case "enter", "right", "l":
-		if len(m.entries) > 0 && m.cursor < len(m.entries) {
-			entry := m.entries[m.cursor]
-			if entry.IsDir() {
-				newPath := filepath.Join(m.currentDir, entry.Name())
-				m.currentDir = newPath
-				return m, m.loadDirectory(newPath)
-			}
+		if len(entries) > 0 && m.cursor < len(entries) {
+			return m.openEntry(entries[m.cursor])
 		}
 
 	case "backspace", "h", "left":
 		parent := filepath.Dir(m.currentDir)
 		if parent != m.currentDir {
 			m.currentDir = parent
+			m.browseFilter.SetValue("")
 			return m, m.loadDirectory(parent)
 		}
 
+	case "/":
+		return m, m.browseFilter.Focus()
+
+	case ".":
+		m.showHidden = !m.showHidden
+		return m, m.loadDirectory(m.currentDir)
+
+	case "esc":
+		m.browseFilter.SetValue("")
+		return m.browseTo(0), nil
+
 	case "s", " ":
 		// Select current directory
 		m.selectedDir = m.currentDir
 		m.inputs[FocusInputDir].SetValue(m.selectedDir)
 		m.screen = ScreenParams
 		return m, nil
+
+	default:
+		// Other letters and digits jump to the next directory starting
+		// with them; capitals reach the letters taken by keys above
+		if r := []rune(key); len(r) == 1 && (unicode.IsLetter(r[0]) || unicode.IsDigit(r[0])) {
+			return m.jumpTo(r[0]), nil
+		}
 	}
 
 	return m, nil
 }
 
+// visibleEntries returns the directories matching the browser's filter
+func (m Model) visibleEntries() []dirEntry {
+	// Entries of the previous directory while the current one loads
+	if m.entriesDir != m.currentDir {
+		return nil
+	}
+	filter := strings.ToLower(m.browseFilter.Value())
+	if filter == "" {
+		return m.entries
+	}
+	var entries []dirEntry
+	for _, e := range m.entries {
+		if strings.Contains(strings.ToLower(e.name), filter) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// browseTo moves the browser's cursor to entry i and scrolls it into view
+func (m Model) browseTo(i int) Model {
+	maxVisible := max(m.height-10, 3)
+	m.cursor = i
+	if m.cursor < m.browseScroll {
+		m.browseScroll = m.cursor
+	}
+	if m.cursor >= m.browseScroll+maxVisible {
+		m.browseScroll = m.cursor - maxVisible + 1
+	}
+	return m
+}
+
+// jumpTo moves the cursor to the next directory after it whose name
+// starts with r, so pressing a letter again cycles through them
+func (m Model) jumpTo(r rune) Model {
+	entries := m.visibleEntries()
+	prefix := strings.ToLower(string(r))
+	for k := 1; k <= len(entries); k++ {
+		i := (m.cursor + k) % len(entries)
+		if strings.HasPrefix(strings.ToLower(entries[i].name), prefix) {
+			return m.browseTo(i)
+		}
+	}
+	return m
+}
+
+// openEntry enters a directory of the browser. A symlink is followed
+// under its own name, so going up returns to where it was entered; one
+// leading back up the tree isn't, as the path would grow forever.
+func (m Model) openEntry(entry dirEntry) (tea.Model, tea.Cmd) {
+	if entry.cycle {
+		return m, nil
+	}
+	m.currentDir = filepath.Join(m.currentDir, entry.name)
+	m.browseFilter.SetValue("")
+	return m, m.loadDirectory(m.currentDir)
+}
+
 func (m Model) updateParams(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	m.notice = ""
+	m.confirmDiscard = false
+
+	// The pipeline selector cycles through the available pipelines
+	if m.focusedField == m.pipelineField() && len(m.pipelines) > 0 {
+		switch msg.String() {
+		case "left", "h":
+			return m.selectPipeline((m.pipelineIdx - 1 + len(m.pipelines)) % len(m.pipelines)), nil
+		case "right", "l", " ":
+			return m.selectPipeline((m.pipelineIdx + 1) % len(m.pipelines)), nil
+		}
+	}
+
 	switch msg.String() {
+	case "ctrl+z":
+		m = m.undoForm()
+		return m, m.updateFocus()
+
 	case "tab", "down":
-		m.focusedField = (m.focusedField + 1) % FocusFieldCount
+		m.focusedField = m.stepField(1)
 		return m, m.updateFocus()
 
 	case "shift+tab", "up":
-		m.focusedField = (m.focusedField - 1 + FocusFieldCount) % FocusFieldCount
+		m.focusedField = m.stepField(-1)
 		return m, m.updateFocus()
 
 	case "enter":
-		if m.focusedField == FocusStartButton {
+		if m.focusedField == m.startField() {
 			return m.startProcessing()
 		}
 		// Move to next field
-		m.focusedField = (m.focusedField + 1) % FocusFieldCount
+		m.focusedField = m.stepField(1)
 		return m, m.updateFocus()
 
 	case "b", "ctrl+b":
+		// Patterns, metadata, labels and parameters are typed in, so b is
+		// typed there
+		if msg.String() == "b" && m.typingField() {
+			break
+		}
 		// Open file browser
 		m.screen = ScreenFileBrowser
+		m.browseFilter.SetValue("")
 		return m, m.loadDirectory(m.currentDir)
 
+	case "p", "ctrl+p":
+		if msg.String() == "p" && m.typingField() {
+			break
+		}
+		return m.openPreview()
+
+	case "f", "ctrl+f":
+		if msg.String() == "f" && m.typingField() {
+			break
+		}
+		return m.openFiles()
+
+	case "y", "ctrl+y":
+		if msg.String() == "y" && m.typingField() {
+			break
+		}
+		return m.copyCommands()
+
+	case "e", "ctrl+e":
+		if msg.String() == "e" && m.typingField() {
+			break
+		}
+		return m.exportDescriptor()
+
 	case "ctrl+v":
 		// Paste from clipboard
 		if int(m.focusedField) < len(m.inputs) {
@@ -612,25 +1493,163 @@ func (m Model) updateParams(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 func (m Model) updateProcessing(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// Ctrl+C is handled globally
+	switch msg.String() {
+	case "l":
+		// Switch between the dashboard and the log alone; inline mode
+		// prints the log into the scrollback either way
+		if !m.inline {
+			m.rawLog = !m.rawLog
+		}
+		return m, nil
+	case "d":
+		// Leave the background session running and exit
+		if m.detachable() {
+			m.source.(*session.Follower).Detach()
+			return m, tea.Quit
+		}
+	}
 	return m, nil
 }
 
 func (m Model) updateResults(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m, cmd, ok := m.updateReview(msg); ok {
+		return m, cmd
+	}
+	if len(m.result.Files) > 1 {
+		if m, ok := m.updateResultsTable(msg); ok {
+			return m, nil
+		}
+	}
 	switch msg.String() {
 	case "enter", " ", "r":
 		// Reset and go back to welcome
+		m = m.clearResults()
 		m.screen = ScreenWelcome
-		m.logs = make([]processor.LogEntry, 0)
-		m.filesDone = 0
-		m.currentFile = ""
-		m.err = nil
-		return m, nil
+		// The finished run calibrates the next runtime estimate, and
+		// heads the recent runs
+		return m, tea.Batch(loadCalibration, loadRecent)
+	case "D":
+		if m.result.ReportPath != "" {
+			return m.openDelivery(), nil
+		}
+	case "e":
+		// Back to the form with this run's settings and files, to change
+		// a parameter and run again. The files were just processed, so
+		// they aren't skipped as already done.
+		params := m.params
+		params.SkipExisting = false
+		m = m.clearResults()
+		m = m.pushUndo(m.formState())
+		m = m.applyParams(params)
+		m.imported = nil
+		m = m.openForm()
+		m.focusedField = FocusParams
+		return refreshFormSave(refreshParams(m, tea.Batch(m.updateFocus(), loadCalibration)))
 	}
 	return m, nil
 }
 
+// clearResults drops the log and progress of the finished run
+func (m Model) clearResults() Model {
+	m.logs = make([]processor.LogEntry, 0)
+	m.filesDone = 0
+	m.fileOutcomes = nil
+	m.currentFile = ""
+	m.err = nil
+	m.reviewNote.Blur()
+	m.reviewErr = nil
+	return m
+}
+
 // Helper functions
 
+// typingField reports whether the focused field takes free text, where
+// letter shortcuts are typed instead; paths such as \\backup\scans are
+// typed too
+func (m Model) typingField() bool {
+	return int(m.focusedField) < len(m.inputs)
+}
+
+// stepField returns the form field delta steps from the focused one,
+// skipping the pipeline selector when there is nothing to select
+func (m Model) stepField(delta int) FocusedField {
+	count := m.startField() + 1
+	field := (m.focusedField + FocusedField(delta) + count) % count
+	if field == m.pipelineField() && !m.hasPipelineChoice() {
+		field = (field + FocusedField(delta) + count) % count
+	}
+	return field
+}
+
+// pipelineField is the pipeline selector, after the text inputs
+func (m Model) pipelineField() FocusedField {
+	return FocusedField(len(m.inputs))
+}
+
+// startField is the start button, after the pipeline selector
+func (m Model) startField() FocusedField {
+	return m.pipelineField() + 1
+}
+
+// schema returns the parameters of the selected pipeline
+func (m Model) schema() []processor.ParamSpec {
+	if m.pipelineIdx < len(m.pipelines) {
+		return m.pipelines[m.pipelineIdx].Params
+	}
+	return processor.DefaultSchema
+}
+
+// paramValue returns the form value of a pipeline parameter, or the
+// default when the field is empty
+func (m Model) paramValue(name string) string {
+	for i, spec := range m.schema() {
+		if spec.Name != name {
+			continue
+		}
+		if value := m.inputs[int(FocusParams)+i].Value(); value != "" {
+			return value
+		}
+		return spec.Default
+	}
+	return ""
+}
+
+// newParamInputs creates a text input for every parameter in schema
+func newParamInputs(schema []processor.ParamSpec) []textinput.Model {
+	inputs := make([]textinput.Model, len(schema))
+	for i, spec := range schema {
+		inputs[i] = textinput.New()
+		inputs[i].Placeholder = spec.Default
+		inputs[i].CharLimit = 64
+		inputs[i].Width = 10
+	}
+	return inputs
+}
+
+// selectPipeline switches to another pipeline and rebuilds the parameter
+// inputs from its schema, keeping values of parameters both pipelines share
+func (m Model) selectPipeline(idx int) Model {
+	entered := make(map[string]string)
+	for i, spec := range m.schema() {
+		entered[spec.Name] = m.inputs[int(FocusParams)+i].Value()
+	}
+
+	m.pipelineIdx = idx
+	schema := m.schema()
+	inputs := append(m.inputs[:FocusParams:FocusParams], newParamInputs(schema)...)
+	for i, spec := range schema {
+		inputs[int(FocusParams)+i].SetValue(entered[spec.Name])
+	}
+	m.inputs = inputs
+	m.focusedField = m.pipelineField()
+	return m
+}
+
+// hasPipelineChoice reports whether more than one pipeline is available
+func (m Model) hasPipelineChoice() bool {
+	return len(m.pipelines) > 1
+}
+
 func (m Model) updateFocus() tea.Cmd {
 	cmds := make([]tea.Cmd, len(m.inputs))
 	for i := range m.inputs {
@@ -644,61 +1663,187 @@ func (m Model) updateFocus() tea.Cmd {
 }
 
 func (m Model) startProcessing() (tea.Model, tea.Cmd) {
-	// Parse parameters from inputs
-	m.params.InputDir = m.inputs[FocusInputDir].Value()
-	if m.params.InputDir == "" {
-		m.params.InputDir = m.selectedDir
+	if m.preparing {
+		return m, nil
 	}
 
-	m.params.OutputSubdir = m.inputs[FocusOutputSubdir].Value()
-	if m.params.OutputSubdir == "" {
-		m.params.OutputSubdir = "Processed"
+	params, err := m.formParams()
+	if err != nil {
+		m.err = err
+		return m, nil
 	}
+	m.params = params
 
-	// Parse numeric values with defaults
-	m.params.KNN = 6
-	fmt.Sscanf(m.inputs[FocusKNN].Value(), "%d", &m.params.KNN)
-	if m.params.KNN <= 0 {
-		m.params.KNN = 6
+	// Listed files replace the input directory once they are all usable
+	if len(m.params.Files) > 0 {
+		if !m.filesChecked() {
+			m.err = fmt.Errorf("listed files are still being checked")
+			return m, nil
+		}
+		if n := m.invalidFiles(); n > 0 {
+			m.err = fmt.Errorf("%d listed file(s) can't be processed, see Files (Ctrl+F)", n)
+			return m, nil
+		}
 	}
 
-	m.params.OctreeDepth = 11
-	fmt.Sscanf(m.inputs[FocusOctreeDepth].Value(), "%d", &m.params.OctreeDepth)
-	if m.params.OctreeDepth <= 0 {
-		m.params.OctreeDepth = 11
+	// A network directory is checked first, with a timeout, as reading a
+	// dead share would freeze the screen; listed files were checked already
+	if len(m.params.Files) == 0 {
+		if err := processor.CheckNetworkPath(m.params.InputDir); err != nil {
+			m.err = err
+			return m, nil
+		}
+		if !m.dirUsable(m.params.InputDir) {
+			m.startAfterCheck = true
+			if m.dirChecking == m.params.InputDir {
+				return m, nil
+			}
+			m.dirChecking = m.params.InputDir
+			m.err = nil
+			return m, tea.Batch(checkDir(m.params.InputDir), m.spinner.Tick)
+		}
 	}
 
-	m.params.SamplesPerNode = 1.5
-	fmt.Sscanf(m.inputs[FocusSamplesPerNode].Value(), "%f", &m.params.SamplesPerNode)
-	if m.params.SamplesPerNode <= 0 {
-		m.params.SamplesPerNode = 1.5
+	// Create processor; the input directory is read in the background
+	m.processor = processor.New(m.params)
+	m.preparing = true
+	m.err = nil
+	return m, tea.Batch(prepareRun(m.processor, m.params), m.spinner.Tick)
+}
+
+// formParams returns the run parameters set on the Configuration screen,
+// or why they are invalid
+func (m Model) formParams() (processor.Params, error) {
+	params := m.params
+	params.InputDir = m.inputs[FocusInputDir].Value()
+	if params.InputDir == "" {
+		params.InputDir = m.selectedDir
 	}
+	params.Files = m.listedFiles()
 
-	m.params.PointWeight = 2.0
-	fmt.Sscanf(m.inputs[FocusPointWeight].Value(), "%f", &m.params.PointWeight)
-	if m.params.PointWeight <= 0 {
-		m.params.PointWeight = 2.0
+	params.OutputSubdir = m.inputs[FocusOutputSubdir].Value()
+	if params.OutputSubdir == "" {
+		params.OutputSubdir = "Processed"
 	}
 
-	m.params.BoundaryType = 2
-	fmt.Sscanf(m.inputs[FocusBoundaryType].Value(), "%d", &m.params.BoundaryType)
-	if m.params.BoundaryType < 0 || m.params.BoundaryType > 2 {
-		m.params.BoundaryType = 2
+	// File name patterns
+	params.Include = processor.ParsePatterns(m.inputs[FocusInclude].Value())
+	params.Exclude = processor.ParsePatterns(m.inputs[FocusExclude].Value())
+	for _, patterns := range [][]string{params.Include, params.Exclude} {
+		if err := processor.CheckPatterns(patterns); err != nil {
+			return params, err
+		}
 	}
 
-	// Create processor
-	m.processor = processor.New(m.params)
+	// Web export; empty means none
+	params.WebExport = strings.TrimSpace(m.inputs[FocusWebExport].Value())
+	if params.WebExport != "" {
+		if err := processor.CheckWebExport(params.WebExport); err != nil {
+			return params, err
+		}
+	}
 
-	// Validate
-	if err := m.processor.ValidateInputDir(); err != nil {
+	// Metadata from the form, on top of any extra keys from the config file
+	metadata := make(map[string]string)
+	for key, value := range params.Metadata {
+		metadata[key] = value
+	}
+	for i, field := range processor.MetadataFields {
+		if value := strings.TrimSpace(m.inputs[FocusMetadata+FocusedField(i)].Value()); value != "" {
+			metadata[field.Key] = value
+		} else {
+			delete(metadata, field.Key)
+		}
+	}
+	params.Metadata = metadata
+	params.Labels = history.ParseLabels(m.inputs[FocusLabels].Value())
+
+	// Validate the pipeline parameters; empty fields use the default
+	params.Values = make(map[string]string)
+	for i, spec := range m.schema() {
+		value, err := spec.Validate(m.inputs[int(FocusParams)+i].Value())
+		if err != nil {
+			return params, fmt.Errorf("%s: %v", spec.DisplayLabel(false), err)
+		}
+		params.Values[spec.Name] = value
+	}
+
+	// The default pipeline runs process_las_files.py
+	params.Script = ""
+	if m.pipelineIdx > 0 && m.pipelineIdx < len(m.pipelines) {
+		params.Script = m.pipelines[m.pipelineIdx].Script
+	}
+	return params, nil
+}
+
+// commandsCopiedMsg reports copying the command lines of a run
+type commandsCopiedMsg struct {
+	files int
+	err   error
+}
+
+// copyCommands copies the commands a run with the form's parameters would
+// execute to the clipboard, listing the files in the background
+func (m Model) copyCommands() (tea.Model, tea.Cmd) {
+	params, err := m.formParams()
+	if err != nil {
 		m.err = err
 		return m, nil
 	}
+	if len(params.Files) == 0 && !m.dirUsable(params.InputDir) {
+		m.err = fmt.Errorf("network path %s hasn't been reached yet", params.InputDir)
+		return m, nil
+	}
+	m.err = nil
+	return m, func() tea.Msg {
+		p := processor.New(params)
+		lines, err := p.CommandLines()
+		if err != nil {
+			return commandsCopiedMsg{err: err}
+		}
+		files, _ := p.CountLASFiles()
+		return commandsCopiedMsg{files: files, err: clipboard.WriteAll(strings.Join(lines, "\n") + "\n")}
+	}
+}
+
+// runPreparedMsg reports the checks of the input directory before a run,
+// with the number of files to process
+type runPreparedMsg struct {
+	count int
+	err   error
+}
+
+// prepareRun checks the input directory and the output lock, and counts
+// the files to process
+func prepareRun(p *processor.Processor, params processor.Params) tea.Cmd {
+	return func() tea.Msg {
+		if err := p.ValidateInputDir(); err != nil {
+			return runPreparedMsg{err: err}
+		}
+
+		// Refuse to start while another run writes to the same outputs
+		files, _ := p.ListLASFiles()
+		for _, outputDir := range params.OutputDirs(files) {
+			if holder, ok := lock.Active(outputDir); ok {
+				return runPreparedMsg{err: &lock.BusyError{Dir: outputDir, Holder: holder}}
+			}
+		}
+
+		// Count the files that will be processed, so the progress bar isn't
+		// held back by files SkipExisting leaves out
+		count, _ := p.CountLASFiles()
+		if count == 0 && params.SkipExisting {
+			return runPreparedMsg{err: fmt.Errorf("all LAS files already have outputs in %s", params.OutputSubdir)}
+		}
+		return runPreparedMsg{count: count}
+	}
+}
 
-	// Count files
-	count, _ := m.processor.CountLASFiles()
+// launchRun starts a prepared run of count files
+func (m Model) launchRun(count int) (tea.Model, tea.Cmd) {
 	m.filesTotal = count
 	m.filesDone = 0
+	m.fileOutcomes = nil
 
 	// Find scripts
 	if err := m.processor.FindScripts(); err != nil {
@@ -706,10 +1851,49 @@ func (m Model) startProcessing() (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
-	// Start processing
+	// Run the batch in a background session so it survives the TUI exiting;
+	// fall back to processing in-process if the session can't be started
+	if follower, err := session.Start(m.params, count); err == nil {
+		m.source = follower
+	} else {
+		if err := m.processor.Start(); err != nil {
+			m.err = err
+			return m, nil
+		}
+		m.source = m.processor
+	}
+
+	params := m.params
+	m.lastRun = &params
+	m = m.resetProcessing(time.Now())
+	return m, tea.Batch(m.processingCmds(), saveLastRun(params))
+}
+
+// attachSession switches to the processing screen following a background
+// session started by an earlier instance
+func (m Model) attachSession(state session.State) (tea.Model, tea.Cmd) {
+	follower, err := session.Attach(state.Dir)
+	if err != nil {
+		m.err = err
+		m.runningSession = nil
+		return m, nil
+	}
+
+	m.source = follower
+	m.params = state.Params
+	m.filesTotal = state.FilesTotal
+	m.filesDone = 0
+	m.fileOutcomes = nil
+	m.runningSession = nil
+	m = m.resetProcessing(state.StartedAt)
+	return m, m.processingCmds()
+}
+
+// resetProcessing clears progress state and enters the processing screen
+func (m Model) resetProcessing(started time.Time) Model {
 	m.processing = true
 	m.screen = ScreenProcessing
-	m.startTime = time.Now()
+	m.startTime = started
 	m.elapsedTime = 0
 	m.logs = make([]processor.LogEntry, 0)
 	m.currentFile = ""
@@ -720,18 +1904,19 @@ func (m Model) startProcessing() (tea.Model, tea.Cmd) {
 	m.animFrame = 0
 	m.animTick = 0
 	m.particlePos = 0
-	m.completedSteps = make([]bool, 5)
+	m.steps = nil
 	m.celebrating = false
 	m.celebrateFrame = 0
+	m.animating = true // Started by processingCmds
+	m.stalledSince = time.Time{}
+	m.transfers = nil
 	m.err = nil
+	return m
+}
 
-	if err := m.processor.Start(); err != nil {
-		m.err = err
-		m.processing = false
-		return m, nil
-	}
-
-	return m, tea.Batch(
+// processingCmds starts result listening, log polling and the timers
+func (m Model) processingCmds() tea.Cmd {
+	return tea.Batch(
 		m.spinner.Tick,
 		m.listenForResult(),
 		// Start polling for logs
@@ -742,8 +1927,9 @@ func (m Model) startProcessing() (tea.Model, tea.Cmd) {
 		tea.Tick(time.Millisecond*500, func(t time.Time) tea.Msg {
 			return TickMsg(t)
 		}),
-		// Start animation tick
-		tea.Tick(time.Millisecond*80, func(t time.Time) tea.Msg {
+		// Start animation tick; the first one sets the pace, see
+		// nextAnimTick
+		tea.Tick(dashboardFrame, func(t time.Time) tea.Msg {
 			return AnimTickMsg(t)
 		}),
 	)
@@ -751,33 +1937,75 @@ func (m Model) startProcessing() (tea.Model, tea.Cmd) {
 
 func (m Model) listenForResult() tea.Cmd {
 	return func() tea.Msg {
-		if m.processor == nil {
+		if m.source == nil {
 			return nil
 		}
-		result := <-m.processor.ResultChan()
+		result := <-m.source.ResultChan()
 		return ProcessingDoneMsg(result)
 	}
 }
 
 type directoryLoadedMsg struct {
-	entries []os.DirEntry
+	path    string
+	entries []dirEntry
 	err     error
 }
 
+// dirEntry is a subdirectory listed in the browser
+type dirEntry struct {
+	name  string
+	link  string // Target of a symlinked directory, as the link states it
+	cycle bool   // The link leads back to the listed directory or above it
+}
+
 func (m Model) loadDirectory(path string) tea.Cmd {
+	hidden := m.showHidden
 	return func() tea.Msg {
 		entries, err := os.ReadDir(path)
 		// Filter to only show directories
-		var dirs []os.DirEntry
+		var dirs []dirEntry
 		for _, e := range entries {
-			if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
-				dirs = append(dirs, e)
+			if !hidden && strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+			if e.IsDir() {
+				dirs = append(dirs, dirEntry{name: e.Name()})
+				continue
+			}
+			// Symlinks, and junctions on Windows, that lead to a directory
+			if e.Type()&(os.ModeSymlink|os.ModeIrregular) == 0 {
+				continue
+			}
+			if dir, ok := linkedDir(path, e.Name()); ok {
+				dirs = append(dirs, dir)
 			}
 		}
-		return directoryLoadedMsg{entries: dirs, err: err}
+		return directoryLoadedMsg{path: path, entries: dirs, err: err}
 	}
 }
 
+// linkedDir describes the link name in dir if it leads to a directory;
+// broken links are left out
+func linkedDir(dir, name string) (dirEntry, bool) {
+	path := filepath.Join(dir, name)
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return dirEntry{}, false
+	}
+	if info, err := os.Stat(target); err != nil || !info.IsDir() {
+		return dirEntry{}, false
+	}
+	entry := dirEntry{name: name, link: target}
+	if link, err := os.Readlink(path); err == nil {
+		entry.link = link
+	}
+	if real, err := filepath.EvalSymlinks(dir); err == nil {
+		rel, err := filepath.Rel(target, real)
+		entry.cycle = err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+	}
+	return entry, true
+}
+
 // GetElapsedTime returns the elapsed time (either running or final)
 func (m Model) GetElapsedTime() time.Duration {
 	return m.elapsedTime
@@ -801,20 +2029,8 @@ func max(a, b int) int {
 
 // GetStepSpinner returns an animated spinner for the current step
 func (m Model) GetStepSpinner() string {
-	switch m.currentStepNum {
-	case 1:
-		return loadingFrames[m.animFrame%len(loadingFrames)]
-	case 2:
-		return normalFrames[m.animFrame%len(normalFrames)]
-	case 3:
-		return dipFrames[m.animFrame%len(dipFrames)]
-	case 4:
-		return meshFrames[m.animFrame%len(meshFrames)]
-	case 5:
-		return saveFrames[m.animFrame%len(saveFrames)]
-	default:
-		return pulseFrames[m.animFrame%len(pulseFrames)]
-	}
+	frames := m.currentStepKind().frames
+	return frames[m.animFrame%len(frames)]
 }
 
 // GetStepProgress returns a mini progress bar for the current step
@@ -823,25 +2039,12 @@ func (m Model) GetStepProgress() string {
 		return ""
 	}
 
-	// Animated progress based on time in current step
+	// Animated progress based on time in current step, unless the step
+	// reports its own
 	elapsed := time.Since(m.stepStartTime).Seconds()
 
 	// Different expected durations per step
-	var expectedDuration float64
-	switch m.currentStepNum {
-	case 1:
-		expectedDuration = 5.0
-	case 2:
-		expectedDuration = 60.0
-	case 3:
-		expectedDuration = 2.0
-	case 4:
-		expectedDuration = 300.0 // Poisson takes long
-	case 5:
-		expectedDuration = 10.0
-	default:
-		expectedDuration = 30.0
-	}
+	expectedDuration := m.currentStepKind().expected
 
 	// Calculate progress (cap at 95% to show it's still running)
 	progress := elapsed / expectedDuration
@@ -855,6 +2058,9 @@ func (m Model) GetStepProgress() string {
 	if progress > 0.99 {
 		progress = 0.99
 	}
+	if m.stepPercent >= 0 {
+		progress = float64(m.stepPercent) / 100
+	}
 
 	barWidth := 15
 	filled := int(progress * float64(barWidth))
@@ -884,7 +2090,7 @@ func (m Model) GetParticles() string {
 func (m Model) GetStepStatusLine(stepNum int, stepName string, style lipgloss.Style) string {
 	if stepNum < m.currentStepNum {
 		// Completed step
-		return style.Render(fmt.Sprintf("  ✓ Step %d: %s", stepNum, stepName))
+		return style.Render(fmt.Sprintf("  %s Step %d: %s", m.styles.Icons.Success, stepNum, stepName))
 	} else if stepNum == m.currentStepNum {
 		// Current step with animation
 		spinner := m.GetStepSpinner()
@@ -895,6 +2101,56 @@ func (m Model) GetStepStatusLine(stepNum int, stepName string, style lipgloss.St
 	}
 }
 
+// fileFinished records the outcome of a file from the entry that ends it.
+// Outcomes are kept by path, so an entry seen twice, e.g. when following a
+// session, doesn't count the file twice.
+func (m Model) fileFinished(entry processor.LogEntry) Model {
+	if m.fileOutcomes == nil {
+		m.fileOutcomes = make(map[string]processor.Outcome)
+	}
+	m.fileOutcomes[entry.File] = entry.Outcome
+	m.filesDone = 0
+	for _, outcome := range m.fileOutcomes {
+		if outcome != processor.OutcomeFailed {
+			m.filesDone++
+		}
+	}
+	return m
+}
+
+// noteTransfer tracks the prefetches in progress from their log entries
+func (m Model) noteTransfer(entry processor.LogEntry) Model {
+	if entry.Transfer == nil {
+		return m
+	}
+	transfers := make([]processor.Transfer, 0, len(m.transfers)+1)
+	for _, t := range m.transfers {
+		if t.File != entry.Transfer.File {
+			transfers = append(transfers, t)
+		}
+	}
+	if !entry.Transfer.Done {
+		transfers = append(transfers, *entry.Transfer)
+	}
+	m.transfers = transfers
+	return m
+}
+
+// noteSilence tracks the processor's warnings that the script has gone
+// silent; any other entry means processing has moved on
+func (m Model) noteSilence(entry processor.LogEntry) Model {
+	if entry.Transfer != nil {
+		// About a file being prefetched, not the script
+		return m
+	}
+	if entry.Silent > 0 {
+		m.stalledSince = time.Now().Add(-entry.Silent)
+	} else {
+		m.stalledSince = time.Time{}
+	}
+	return m
+}
+
 // IsCelebrating returns true if we're showing a completion celebration
 func (m Model) IsCelebrating() bool {
 	return m.celebrating
@@ -0,0 +1,81 @@
+package tui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/cloudcompare-automation/internal/session"
+)
+
+// Observer mode only watches: it follows the background session of a
+// batch, waiting for one to start, and shows its progress, log and
+// results, but has no way to start, cancel or change anything. It is safe
+// to leave open on a screen for someone who only wants to see how a
+// delivery is going. Quitting leaves the batch running.
+
+// observePoll is how often observer mode looks for a batch to follow
+const observePoll = 2 * time.Second
+
+// findObservedSession looks for the running session id, or any when id
+// is empty
+func findObservedSession(id string) tea.Cmd {
+	return func() tea.Msg {
+		running, err := session.Running()
+		if err != nil {
+			return runningSessionMsg{}
+		}
+		for i := range running {
+			if id == "" || running[i].ID == id {
+				return runningSessionMsg{state: &running[i]}
+			}
+		}
+		return runningSessionMsg{}
+	}
+}
+
+// observe follows a session found running, unless one is already being
+// followed, and looks again after observePoll. A new batch replaces the
+// results of the last.
+func (m Model) observe(state *session.State) (tea.Model, tea.Cmd) {
+	id := m.observeID
+	next := tea.Tick(observePoll, func(time.Time) tea.Msg {
+		return findObservedSession(id)()
+	})
+	if state == nil || m.processing || state.ID == m.observed {
+		return m, next
+	}
+	m.observed = state.ID
+	m = m.clearResults()
+	model, cmd := m.attachSession(*state)
+	return model, tea.Batch(cmd, next)
+}
+
+// updateObserver handles the keys of observer mode, which only move
+// around what is shown
+func (m Model) updateObserver(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		// Shutdown detaches from the session, leaving it running
+		return m, tea.Quit
+	}
+
+	switch m.screen {
+	case ScreenProcessing:
+		if msg.String() == "l" && !m.inline {
+			m.rawLog = !m.rawLog
+		}
+	case ScreenResults:
+		if len(m.result.Files) > 1 {
+			if m, ok := m.updateResultsTable(msg); ok {
+				return m, nil
+			}
+		}
+		switch msg.String() {
+		case "enter", "esc":
+			m = m.clearResults()
+			m.screen = ScreenWelcome
+		}
+	}
+	return m, nil
+}
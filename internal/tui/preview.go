@@ -0,0 +1,279 @@
+package tui
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/cloudcompare-automation/internal/humanize"
+	"github.com/cloudcompare-automation/internal/las"
+	"github.com/cloudcompare-automation/internal/processor"
+)
+
+// previewPoints is about how many points of a file the preview samples
+const previewPoints = 200000
+
+// heightRamp colors the preview from the lowest to the highest points
+var heightRamp = []lipgloss.Color{"#3B82F6", "#06B6D4", "#10B981", "#84CC16", "#EAB308", "#F97316", "#EF4444"}
+
+// brailleDots are the bits of the dots of a Braille cell, by row and column
+var brailleDots = [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+
+// cloudPreview is a sample of the points of a LAS file
+type cloudPreview struct {
+	path   string
+	header las.Header
+	points [][3]float64
+}
+
+// previewLoadedMsg carries the sampled points of a file, or why they
+// couldn't be read
+type previewLoadedMsg struct {
+	preview cloudPreview
+	err     error
+}
+
+// loadPreview samples the points of a LAS file
+func loadPreview(path string) tea.Cmd {
+	return func() tea.Msg {
+		preview := cloudPreview{path: path}
+		h, err := las.ReadHeader(path)
+		if err != nil {
+			return previewLoadedMsg{preview: preview, err: err}
+		}
+		step := max(1, int(h.PointCount/previewPoints))
+		preview.header, err = las.ReadPoints(path, step, func(p las.Point) {
+			preview.points = append(preview.points, [3]float64{p.X, p.Y, p.Z})
+		})
+		return previewLoadedMsg{preview: preview, err: err}
+	}
+}
+
+// previewFilesMsg carries the files to preview, or why there are none
+type previewFilesMsg struct {
+	files []string
+	err   error
+}
+
+// openPreview switches to the preview of the files the Configuration
+// screen selects, which are listed in the background
+func (m Model) openPreview() (tea.Model, tea.Cmd) {
+	if files := m.listedFiles(); len(files) > 0 {
+		m.err = nil
+		m.screen = ScreenPreview
+		m.previewFiles = files
+		m.previewIdx = 0
+		return m.showPreview()
+	}
+
+	dir := m.inputDir()
+	if !m.dirUsable(dir) {
+		m.err = fmt.Errorf("network path %s hasn't been reached yet", dir)
+		return m, nil
+	}
+	include := processor.ParsePatterns(m.inputs[FocusInclude].Value())
+	exclude := processor.ParsePatterns(m.inputs[FocusExclude].Value())
+	m.err = nil
+	m.screen = ScreenPreview
+	m.previewFiles = nil
+	m.previewIdx = 0
+	cache := m.lasFiles
+	return m, func() tea.Msg {
+		files, err := cache.list(dir)
+		files, _ = processor.SplitFiles(files, include, exclude)
+		if err == nil && len(files) == 0 {
+			err = fmt.Errorf("no LAS files to preview in %s", dir)
+		}
+		return previewFilesMsg{files: files, err: err}
+	}
+}
+
+// previewFilesListed shows the first listed file, or returns to the
+// Configuration screen with the error
+func (m Model) previewFilesListed(msg previewFilesMsg) (tea.Model, tea.Cmd) {
+	if m.screen != ScreenPreview || m.previewFiles != nil {
+		return m, nil
+	}
+	if msg.err != nil {
+		m.err = msg.err
+		m.screen = ScreenParams
+		return m, nil
+	}
+	m.previewFiles = msg.files
+	return m.showPreview()
+}
+
+// showPreview starts loading the selected file
+func (m Model) showPreview() (tea.Model, tea.Cmd) {
+	m.preview = cloudPreview{}
+	m.previewErr = nil
+	return m, loadPreview(m.previewFiles[m.previewIdx])
+}
+
+func (m Model) updatePreview(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "right", "l", "n":
+		if len(m.previewFiles) > 1 {
+			m.previewIdx = (m.previewIdx + 1) % len(m.previewFiles)
+			return m.showPreview()
+		}
+	case "left", "h", "p":
+		if len(m.previewFiles) > 1 {
+			m.previewIdx = (m.previewIdx - 1 + len(m.previewFiles)) % len(m.previewFiles)
+			return m.showPreview()
+		}
+	case "v":
+		m.previewIso = !m.previewIso
+	case "esc":
+		m.screen = ScreenParams
+	}
+	return m, nil
+}
+
+// viewPreview renders the sampled points of the selected file as Braille
+// dots, colored by height
+func (m Model) viewPreview() string {
+	s := m.styles
+
+	view := "top"
+	if m.previewIso {
+		view = "isometric"
+	}
+	var parts []string
+	if len(m.previewFiles) == 0 {
+		parts = append(parts, s.HeaderTitle.Render("🔍 Preview"), "", s.TextMuted.Render("Finding LAS files..."))
+		return lipgloss.JoinVertical(lipgloss.Left, parts...)
+	}
+	parts = append(parts, s.HeaderTitle.Render(fmt.Sprintf("🔍 Preview  %s (%d/%d), %s view",
+		filepath.Base(m.previewFiles[m.previewIdx]), m.previewIdx+1, len(m.previewFiles), view)), "")
+
+	width, height := m.width-4, m.height-8
+	switch {
+	case m.previewErr != nil:
+		parts = append(parts, s.StatusError.Render("⚠ "+truncate(m.previewErr.Error(), m.width-10)))
+	case m.preview.path == "":
+		parts = append(parts, s.TextMuted.Render("Reading points..."))
+	case len(m.preview.points) == 0:
+		parts = append(parts, s.TextMuted.Render("The file has no points"))
+	default:
+		parts = append(parts, renderCloud(m.preview.points, m.previewIso, width, height)...)
+		h := m.preview.header
+		parts = append(parts, "", s.TextMuted.Render(fmt.Sprintf("%s points, %s shown · %s × %s · height %.1f to %.1f m",
+			humanize.Count(int64(h.PointCount)), humanize.Count(int64(len(m.preview.points))),
+			formatLength(h.MaxX-h.MinX), formatLength(h.MaxY-h.MinY), h.MinZ, h.MaxZ)))
+	}
+
+	footer := s.Footer.Render(
+		s.RenderKeyHelp("←→", "file") + " " +
+			s.RenderKeyHelp("v", "view") + " " +
+			s.RenderKeyHelp("esc", "back"),
+	)
+	parts = append(parts, "", footer)
+
+	return lipgloss.JoinVertical(lipgloss.Left, parts...)
+}
+
+// renderCloud projects points onto a canvas of width by height Braille
+// cells, from above with north up or from the south-west. Each cell takes
+// the color of the height of the point nearest the viewer.
+func renderCloud(points [][3]float64, iso bool, width, height int) []string {
+	if width < 2 || height < 2 {
+		return nil
+	}
+
+	lo, hi := points[0], points[0]
+	for _, p := range points {
+		for i := range p {
+			lo[i], hi[i] = math.Min(lo[i], p[i]), math.Max(hi[i], p[i])
+		}
+	}
+	center := [3]float64{(lo[0] + hi[0]) / 2, (lo[1] + hi[1]) / 2, (lo[2] + hi[2]) / 2}
+
+	// Isometric: turned 45° and tilted 35° down
+	project := func(p [3]float64) (u, v, depth float64) {
+		x, y, z := p[0]-center[0], p[1]-center[1], p[2]-center[2]
+		if !iso {
+			return x, y, z
+		}
+		const sinA, cosA = math.Sqrt2 / 2, math.Sqrt2 / 2
+		const sinE, cosE = 0.573576, 0.819152
+		x1 := x*cosA - y*sinA
+		y1 := x*sinA + y*cosA
+		return x1, y1*sinE + z*cosE, z*sinE - y1*cosE
+	}
+
+	minU, maxU := math.Inf(1), math.Inf(-1)
+	minV, maxV := math.Inf(1), math.Inf(-1)
+	for _, p := range points {
+		u, v, _ := project(p)
+		minU, maxU = math.Min(minU, u), math.Max(maxU, u)
+		minV, maxV = math.Min(minV, v), math.Max(maxV, v)
+	}
+
+	// A cell holds 2×4 dots and is about twice as tall as wide, so dots
+	// are square
+	dotsX, dotsY := width*2, height*4
+	spanU, spanV := math.Max(maxU-minU, 1e-9), math.Max(maxV-minV, 1e-9)
+	scale := math.Min(float64(dotsX-1)/spanU, float64(dotsY-1)/spanV)
+	usedX := int(spanU*scale) + 1
+	usedY := int(spanV*scale) + 1
+	cols, rows := (usedX+1)/2, (usedY+3)/4
+
+	cells := make([]rune, cols*rows)
+	depth := make([]float64, cols*rows)
+	heights := make([]float64, cols*rows)
+	for i := range depth {
+		depth[i] = math.Inf(-1)
+	}
+	for _, p := range points {
+		u, v, d := project(p)
+		x := int((u - minU) * scale)
+		y := int((maxV - v) * scale)
+		cell := y/4*cols + x/2
+		cells[cell] |= brailleDots[y%4][x%2]
+		if d > depth[cell] {
+			depth[cell] = d
+			heights[cell] = p[2]
+		}
+	}
+
+	lines := make([]string, rows)
+	for r := 0; r < rows; r++ {
+		var line strings.Builder
+		var run strings.Builder
+		runColor := -1
+		flush := func() {
+			if run.Len() > 0 {
+				line.WriteString(lipgloss.NewStyle().Foreground(heightRamp[runColor]).Render(run.String()))
+				run.Reset()
+			}
+		}
+		for c := 0; c < cols; c++ {
+			i := r*cols + c
+			// Blanks continue the current run
+			color := max(runColor, 0)
+			if cells[i] != 0 {
+				color = 0
+				if hi[2] > lo[2] {
+					color = min(int((heights[i]-lo[2])/(hi[2]-lo[2])*float64(len(heightRamp))), len(heightRamp)-1)
+				}
+			}
+			if color != runColor {
+				flush()
+				runColor = color
+			}
+			if cells[i] == 0 {
+				run.WriteRune(' ')
+			} else {
+				run.WriteRune(0x2800 + cells[i])
+			}
+		}
+		flush()
+		lines[r] = line.String()
+	}
+	return lines
+}
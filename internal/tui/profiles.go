@@ -0,0 +1,142 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/cloudcompare-automation/internal/profile"
+)
+
+// profilePicker asks which profile to start with, when a shared machine
+// has profiles and none was given by --profile or CCAUTO_PROFILE. It runs
+// as a program of its own before the TUI, as the form and the last run
+// restored at the start depend on the profile.
+type profilePicker struct {
+	styles   Styles
+	names    []string // Profiles, after the shared state
+	cursor   int      // 0 is the shared state, len(names)+1 a new profile
+	naming   bool     // Typing the name of a new profile
+	input    textinput.Model
+	err      string
+	chosen   string
+	done     bool
+	canceled bool
+}
+
+// PickProfile lets the user choose one of names, the shared state ("") or
+// a new profile, which it creates. ok is false when they quit instead.
+func PickProfile(names []string, palette string) (name string, ok bool, err error) {
+	input := textinput.New()
+	input.Placeholder = "name"
+	input.CharLimit = profile.MaxName
+	picker := profilePicker{styles: NewStyles(palette), names: names, input: input}
+
+	final, err := tea.NewProgram(picker).Run()
+	if err != nil {
+		return "", false, err
+	}
+	picker = final.(profilePicker)
+	if picker.canceled {
+		return "", false, nil
+	}
+	if picker.chosen != "" {
+		if err := profile.Create(picker.chosen); err != nil {
+			return "", false, err
+		}
+	}
+	return picker.chosen, true, nil
+}
+
+func (p profilePicker) Init() tea.Cmd {
+	return nil
+}
+
+func (p profilePicker) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return p, nil
+	}
+	if key.String() == "ctrl+c" {
+		p.canceled = true
+		return p, tea.Quit
+	}
+
+	if p.naming {
+		switch key.String() {
+		case "esc":
+			p.naming, p.err = false, ""
+			p.input.Blur()
+			return p, nil
+		case "enter":
+			name := strings.TrimSpace(p.input.Value())
+			if err := profile.Check(name); err != nil {
+				p.err = err.Error()
+				return p, nil
+			}
+			p.chosen, p.done = name, true
+			return p, tea.Quit
+		}
+		var cmd tea.Cmd
+		p.input, cmd = p.input.Update(msg)
+		p.err = ""
+		return p, cmd
+	}
+
+	switch key.String() {
+	case "up", "k":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case "down", "j":
+		if p.cursor < len(p.names)+1 {
+			p.cursor++
+		}
+	case "enter":
+		switch {
+		case p.cursor == len(p.names)+1:
+			p.naming = true
+			return p, p.input.Focus()
+		case p.cursor > 0:
+			p.chosen = p.names[p.cursor-1]
+		}
+		p.done = true
+		return p, tea.Quit
+	case "q", "esc":
+		p.canceled = true
+		return p, tea.Quit
+	}
+	return p, nil
+}
+
+func (p profilePicker) View() string {
+	if p.done || p.canceled {
+		return ""
+	}
+	s := p.styles
+
+	var b strings.Builder
+	b.WriteString(s.Title.Render("Choose a profile") + "\n\n")
+	items := append([]string{"Shared (no profile)"}, p.names...)
+	items = append(items, "New profile…")
+	for i, item := range items {
+		if i == p.cursor {
+			b.WriteString(s.ListItemSelected.Render("> "+item) + "\n")
+		} else {
+			b.WriteString(s.ListItem.Render("  "+item) + "\n")
+		}
+	}
+
+	if p.naming {
+		b.WriteString("\n" + s.FormLabel.Render("Profile name: ") + p.input.View() + "\n")
+		if p.err != "" {
+			b.WriteString(s.TextError.Render(p.err) + "\n")
+		}
+		b.WriteString("\n" + s.RenderKeyHelp("enter", "create") + "  " + s.RenderKeyHelp("esc", "back") + "\n")
+		return b.String()
+	}
+	b.WriteString(fmt.Sprintf("\n%s  %s  %s\n", s.RenderKeyHelp("↑/↓", "select"), s.RenderKeyHelp("enter", "start"), s.RenderKeyHelp("q", "quit")))
+	return b.String()
+}
@@ -0,0 +1,176 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/cloudcompare-automation/internal/config"
+	"github.com/cloudcompare-automation/internal/processor"
+)
+
+// A dataset can carry its own settings in a .cloudcompare.yaml in its
+// directory or a parent. Once the Configuration screen's input directory is
+// left, the file that applies is looked up in the background and its
+// settings are filled into the form; moving on to a directory it doesn't
+// cover puts back the values it replaced, unless they were edited since.
+
+// projectLoadedMsg carries the project configuration found for a directory
+type projectLoadedMsg struct {
+	dir     string
+	path    string
+	project config.Project
+	err     error
+}
+
+// projectOverride is a form field set from a project configuration, with
+// the value it had before
+type projectOverride struct {
+	field  FocusedField
+	before string
+	value  string
+}
+
+// loadProject looks up and reads the project configuration of dir
+func loadProject(dir string) tea.Cmd {
+	return func() tea.Msg {
+		project, path, err := config.LoadProject(dir)
+		return projectLoadedMsg{dir: dir, path: path, project: project, err: err}
+	}
+}
+
+// refreshProject starts looking up the project configuration when the
+// Configuration screen's input directory was left on a directory it wasn't
+// looked up for
+func refreshProject(model tea.Model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	m, ok := model.(Model)
+	if !ok || m.screen != ScreenParams || m.focusedField == FocusInputDir {
+		return model, cmd
+	}
+	dir := m.inputDir()
+	if dir == "" || dir == m.projectDir || !m.dirUsable(dir) {
+		return model, cmd
+	}
+	m.projectDir = dir
+	return m, tea.Batch(cmd, loadProject(dir))
+}
+
+// projectLoaded switches the form to the project configuration found
+func (m Model) projectLoaded(msg projectLoadedMsg) (tea.Model, tea.Cmd) {
+	// Ignore a directory the form has moved on from
+	if msg.dir != m.projectDir {
+		return m, nil
+	}
+	m.projectErr = msg.err
+	if msg.err == nil && msg.path == m.projectPath {
+		return m, nil
+	}
+	m = m.revertProject()
+	if msg.err != nil || msg.path == "" {
+		return refreshFormSave(refreshParams(m, nil))
+	}
+	m.projectPath = msg.path
+	m = m.applyProject(msg.project)
+	return refreshFormSave(refreshParams(m, nil))
+}
+
+// applyProject fills the form with a project configuration's settings,
+// remembering what they replace
+func (m Model) applyProject(project config.Project) Model {
+	m.projectBefore = m.params
+	m.projectOverrides = nil
+	set := func(field FocusedField, value string) {
+		if int(field) >= len(m.inputs) {
+			return
+		}
+		m.projectOverrides = append(m.projectOverrides, projectOverride{field: field, before: m.inputs[field].Value(), value: value})
+		m.inputs[field].SetValue(value)
+	}
+
+	if project.OutputSubdir != "" {
+		set(FocusOutputSubdir, project.OutputSubdir)
+	}
+	if len(project.Include) > 0 {
+		set(FocusInclude, strings.Join(project.Include, ", "))
+	}
+	if len(project.Exclude) > 0 {
+		set(FocusExclude, strings.Join(project.Exclude, ", "))
+	}
+	if project.WebExport != "" {
+		set(FocusWebExport, project.WebExport)
+	}
+	if len(project.Labels) > 0 {
+		set(FocusLabels, strings.Join(project.Labels, ", "))
+	}
+
+	// Metadata without a form field, the output name and layout and
+	// skipping processed files have no field, so they go to the parameters
+	metadata := make(map[string]string, len(m.params.Metadata))
+	for key, value := range m.params.Metadata {
+		metadata[key] = value
+	}
+	for key, value := range project.Metadata {
+		metadata[key] = value
+	}
+	for i, field := range processor.MetadataFields {
+		if value, ok := project.Metadata[field.Key]; ok {
+			set(FocusMetadata+FocusedField(i), value)
+		}
+	}
+	m.params.Metadata = metadata
+	if project.OutputName != "" {
+		m.params.OutputName = project.OutputName
+	}
+	if project.OutputLayout != "" {
+		m.params.OutputLayout = project.OutputLayout
+	}
+	if project.SkipExisting != nil {
+		m.params.SkipExisting = *project.SkipExisting
+	}
+
+	values, err := project.Values(m.schema())
+	if err != nil {
+		m.projectErr = fmt.Errorf("invalid project config %s: %v", m.projectPath, err)
+	}
+	for i, spec := range m.schema() {
+		if value, ok := values[spec.Name]; ok {
+			set(FocusParams+FocusedField(i), value)
+		}
+	}
+	return m
+}
+
+// revertProject puts back the form values the project configuration in
+// effect replaced, except those edited since
+func (m Model) revertProject() Model {
+	if m.projectPath == "" {
+		return m
+	}
+	for _, o := range m.projectOverrides {
+		if int(o.field) < len(m.inputs) && m.inputs[o.field].Value() == o.value {
+			m.inputs[o.field].SetValue(o.before)
+		}
+	}
+	m.params.Metadata = m.projectBefore.Metadata
+	m.params.OutputName = m.projectBefore.OutputName
+	m.params.OutputLayout = m.projectBefore.OutputLayout
+	m.params.SkipExisting = m.projectBefore.SkipExisting
+	m.projectPath = ""
+	m.projectOverrides = nil
+	return m
+}
+
+// projectLines tell the summary panel which project configuration is in
+// effect, or why it couldn't be read
+func (m Model) projectLines(width int) []string {
+	s := m.styles
+	var lines []string
+	if m.projectPath != "" {
+		lines = append(lines, "", s.Text.Render("⚙ Project config:"), s.StatusInfo.Render(" "+truncateLeft(m.projectPath, width-2)))
+	}
+	if m.projectErr != nil {
+		lines = append(lines, s.StatusWarning.Render("⚠ "+truncate(m.projectErr.Error(), width-2)))
+	}
+	return lines
+}
@@ -0,0 +1,96 @@
+package tui
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/cloudcompare-automation/internal/processor"
+	"github.com/cloudcompare-automation/internal/profile"
+)
+
+// The parameters of the last run started are saved next to the form, so
+// the Welcome screen can run them again on their directory with one key
+// and a confirmation, for the folder processed every day.
+
+// lastRunPath returns the file the last run's parameters are saved in
+func lastRunPath() (string, error) {
+	dir, err := profile.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "lastrun.json"), nil
+}
+
+// loadLastRun reads the parameters of the last run; nil when there is
+// none or it can't be read
+func loadLastRun() *processor.Params {
+	path, err := lastRunPath()
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var params processor.Params
+	if err := json.Unmarshal(data, &params); err != nil || params.InputDir == "" {
+		return nil
+	}
+	return &params
+}
+
+// saveLastRun writes the parameters of a run that started, in the
+// background
+func saveLastRun(params processor.Params) tea.Cmd {
+	return func() tea.Msg {
+		path, err := lastRunPath()
+		if err != nil {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil
+		}
+		data, err := json.MarshalIndent(params, "", "  ")
+		if err != nil {
+			return nil
+		}
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0o644); err == nil {
+			os.Rename(tmp, path)
+		}
+		return nil
+	}
+}
+
+// quickRunText describes what a quick run would process, for the
+// confirmation
+func (m Model) quickRunText() string {
+	params := *m.lastRun
+	params.Files = nil
+	return m.runText(params) + " with the last settings?"
+}
+
+// quickRun fills the form with the last run's settings and starts
+// processing its directory; a listed file selection is left out. Whatever
+// stops it from starting is shown on the Configuration screen, where it
+// can be fixed.
+func (m Model) quickRun() (tea.Model, tea.Cmd) {
+	m.quickRunConfirm = false
+	params := *m.lastRun
+	params.Files = nil
+	return m.runAgain(params)
+}
+
+// runAgain fills the form with params and starts processing them, or
+// shows on the Configuration screen what stops them from starting
+func (m Model) runAgain(params processor.Params) (tea.Model, tea.Cmd) {
+	m.relaunch = nil
+	m = m.pushUndo(m.formState())
+	m = m.applyParams(params)
+	m.imported = nil
+	m = m.openForm()
+	return m.startProcessing()
+}
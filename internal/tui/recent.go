@@ -0,0 +1,182 @@
+package tui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/cloudcompare-automation/internal/history"
+	"github.com/cloudcompare-automation/internal/processor"
+)
+
+// The Welcome screen lists the last few runs recorded in the history, to
+// open the results of one again, or to run it again with its settings
+// after a confirmation, like the quick run.
+
+// recentCount is the most runs the Welcome screen lists
+const recentCount = 5
+
+// recentLoadedMsg carries the latest recorded runs
+type recentLoadedMsg struct {
+	runs []history.Entry
+}
+
+// recentOpenedMsg carries the result of a recorded run, rebuilt from its
+// report, with the settings it ran with as far as they can be found
+type recentOpenedMsg struct {
+	run    history.Entry
+	result processor.ProcessingResult
+	params processor.Params
+}
+
+// relaunchMsg carries the settings to run a recorded run again with, or
+// why it can't be
+type relaunchMsg struct {
+	params processor.Params
+	err    error
+}
+
+// loadRecent reads the latest recorded runs; a history that can't be
+// read lists none
+func loadRecent() tea.Msg {
+	runs, _ := history.List()
+	return recentLoadedMsg{runs: runs[:min(len(runs), recentCount)]}
+}
+
+// openRecent reads the report of a recorded run in the background. The
+// settings are those RerunParams finds, over params; when the input is
+// gone, only its directory and labels.
+func openRecent(run history.Entry, params processor.Params, pipelines []processor.Pipeline) tea.Cmd {
+	return func() tea.Msg {
+		msg := recentOpenedMsg{run: run, result: processor.ResultFromReport(run)}
+		var err error
+		if msg.params, err = processor.RerunParams(params, pipelines, run); err != nil {
+			msg.params = params
+			msg.params.InputDir, msg.params.InputFile, msg.params.Files = run.Input, "", nil
+			msg.params.Labels = run.Labels
+		}
+		return msg
+	}
+}
+
+// relaunchRecent finds the settings to run a recorded run again with, in
+// the background. Its files were processed already, so they aren't
+// skipped as done.
+func relaunchRecent(run history.Entry, params processor.Params, pipelines []processor.Pipeline) tea.Cmd {
+	return func() tea.Msg {
+		params, err := processor.RerunParams(params, pipelines, run)
+		params.SkipExisting = false
+		return relaunchMsg{params: params, err: err}
+	}
+}
+
+// recentPipelines are the pipelines a recorded run can have used; without
+// any given, the default with its built-in schema, like the form
+func (m Model) recentPipelines() []processor.Pipeline {
+	if len(m.pipelines) == 0 {
+		return []processor.Pipeline{{Name: processor.DefaultPipeline, Params: processor.DefaultSchema}}
+	}
+	return m.pipelines
+}
+
+// recentLoaded keeps the latest runs for the Welcome screen, with the
+// same one selected if it is still listed
+func (m Model) recentLoaded(msg recentLoadedMsg) Model {
+	selected := ""
+	if m.recentCursor < len(m.recentRuns) {
+		selected = m.recentRuns[m.recentCursor].ID
+	}
+	m.recentRuns = msg.runs
+	m.recentCursor = 0
+	for i, run := range m.recentRuns {
+		if run.ID == selected {
+			m.recentCursor = i
+		}
+	}
+	return m
+}
+
+// recentOpened switches to the results screen of a recorded run
+func (m Model) recentOpened(msg recentOpenedMsg) Model {
+	m = m.clearResults()
+	m.params = msg.params
+	m.result = msg.result
+	m.filesDone = msg.result.SuccessCount
+	m.filesTotal = msg.result.TotalFiles
+	m.elapsedTime = msg.run.FinishedAt.Sub(msg.run.StartedAt)
+
+	m.screen = ScreenResults
+	m.resultsPage = 0
+	m.resultsCursor = 0
+	m.resultsFailedOnly = false
+	m.resultsShowLog = false
+	m.reviews = nil
+	m.reviewErr = nil
+	m.delivered = msg.run.Delivered
+	return m
+}
+
+// relaunched asks to run a recorded run again with the settings found
+func (m Model) relaunched(msg relaunchMsg) Model {
+	m.recentErr = msg.err
+	if msg.err == nil && m.screen == ScreenWelcome {
+		m.relaunch = &msg.params
+	}
+	return m
+}
+
+// runText describes what a run with params would process, e.g. "Run Mesh
+// reconstruction on D:\Surveys\harbour"
+func (m Model) runText(params processor.Params) string {
+	pipeline := processor.DefaultPipeline
+	for _, p := range m.pipelines {
+		if params.Script != "" && p.Script == params.Script {
+			pipeline = p.Name
+		}
+	}
+	input := params.InputDir
+	switch {
+	case params.InputFile != "":
+		input = params.InputFile
+	case len(params.Files) > 0:
+		input = fmt.Sprintf("%d listed file(s)", len(params.Files))
+	}
+	return fmt.Sprintf("Run %s on %s", pipeline, input)
+}
+
+// recentIcon marks the outcome of a recorded run
+func (m Model) recentIcon(run history.Entry) string {
+	s := m.styles
+	switch {
+	case run.Aborted != "" || run.Total > 0 && run.Failed == run.Total:
+		return s.StatusError.Render(s.Icons.Error)
+	case run.Failed > 0 || run.Warned > 0 || run.Stopped:
+		return s.StatusWarning.Render(s.Icons.Warning)
+	default:
+		return s.StatusSuccess.Render(s.Icons.Success)
+	}
+}
+
+// viewRecent renders the latest runs, at most rows of them, with the
+// selected one marked
+func (m Model) viewRecent(rows int) string {
+	s := m.styles
+	width := min(m.width-8, 76)
+
+	lines := []string{s.TextMuted.Render("Recent runs")}
+	for i, run := range m.recentRuns[:min(rows, len(m.recentRuns))] {
+		row := m.recentIcon(run) + " " + historyRow(run, width-4)
+		if i == m.recentCursor {
+			lines = append(lines, s.SelectedItem.Render("▶ "+row))
+		} else {
+			lines = append(lines, s.Text.Render("  "+row))
+		}
+	}
+	if m.recentErr != nil {
+		lines = append(lines, s.StatusError.Render("⚠ "+truncate(m.recentErr.Error(), width-2)))
+	} else {
+		lines = append(lines, s.TextMuted.Render("↑↓ select · o results · l run again"))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
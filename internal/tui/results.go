@@ -0,0 +1,217 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/cloudcompare-automation/internal/humanize"
+	"github.com/cloudcompare-automation/internal/processor"
+)
+
+// resultOrder is how the results screen sorts the processed files
+type resultOrder int
+
+const (
+	orderName     resultOrder = iota
+	orderDuration             // Slowest first
+	orderPoints               // Largest first
+	orderOutcome              // Failures first
+)
+
+var resultOrderNames = []string{"name", "duration", "points", "outcome"}
+
+// outcomeRank orders outcomes for sorting, failures first
+var outcomeRank = map[processor.Outcome]int{
+	processor.OutcomeFailed:  0,
+	processor.OutcomeWarning: 1,
+	processor.OutcomeSuccess: 2,
+}
+
+// resultFiles returns the processed files in the chosen order, only the
+// failed ones if so filtered
+func (m Model) resultFiles() []processor.FileResult {
+	var files []processor.FileResult
+	for _, f := range m.result.Files {
+		if !m.resultsFailedOnly || f.Outcome() == processor.OutcomeFailed {
+			files = append(files, f)
+		}
+	}
+	name := func(f processor.FileResult) string { return strings.ToLower(filepath.Base(f.InputFile)) }
+	sort.SliceStable(files, func(i, j int) bool {
+		a, b := files[i], files[j]
+		switch m.resultsOrder {
+		case orderDuration:
+			return a.Duration > b.Duration
+		case orderPoints:
+			return a.Points > b.Points
+		case orderOutcome:
+			if ra, rb := outcomeRank[a.Outcome()], outcomeRank[b.Outcome()]; ra != rb {
+				return ra < rb
+			}
+		}
+		return name(a) < name(b)
+	})
+	return files
+}
+
+// resultsPageSize returns how many files fit on a page of the table,
+// above the review of the selected file
+func (m Model) resultsPageSize() int {
+	return max(m.height-26, 5)
+}
+
+// resultsPages returns the number of pages of the table
+func (m Model) resultsPages() int {
+	n := len(m.resultFiles())
+	return max((n+m.resultsPageSize()-1)/m.resultsPageSize(), 1)
+}
+
+// showFileTable reports whether the results screen lists the files rather
+// than the log; a single file's log says it all
+func (m Model) showFileTable() bool {
+	return len(m.result.Files) > 1 && !m.resultsShowLog
+}
+
+// updateResultsTable handles the keys of the file table, reporting whether
+// the key was one of them
+func (m Model) updateResultsTable(msg tea.KeyMsg) (Model, bool) {
+	switch msg.String() {
+	case "tab":
+		m.resultsShowLog = !m.resultsShowLog
+	case "o":
+		m.resultsOrder = (m.resultsOrder + 1) % resultOrder(len(resultOrderNames))
+		m.resultsPage, m.resultsCursor = 0, 0
+	case "f":
+		m.resultsFailedOnly = !m.resultsFailedOnly
+		m.resultsPage, m.resultsCursor = 0, 0
+	case "down", "j":
+		m.resultsCursor = min(m.resultsCursor+1, max(len(m.resultFiles())-1, 0))
+		m.resultsPage = m.resultsCursor / m.resultsPageSize()
+	case "up", "k":
+		m.resultsCursor = max(m.resultsCursor-1, 0)
+		m.resultsPage = m.resultsCursor / m.resultsPageSize()
+	case "right", "l", "pgdown":
+		m.resultsPage = min(m.resultsPage+1, m.resultsPages()-1)
+		m.resultsCursor = min(m.resultsPage*m.resultsPageSize(), max(len(m.resultFiles())-1, 0))
+	case "left", "h", "pgup":
+		m.resultsPage = max(m.resultsPage-1, 0)
+		m.resultsCursor = m.resultsPage * m.resultsPageSize()
+	default:
+		return m, false
+	}
+	m.reviewErr = nil
+	return m, true
+}
+
+// viewFileTable renders a page of the processed files with their outcome,
+// duration and size, whether their mesh is open, whether they were
+// reviewed or noted, and the error or first warning, then the mesh quality
+// and review of the selected one
+func (m Model) viewFileTable() string {
+	s := m.styles
+	files := m.resultFiles()
+
+	title := fmt.Sprintf("📋 Files by %s", resultOrderNames[m.resultsOrder])
+	if m.resultsFailedOnly {
+		title += ", failed only"
+	}
+	lines := []string{s.BoxTitle.Render(title)}
+
+	nameWidth := 28
+	size := m.resultsPageSize()
+	first := m.resultsPage * size
+	for i := first; i < len(files) && i < first+size; i++ {
+		f := files[i]
+		points := ""
+		if f.Points > 0 {
+			points = humanize.Count(f.Points)
+		}
+		row := fmt.Sprintf("%-*s %9s %8s", nameWidth, truncate(filepath.Base(f.InputFile), nameWidth), humanize.Duration(f.Duration), points)
+		if q := f.Quality; q != nil && !q.Watertight {
+			row += " [open]"
+		}
+		if review, ok := m.reviews[f.InputFile]; ok {
+			if review.Reviewed {
+				row += " [reviewed]"
+			}
+			if review.Note != "" {
+				row += " [note]"
+			}
+		}
+		note := f.Error
+		if note == "" && len(f.Warnings) > 0 {
+			note = f.Warnings[0]
+		}
+		if note != "" {
+			row += "  " + note
+		}
+		row = truncate(row, m.width-8)
+
+		cursor := "  "
+		if i == m.resultsCursor {
+			cursor = "▶ "
+		}
+		switch f.Outcome() {
+		case processor.OutcomeFailed:
+			lines = append(lines, s.TextError.Render(cursor+s.Icons.Error+" "+row))
+		case processor.OutcomeWarning:
+			lines = append(lines, s.StatusWarning.Render(cursor+s.Icons.Warning+" "+row))
+		default:
+			lines = append(lines, s.TextSuccess.Render(cursor+s.Icons.Success+" "+row))
+		}
+	}
+	switch {
+	case len(files) == 0:
+		lines = append(lines, s.TextMuted.Render("  No failed files"))
+	case m.resultsPages() > 1:
+		lines = append(lines, s.TextMuted.Render(fmt.Sprintf("  Page %d of %d [%d-%d of %d]",
+			m.resultsPage+1, m.resultsPages(), first+1, min(first+size, len(files)), len(files))))
+	}
+	if quality := m.viewQuality(); quality != "" {
+		lines = append(lines, quality)
+	}
+	lines = append(lines, m.viewReview())
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+// viewQuality renders the mesh quality of the selected file, if the
+// script measured it
+func (m Model) viewQuality() string {
+	f, ok := m.selectedResult()
+	if !ok || f.Quality == nil {
+		return ""
+	}
+	return m.styles.TextMuted.Render(truncate("🔺 Mesh: "+f.Quality.String(), m.width-8))
+}
+
+// resultsTableKeys are the key help of the file table and the review of
+// the selected file
+func (m Model) resultsTableKeys() string {
+	s := m.styles
+	if m.reviewNote.Focused() {
+		return s.RenderKeyHelp("enter", "save note") + "  " + s.RenderKeyHelp("esc", "cancel") + "  "
+	}
+	review := s.RenderKeyHelp("n", "note") + "  " + s.RenderKeyHelp("v", "reviewed") + "  "
+	if m.observer {
+		review = ""
+	}
+	if !m.showFileTable() {
+		if len(m.result.Files) > 1 {
+			return s.RenderKeyHelp("tab", "files") + "  "
+		}
+		if len(m.result.Files) == 1 {
+			return review
+		}
+		return ""
+	}
+	keys := s.RenderKeyHelp("↑↓", "select") + "  " + review + s.RenderKeyHelp("o", "sort") + "  " + s.RenderKeyHelp("f", "failed only") + "  "
+	if m.resultsPages() > 1 {
+		keys += s.RenderKeyHelp("←→", "page") + "  "
+	}
+	return keys + s.RenderKeyHelp("tab", "log") + "  "
+}
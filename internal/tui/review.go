@@ -0,0 +1,174 @@
+package tui
+
+import (
+	"errors"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/cloudcompare-automation/internal/history"
+	"github.com/cloudcompare-automation/internal/processor"
+	"github.com/cloudcompare-automation/internal/report"
+)
+
+// On the results screen the operator can note what they found in a file's
+// outputs and sign them off as reviewed. Both are kept in the run's report
+// and its history entry, for QA of deliverables.
+
+// errNoReport is shown when a review can't be saved as the run wrote no
+// report
+var errNoReport = errors.New("the run wrote no report to keep the review in")
+
+// reviewSavedMsg reports the outcome of saving a review
+type reviewSavedMsg struct {
+	err error
+}
+
+// saveReview writes the review of a file to the run's report and history
+func saveReview(reportPath, input string, review report.Review) tea.Cmd {
+	return func() tea.Msg {
+		return reviewSavedMsg{err: history.SetReview(reportPath, input, review)}
+	}
+}
+
+// newReviewInput creates the note input of the results screen
+func newReviewInput() textinput.Model {
+	input := textinput.New()
+	input.Placeholder = "what you found in the outputs"
+	input.CharLimit = 256
+	input.Width = 50
+	return input
+}
+
+// reviewer returns who signs a review off: the run's operator, or the
+// user logged in
+func (m Model) reviewer() string {
+	if operator := m.params.Metadata["operator"]; operator != "" {
+		return operator
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+// reviewTyping reports whether the note input has the keyboard
+func (m Model) reviewTyping() bool {
+	return m.screen == ScreenResults && m.reviewNote.Focused()
+}
+
+// selectedResult returns the file under the cursor of the file table, or
+// the only file of the run
+func (m Model) selectedResult() (processor.FileResult, bool) {
+	if len(m.result.Files) == 1 {
+		return m.result.Files[0], true
+	}
+	files := m.resultFiles()
+	if !m.showFileTable() || m.resultsCursor >= len(files) {
+		return processor.FileResult{}, false
+	}
+	return files[m.resultsCursor], true
+}
+
+// updateReview handles the keys that note or sign off the selected file,
+// reporting whether the key was one of them
+func (m Model) updateReview(msg tea.KeyMsg) (Model, tea.Cmd, bool) {
+	f, ok := m.selectedResult()
+	if !ok {
+		return m, nil, false
+	}
+	review := m.reviews[f.InputFile]
+
+	if m.reviewNote.Focused() {
+		switch msg.String() {
+		case "enter":
+			m.reviewNote.Blur()
+			review.Note = strings.TrimSpace(m.reviewNote.Value())
+			return m.setReview(f.InputFile, review)
+		case "esc":
+			m.reviewNote.Blur()
+			return m, nil, true
+		}
+		var cmd tea.Cmd
+		m.reviewNote, cmd = m.reviewNote.Update(msg)
+		return m, cmd, true
+	}
+
+	switch msg.String() {
+	case "n":
+		m.reviewNote.SetValue(review.Note)
+		m.reviewNote.CursorEnd()
+		return m, m.reviewNote.Focus(), true
+	case "v":
+		review.Reviewed = !review.Reviewed
+		review.ReviewedBy, review.ReviewedAt = "", nil
+		if review.Reviewed {
+			now := time.Now()
+			review.ReviewedBy, review.ReviewedAt = m.reviewer(), &now
+		}
+		return m.setReview(f.InputFile, review)
+	}
+	return m, nil, false
+}
+
+// setReview keeps the review of a file and saves it with the run's report;
+// a run without a report keeps it only on screen
+func (m Model) setReview(input string, review report.Review) (Model, tea.Cmd, bool) {
+	reviews := make(map[string]report.Review, len(m.reviews)+1)
+	for file, r := range m.reviews {
+		reviews[file] = r
+	}
+	delete(reviews, input)
+	if !review.IsZero() {
+		reviews[input] = review
+	}
+	m.reviews = reviews
+
+	if m.result.ReportPath == "" {
+		m.reviewErr = errNoReport
+		return m, nil, true
+	}
+	m.reviewErr = nil
+	return m, saveReview(m.result.ReportPath, input, review), true
+}
+
+// viewReview renders the note and sign-off of the selected file, or the
+// note input while it is edited
+func (m Model) viewReview() string {
+	s := m.styles
+	if m.reviewNote.Focused() {
+		return s.FormLabel.Render("Note: ") + m.reviewNote.View()
+	}
+	if m.reviewErr != nil {
+		return s.StatusError.Render("⚠ " + truncate(m.reviewErr.Error(), m.width-10))
+	}
+	f, ok := m.selectedResult()
+	if !ok {
+		return ""
+	}
+	review, ok := m.reviews[f.InputFile]
+	if !ok {
+		return ""
+	}
+
+	var parts []string
+	if review.Reviewed {
+		signoff := "Reviewed"
+		if review.ReviewedBy != "" {
+			signoff += " by " + review.ReviewedBy
+		}
+		if review.ReviewedAt != nil {
+			signoff += " at " + review.ReviewedAt.Format("15:04")
+		}
+		parts = append(parts, s.Icons.Success+" "+signoff)
+	}
+	if review.Note != "" {
+		parts = append(parts, "📝 "+review.Note)
+	}
+	line := filepath.Base(f.InputFile) + ": " + strings.Join(parts, "  ")
+	return s.StatusInfo.Render(truncate(line, m.width-6))
+}
@@ -0,0 +1,84 @@
+package tui
+
+import (
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/cloudcompare-automation/internal/processor"
+)
+
+// selection is what the Configuration screen selects in the input
+// directory, counted in the background as reading a large or remote
+// directory can take a while
+type selection struct {
+	key      string
+	matched  []string
+	skipped  []string
+	existing int    // Matched files SkipExisting would leave out
+	example  string // Project of the first matched file, as the layout and lookup name it
+	err      error
+}
+
+// selectionLoadedMsg carries a counted selection
+type selectionLoadedMsg struct {
+	selection selection
+}
+
+// loadSelection lists the LAS files of dir the include and exclude
+// patterns select and skip
+func loadSelection(cache *lasCache, key, dir string, include, exclude []string, params processor.Params) tea.Cmd {
+	return func() tea.Msg {
+		sel := selection{key: key}
+		files, err := cache.list(dir)
+		if err != nil {
+			sel.err = err
+			return selectionLoadedMsg{selection: sel}
+		}
+		sel.matched, sel.skipped = processor.SplitFiles(files, include, exclude)
+		_, sel.existing = params.PendingFiles(sel.matched)
+		if len(sel.matched) > 0 {
+			sel.example = params.Plan(sel.matched, time.Now()).Output(sel.matched[0])
+		}
+		return selectionLoadedMsg{selection: sel}
+	}
+}
+
+// refreshSelection starts counting the LAS files when the Configuration
+// screen shows a directory, patterns or output directory they weren't
+// counted for
+func refreshSelection(model tea.Model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	m, ok := model.(Model)
+	if !ok || m.screen != ScreenParams {
+		return model, cmd
+	}
+	dir := m.inputDir()
+	if dir == "" || !m.dirUsable(dir) {
+		return model, cmd
+	}
+	params := m.params
+	params.OutputSubdir = m.inputs[FocusOutputSubdir].Value()
+	if params.OutputSubdir == "" {
+		params.OutputSubdir = "Processed"
+	}
+	include := m.inputs[FocusInclude].Value()
+	exclude := m.inputs[FocusExclude].Value()
+	key := strings.Join([]string{dir, include, exclude, params.OutputSubdir, params.OutputLayout}, "\x00")
+	if key == m.selectionKey {
+		return model, cmd
+	}
+	m.selectionKey = key
+	return m, tea.Batch(cmd, loadSelection(m.lasFiles, key, dir, processor.ParsePatterns(include), processor.ParsePatterns(exclude), params))
+}
+
+// selectionCounted reports whether the summary's file count is up to date
+func (m Model) selectionCounted() bool {
+	return m.selectionKey != "" && m.selection.key == m.selectionKey
+}
+
+// refreshParams starts the background work the Configuration screen's
+// summary needs after a key press
+func refreshParams(model tea.Model, cmd tea.Cmd) (tea.Model, tea.Cmd) {
+	return refreshProject(refreshDirCheck(refreshSelection(refreshBounds(model, cmd))))
+}
@@ -0,0 +1,135 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/cloudcompare-automation/internal/history"
+	"github.com/cloudcompare-automation/internal/humanize"
+)
+
+// statsMonths is how many months the statistics screen covers
+const statsMonths = 12
+
+// sparkBars are the sparkline levels, lowest first
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// openStats switches to the statistics screen and loads the history
+func (m Model) openStats() (tea.Model, tea.Cmd) {
+	m.screen = ScreenStats
+	m.err = nil
+	return m, loadHistory
+}
+
+func (m Model) updateStats(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "r":
+		return m, loadHistory
+	case "h":
+		return m.openHistory()
+	case "esc":
+		m.screen = ScreenWelcome
+	}
+	return m, nil
+}
+
+// viewStats renders processed volume per month from the history
+func (m Model) viewStats() string {
+	s := m.styles
+
+	periods := history.Monthly(m.runs, statsMonths, time.Now())
+	total := history.Total(periods)
+
+	var parts []string
+	parts = append(parts, s.HeaderTitle.Render("📈 Statistics"))
+	if m.err != nil {
+		parts = append(parts, s.StatusError.Render("⚠ "+truncate(m.err.Error(), m.width-10)))
+	}
+	parts = append(parts,
+		s.TextMuted.Render(fmt.Sprintf("Last %d months: %s – %s, %d run(s)",
+			statsMonths, periods[0].Start.Format("Jan 2006"), periods[len(periods)-1].Start.Format("Jan 2006"), total.Runs)),
+		"",
+	)
+
+	// One sparkline per metric, a column per month
+	metric := func(label string, value func(history.Period) float64, summary string) string {
+		values := make([]float64, len(periods))
+		for i, p := range periods {
+			values[i] = value(p)
+		}
+		return s.FormLabel.Copy().Width(15).Render(label) +
+			s.StatusInfo.Render(sparkline(values)) + "  " +
+			s.Text.Render(summary)
+	}
+	parts = append(parts,
+		metric("Files", func(p history.Period) float64 { return float64(p.Files) }, fmt.Sprintf("%d total", total.Files)),
+		metric("Points", func(p history.Period) float64 { return float64(p.Points) }, humanize.Count(total.Points)+" total"),
+		metric("Compute hours", func(p history.Period) float64 { return p.Hours }, fmt.Sprintf("%.1f h total", total.Hours)),
+		metric("Failure rate", history.Period.FailureRate, fmt.Sprintf("%.1f%% overall", total.FailureRate()*100)),
+		s.TextMuted.Render(strings.Repeat(" ", 15)+monthAxis(periods)),
+		"",
+	)
+
+	// Month by month, newest first, as far as the screen allows
+	rows := []string{s.TextBold.Render(fmt.Sprintf("%-9s %7s %10s %9s %8s", "Month", "Files", "Points", "Hours", "Failed"))}
+	maxRows := max(m.height-18, 1)
+	for i := len(periods) - 1; i >= 0 && len(rows) <= maxRows; i-- {
+		p := periods[i]
+		row := fmt.Sprintf("%-9s %7d %10s %9.1f %7.1f%%",
+			p.Start.Format("Jan 2006"), p.Files, humanize.Count(p.Points), p.Hours, p.FailureRate()*100)
+		if p.Runs == 0 {
+			rows = append(rows, s.TextMuted.Render(row))
+		} else {
+			rows = append(rows, s.Text.Render(row))
+		}
+	}
+	parts = append(parts, lipgloss.JoinVertical(lipgloss.Left, rows...))
+
+	if len(m.runs) == 0 {
+		parts = append(parts, "", s.TextMuted.Render("No runs recorded yet"))
+	}
+
+	footer := s.Footer.Render(
+		s.RenderKeyHelp("h", "history") + " " +
+			s.RenderKeyHelp("r", "refresh") + " " +
+			s.RenderKeyHelp("esc", "back"),
+	)
+	parts = append(parts, "", footer)
+
+	return lipgloss.JoinVertical(lipgloss.Left, parts...)
+}
+
+// sparkline draws values as bars two cells wide, scaled to the largest.
+// A zero value is drawn as a blank so empty months stand out.
+func sparkline(values []float64) string {
+	var top float64
+	for _, v := range values {
+		if v > top {
+			top = v
+		}
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		bar := ' '
+		if v > 0 && top > 0 {
+			bar = sparkBars[min(int(v/top*float64(len(sparkBars)-1)+0.5), len(sparkBars)-1)]
+		}
+		b.WriteRune(bar)
+		b.WriteRune(bar)
+	}
+	return b.String()
+}
+
+// monthAxis labels the sparkline columns with month initials
+func monthAxis(periods []history.Period) string {
+	var b strings.Builder
+	for _, p := range periods {
+		b.WriteString(p.Start.Format("Jan")[:1] + " ")
+	}
+	return b.String()
+}
@@ -1,23 +1,165 @@
 package tui
 
 import (
+	"fmt"
+
 	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// Palette is the colors of the TUI
+type Palette struct {
+	Primary     lipgloss.Color
+	Secondary   lipgloss.Color
+	Success     lipgloss.Color
+	Warning     lipgloss.Color
+	Error       lipgloss.Color
+	Muted       lipgloss.Color
+	Text        lipgloss.Color
+	DimText     lipgloss.Color
+	Bg          lipgloss.Color
+	HighlightBg lipgloss.Color
+}
+
+// Icons mark the outcome of files and runs, so it doesn't rest on color
+// alone
+type Icons struct {
+	Success string
+	Error   string
+	Warning string
+	Info    string
+
+	// Headings of the results screen
+	Complete string
+	Failed   string
+	Partial  string
+}
+
+// Palette names for the palette config setting
+const (
+	PaletteStandard   = "standard"
+	PaletteColorBlind = "colorblind"
+	PaletteMuted      = "muted"
 )
 
-// Color palette
+var standardPalette = Palette{
+	Primary:     lipgloss.Color("#7C3AED"), // Purple
+	Secondary:   lipgloss.Color("#06B6D4"), // Cyan
+	Success:     lipgloss.Color("#10B981"), // Green
+	Warning:     lipgloss.Color("#F59E0B"), // Amber
+	Error:       lipgloss.Color("#EF4444"), // Red
+	Muted:       lipgloss.Color("#6B7280"), // Gray
+	Text:        lipgloss.Color("#F9FAFB"), // White
+	DimText:     lipgloss.Color("#9CA3AF"), // Light gray
+	Bg:          lipgloss.Color("#1F2937"), // Dark gray
+	HighlightBg: lipgloss.Color("#374151"), // Lighter gray
+}
+
+var standardIcons = Icons{
+	Success: "✓", Error: "✗", Warning: "⚠", Info: "ℹ",
+	Complete: "✅", Failed: "❌", Partial: "⚠️",
+}
+
+// The color-blind palette takes its status colors from the Okabe-Ito
+// set, which stay apart with any kind of color blindness: blue for
+// success, vermillion for errors and yellow for warnings. Its icons are
+// solid shapes that differ at a glance.
+var colorBlindPalette = Palette{
+	Primary:     standardPalette.Primary,
+	Secondary:   lipgloss.Color("#CC79A7"), // Reddish purple
+	Success:     lipgloss.Color("#56B4E9"), // Sky blue
+	Warning:     lipgloss.Color("#F0E442"), // Yellow
+	Error:       lipgloss.Color("#D55E00"), // Vermillion
+	Muted:       standardPalette.Muted,
+	Text:        standardPalette.Text,
+	DimText:     standardPalette.DimText,
+	Bg:          standardPalette.Bg,
+	HighlightBg: standardPalette.HighlightBg,
+}
+
+var colorBlindIcons = Icons{
+	Success: "✔", Error: "✖", Warning: "▲", Info: "●",
+	Complete: "✔", Failed: "✖", Partial: "◐",
+}
+
+// The muted palette trades the purple and the bright status colors for
+// slate and grayed ones, for settings where a colorful UI is out of place
+var mutedPalette = Palette{
+	Primary:     lipgloss.Color("#64748B"), // Slate
+	Secondary:   lipgloss.Color("#94A3B8"), // Light slate
+	Success:     lipgloss.Color("#6B8F71"), // Sage
+	Warning:     lipgloss.Color("#B59F6B"), // Ochre
+	Error:       lipgloss.Color("#B07070"), // Dusty red
+	Muted:       standardPalette.Muted,
+	Text:        lipgloss.Color("#E5E7EB"), // Off-white
+	DimText:     standardPalette.DimText,
+	Bg:          standardPalette.Bg,
+	HighlightBg: standardPalette.HighlightBg,
+}
+
+// On terminals with the 16 basic colors, the nearest basic color to each
+// of the palette's picks the same dark gray for text and background, and
+// the purple turns blue; these pick the basic colors by their role
+// instead. The terminal's theme decides how they look.
 var (
-	primaryColor   = lipgloss.Color("#7C3AED") // Purple
-	secondaryColor = lipgloss.Color("#06B6D4") // Cyan
-	successColor   = lipgloss.Color("#10B981") // Green
-	warningColor   = lipgloss.Color("#F59E0B") // Amber
-	errorColor     = lipgloss.Color("#EF4444") // Red
-	mutedColor     = lipgloss.Color("#6B7280") // Gray
-	textColor      = lipgloss.Color("#F9FAFB") // White
-	dimTextColor   = lipgloss.Color("#9CA3AF") // Light gray
-	bgColor        = lipgloss.Color("#1F2937") // Dark gray
-	highlightBg    = lipgloss.Color("#374151") // Lighter gray
+	standardANSI = Palette{
+		Primary:     lipgloss.Color("5"),  // Magenta
+		Secondary:   lipgloss.Color("6"),  // Cyan
+		Success:     lipgloss.Color("2"),  // Green
+		Warning:     lipgloss.Color("3"),  // Yellow
+		Error:       lipgloss.Color("1"),  // Red
+		Muted:       lipgloss.Color("8"),  // Bright black
+		Text:        lipgloss.Color("15"), // Bright white
+		DimText:     lipgloss.Color("7"),  // White
+		Bg:          lipgloss.Color("0"),  // Black
+		HighlightBg: lipgloss.Color("8"),  // Bright black
+	}
+	colorBlindANSI = Palette{
+		Primary:     standardANSI.Primary,
+		Secondary:   lipgloss.Color("13"), // Bright magenta
+		Success:     lipgloss.Color("12"), // Bright blue
+		Warning:     lipgloss.Color("11"), // Bright yellow
+		Error:       lipgloss.Color("9"),  // Bright red
+		Muted:       standardANSI.Muted,
+		Text:        standardANSI.Text,
+		DimText:     standardANSI.DimText,
+		Bg:          standardANSI.Bg,
+		HighlightBg: standardANSI.HighlightBg,
+	}
+	mutedANSI = Palette{
+		Primary:     lipgloss.Color("4"), // Blue
+		Secondary:   lipgloss.Color("7"), // White
+		Success:     standardANSI.Success,
+		Warning:     standardANSI.Warning,
+		Error:       standardANSI.Error,
+		Muted:       standardANSI.Muted,
+		Text:        lipgloss.Color("7"), // White
+		DimText:     standardANSI.Muted,
+		Bg:          standardANSI.Bg,
+		HighlightBg: standardANSI.HighlightBg,
+	}
 )
 
+// palettes are the colors, for terminals with 256 colors or more and for
+// those with 16, and the icons by palette name
+var palettes = map[string]struct {
+	colors Palette
+	ansi   Palette
+	icons  Icons
+}{
+	PaletteStandard:   {standardPalette, standardANSI, standardIcons},
+	PaletteColorBlind: {colorBlindPalette, colorBlindANSI, colorBlindIcons},
+	PaletteMuted:      {mutedPalette, mutedANSI, standardIcons},
+}
+
+// CheckPalette reports an unknown palette name
+func CheckPalette(name string) error {
+	if _, ok := palettes[name]; !ok {
+		return fmt.Errorf("palette must be %s, %s or %s", PaletteStandard, PaletteColorBlind, PaletteMuted)
+	}
+	return nil
+}
+
 // Styles contains all the lipgloss styles for the TUI
 type Styles struct {
 	// App container
@@ -95,11 +237,33 @@ type Styles struct {
 	TextBold    lipgloss.Style
 	TextSuccess lipgloss.Style
 	TextError   lipgloss.Style
+
+	// Colors and icons the styles are made of, for one-off styles
+	Colors Palette
+	Icons  Icons
 }
 
 // DefaultStyles returns the default styling for the TUI
 func DefaultStyles() Styles {
+	return NewStyles(PaletteStandard)
+}
+
+// NewStyles returns the styling of a palette for the colors of the
+// terminal, see lipgloss.ColorProfile; an unknown palette gives the
+// standard one
+func NewStyles(palette string) Styles {
+	set, ok := palettes[palette]
+	if !ok {
+		set = palettes[PaletteStandard]
+	}
+	c := set.colors
+	if lipgloss.ColorProfile() == termenv.ANSI {
+		c = set.ansi
+	}
 	return Styles{
+		Colors: c,
+		Icons:  set.icons,
+
 		// App container
 		App: lipgloss.NewStyle().
 			Padding(1, 2),
@@ -107,28 +271,28 @@ func DefaultStyles() Styles {
 		// Header styles
 		Header: lipgloss.NewStyle().
 			BorderStyle(lipgloss.RoundedBorder()).
-			BorderForeground(primaryColor).
+			BorderForeground(c.Primary).
 			Padding(0, 2).
 			MarginBottom(1),
 
 		HeaderTitle: lipgloss.NewStyle().
-			Foreground(primaryColor).
+			Foreground(c.Primary).
 			Bold(true).
 			MarginRight(2),
 
 		HeaderHelp: lipgloss.NewStyle().
-			Foreground(dimTextColor),
+			Foreground(c.DimText),
 
 		// Navigation tabs
 		Tab: lipgloss.NewStyle().
 			Padding(0, 2).
-			Foreground(dimTextColor).
+			Foreground(c.DimText).
 			MarginRight(1),
 
 		ActiveTab: lipgloss.NewStyle().
 			Padding(0, 2).
-			Foreground(textColor).
-			Background(primaryColor).
+			Foreground(c.Text).
+			Background(c.Primary).
 			Bold(true).
 			MarginRight(1),
 
@@ -137,123 +301,123 @@ func DefaultStyles() Styles {
 
 		// Form styles
 		FormLabel: lipgloss.NewStyle().
-			Foreground(secondaryColor).
+			Foreground(c.Secondary).
 			Bold(true).
 			Width(20),
 
 		FormValue: lipgloss.NewStyle().
-			Foreground(textColor),
+			Foreground(c.Text),
 
 		FormInput: lipgloss.NewStyle().
 			BorderStyle(lipgloss.RoundedBorder()).
-			BorderForeground(mutedColor).
+			BorderForeground(c.Muted).
 			Padding(0, 1).
 			Width(30),
 
 		FormInputActive: lipgloss.NewStyle().
 			BorderStyle(lipgloss.RoundedBorder()).
-			BorderForeground(primaryColor).
+			BorderForeground(c.Primary).
 			Padding(0, 1).
 			Width(30),
 
 		FormHelp: lipgloss.NewStyle().
-			Foreground(dimTextColor).
+			Foreground(c.DimText).
 			Italic(true).
 			MarginLeft(2),
 
 		// List styles
 		ListItem: lipgloss.NewStyle().
-			Foreground(textColor).
+			Foreground(c.Text).
 			PaddingLeft(2),
 
 		ListItemSelected: lipgloss.NewStyle().
-			Foreground(primaryColor).
+			Foreground(c.Primary).
 			Bold(true).
 			PaddingLeft(2),
 
 		ListItemDim: lipgloss.NewStyle().
-			Foreground(mutedColor).
+			Foreground(c.Muted).
 			PaddingLeft(2),
 
 		// File browser styles
 		Directory: lipgloss.NewStyle().
-			Foreground(secondaryColor).
+			Foreground(c.Secondary).
 			Bold(true),
 
 		File: lipgloss.NewStyle().
-			Foreground(textColor),
+			Foreground(c.Text),
 
 		SelectedItem: lipgloss.NewStyle().
-			Background(highlightBg).
-			Foreground(primaryColor).
+			Background(c.HighlightBg).
+			Foreground(c.Primary).
 			Bold(true).
 			Padding(0, 1),
 
 		CurrentPath: lipgloss.NewStyle().
-			Foreground(warningColor).
+			Foreground(c.Warning).
 			Bold(true).
 			MarginBottom(1),
 
 		// Progress styles
 		ProgressBar: lipgloss.NewStyle().
-			Foreground(primaryColor),
+			Foreground(c.Primary),
 
 		ProgressText: lipgloss.NewStyle().
-			Foreground(textColor),
+			Foreground(c.Text),
 
 		ProgressPercent: lipgloss.NewStyle().
-			Foreground(secondaryColor).
+			Foreground(c.Secondary).
 			Bold(true),
 
 		// Status styles
 		StatusSuccess: lipgloss.NewStyle().
-			Foreground(successColor).
+			Foreground(c.Success).
 			Bold(true),
 
 		StatusError: lipgloss.NewStyle().
-			Foreground(errorColor).
+			Foreground(c.Error).
 			Bold(true),
 
 		StatusWarning: lipgloss.NewStyle().
-			Foreground(warningColor).
+			Foreground(c.Warning).
 			Bold(true),
 
 		StatusInfo: lipgloss.NewStyle().
-			Foreground(secondaryColor),
+			Foreground(c.Secondary),
 
 		// Log styles
 		LogContainer: lipgloss.NewStyle().
 			BorderStyle(lipgloss.RoundedBorder()).
-			BorderForeground(mutedColor).
+			BorderForeground(c.Muted).
 			Padding(1).
 			MarginTop(1),
 
 		LogEntry: lipgloss.NewStyle().
-			Foreground(textColor),
+			Foreground(c.Text),
 
 		LogTimestamp: lipgloss.NewStyle().
-			Foreground(dimTextColor).
+			Foreground(c.DimText).
 			Width(10),
 
 		LogSuccess: lipgloss.NewStyle().
-			Foreground(successColor),
+			Foreground(c.Success),
 
 		LogError: lipgloss.NewStyle().
-			Foreground(errorColor),
+			Foreground(c.Error),
 
 		LogInfo: lipgloss.NewStyle().
-			Foreground(dimTextColor),
+			Foreground(c.DimText),
 
 		// Button styles
 		Button: lipgloss.NewStyle().
-			Foreground(textColor).
-			Background(mutedColor).
+			Foreground(c.Text).
+			Background(c.Muted).
 			Padding(0, 3).
 			MarginRight(1),
 
 		ButtonActive: lipgloss.NewStyle().
-			Foreground(textColor).
-			Background(primaryColor).
+			Foreground(c.Text).
+			Background(c.Primary).
 			Bold(true).
 			Padding(0, 3).
 			MarginRight(1),
@@ -261,63 +425,63 @@ func DefaultStyles() Styles {
 		// Box styles
 		Box: lipgloss.NewStyle().
 			BorderStyle(lipgloss.RoundedBorder()).
-			BorderForeground(mutedColor).
+			BorderForeground(c.Muted).
 			Padding(1, 2),
 
 		BoxTitle: lipgloss.NewStyle().
-			Foreground(primaryColor).
+			Foreground(c.Primary).
 			Bold(true).
 			MarginBottom(1),
 
 		BoxSelected: lipgloss.NewStyle().
 			BorderStyle(lipgloss.RoundedBorder()).
-			BorderForeground(primaryColor).
+			BorderForeground(c.Primary).
 			Padding(1, 2),
 
 		// Footer
 		Footer: lipgloss.NewStyle().
 			BorderStyle(lipgloss.NormalBorder()).
-			BorderForeground(mutedColor).
+			BorderForeground(c.Muted).
 			BorderTop(true).
 			MarginTop(1).
 			Padding(0, 1),
 
 		FooterKey: lipgloss.NewStyle().
-			Foreground(primaryColor).
+			Foreground(c.Primary).
 			Bold(true),
 
 		FooterDesc: lipgloss.NewStyle().
-			Foreground(dimTextColor),
+			Foreground(c.DimText),
 
 		// Spinner
 		Spinner: lipgloss.NewStyle().
-			Foreground(primaryColor),
+			Foreground(c.Primary),
 
 		// General text styles
 		Title: lipgloss.NewStyle().
-			Foreground(textColor).
+			Foreground(c.Text).
 			Bold(true).
 			MarginBottom(1),
 
 		Subtitle: lipgloss.NewStyle().
-			Foreground(dimTextColor).
+			Foreground(c.DimText).
 			MarginBottom(1),
 
 		Text: lipgloss.NewStyle().
-			Foreground(textColor),
+			Foreground(c.Text),
 
 		TextMuted: lipgloss.NewStyle().
-			Foreground(mutedColor),
+			Foreground(c.Muted),
 
 		TextBold: lipgloss.NewStyle().
-			Foreground(textColor).
+			Foreground(c.Text).
 			Bold(true),
 
 		TextSuccess: lipgloss.NewStyle().
-			Foreground(successColor),
+			Foreground(c.Success),
 
 		TextError: lipgloss.NewStyle().
-			Foreground(errorColor),
+			Foreground(c.Error),
 	}
 }
 
@@ -332,13 +496,13 @@ func (s Styles) RenderKeyHelp(key, description string) string {
 func (s Styles) RenderStatus(status, message string) string {
 	switch status {
 	case "success":
-		return s.StatusSuccess.Render("✓ " + message)
+		return s.StatusSuccess.Render(s.Icons.Success + " " + message)
 	case "error":
-		return s.StatusError.Render("✗ " + message)
+		return s.StatusError.Render(s.Icons.Error + " " + message)
 	case "warning":
-		return s.StatusWarning.Render("⚠ " + message)
+		return s.StatusWarning.Render(s.Icons.Warning + " " + message)
 	default:
-		return s.StatusInfo.Render("ℹ " + message)
+		return s.StatusInfo.Render(s.Icons.Info + " " + message)
 	}
 }
 
@@ -0,0 +1,132 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxUndo is how many form changes can be undone
+const maxUndo = 50
+
+// typedKey reports whether msg types into a field rather than moving
+// around or replacing it; typing into the same field is undone at once
+func typedKey(msg tea.KeyMsg) bool {
+	switch msg.Type {
+	case tea.KeyRunes, tea.KeySpace, tea.KeyBackspace, tea.KeyDelete:
+		return true
+	}
+	return false
+}
+
+// typingKey names what a typed key goes into: a form field, or the file
+// list on the Files screen
+func (m Model) typingKey() string {
+	if m.screen == ScreenFiles {
+		return "files"
+	}
+	if m.typingField() {
+		return m.formKey(m.focusedField)
+	}
+	return ""
+}
+
+// recordUndo keeps the form as it was before msg changed it in model, so
+// ctrl+z can bring it back. The inputs are shared with model, so before
+// must be taken before the key is handled.
+func (m Model) recordUndo(before formState, msg tea.KeyMsg, model tea.Model) tea.Model {
+	after, ok := model.(Model)
+	if !ok || msg.String() == "ctrl+z" {
+		return model
+	}
+	if before.equal(after.formState()) {
+		return model
+	}
+	typing := ""
+	if typedKey(msg) {
+		typing = m.typingKey()
+	}
+	if typing == "" || typing != m.undoTyping || len(after.undo) == 0 {
+		after = after.pushUndo(before)
+	}
+	after.undoTyping = typing
+	return after
+}
+
+// pushUndo adds a form state to the undo list, dropping the oldest when it
+// is full
+func (m Model) pushUndo(state formState) Model {
+	m.undo = append(m.undo[max(len(m.undo)+1-maxUndo, 0):len(m.undo):len(m.undo)], state)
+	m.undoTyping = ""
+	return m
+}
+
+// undoForm brings back the form as it was before the last change
+func (m Model) undoForm() Model {
+	if len(m.undo) == 0 {
+		return m
+	}
+	state := m.undo[len(m.undo)-1]
+	m.undo = m.undo[:len(m.undo)-1]
+	m.undoTyping = ""
+	return m.applyForm(state)
+}
+
+// applyForm sets the whole form to state, clearing the fields it has no
+// value for
+func (m Model) applyForm(state formState) Model {
+	if state.Dir != "" {
+		m.currentDir = state.Dir
+	}
+	m.selectedDir = state.Dir
+
+	pipelineIdx := 0
+	for i, pipeline := range m.pipelines {
+		if i > 0 && pipeline.Script == state.Pipeline {
+			pipelineIdx = i
+		}
+	}
+	if pipelineIdx != m.pipelineIdx && pipelineIdx < len(m.pipelines) {
+		focused := m.focusedField
+		m = m.selectPipeline(pipelineIdx)
+		if focused <= m.startField() {
+			m.focusedField = focused
+		}
+	}
+
+	for i := range m.inputs {
+		m.inputs[i].SetValue(state.Fields[m.formKey(FocusedField(i))])
+	}
+	m.fileList.SetValue(state.Files)
+	return m
+}
+
+// formChanged reports whether the form differs from when the
+// Configuration screen was opened
+func (m Model) formChanged() bool {
+	return !m.formState().equal(m.formOpened)
+}
+
+// openForm shows the Configuration screen, remembering the form as it is
+// for Esc to go back to
+func (m Model) openForm() Model {
+	m.screen = ScreenParams
+	m.formOpened = m.formState()
+	m.confirmDiscard = false
+	return m
+}
+
+// leaveForm goes back to the Welcome screen from the Configuration
+// screen. Changes made since it was opened are dropped, once confirmed
+// with a second Esc; ctrl+z brings them back.
+func (m Model) leaveForm() Model {
+	if m.formChanged() {
+		if !m.confirmDiscard {
+			m.confirmDiscard = true
+			return m
+		}
+		m = m.pushUndo(m.formState())
+		m = m.applyForm(m.formOpened)
+	}
+	m.confirmDiscard = false
+	m.screen = ScreenWelcome
+	return m
+}
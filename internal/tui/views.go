@@ -3,12 +3,17 @@ package tui
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
 
+	"github.com/cloudcompare-automation/internal/history"
+	"github.com/cloudcompare-automation/internal/humanize"
 	"github.com/cloudcompare-automation/internal/processor"
+	"github.com/cloudcompare-automation/internal/profile"
+	"github.com/cloudcompare-automation/internal/report"
 )
 
 // viewWelcome renders the welcome/home screen
@@ -17,7 +22,9 @@ func (m Model) viewWelcome() string {
 
 	// Compact logo for small terminals
 	var logo string
-	if m.width >= 60 && m.height >= 16 {
+	if m.corporate {
+		logo = "CloudCompare Automation"
+	} else if m.width >= 60 && m.height >= 16 {
 		logo = `
   ╔═╗┬  ┌─┐┬ ┬┌┬┐╔═╗┌─┐┌┬┐┌─┐┌─┐┬─┐┌─┐
   ║  │  │ ││ │ ││║  │ ││││├─┘├─┤├┬┘├┤
@@ -30,12 +37,15 @@ func (m Model) viewWelcome() string {
 	}
 
 	logoStyle := lipgloss.NewStyle().
-		Foreground(primaryColor).
+		Foreground(s.Colors.Primary).
 		Bold(true)
 
 	title := s.Title.Copy().
-		Foreground(secondaryColor).
+		Foreground(s.Colors.Secondary).
 		Render("LAS Point Cloud Processing")
+	if name := profile.Active(); name != "" {
+		title = lipgloss.JoinVertical(lipgloss.Center, title, s.TextMuted.Render("Profile: "+name))
+	}
 
 	var description string
 	if m.height >= 12 {
@@ -45,32 +55,77 @@ func (m Model) viewWelcome() string {
 • Save CloudCompare projects (.bin)`)
 	}
 
-	// Start prompt
+	// Start prompt, or the quick run or recent run to confirm
 	startPrompt := s.ButtonActive.Copy().
 		MarginTop(1).
 		Render(" Press ENTER to Start ")
+	if m.observer {
+		startPrompt = lipgloss.NewStyle().Foreground(s.Colors.Secondary).MarginTop(1).Render("Waiting for a batch to start…")
+	} else if m.quickRunConfirm {
+		startPrompt = lipgloss.JoinVertical(lipgloss.Center,
+			lipgloss.NewStyle().Foreground(s.Colors.Warning).MarginTop(1).Render(truncate(m.quickRunText(), m.width-8)),
+			s.TextMuted.Render("enter or r to start, any other key to cancel"))
+	} else if m.relaunch != nil {
+		startPrompt = lipgloss.JoinVertical(lipgloss.Center,
+			lipgloss.NewStyle().Foreground(s.Colors.Warning).MarginTop(1).Render(truncate(m.runText(*m.relaunch)+" again with its settings?", m.width-8)),
+			s.TextMuted.Render("enter or l to start, any other key to cancel"))
+	}
 
 	// Footer
-	footer := s.Footer.Render(
-		s.RenderKeyHelp("enter", "start") + "  " +
-			s.RenderKeyHelp("q", "quit"),
-	)
+	keys := s.RenderKeyHelp("enter", "start") + "  "
+	if m.lastRun != nil {
+		keys += s.RenderKeyHelp("r", "quick run") + "  "
+	}
+	keys += s.RenderKeyHelp("h", "history") + "  " + s.RenderKeyHelp("s", "stats") + "  "
+	if m.runningSession != nil {
+		keys += s.RenderKeyHelp("a", "attach") + "  "
+	}
+	if m.observer {
+		keys = s.TextMuted.Render("Watching only") + "  "
+	}
+	footer := s.Footer.Render(keys + s.RenderKeyHelp("q", "quit"))
+
+	// Notice for a batch left running by an earlier instance
+	var notice string
+	if m.runningSession != nil {
+		notice = lipgloss.NewStyle().Foreground(s.Colors.Warning).MarginTop(1).Render(fmt.Sprintf("A batch is running in the background (%s, started %s) — press a to attach",
+			filepath.Base(m.runningSession.Params.InputDir), m.runningSession.StartedAt.Format("15:04")))
+	}
 
 	// Build content
-	content := lipgloss.JoinVertical(lipgloss.Center,
-		logoStyle.Render(logo),
-		"",
-		title,
-		description,
-		"",
-		startPrompt,
-	)
+	build := func(description string) string {
+		parts := []string{logoStyle.Render(logo), "", title}
+		if description != "" {
+			parts = append(parts, description, "")
+		}
+		return lipgloss.JoinVertical(lipgloss.Center, append(parts, startPrompt, notice)...)
+	}
+	content := build(description)
+
+	// The recent runs, as many as fit under the rest with their heading
+	// and keys; the description gives way to them
+	var recent string
+	if len(m.recentRuns) > 0 && !m.observer {
+		rows := func(content string) int {
+			return m.height - lipgloss.Height(content) - 7
+		}
+		if rows(content) < len(m.recentRuns) && rows(build("")) > 0 {
+			content = build("")
+		}
+		if n := rows(content); n > 0 {
+			recent = "\n" + m.viewRecent(n)
+		}
+	}
 
 	// Center the content
 	contentBox := lipgloss.NewStyle().
 		Width(m.width - 4).
 		Align(lipgloss.Center).
 		Render(content)
+	if recent != "" {
+		// As a block, for the rows to line up
+		contentBox = lipgloss.JoinVertical(lipgloss.Left, contentBox, lipgloss.PlaceHorizontal(m.width-4, lipgloss.Center, recent))
+	}
 
 	return lipgloss.JoinVertical(lipgloss.Left,
 		contentBox,
@@ -99,6 +154,11 @@ func (m Model) viewFileBrowser() string {
 	if maxVisible < 3 {
 		maxVisible = 3
 	}
+	filtering := m.browseFilter.Focused() || m.browseFilter.Value() != ""
+	if filtering {
+		// Room for the filter line
+		maxVisible--
+	}
 
 	var items []string
 
@@ -110,20 +170,26 @@ func (m Model) viewFileBrowser() string {
 	}
 
 	// Calculate visible range
+	entries := m.visibleEntries()
 	startIdx := m.browseScroll
 	endIdx := startIdx + maxVisible - 1 // -1 for parent dir
-	if endIdx > len(m.entries) {
-		endIdx = len(m.entries)
+	if endIdx > len(entries) {
+		endIdx = len(entries)
 	}
 
 	for i := startIdx; i < endIdx; i++ {
-		entry := m.entries[i]
-		name := entry.Name()
+		entry := entries[i]
+		name := entry.name
+		if entry.link != "" {
+			name += " → " + entry.link
+		}
+		if entry.cycle {
+			name += " (loop)"
+		}
 
 		// Truncate long names
-		maxNameLen := m.width - 10
-		if len(name) > maxNameLen && maxNameLen > 10 {
-			name = name[:maxNameLen-3] + "..."
+		if maxNameLen := m.width - 10; maxNameLen > 10 {
+			name = truncate(name, maxNameLen)
 		}
 
 		if i == m.cursor {
@@ -133,16 +199,27 @@ func (m Model) viewFileBrowser() string {
 		}
 	}
 
-	if len(m.entries) == 0 {
+	if m.entriesDir != m.currentDir {
+		items = append(items, s.TextMuted.Render("  Loading..."))
+	} else if len(m.entries) == 0 {
 		items = append(items, s.TextMuted.Render("  (no subdirectories)"))
+	} else if len(entries) == 0 {
+		items = append(items, s.TextMuted.Render("  (no matching subdirectories)"))
 	}
 
 	listing := lipgloss.JoinVertical(lipgloss.Left, items...)
 
-	// Scroll indicator
+	// Scroll indicator, or the filter while one is typed or set
 	scrollInfo := ""
-	if len(m.entries) > maxVisible-1 {
-		scrollInfo = s.TextMuted.Render(fmt.Sprintf(" [%d-%d of %d]", startIdx+1, endIdx, len(m.entries)))
+	if len(entries) > maxVisible-1 {
+		scrollInfo = s.TextMuted.Render(fmt.Sprintf(" [%d-%d of %d]", startIdx+1, endIdx, len(entries)))
+	}
+	if filtering {
+		filter := s.FormLabel.Render("Filter: ") + m.browseFilter.View()
+		if !m.browseFilter.Focused() {
+			filter += s.TextMuted.Render(fmt.Sprintf("  %d of %d", len(entries), len(m.entries)))
+		}
+		scrollInfo = lipgloss.JoinVertical(lipgloss.Left, scrollInfo, filter)
 	}
 
 	// Selected info
@@ -154,8 +231,18 @@ func (m Model) viewFileBrowser() string {
 			s.RenderKeyHelp("enter", "open") + " " +
 			s.RenderKeyHelp("←", "parent") + " " +
 			s.RenderKeyHelp("space", "select") + " " +
+			s.RenderKeyHelp("/", "filter") + " " +
+			s.RenderKeyHelp("a-z", "jump") + " " +
+			s.RenderKeyHelp(".", "hidden") + " " +
 			s.RenderKeyHelp("esc", "cancel"),
 	)
+	if m.browseFilter.Focused() {
+		footer = s.Footer.Render(
+			s.RenderKeyHelp("↑↓", "nav") + " " +
+				s.RenderKeyHelp("enter", "open") + " " +
+				s.RenderKeyHelp("esc", "clear"),
+		)
+	}
 
 	return lipgloss.JoinVertical(lipgloss.Left,
 		header,
@@ -190,6 +277,10 @@ func (m Model) viewParams() string {
 			errText = errText[:maxErrLen-3] + "..."
 		}
 		errorMsg = s.StatusError.Render("⚠ " + errText)
+	} else if m.confirmDiscard {
+		errorMsg = s.StatusWarning.Render(truncate("⚠ Press esc again to drop your changes and go back, any other key to keep editing", m.width-4))
+	} else if m.notice != "" {
+		errorMsg = s.TextSuccess.Render(s.Icons.Success + " " + truncate(m.notice, m.width-10))
 	}
 
 	// Determine label width based on terminal width
@@ -200,7 +291,8 @@ func (m Model) viewParams() string {
 		formWidth = 30
 	}
 
-	// Form fields - must match FocusedField order in model.go
+	// Form fields - must match FocusedField order in model.go; the
+	// parameter rows come from the selected pipeline's schema
 	type formField struct {
 		label string
 		short string
@@ -210,23 +302,43 @@ func (m Model) viewParams() string {
 	fields := []formField{
 		{"Input Dir", "Input", FocusInputDir},
 		{"Output Dir", "Output", FocusOutputSubdir},
-		{"KNN", "KNN", FocusKNN},
-		{"Octree Depth", "Depth", FocusOctreeDepth},
-		{"Samples/Node", "Samples", FocusSamplesPerNode},
-		{"Point Weight", "Weight", FocusPointWeight},
-		{"Boundary", "Bound", FocusBoundaryType},
+		{"Include", "Include", FocusInclude},
+		{"Exclude", "Exclude", FocusExclude},
+		{"Web Export", "Web", FocusWebExport},
+	}
+	for i, field := range processor.MetadataFields {
+		fields = append(fields, formField{field.Label, field.Short, FocusMetadata + FocusedField(i)})
+	}
+	fields = append(fields, formField{"Labels", "Labels", FocusLabels})
+	for i, spec := range m.schema() {
+		fields = append(fields, formField{spec.DisplayLabel(false), spec.DisplayLabel(true), FocusParams + FocusedField(i)})
+	}
+	if m.hasPipelineChoice() {
+		fields = append(fields, formField{"Pipeline", "Pipeline", m.pipelineField()})
 	}
 
 	// Determine which fields to show based on height
+	// Each bordered field takes 3 lines; the rest of the screen about 12
 	maxFields := len(fields)
+	if fit := (m.height - 12) / 3; fit < maxFields {
+		maxFields = max(fit, 2)
+	}
 	if isCompact {
-		maxFields = 4 // Show first 4 fields in compact mode
+		maxFields = min(maxFields, 4) // Show at most 4 fields in compact mode
+	}
+
+	// Scroll the form so the focused field stays visible
+	first := 0
+	for i, f := range fields {
+		if f.field == m.focusedField && i >= maxFields {
+			first = i - maxFields + 1
+		}
 	}
 
 	var formRows []string
 	for i, f := range fields {
-		if i >= maxFields {
-			break
+		if i < first || i >= first+maxFields {
+			continue
 		}
 
 		labelText := f.label
@@ -239,7 +351,7 @@ func (m Model) viewParams() string {
 		var input string
 		if int(f.field) < len(m.inputs) {
 			// Set width based on field type
-			if f.field == FocusInputDir || f.field == FocusOutputSubdir {
+			if f.field < FocusParams {
 				m.inputs[f.field].Width = formWidth - labelWidth
 			} else {
 				m.inputs[f.field].Width = 10
@@ -250,6 +362,13 @@ func (m Model) viewParams() string {
 			} else {
 				input = s.FormInput.Render(m.inputs[f.field].View())
 			}
+		} else if f.field == m.pipelineField() {
+			selector := "◀ " + m.pipelineName() + " ▶"
+			if m.focusedField == f.field {
+				input = s.FormInputActive.Render(selector)
+			} else {
+				input = s.FormInput.Render(selector)
+			}
 		}
 
 		row := lipgloss.JoinHorizontal(lipgloss.Left, label, input)
@@ -257,18 +376,21 @@ func (m Model) viewParams() string {
 	}
 
 	if maxFields < len(fields) {
-		formRows = append(formRows, s.TextMuted.Render(fmt.Sprintf("  (+%d more in larger window)", len(fields)-maxFields)))
+		formRows = append(formRows, s.TextMuted.Render(fmt.Sprintf("  (%d-%d of %d fields, tab to scroll)", first+1, first+maxFields, len(fields))))
 	}
 
 	form := lipgloss.JoinVertical(lipgloss.Left, formRows...)
 
 	// Start button
 	var startButton string
-	if m.focusedField == FocusStartButton {
+	if m.focusedField == m.startField() {
 		startButton = s.ButtonActive.Render(" ▶ Start Processing ")
 	} else {
 		startButton = s.Button.Render(" ▶ Start Processing ")
 	}
+	if m.preparing {
+		startButton += " " + s.StatusInfo.Render(m.spinner.View()+" Checking input...")
+	}
 
 	// Build left panel (form)
 	leftPanel := lipgloss.JoinVertical(lipgloss.Left,
@@ -294,10 +416,7 @@ func (m Model) viewParams() string {
 		// Build full output path
 		outputPath := inputDir + "/" + outputSubdir
 
-		octreeDepth := m.inputs[FocusOctreeDepth].Value()
-		if octreeDepth == "" {
-			octreeDepth = "11"
-		}
+		octreeDepth := m.paramValue("octree-depth")
 
 		// Calculate summary panel width to fill remaining space
 		summaryWidth := m.width - formWidth - 8 // 8 for margins/padding
@@ -329,21 +448,117 @@ func (m Model) viewParams() string {
 		for _, line := range wrapPath(inputDir, summaryWidth) {
 			summaryLines = append(summaryLines, s.StatusInfo.Render(" "+line))
 		}
+		if line := m.dirCheckLine(inputDir, summaryWidth-4); line != "" {
+			summaryLines = append(summaryLines, " "+line)
+		}
 
 		summaryLines = append(summaryLines, "")
 		summaryLines = append(summaryLines, s.Text.Render("Output:"))
 		for _, line := range wrapPath(outputPath, summaryWidth) {
 			summaryLines = append(summaryLines, s.StatusInfo.Render(" "+line))
 		}
+		// Where the first file's project goes, as the layout places it and
+		// the lookup names it
+		layout := m.params.OutputLayout != "" && m.params.OutputLayout != processor.LayoutFlat
+		if (layout || m.params.MetadataLookup.Enabled()) && m.selectionCounted() && m.selection.example != "" {
+			example := m.selection.example
+			if rel, err := filepath.Rel(inputDir, example); err == nil {
+				example = rel
+			}
+			example = "e.g. " + filepath.ToSlash(example)
+			if layout {
+				example = m.params.OutputLayout + ", " + example
+			}
+			for _, line := range wrapPath(example, summaryWidth) {
+				summaryLines = append(summaryLines, s.TextMuted.Render(" "+line))
+			}
+		}
 
 		summaryLines = append(summaryLines, "")
-		summaryLines = append(summaryLines, s.Text.Render("Quality: Depth "+octreeDepth))
+		if octreeDepth != "" {
+			summaryLines = append(summaryLines, s.Text.Render("Quality: Depth "+octreeDepth))
+		}
+		if project := m.inputs[FocusMetadata].Value(); project != "" {
+			summaryLines = append(summaryLines, s.Text.Render("Project: "+project))
+		}
+		if lookup := m.params.MetadataLookup; lookup.Enabled() {
+			summaryLines = append(summaryLines, s.TextMuted.Render("Metadata per file from "+lookup.Source()))
+		}
+		if labels := history.ParseLabels(m.inputs[FocusLabels].Value()); len(labels) > 0 {
+			summaryLines = append(summaryLines, s.Text.Render("Labels: "+strings.Join(labels, ", ")))
+		}
+		if m.pipelineIdx < len(m.pipelines) && m.pipelines[m.pipelineIdx].Description != "" {
+			summaryLines = append(summaryLines, s.TextMuted.Render(m.pipelines[m.pipelineIdx].Description))
+		}
+		if gpu, ok := m.params.Env["CUDA_VISIBLE_DEVICES"]; ok {
+			summaryLines = append(summaryLines, s.Text.Render("GPU: "+gpu))
+		}
+		if n := len(m.params.Env); n > 0 {
+			summaryLines = append(summaryLines, s.TextMuted.Render(fmt.Sprintf("%d env variable(s) from config", n)))
+		}
+		if m.params.LowPriority || m.params.Threads > 0 || m.params.Deterministic || m.params.BoostSave {
+			summaryLines = append(summaryLines, s.TextMuted.Render(backgroundSummary(m.params)))
+		}
+		if rules := m.params.Acceptance; rules.Enabled() {
+			summaryLines = append(summaryLines, s.TextMuted.Render(truncate("Accepting "+rules.String(), summaryWidth)))
+		}
 
-		// Count LAS files if possible
-		if inputDir != "" {
-			if count, err := countLASFiles(inputDir); err == nil && count > 0 {
+		summaryLines = append(summaryLines, m.importLines(summaryWidth)...)
+		summaryLines = append(summaryLines, m.projectLines(summaryWidth-4)...)
+
+		// Count the LAS files the patterns select, with a few names so a
+		// pattern typo shows before the run. Listed files replace them, and
+		// the directory's estimates and map don't apply.
+		listed := len(m.listedFiles()) > 0
+		if listed {
+			line := s.TextSuccess.Render(m.filesSummary())
+			if n := m.invalidFiles(); m.filesChecked() && n > 0 {
+				line = s.StatusWarning.Render(fmt.Sprintf("📄 %d listed file(s) can't be processed", n))
+			}
+			summaryLines = append(summaryLines, "", line)
+		} else if inputDir != "" && m.dirUsable(inputDir) && !m.selectionCounted() {
+			summaryLines = append(summaryLines, "", s.TextMuted.Render("📁 Counting LAS files..."))
+		} else if inputDir != "" && m.dirUsable(inputDir) {
+			matched, skipped, err := m.selection.matched, m.selection.skipped, m.selection.err
+			if err == nil && len(matched)+len(skipped) > 0 {
 				summaryLines = append(summaryLines, "")
-				summaryLines = append(summaryLines, s.TextSuccess.Render(fmt.Sprintf("📁 %d LAS file(s) found", count)))
+				count := fmt.Sprintf("📁 %d LAS file(s) found", len(matched))
+				if points := m.tilePoints(); points > 0 {
+					count += fmt.Sprintf(", %s points", humanize.Count(int64(points)))
+				}
+				if len(skipped) > 0 {
+					count += fmt.Sprintf(", %d skipped", len(skipped))
+				}
+				if n := m.selection.existing; n > 0 {
+					count += fmt.Sprintf(", %d already processed", n)
+				}
+				if len(matched) == 0 {
+					summaryLines = append(summaryLines, s.StatusWarning.Render(count))
+				} else {
+					summaryLines = append(summaryLines, s.TextSuccess.Render(count))
+				}
+				if len(skipped) > 0 && !isCompact {
+					summaryLines = append(summaryLines, s.TextMuted.Render(filePreview(matched, 3)))
+				}
+			}
+		}
+
+		// What the octree depth means for the selected files
+		if impact := m.impactLines(); !isCompact && !listed && len(impact) > 0 {
+			summaryLines = append(summaryLines, "")
+			summaryLines = append(summaryLines, impact...)
+		}
+
+		// Top-down map of the tiles, to spot gaps and misplaced files
+		if !isCompact && !listed && len(m.tiles) > 0 {
+			inliers, outliers := splitOutliers(m.tiles)
+			summaryLines = append(summaryLines, "")
+			for _, line := range renderMinimap(inliers, summaryWidth-4, 8) {
+				summaryLines = append(summaryLines, s.StatusInfo.Render(" "+line))
+			}
+			summaryLines = append(summaryLines, s.TextMuted.Render(" ▒ tile  █ overlap  · gap"))
+			for _, line := range minimapSummary(outliers, countOverlaps(inliers)) {
+				summaryLines = append(summaryLines, s.TextMuted.Render(" "+line))
 			}
 		}
 
@@ -364,13 +579,18 @@ func (m Model) viewParams() string {
 	}
 
 	// Browse hint
-	browseHint := s.TextMuted.Render("Press 'b' to browse directories")
+	browseHint := s.TextMuted.Render("Press 'b' to browse directories, 'p' to preview, 'f' to list files (ctrl+b/p/f while typing)")
 
 	// Footer
 	footer := s.Footer.Render(
 		s.RenderKeyHelp("tab", "next") + " " +
 			s.RenderKeyHelp("b", "browse") + " " +
+			s.RenderKeyHelp("p", "preview") + " " +
+			s.RenderKeyHelp("f", "files") + " " +
+			s.RenderKeyHelp("y", "copy") + " " +
+			s.RenderKeyHelp("e", "export") + " " +
 			s.RenderKeyHelp("enter", "start") + " " +
+			s.RenderKeyHelp("ctrl+z", "undo") + " " +
 			s.RenderKeyHelp("esc", "back"),
 	)
 
@@ -392,13 +612,26 @@ func (m Model) viewParams() string {
 
 // viewProcessing renders the processing progress screen
 func (m Model) viewProcessing() string {
+	if m.accessible {
+		return m.viewProcessingAccessible()
+	}
+	if m.inline {
+		return m.viewProcessingInline()
+	}
+	if m.rawLog {
+		return m.viewProcessingLog()
+	}
+	if m.height < compactHeight {
+		return m.viewProcessingCompact()
+	}
+
 	s := m.styles
 
 	// Check for celebration mode
 	var celebrationLine string
 	if m.IsCelebrating() {
 		celebStyle := lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#10B981")).
+			Foreground(s.Colors.Success).
 			Bold(true)
 		celebrationLine = celebStyle.Render(m.GetCelebration())
 	}
@@ -413,20 +646,20 @@ func (m Model) viewProcessing() string {
 	}
 
 	headerStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#7C3AED")).
+		Foreground(s.Colors.Primary).
 		Bold(true)
 
 	header := headerStyle.Render(fmt.Sprintf("%s %s Processing %s %s", particle, wave, wave, particle))
 
 	// Progress info with animated separator
-	elapsed := m.elapsedTime.Round(time.Second)
+	elapsed := humanize.Duration(m.elapsedTime)
 	separators := []string{"│", "┃", "│", "┃"}
 	sep := separators[m.animFrame%len(separators)]
 
 	progressInfo := s.Text.Render(fmt.Sprintf(
 		"Files: %d/%d %s Time: %s",
 		m.filesDone, m.filesTotal, sep, elapsed,
-	))
+	)) + m.stallBadge()
 
 	// Progress bar
 	var progressPercent float64
@@ -443,6 +676,10 @@ func (m Model) viewProcessing() string {
 	}
 	m.progress.Width = barWidth
 	progressBar := m.progress.ViewAs(progressPercent)
+	transfer := m.transferLine()
+	if transfer != "" {
+		progressBar += "\n" + transfer
+	}
 
 	// Current file info box
 	var fileInfoLines []string
@@ -458,50 +695,44 @@ func (m Model) viewProcessing() string {
 
 		// Point count (if loaded)
 		if m.pointCount != "" {
-			fileInfoLines = append(fileInfoLines, s.TextSuccess.Render("   ✓ "+m.pointCount))
+			fileInfoLines = append(fileInfoLines, s.TextSuccess.Render("   "+s.Icons.Success+" "+m.pointCount))
 		}
 
 		// Mesh faces (if created)
 		if m.meshFaces != "" {
-			fileInfoLines = append(fileInfoLines, s.TextSuccess.Render("   ✓ "+m.meshFaces))
+			fileInfoLines = append(fileInfoLines, s.TextSuccess.Render("   "+s.Icons.Success+" "+m.meshFaces))
 		}
 
 		fileInfoLines = append(fileInfoLines, "")
 
 		// Step progress visualization
-		stepNames := []string{
-			"Loading point cloud",
-			"Computing normals",
-			"Converting to DIP",
-			"Poisson reconstruction",
-			"Saving project",
-		}
-
 		fileInfoLines = append(fileInfoLines, s.BoxTitle.Render("📊 Pipeline Progress"))
 		fileInfoLines = append(fileInfoLines, "")
 
-		for i, name := range stepNames {
+		steps := m.pipelineSteps()
+		for i, step := range steps {
 			stepNum := i + 1
+			name := stepLabel(step)
 			var stepLine string
 
 			if stepNum < m.currentStepNum {
 				// Completed step - green checkmark
-				stepLine = s.TextSuccess.Render(fmt.Sprintf("   ✓ [%d/5] %s", stepNum, name))
+				stepLine = s.TextSuccess.Render(fmt.Sprintf("   %s [%d/%d] %s", s.Icons.Success, stepNum, len(steps), name))
 			} else if stepNum == m.currentStepNum {
 				// Current step - animated spinner and progress bar
 				spinner := m.GetStepSpinner()
 				miniProgress := m.GetStepProgress()
 
-				stepStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#7C3AED")).Bold(true)
-				stepLine = stepStyle.Render(fmt.Sprintf("   %s [%d/5] %s", spinner, stepNum, name))
+				stepStyle := lipgloss.NewStyle().Foreground(s.Colors.Primary).Bold(true)
+				stepLine = stepStyle.Render(fmt.Sprintf("   %s [%d/%d] %s", spinner, stepNum, len(steps), name))
 				fileInfoLines = append(fileInfoLines, stepLine)
 
 				// Add mini progress bar for current step
-				progressStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#06B6D4"))
+				progressStyle := lipgloss.NewStyle().Foreground(s.Colors.Secondary)
 				stepLine = progressStyle.Render(fmt.Sprintf("         %s", miniProgress))
 			} else {
 				// Future step - dimmed
-				stepLine = s.TextMuted.Render(fmt.Sprintf("   ○ [%d/5] %s", stepNum, name))
+				stepLine = s.TextMuted.Render(fmt.Sprintf("   ○ [%d/%d] %s", stepNum, len(steps), name))
 			}
 
 			fileInfoLines = append(fileInfoLines, stepLine)
@@ -516,7 +747,7 @@ func (m Model) viewProcessing() string {
 		dots := []string{"   ", ".  ", ".. ", "...", " ..", "  .", "   "}
 		dot := dots[m.animFrame%len(dots)]
 
-		initStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#7C3AED")).Bold(true)
+		initStyle := lipgloss.NewStyle().Foreground(s.Colors.Primary).Bold(true)
 		fileInfoLines = append(fileInfoLines, initStyle.Render(fmt.Sprintf("   %s Initializing CloudComPy%s", frame, dot)))
 		fileInfoLines = append(fileInfoLines, "")
 
@@ -534,7 +765,7 @@ func (m Model) viewProcessing() string {
 				loadBar += "░"
 			}
 		}
-		loadStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#06B6D4"))
+		loadStyle := lipgloss.NewStyle().Foreground(s.Colors.Secondary)
 		fileInfoLines = append(fileInfoLines, loadStyle.Render("   "+loadBar))
 		fileInfoLines = append(fileInfoLines, "")
 		fileInfoLines = append(fileInfoLines, s.TextMuted.Render("   Setting up environment..."))
@@ -546,6 +777,9 @@ func (m Model) viewProcessing() string {
 	logTitle := s.BoxTitle.Render("📜 Log")
 
 	maxLogLines := m.height - 25 - len(fileInfoLines)
+	if transfer != "" {
+		maxLogLines--
+	}
 	if maxLogLines < 2 {
 		maxLogLines = 2
 	}
@@ -565,14 +799,14 @@ func (m Model) viewProcessing() string {
 		// Animated waiting message
 		waitFrames := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
 		waitFrame := waitFrames[m.animFrame%len(waitFrames)]
-		waitStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#6B7280"))
+		waitStyle := lipgloss.NewStyle().Foreground(s.Colors.Muted)
 		logLines = append(logLines, waitStyle.Render(fmt.Sprintf(" %s Waiting for output...", waitFrame)))
 	}
 
 	logContent := strings.Join(logLines, "\n")
 
 	// Footer with subtle animation
-	cancelHint := s.RenderKeyHelp("ctrl+c", "cancel")
+	cancelHint := m.processingKeys()
 
 	// Add a subtle breathing effect to the footer
 	footerAccent := []string{"─", "━", "─", "━"}
@@ -602,6 +836,265 @@ func (m Model) viewProcessing() string {
 	return lipgloss.JoinVertical(lipgloss.Left, parts...)
 }
 
+// viewProcessingCompact renders the processing screen for short terminals
+// (down to 80x24): one-line pipeline, single stats line and a log that
+// fills whatever height remains
+func (m Model) viewProcessingCompact() string {
+	s := m.styles
+
+	elapsed := humanize.Duration(m.elapsedTime)
+	header := s.HeaderTitle.Render(fmt.Sprintf("%s Processing", m.GetStepSpinner())) +
+		s.Text.Render(fmt.Sprintf("  Files: %d/%d │ Time: %s", m.filesDone, m.filesTotal, elapsed)) + m.stallBadge()
+
+	var progressPercent float64
+	if m.filesTotal > 0 {
+		progressPercent = float64(m.filesDone) / float64(m.filesTotal)
+	}
+	m.progress.Width = max(20, min(m.width-6, 60))
+	progressBar := m.progress.ViewAs(progressPercent)
+	transfer := m.transferLine()
+	if transfer != "" {
+		progressBar += "\n" + transfer
+	}
+
+	notice := ""
+	if m.IsCelebrating() {
+		notice = s.TextSuccess.Render(m.GetCelebration())
+	}
+
+	// Current file and one-line pipeline
+	fileLine := s.TextMuted.Render("Initializing CloudComPy...")
+	pipelineLine := ""
+	statsLine := ""
+	if m.currentFile != "" {
+		fileLine = s.StatusInfo.Render("📄 " + truncateLeft(m.currentFile, m.width-6))
+
+		var steps []string
+		for i, step := range m.pipelineSteps() {
+			stepNum := i + 1
+			name := shortStepName(step)
+			switch {
+			case stepNum < m.currentStepNum:
+				steps = append(steps, s.TextSuccess.Render(s.Icons.Success+" "+name))
+			case stepNum == m.currentStepNum:
+				steps = append(steps, lipgloss.NewStyle().Foreground(s.Colors.Primary).Bold(true).Render(m.GetStepSpinner()+" "+name))
+			default:
+				steps = append(steps, s.TextMuted.Render("○ "+name))
+			}
+		}
+		pipelineLine = strings.Join(steps, " ")
+		if m.currentStepNum > 0 {
+			pipelineLine += "  " + s.StatusInfo.Render(m.GetStepProgress())
+		}
+
+		var stats []string
+		if m.pointCount != "" {
+			stats = append(stats, m.pointCount)
+		}
+		if m.meshFaces != "" {
+			stats = append(stats, m.meshFaces)
+		}
+		statsLine = s.TextMuted.Render(truncate(strings.Join(stats, " · "), m.width-2))
+	}
+
+	footer := m.processingKeys()
+
+	// Everything except the log takes a fixed number of lines
+	const fixedLines = 9
+	maxLogLines := max(2, m.height-fixedLines)
+	if transfer != "" {
+		maxLogLines = max(2, maxLogLines-1)
+	}
+
+	startLog := max(0, len(m.logs)-maxLogLines)
+	var logLines []string
+	for i := startLog; i < len(m.logs); i++ {
+		log := m.logs[i]
+		logLines = append(logLines, s.RenderLogEntry(string(log.Level), truncate(log.Message, m.width-12)))
+	}
+	if len(logLines) == 0 {
+		logLines = append(logLines, s.TextMuted.Render(" Waiting for output..."))
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		header,
+		progressBar,
+		notice,
+		fileLine,
+		pipelineLine,
+		statsLine,
+		s.BoxTitle.Copy().MarginBottom(0).Render("📜 Log"),
+		strings.Join(logLines, "\n"),
+		footer,
+	)
+}
+
+// viewProcessingLog renders the processing screen as the script's output
+// filling the screen under a single status line
+func (m Model) viewProcessingLog() string {
+	s := m.styles
+
+	status := s.HeaderTitle.Render(fmt.Sprintf("%s Processing", m.GetStepSpinner())) +
+		s.Text.Render(fmt.Sprintf("  Files: %d/%d │ Time: %s", m.filesDone, m.filesTotal, humanize.Duration(m.elapsedTime)))
+	if m.currentFile != "" {
+		status += s.TextMuted.Render("  " + filepath.Base(m.currentFile))
+	}
+	status += m.stallBadge()
+
+	maxLogLines := max(2, m.height-3)
+	var logLines []string
+	for i := max(0, len(m.logs)-maxLogLines); i < len(m.logs); i++ {
+		log := m.logs[i]
+		logLines = append(logLines, s.RenderLogEntry(string(log.Level), truncate(log.Message, m.width-12)))
+	}
+	if len(logLines) == 0 {
+		logLines = append(logLines, s.TextMuted.Render(" Waiting for output..."))
+	}
+	for len(logLines) < maxLogLines {
+		logLines = append(logLines, "")
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		status,
+		strings.Join(logLines, "\n"),
+		m.processingKeys(),
+	)
+}
+
+// viewTooSmall replaces every screen when the terminal is below the minimum size
+func (m Model) viewTooSmall() string {
+	s := m.styles
+	return lipgloss.JoinVertical(lipgloss.Left,
+		s.StatusWarning.Render("Terminal too small"),
+		s.Text.Render(fmt.Sprintf("Need %dx%d, have %dx%d", minWidth, minHeight, m.width, m.height)),
+		s.TextMuted.Render("Resize the window to continue"),
+	)
+}
+
+// truncate shortens s to at most n cells, marking the cut with "..."; wide
+// characters, such as the icons scripts announce their steps with, take two
+func truncate(s string, n int) string {
+	if n <= 3 || lipgloss.Width(s) <= n {
+		return s
+	}
+	width := 0
+	for i, r := range s {
+		if width += lipgloss.Width(string(r)); width > n-3 {
+			return s[:i] + "..."
+		}
+	}
+	return s
+}
+
+// stallBadge flags a script the processor has warned has gone silent, as
+// the spinner and animations keep moving regardless
+func (m Model) stallBadge() string {
+	if m.stalledSince.IsZero() {
+		return ""
+	}
+	silent := humanize.Duration(time.Since(m.stalledSince))
+	return "  " + m.styles.StatusWarning.Render(fmt.Sprintf("⏸ Possibly stalled: no output for %s", silent))
+}
+
+// transferLine renders the prefetches in progress as a secondary bar, or
+// "" when there are none
+func (m Model) transferLine() string {
+	if len(m.transfers) == 0 {
+		return ""
+	}
+	var copied, size int64
+	for _, t := range m.transfers {
+		copied += t.Copied
+		size += t.Size
+	}
+	var percent float64
+	if size > 0 {
+		percent = float64(copied) / float64(size)
+	}
+	what := filepath.Base(m.transfers[0].File)
+	if len(m.transfers) > 1 {
+		what = fmt.Sprintf("%d files", len(m.transfers))
+	}
+
+	bar := m.progress
+	bar.Width = max(10, m.progress.Width/2)
+	label := fmt.Sprintf("⇣ Prefetching %s: %s of %s", what, humanize.Bytes(uint64(copied)), humanize.Bytes(uint64(size)))
+	return bar.ViewAs(percent) + "  " + m.styles.TextMuted.Render(truncate(label, max(10, m.width-bar.Width-4)))
+}
+
+// truncateLeft shortens s to at most n runes, keeping the end (for paths)
+func truncateLeft(s string, n int) string {
+	runes := []rune(s)
+	if n <= 3 || len(runes) <= n {
+		return s
+	}
+	return "..." + string(runes[len(runes)-n+3:])
+}
+
+// viewProcessingInline renders a compact status block for inline mode;
+// log lines are printed into the scrollback above it as they arrive
+func (m Model) viewProcessingInline() string {
+	s := m.styles
+
+	elapsed := humanize.Duration(m.elapsedTime)
+	status := s.Text.Render(fmt.Sprintf("%s Processing  Files: %d/%d  Time: %s",
+		m.spinner.View(), m.filesDone, m.filesTotal, elapsed)) + m.stallBadge()
+
+	var progressPercent float64
+	if m.filesTotal > 0 {
+		progressPercent = float64(m.filesDone) / float64(m.filesTotal)
+	}
+	m.progress.Width = max(20, min(m.width-6, 60))
+
+	current := s.TextMuted.Render("Initializing CloudComPy...")
+	if m.currentFile != "" {
+		current = s.StatusInfo.Render(m.currentFile)
+		if m.currentStep != "" {
+			current += s.TextMuted.Render("  " + m.currentStep)
+		}
+	}
+
+	bar := m.progress.ViewAs(progressPercent)
+	if transfer := m.transferLine(); transfer != "" {
+		bar += "\n" + transfer
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		status,
+		bar,
+		current,
+		s.TextMuted.Render(m.processingKeysPlain()),
+	)
+}
+
+// processingKeys renders the key hints for the processing screen
+func (m Model) processingKeys() string {
+	keys := m.styles.RenderKeyHelp("ctrl+c", "cancel") + "  "
+	if m.observer {
+		keys = m.styles.RenderKeyHelp("q", "stop watching") + "  "
+	}
+	if m.rawLog {
+		keys += m.styles.RenderKeyHelp("l", "dashboard")
+	} else {
+		keys += m.styles.RenderKeyHelp("l", "log")
+	}
+	if m.detachable() && !m.observer {
+		keys += "  " + m.styles.RenderKeyHelp("d", "detach")
+	}
+	return keys
+}
+
+// processingKeysPlain is processingKeys without styling, for inline mode
+func (m Model) processingKeysPlain() string {
+	if m.observer {
+		return "q stop watching"
+	}
+	if m.detachable() {
+		return "ctrl+c cancel  d detach"
+	}
+	return "ctrl+c cancel"
+}
+
 // viewResults renders the results screen
 func (m Model) viewResults() string {
 	s := m.styles
@@ -623,44 +1116,56 @@ func (m Model) viewResults() string {
 	}
 
 	// If we have successful files and no failures, it's a success
-	if successCount > 0 && failedCount == 0 {
-		statusIcon = "✅"
+	if m.result.Stopped {
+		statusIcon = "⏹"
+		statusText = "Stopped"
+		statusStyle = s.StatusWarning
+	} else if m.result.Aborted != "" {
+		statusIcon = s.Icons.Failed
+		statusText = "Aborted"
+		statusStyle = s.StatusError
+	} else if successCount > 0 && failedCount == 0 && m.result.WarningCount > 0 {
+		statusIcon = s.Icons.Warning
+		statusText = "Complete with warnings"
+		statusStyle = s.StatusWarning
+	} else if successCount > 0 && failedCount == 0 {
+		statusIcon = s.Icons.Complete
 		statusText = "Complete!"
 		statusStyle = s.StatusSuccess
 	} else if successCount == 0 && failedCount > 0 {
-		statusIcon = "❌"
+		statusIcon = s.Icons.Failed
 		statusText = "Failed"
 		statusStyle = s.StatusError
 	} else if successCount > 0 && failedCount > 0 {
-		statusIcon = "⚠️"
+		statusIcon = s.Icons.Partial
 		statusText = "Partial"
 		statusStyle = s.StatusWarning
 	} else {
 		// No data - check if any success messages in logs
 		hasSuccess := false
 		for _, log := range m.logs {
-			if log.Level == processor.LogSuccess && strings.Contains(log.Message, "Successfully processed") {
+			if log.Outcome == processor.OutcomeSuccess || log.Outcome == processor.OutcomeWarning {
 				hasSuccess = true
 				successCount = 1
 				break
 			}
 		}
 		if hasSuccess {
-			statusIcon = "✅"
+			statusIcon = s.Icons.Complete
 			statusText = "Complete!"
 			statusStyle = s.StatusSuccess
 		} else {
-			statusIcon = "❌"
+			statusIcon = s.Icons.Failed
 			statusText = "Failed"
 			statusStyle = s.StatusError
 		}
 	}
 
 	// Header
-	header := statusStyle.Copy().Bold(true).Render(statusIcon + " " + statusText)
+	header := statusStyle.Copy().Bold(true).Render(strings.TrimSpace(statusIcon + " " + statusText))
 
 	// Stats
-	elapsed := m.elapsedTime.Round(time.Millisecond * 100)
+	elapsed := humanize.Duration(m.elapsedTime)
 
 	// Ensure we show at least 1 for total if we have any data
 	if totalFiles == 0 && (successCount > 0 || failedCount > 0) {
@@ -670,12 +1175,35 @@ func (m Model) viewResults() string {
 		totalFiles = 1 // At least 1 file was attempted
 	}
 
-	stats := lipgloss.JoinVertical(lipgloss.Left,
+	// Files with warnings get their own line rather than counting as clean
+	warningCount := m.result.WarningCount
+	statLines := []string{
 		s.Text.Render(fmt.Sprintf("Total:      %d", totalFiles)),
-		s.TextSuccess.Render(fmt.Sprintf("Success:    %d", successCount)),
+		s.TextSuccess.Render(fmt.Sprintf("Success:    %d", successCount-warningCount)),
+	}
+	if warningCount > 0 {
+		statLines = append(statLines, s.StatusWarning.Render(fmt.Sprintf("Warnings:   %d", warningCount)))
+	}
+	if m.result.Skipped > 0 {
+		statLines = append(statLines, s.TextMuted.Render(fmt.Sprintf("Skipped:    %d (already processed)", m.result.Skipped)))
+	}
+	statLines = append(statLines,
 		s.TextError.Render(fmt.Sprintf("Failed:     %d", failedCount)),
+	)
+	if m.result.Aborted != "" {
+		left := totalFiles - len(m.result.Files)
+		statLines = append(statLines, s.TextError.Render(fmt.Sprintf("Aborted:    %s; %d left", m.result.Aborted, left)))
+	}
+	statLines = append(statLines,
 		s.TextMuted.Render(fmt.Sprintf("Time:       %s", elapsed)),
 	)
+	if cpu := m.result.CPUTime(); cpu > 0 {
+		statLines = append(statLines, s.TextMuted.Render(fmt.Sprintf("CPU time:   %s", humanize.Duration(cpu))))
+	}
+	if estimate := m.params.Rates.Estimate(m.result.CPUTime(), m.result.ProcessingTime()); estimate != nil {
+		statLines = append(statLines, s.TextMuted.Render(fmt.Sprintf("Estimate:   %s", processor.FormatEstimate(estimate))))
+	}
+	stats := lipgloss.JoinVertical(lipgloss.Left, statLines...)
 
 	// Output info
 	outputDir := m.params.InputDir
@@ -683,6 +1211,12 @@ func (m Model) viewResults() string {
 		outputDir, _ = os.Getwd()
 	}
 	outputPath := fmt.Sprintf("%s/%s", outputDir, m.params.OutputSubdir)
+	if m.result.OutputDir != "" {
+		outputPath = m.result.OutputDir
+	}
+	if len(m.params.Files) > 0 && m.params.OutputLayout != processor.LayoutMirror {
+		outputPath = m.params.OutputSubdir + " next to each listed file"
+	}
 
 	// Truncate path if needed
 	maxPathLen := m.width - 10
@@ -694,6 +1228,30 @@ func (m Model) viewResults() string {
 		s.TextMuted.Render("Output:"),
 		s.StatusInfo.Render("📂 "+outputPath),
 	)
+	if len(m.params.Labels) > 0 {
+		outputInfo = lipgloss.JoinVertical(lipgloss.Left, outputInfo, s.TextMuted.Render("🏷  "+strings.Join(m.params.Labels, ", ")))
+	}
+	if reportPath := m.result.ReportPath; reportPath != "" {
+		if len(reportPath) > maxPathLen && maxPathLen > 10 {
+			reportPath = "..." + reportPath[len(reportPath)-maxPathLen+3:]
+		}
+		outputInfo = lipgloss.JoinVertical(lipgloss.Left, outputInfo, s.TextMuted.Render("📄 "+reportPath))
+	}
+	if n := m.result.SnapshotCount(); n > 0 && m.result.ReportPath != "" {
+		html := filepath.Base(report.HTMLPath(m.result.ReportPath))
+		outputInfo = lipgloss.JoinVertical(lipgloss.Left, outputInfo, s.TextMuted.Render(fmt.Sprintf("🖼  %d snapshot(s) in %s", n, html)))
+	}
+	if n := m.result.ArchiveCount(); n > 0 {
+		outputInfo = lipgloss.JoinVertical(lipgloss.Left, outputInfo, s.TextMuted.Render(fmt.Sprintf("🗜  %d file(s) archived (%s)", n, m.params.Archive)))
+	}
+	// A batch shows the quality and review of the selected file under its
+	// table
+	if quality := m.viewQuality(); quality != "" && len(m.result.Files) == 1 {
+		outputInfo = lipgloss.JoinVertical(lipgloss.Left, outputInfo, quality)
+	}
+	if review := m.viewReview(); review != "" && len(m.result.Files) == 1 {
+		outputInfo = lipgloss.JoinVertical(lipgloss.Left, outputInfo, review)
+	}
 
 	// Recent logs (compact)
 	maxLogLines := m.height - 16
@@ -721,12 +1279,42 @@ func (m Model) viewResults() string {
 	}
 
 	// Footer
+	deliver := ""
+	if m.result.ReportPath != "" {
+		deliver = s.RenderKeyHelp("D", "deliver") + "  "
+		if m.delivered != nil {
+			deliver = s.RenderKeyHelp("D", "delivered") + "  "
+		}
+	}
 	footer := s.Footer.Render(
-		s.RenderKeyHelp("enter", "restart") + "  " +
+		m.resultsTableKeys() + deliver +
+			s.RenderKeyHelp("e", "edit & rerun") + "  " +
+			s.RenderKeyHelp("enter", "restart") + "  " +
 			s.RenderKeyHelp("q", "quit"),
 	)
+	if m.observer {
+		footer = s.Footer.Render(m.resultsTableKeys() + s.RenderKeyHelp("enter", "back") + "  " + s.RenderKeyHelp("q", "quit"))
+	}
+	if m.reviewTyping() {
+		footer = s.Footer.Render(strings.TrimSpace(m.resultsTableKeys()))
+	}
 
-	// Build view based on available space
+	// Build view based on available space; the files of a batch replace
+	// the log unless it is asked for
+	if m.height >= 18 && m.showFileTable() {
+		return lipgloss.JoinVertical(lipgloss.Left,
+			header,
+			"",
+			s.BoxTitle.Render("📊 Statistics"),
+			stats,
+			"",
+			outputInfo,
+			"",
+			m.viewFileTable(),
+			"",
+			footer,
+		)
+	}
 	if m.height >= 18 && len(logLines) > 0 {
 		return lipgloss.JoinVertical(lipgloss.Left,
 			header,
@@ -754,22 +1342,43 @@ func (m Model) viewResults() string {
 	}
 }
 
-// Helper function to count LAS files in a directory
-func countLASFiles(dir string) (int, error) {
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return 0, err
+// filePreview lists the first n file names, e.g. "a.las, b.las, +3 more"
+func filePreview(files []string, n int) string {
+	if len(files) == 0 {
+		return "no file matches the patterns"
 	}
-
-	count := 0
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		name := strings.ToLower(entry.Name())
-		if strings.HasSuffix(name, ".las") {
-			count++
+	var names []string
+	for i, file := range files {
+		if i == n {
+			names = append(names, fmt.Sprintf("+%d more", len(files)-n))
+			break
 		}
+		names = append(names, filepath.Base(file))
+	}
+	return strings.Join(names, ", ")
+}
+
+// backgroundSummary describes the priority and thread settings from config
+func backgroundSummary(params processor.Params) string {
+	var parts []string
+	if params.LowPriority {
+		parts = append(parts, "low priority")
+	}
+	if params.Deterministic {
+		parts = append(parts, "deterministic, 1 thread")
+	} else if params.Threads > 0 {
+		parts = append(parts, fmt.Sprintf("%d thread(s)", params.Threads))
+	}
+	if params.BoostSave {
+		parts = append(parts, "boosted save")
+	}
+	return "Background: " + strings.Join(parts, ", ")
+}
+
+// pipelineName returns the name of the selected pipeline
+func (m Model) pipelineName() string {
+	if m.pipelineIdx < len(m.pipelines) {
+		return m.pipelines[m.pipelineIdx].Name
 	}
-	return count, nil
+	return processor.DefaultPipeline
 }
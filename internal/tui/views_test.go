@@ -0,0 +1,367 @@
+package tui
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/exp/golden"
+	"github.com/charmbracelet/x/exp/teatest"
+	"github.com/muesli/termenv"
+
+	"github.com/cloudcompare-automation/internal/history"
+	"github.com/cloudcompare-automation/internal/processor"
+	"github.com/cloudcompare-automation/internal/profile"
+)
+
+// The screens are rendered by running the model in a test program, as the
+// terminal would, and driving it with window sizes, key presses and the
+// log and result of a run. The screen each test leaves it on is compared
+// with its snapshot in testdata/TestScreens. After a deliberate change to
+// a layout, write the snapshots again with
+//
+//	go test ./internal/tui -run TestScreens -update
+//
+// and review their diff.
+
+func TestMain(m *testing.M) {
+	// Snapshots are plain text, whatever the terminal running the tests
+	lipgloss.SetColorProfile(termenv.Ascii)
+	os.Exit(m.Run())
+}
+
+// surveyDir is the directory the form and the browser start in
+const surveyDir = "testdata/survey"
+
+// runTime is the time a run shown in a snapshot took
+const runTime = 4*time.Minute + 12*time.Second
+
+// fakeRun stands in for the background session of a run, with its log
+// and result to play
+type fakeRun struct {
+	log    chan processor.LogEntry
+	result chan processor.ProcessingResult
+}
+
+func (r *fakeRun) LogChan() <-chan processor.LogEntry            { return r.log }
+func (r *fakeRun) ResultChan() <-chan processor.ProcessingResult { return r.result }
+func (r *fakeRun) Stop()                                         {}
+
+// logLines makes the entries the processor sends for lines of the
+// script's output about file index, e.g. "[SUCCESS] Normals computed"
+func logLines(index int, file string, lines ...string) []processor.LogEntry {
+	entries := make([]processor.LogEntry, len(lines))
+	for i, line := range lines {
+		level, message, _ := strings.Cut(strings.TrimPrefix(line, "["), "] ")
+		entries[i] = processor.LogEntry{Level: processor.LogLevel(level), Message: message, FileIndex: index, File: file}
+	}
+	return entries
+}
+
+// harbourLog is the log of the harbour survey up to the reconstruction of
+// its second file
+var harbourLog = append(append(logLines(1, "north.las",
+	"[INFO] Processing: north.las",
+	"[INFO] Steps: Load LAS file; short=Load; icon=📂 | Compute normals; short=Normals; icon=🧭 | Convert normals; short=DIP; icon=🔄 | Poisson reconstruction; short=Poisson; icon=🔺 | Save project; short=Save; icon=💾",
+	"[INFO] [1/5] Loading LAS file...",
+	"[SUCCESS] Loaded 12,482,113 points",
+	"[INFO] [2/5] Computing normals (knn=6)...",
+	"[SUCCESS] Normals computed",
+	"[INFO] [3/5] Converting normals to DIP/Dip Direction...",
+	"[SUCCESS] DIP scalar fields created",
+	"[INFO] [4/5] Poisson Reconstruction (depth=11)...",
+	"[SUCCESS] Mesh created with 2,104,388 faces",
+	"[INFO] [5/5] Saving project file...",
+	"[SUCCESS] Saved: north.bin (1.9 GB in 41.3 s, 46.0 MB/s)",
+	"[SUCCESS] Successfully processed: north.las",
+), processor.LogEntry{Level: processor.LogSuccess, Message: "Finished: north.las (success)", FileIndex: 1, File: "north.las", Outcome: processor.OutcomeSuccess}),
+	logLines(2, "south.las",
+		"[INFO] Processing: south.las",
+		"[INFO] Steps: Load LAS file; short=Load; icon=📂 | Compute normals; short=Normals; icon=🧭 | Convert normals; short=DIP; icon=🔄 | Poisson reconstruction; short=Poisson; icon=🔺 | Save project; short=Save; icon=💾",
+		"[INFO] [1/5] Loading LAS file...",
+		"[SUCCESS] Loaded 3,200,000 points",
+		"[INFO] [2/5] Computing normals (knn=6)...",
+		"[SUCCESS] Normals computed",
+		"[INFO] [3/5] Converting normals to DIP/Dip Direction...",
+		"[SUCCESS] DIP scalar fields created",
+		"[INFO] [4/5] Poisson Reconstruction (depth=11)...",
+		"[INFO] [Progress 40%] Solving the Poisson equation",
+	)...)
+
+// harbourResult is the result of the harbour survey
+var harbourResult = processor.ProcessingResult{
+	TotalFiles:   2,
+	SuccessCount: 2,
+	WarningCount: 1,
+	OutputDir:    "testdata/survey/harbour/Processed",
+	Files: []processor.FileResult{
+		{
+			InputFile:  "testdata/survey/harbour/north.las",
+			OutputFile: "testdata/survey/harbour/Processed/north.bin",
+			Success:    true,
+			Duration:   2*time.Minute + 31*time.Second,
+			Points:     12482113,
+			Faces:      2104388,
+		},
+		{
+			InputFile:  "testdata/survey/harbour/south.las",
+			OutputFile: "testdata/survey/harbour/Processed/south.bin",
+			Success:    true,
+			Warnings:   []string{"Density trim removed 60% of the mesh"},
+			Duration:   time.Minute + 38*time.Second,
+			Points:     3200000,
+			Faces:      1902118,
+		},
+	},
+}
+
+// harbourRuns are the runs in the history
+var harbourRuns = []history.Entry{
+	{
+		Pipeline:   processor.DefaultPipeline,
+		Input:      "testdata/survey/harbour",
+		OutputDir:  "testdata/survey/harbour/Processed",
+		Params:     map[string]string{"octree-depth": "11"},
+		Labels:     []string{"delivery-v1"},
+		StartedAt:  time.Date(2026, 3, 2, 9, 15, 0, 0, time.UTC),
+		FinishedAt: time.Date(2026, 3, 2, 9, 19, 12, 0, time.UTC),
+		Total:      2,
+		Succeeded:  1,
+		Warned:     1,
+	},
+	{
+		Pipeline:   processor.DefaultPipeline,
+		Input:      "testdata/survey/quarry",
+		OutputDir:  "testdata/survey/quarry/Processed",
+		Params:     map[string]string{"octree-depth": "12"},
+		StartedAt:  time.Date(2026, 3, 4, 14, 40, 0, 0, time.UTC),
+		FinishedAt: time.Date(2026, 3, 4, 14, 52, 30, 0, time.UTC),
+		Total:      1,
+		Failed:     1,
+	},
+}
+
+// recordRuns records harbourRuns and waits for the Welcome screen to
+// list them
+func recordRuns(t *testing.T, tm *teatest.TestModel) {
+	t.Helper()
+	for _, run := range harbourRuns {
+		if _, err := history.Record(run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tm.Send(loadRecent())
+	waitFor(t, tm, "Recent runs")
+}
+
+// running puts the model on the processing screen of the harbour survey,
+// with harbourLog waiting to be polled. done also closes the log and
+// returns the result to send once it was.
+func running(m Model, done bool) Model {
+	run := &fakeRun{log: make(chan processor.LogEntry, len(harbourLog)), result: make(chan processor.ProcessingResult, 1)}
+	for _, entry := range harbourLog {
+		run.log <- entry
+	}
+	if done {
+		close(run.log)
+	}
+	m.params.InputDir = "testdata/survey/harbour"
+	m.source = run
+	m.filesTotal = 2
+	return m.resetProcessing(time.Now())
+}
+
+// inHarbour selects the harbour survey, whose files the Configuration
+// screen counts before it is compared
+func inHarbour(m Model) Model {
+	m.currentDir, m.selectedDir = "testdata/survey/harbour", "testdata/survey/harbour"
+	return m
+}
+
+// startModel runs the model, on the welcome screen in surveyDir unless
+// setup changes it, in a test program of width x height, with a user
+// state of its own
+func startModel(t *testing.T, width, height int, setup func(Model) Model) *teatest.TestModel {
+	t.Helper()
+	home := t.TempDir()
+	for _, env := range []string{"HOME", "XDG_CONFIG_HOME", "XDG_CACHE_HOME", "APPDATA", "LOCALAPPDATA"} {
+		t.Setenv(env, home)
+	}
+	t.Setenv(profile.EnvVar, "")
+
+	m := New(Options{Params: processor.DefaultParams(), Version: "1.0.0"})
+	m.currentDir, m.selectedDir = surveyDir, surveyDir
+	if setup != nil {
+		m = setup(m)
+	}
+	return teatest.NewTestModel(t, m, teatest.WithInitialTermSize(width, height))
+}
+
+// waitFor waits until the program renders text
+func waitFor(t *testing.T, tm *teatest.TestModel, text string) {
+	t.Helper()
+	teatest.WaitFor(t, tm.Output(), func(out []byte) bool {
+		return bytes.Contains(out, []byte(text))
+	}, teatest.WithDuration(10*time.Second), teatest.WithCheckInterval(10*time.Millisecond))
+}
+
+// press sends the keys, e.g. "enter", "ctrl+b" or "l", one at a time
+func press(tm *teatest.TestModel, keys ...string) {
+	for _, key := range keys {
+		switch key {
+		case "enter":
+			tm.Send(tea.KeyMsg{Type: tea.KeyEnter})
+		case "tab":
+			tm.Send(tea.KeyMsg{Type: tea.KeyTab})
+		case "down":
+			tm.Send(tea.KeyMsg{Type: tea.KeyDown})
+		case "ctrl+b":
+			tm.Send(tea.KeyMsg{Type: tea.KeyCtrlB})
+		default:
+			tm.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		}
+	}
+}
+
+// snapshot quits the program and compares the screen it was left on with
+// the test's snapshot
+func snapshot(t *testing.T, tm *teatest.TestModel) {
+	t.Helper()
+	if err := tm.Quit(); err != nil {
+		t.Fatal(err)
+	}
+	m := tm.FinalModel(t, teatest.WithFinalTimeout(10*time.Second)).(Model)
+	// The time a run took depends on how fast the test ran
+	if !m.startTime.IsZero() {
+		m.elapsedTime = runTime
+	}
+	golden.RequireEqual(t, []byte(m.View()))
+}
+
+func TestScreens(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		setup         func(Model) Model
+		drive         func(t *testing.T, tm *teatest.TestModel)
+	}{
+		{name: "welcome", width: 100, height: 32},
+		{name: "welcome_small", width: 60, height: 16},
+		{name: "too_small", width: 50, height: 12},
+		{
+			name: "params", width: 100, height: 32, setup: inHarbour,
+			drive: func(t *testing.T, tm *teatest.TestModel) {
+				press(tm, "enter")
+				waitFor(t, tm, "LAS file(s) found")
+			},
+		},
+		{
+			name: "params_scrolled", width: 100, height: 32, setup: inHarbour,
+			drive: func(t *testing.T, tm *teatest.TestModel) {
+				press(tm, "enter", "tab", "tab", "tab", "tab", "tab", "tab", "tab")
+				waitFor(t, tm, "LAS file(s) found")
+			},
+		},
+		{
+			name: "file_browser", width: 100, height: 32,
+			drive: func(t *testing.T, tm *teatest.TestModel) {
+				press(tm, "enter", "ctrl+b")
+				waitFor(t, tm, "quarry")
+				press(tm, "down")
+			},
+		},
+		{
+			name: "processing", width: 100, height: 40,
+			setup: func(m Model) Model { return running(m, false) },
+			drive: func(t *testing.T, tm *teatest.TestModel) {
+				tm.Send(PollLogsMsg{})
+				waitFor(t, tm, "Solving the Poisson equation")
+			},
+		},
+		{
+			name: "processing_compact", width: 80, height: 24,
+			setup: func(m Model) Model { return running(m, false) },
+			drive: func(t *testing.T, tm *teatest.TestModel) {
+				tm.Send(PollLogsMsg{})
+				waitFor(t, tm, "Solving the Poisson equation")
+			},
+		},
+		{
+			name: "processing_log", width: 100, height: 40,
+			setup: func(m Model) Model { return running(m, false) },
+			drive: func(t *testing.T, tm *teatest.TestModel) {
+				tm.Send(PollLogsMsg{})
+				waitFor(t, tm, "Solving the Poisson equation")
+				press(tm, "l")
+			},
+		},
+		{
+			name: "results", width: 100, height: 32,
+			setup: func(m Model) Model { return running(m, true) },
+			drive: func(t *testing.T, tm *teatest.TestModel) {
+				tm.Send(PollLogsMsg{})
+				tm.Send(ProcessingDoneMsg(harbourResult))
+				waitFor(t, tm, "Complete with warnings")
+			},
+		},
+		{
+			name: "results_resized", width: 100, height: 32,
+			setup: func(m Model) Model { return running(m, true) },
+			drive: func(t *testing.T, tm *teatest.TestModel) {
+				tm.Send(PollLogsMsg{})
+				tm.Send(ProcessingDoneMsg(harbourResult))
+				waitFor(t, tm, "Complete with warnings")
+				tm.Send(tea.WindowSizeMsg{Width: 72, Height: 17})
+			},
+		},
+		{
+			name: "history", width: 100, height: 32,
+			drive: func(t *testing.T, tm *teatest.TestModel) {
+				for _, run := range harbourRuns {
+					if _, err := history.Record(run); err != nil {
+						t.Fatal(err)
+					}
+				}
+				press(tm, "h")
+				waitFor(t, tm, "quarry")
+				press(tm, "down")
+			},
+		},
+		{
+			name: "welcome_recent", width: 100, height: 32,
+			drive: func(t *testing.T, tm *teatest.TestModel) {
+				recordRuns(t, tm)
+				press(tm, "down")
+			},
+		},
+		{
+			name: "welcome_relaunch", width: 80, height: 24,
+			drive: func(t *testing.T, tm *teatest.TestModel) {
+				recordRuns(t, tm)
+				press(tm, "l")
+				waitFor(t, tm, "enter or l to start")
+			},
+		},
+		{
+			name: "recent_results", width: 100, height: 32,
+			drive: func(t *testing.T, tm *teatest.TestModel) {
+				recordRuns(t, tm)
+				press(tm, "down", "o")
+				waitFor(t, tm, "Complete with warnings")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm := startModel(t, tt.width, tt.height, tt.setup)
+			if tt.drive != nil {
+				tt.drive(t, tm)
+			}
+			snapshot(t, tm)
+		})
+	}
+}
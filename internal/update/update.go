@@ -0,0 +1,172 @@
+package update
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ReleasesURL is the GitHub API endpoint for the latest published release
+const ReleasesURL = "https://api.github.com/repos/newmedia-centre/cloudcompare-automation/releases/latest"
+
+// Asset is a downloadable file attached to a release
+type Asset struct {
+	Name        string `json:"name"`
+	DownloadURL string `json:"browser_download_url"`
+	Size        int64  `json:"size"`
+}
+
+// Release describes a published release of the tool
+type Release struct {
+	TagName string  `json:"tag_name"`
+	URL     string  `json:"html_url"`
+	Assets  []Asset `json:"assets"`
+}
+
+// Version returns the release version without the leading "v"
+func (r *Release) Version() string {
+	return strings.TrimPrefix(r.TagName, "v")
+}
+
+// AssetFor returns the release archive built for the given platform
+func (r *Release) AssetFor(goos, goarch string) (Asset, bool) {
+	suffix := fmt.Sprintf("_%s_%s.", goos, goarch)
+	for _, asset := range r.Assets {
+		if strings.HasPrefix(asset.Name, "cloudcompare-tui_") && strings.Contains(asset.Name, suffix) {
+			return asset, true
+		}
+	}
+	return Asset{}, false
+}
+
+// Latest queries the release endpoint for the newest published release
+func Latest(ctx context.Context) (*Release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ReleasesURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query releases: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("release endpoint returned %s", resp.Status)
+	}
+
+	var release Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("invalid release response: %v", err)
+	}
+	if release.TagName == "" {
+		return nil, fmt.Errorf("release response has no tag")
+	}
+	return &release, nil
+}
+
+// Download saves a release asset into dir and returns the written path
+func Download(ctx context.Context, asset Asset, dir string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, asset.DownloadURL, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %v", asset.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download of %s returned %s", asset.Name, resp.Status)
+	}
+
+	path := filepath.Join(dir, asset.Name)
+	out, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		out.Close()
+		os.Remove(path)
+		return "", fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	if err := out.Close(); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// IsNewer reports whether latest is a higher version than current.
+// Development builds ("dev" or empty) never compare as outdated.
+func IsNewer(latest, current string) bool {
+	l, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	c, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return false
+}
+
+// parseVersion splits a "major.minor.patch" string, ignoring any pre-release suffix
+func parseVersion(v string) ([3]int, bool) {
+	var parts [3]int
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	fields := strings.Split(v, ".")
+	if len(fields) == 0 || len(fields) > 3 {
+		return parts, false
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+var scriptVersionRegex = regexp.MustCompile(`^__version__\s*=\s*["']([^"']+)["']`)
+
+// ScriptVersion reads the __version__ declared in a pipeline script
+func ScriptVersion(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if matches := scriptVersionRegex.FindStringSubmatch(scanner.Text()); matches != nil {
+			return matches[1], nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no __version__ found in %s", filepath.Base(path))
+}
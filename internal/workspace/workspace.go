@@ -0,0 +1,230 @@
+package workspace
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudcompare-automation/internal/lock"
+)
+
+// The workspace is a directory managed by the tool where each batch gets
+// a run directory, and each file processed in it gets its own working
+// directory. CloudComPy and PoissonRecon drop temporary files in the
+// current directory, so separate directories keep concurrent runs apart
+// and make any stray files easy to attribute. A batch locks its run
+// directory while it runs, so cleaning up leaves it alone.
+
+// Root returns the workspace directory
+func Root() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "cloudcompare-automation", "workspace"), nil
+}
+
+// stagesDir holds the stage directories in the workspace
+const stagesDir = "stages"
+
+// Run is the workspace directory of one batch
+type Run struct {
+	Dir  string
+	lock *lock.Lock
+}
+
+// NewRun creates a run directory in the workspace
+func NewRun() (*Run, error) {
+	root, err := Root()
+	if err != nil {
+		return nil, err
+	}
+	// The pid keeps runs started in the same second apart
+	id := fmt.Sprintf("%s-%d", time.Now().Format("20060102-150405"), os.Getpid())
+	dir := filepath.Join(root, id)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %v", err)
+	}
+	l, err := lock.Acquire(dir)
+	if err != nil {
+		os.Remove(dir)
+		return nil, fmt.Errorf("failed to lock workspace: %v", err)
+	}
+	return &Run{Dir: dir, lock: l}, nil
+}
+
+// FileDir creates the working directory for a LAS file in this run,
+// keyed by its absolute path, as listed files of the same name may come
+// from different directories
+func (r *Run) FileDir(file string) (string, error) {
+	name, err := fileKey(file)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(r.Dir, name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create working directory: %v", err)
+	}
+	return dir, nil
+}
+
+// StageDir returns the stage cache directory of a LAS file, where the
+// script keeps intermediate results that later runs can start from. Each
+// file has one directory per key, the hash of whatever the intermediate
+// results depend on; the directories outlive the run directories.
+func StageDir(file, key string) (string, error) {
+	dir, err := stageFileDir(file)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, key), nil
+}
+
+// stageFileDir holds the stage directories of a LAS file, keyed by its
+// absolute path
+func stageFileDir(file string) (string, error) {
+	root, err := Root()
+	if err != nil {
+		return "", err
+	}
+	name, err := fileKey(file)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, stagesDir, name), nil
+}
+
+// fileKey names a directory for a LAS file: its name, for people looking
+// around, and a short hash of its absolute path to keep files of the same
+// name apart
+func fileKey(file string) (string, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(abs))
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	return name + "-" + hex.EncodeToString(sum[:6]), nil
+}
+
+// PruneStages removes all but the keep most recently used stage
+// directories of a LAS file, as each holds a full copy of the cloud
+func PruneStages(file string, keep int) error {
+	dir, err := stageFileDir(file)
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	type stage struct {
+		path string
+		used time.Time
+	}
+	var stages []stage
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		stages = append(stages, stage{filepath.Join(dir, entry.Name()), info.ModTime()})
+	}
+	sort.Slice(stages, func(i, j int) bool { return stages[i].used.After(stages[j].used) })
+	for i := keep; i < len(stages); i++ {
+		if err := os.RemoveAll(stages[i].path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TouchStage marks a stage directory as used, so pruning keeps it
+func TouchStage(dir string) {
+	now := time.Now()
+	os.Chtimes(dir, now, now)
+}
+
+// Collect returns the files left in a working directory, relative to it.
+// An empty directory is removed.
+func Collect(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		os.RemoveAll(dir)
+	}
+	return files, nil
+}
+
+// Entry is a directory in the workspace: the run directory of a batch,
+// or the stage directories of a LAS file
+type Entry struct {
+	Path     string
+	Stages   bool
+	Modified time.Time
+	Active   bool // A run directory of a batch still running
+}
+
+// List returns the run directories and the stage directories of each LAS
+// file in the workspace
+func List() ([]Entry, error) {
+	root, err := Root()
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for _, stages := range []bool{false, true} {
+		dir := root
+		if stages {
+			dir = filepath.Join(root, stagesDir)
+		}
+		dirs, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, d := range dirs {
+			info, err := d.Info()
+			if err != nil || !d.IsDir() || (!stages && d.Name() == stagesDir) {
+				continue
+			}
+			path := filepath.Join(dir, d.Name())
+			entry := Entry{Path: path, Stages: stages, Modified: info.ModTime()}
+			if !stages {
+				_, entry.Active = lock.Active(path)
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// Close removes the run directory if nothing was left in it
+func (r *Run) Close() {
+	r.lock.Release()
+	// Remove fails on a non-empty directory, which is what we want
+	os.Remove(r.Dir)
+}
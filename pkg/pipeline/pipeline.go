@@ -0,0 +1,637 @@
+// Package pipeline runs CloudComPy processing from other Go programs,
+// without the terminal UI.
+//
+// A Runner processes submitted jobs one after another, each a batch of LAS
+// files turned into CloudCompare projects by process_las_files.py or
+// another pipeline script. Progress is reported as events and every job
+// ends with one result:
+//
+//	r := pipeline.New(pipeline.Options{ScriptDir: `C:\tools\cloudcompare-automation`})
+//	defer r.Close()
+//	id, err := r.Submit(pipeline.Job{InputDir: `D:\Surveys\harbour`, Values: map[string]string{"octree-depth": "12"}})
+//	...
+//	for {
+//		select {
+//		case e := <-r.Events():
+//			log.Printf("%s [%s] %s", e.JobID, e.Level, e.Message)
+//		case res := <-r.Results():
+//			log.Printf("%s: %d of %d succeeded", res.JobID, res.Succeeded, res.Total)
+//		}
+//	}
+//
+// Both channels must be received from while jobs run: an event that can't
+// be delivered holds up the job, and the processor drops log lines it
+// can't pass on. A program that would rather not can register an Observer,
+// whose callbacks are called as files start, steps start, files end and
+// jobs end; with one, events and results nobody receives are not waited
+// for.
+package pipeline
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cloudcompare-automation/internal/processor"
+	"github.com/cloudcompare-automation/internal/report"
+)
+
+// Processor is what embedding programs drive; Runner implements it
+type Processor interface {
+	// Submit queues a job and returns its ID
+	Submit(job Job) (string, error)
+	// Cancel stops a running job, or drops a queued one
+	Cancel(id string) error
+	// Events delivers the log of the running job
+	Events() <-chan Event
+	// Results delivers the outcome of every submitted job
+	Results() <-chan Result
+	// Observe registers callbacks for the progress of the jobs started
+	// from then on
+	Observe(o Observer)
+}
+
+// Job is a batch of LAS files to process
+type Job struct {
+	InputDir     string   // Directory of the LAS files
+	Files        []string // Process these files instead of those of InputDir
+	Include      []string // File name patterns to process, e.g. tile_??.las (default: all)
+	Exclude      []string // File name patterns to skip, e.g. *_preview.las
+	OutputSubdir string   // Subdirectory next to the files for the outputs (default: Processed)
+
+	// Script is a pipeline script to run instead of process_las_files.py
+	Script string
+	// Values are the pipeline parameters by name, e.g. "octree-depth";
+	// those left out take the default from the pipeline's schema
+	Values map[string]string
+
+	Env           map[string]string // Extra environment for the script
+	Threads       int               // Cap on CloudComPy's worker threads (0 = no cap)
+	LowPriority   bool              // Run at low CPU and I/O priority
+	Deterministic bool              // Run single-threaded for reproducible outputs
+	SkipExisting  bool              // Leave out files whose project already exists
+	Metadata      map[string]string // Run metadata for the report and projects
+	Labels        []string          // Run labels for the history and report
+	StallAfter    time.Duration     // Warn when the script prints nothing for this long (0 = 10 minutes)
+
+	// AbortAfter ends the job after this many files failed in a row, and
+	// AbortRate once more than this percentage of its files failed
+	// (0 = never); see Result.Aborted
+	AbortAfter int
+	AbortRate  float64
+
+	// CrashRecovery waits CrashCooldown (0 = 30 seconds) after the script
+	// crashed, then checks the Python environment before the next file
+	CrashRecovery bool
+	CrashCooldown time.Duration
+
+	// MinFaces, MaxDeviation and MinDensity are the acceptance rules of
+	// each file's outputs: the fewest faces of its mesh, the distance 95%
+	// of its points must be within of the mesh, and the fewest points per
+	// square unit of the mesh's footprint (0 = any). A file breaking one
+	// completes with a warning, or fails with RejectFailed.
+	MinFaces     int
+	MaxDeviation float64
+	MinDensity   float64
+	RejectFailed bool
+
+	// MemoryLimit caps the memory of each file's script process in bytes,
+	// failing the file when it runs over (0 = no cap)
+	MemoryLimit uint64
+
+	// Prefetch copies this many of the next files into the workspace
+	// while the current one is processing, for inputs on slow network
+	// storage (0 = none)
+	Prefetch int
+
+	// Archive packs the outputs of every processed file: gzip or zip
+	// (default: none)
+	Archive string
+
+	// OutputLayout places the outputs in OutputSubdir: flat, per-file,
+	// dated or mirror (default: flat)
+	OutputLayout string
+
+	// MetadataCSV and MetadataCommand give a table of metadata by file
+	// name pattern, in a CSV file or printed as CSV by a command. The
+	// first row whose pattern column matches a file's name fills in its
+	// metadata over Metadata, e.g. its site, client or survey date.
+	MetadataCSV     string
+	MetadataCommand string
+}
+
+// Level is the severity of an event
+type Level string
+
+const (
+	LevelInfo    Level = Level(processor.LogInfo)
+	LevelSuccess Level = Level(processor.LogSuccess)
+	LevelWarning Level = Level(processor.LogWarning)
+	LevelError   Level = Level(processor.LogError)
+)
+
+// Event is a log line of a job
+type Event struct {
+	JobID   string
+	Time    time.Time
+	Level   Level
+	Message string
+
+	Batch     string // ID of the processing batch, see Result.BatchID
+	FileIndex int    // 1-based position of File in the batch, 0 if about the whole batch
+	File      string // Input file the event is about
+
+	// Outcome is set on the one event that ends File: success, warning or
+	// failed
+	Outcome string
+	// Silent is set on the warnings that the script has printed nothing
+	// for this long, see Job.StallAfter
+	Silent time.Duration
+	// Transfer is set on the events reporting the copy of File, see
+	// Job.Prefetch
+	Transfer *Transfer
+}
+
+// Transfer is the progress of a file being prefetched
+type Transfer struct {
+	Copied int64
+	Size   int64
+	Done   bool // Copied or failed; the last event about the copy
+}
+
+// Result is the outcome of a job
+type Result struct {
+	JobID      string
+	BatchID    string // Batch on the job's events; empty if it didn't start
+	Total      int    // Files processed or attempted
+	Succeeded  int    // Including those with warnings
+	Warned     int
+	Failed     int
+	Skipped    int    // Left out as already processed, see Job.SkipExisting
+	Cancelled  bool   // Cancelled before all files were done
+	Aborted    string // Why too many failures ended the job early, see Job.AbortAfter
+	OutputDir  string
+	ReportPath string
+	Files      []FileResult
+	Err        error // Why the job couldn't run at all
+
+	// DroppedLogs counts the job's log lines lost because events weren't
+	// received quickly enough
+	DroppedLogs int
+}
+
+// FileResult is the outcome of one file of a job
+type FileResult struct {
+	Input    string
+	Output   string
+	Success  bool
+	Error    string
+	Warnings []string
+	Duration time.Duration
+	Points   int64             // 0 if not reported
+	Archive  string            // Archive the outputs were packed into, see Job.Archive
+	Metadata map[string]string // Looked up for the file, see Job.MetadataCSV
+	Quality  *MeshQuality      // Measured by the script, nil if not reported
+}
+
+// MeshQuality is what the script measured of a file's mesh
+type MeshQuality struct {
+	Watertight          bool
+	BoundaryEdges       int // Edges of a single triangle
+	NonManifoldEdges    int // Edges of more than two triangles
+	DegenerateTriangles int
+	BBoxMin             [3]float64
+	BBoxMax             [3]float64
+	Deviation           *Deviation // Measured when Job.MaxDeviation is set
+}
+
+// Deviation is how far the points of the cloud are from its mesh
+type Deviation struct {
+	Mean float64
+	P95  float64 // 95% of the points are closer than this
+}
+
+// Step is a step of a file's processing that has started
+type Step struct {
+	File    string
+	Number  int // Counted from 1
+	Total   int
+	Post    bool   // A post-processing step; these follow the script's
+	Message string // The log line announcing it, e.g. "[2/5] Computing normals..."
+}
+
+// Observer is called as jobs progress, in addition to the events and
+// results sent on the channels. Each callback is optional. They are called
+// one at a time from the runner's goroutines, so they should return
+// quickly; they must not call Close.
+type Observer struct {
+	OnFileStart func(jobID string, index, total int, file string) // index counts from 1
+	OnStep      func(jobID string, step Step)
+	OnFileDone  func(jobID string, file FileResult) // Not called for a file interrupted by Cancel
+	OnJobDone   func(result Result)
+}
+
+// set reports whether any callback is set
+func (o Observer) set() bool {
+	return o.OnFileStart != nil || o.OnStep != nil || o.OnFileDone != nil || o.OnJobDone != nil
+}
+
+// Options configure a Runner
+type Options struct {
+	// ScriptDir is the directory of process_las_files.py; when empty it is
+	// searched for around the working directory and the executable
+	ScriptDir string
+
+	// On Windows, the conda environment and CloudComPy installation the
+	// scripts run with; by default the CloudComPy311 environment and
+	// C:\bin\CloudComPy311, as set up by setup_cloudcompy.bat
+	CondaEnv    string
+	CondaPrefix string // Environment directory, if it can't be found from CondaEnv
+	CloudComPy  string
+}
+
+// Runner processes jobs one at a time in the background
+type Runner struct {
+	opts    Options
+	events  chan Event
+	results chan Result
+
+	mu      sync.Mutex
+	nextID  int
+	queue   []queued
+	current *queued
+	closed  bool
+	wake    chan struct{}
+	stop    chan struct{} // Closed by Close, so pending sends give up
+	done    chan struct{}
+
+	observer  Observer   // Guarded by mu
+	observeMu sync.Mutex // Calls the observer one at a time
+}
+
+// queued is a submitted job with its processor parameters
+type queued struct {
+	id     string
+	params processor.Params
+	proc   *processor.Processor
+}
+
+var _ Processor = (*Runner)(nil)
+
+// New starts a Runner; Close stops it
+func New(opts Options) *Runner {
+	r := &Runner{
+		opts:    opts,
+		events:  make(chan Event, 100),
+		results: make(chan Result, 10),
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go r.loop()
+	return r
+}
+
+// Submit checks the job's parameters and queues it
+func (r *Runner) Submit(job Job) (string, error) {
+	params, err := r.params(job)
+	if err != nil {
+		return "", err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return "", fmt.Errorf("runner is closed")
+	}
+	r.nextID++
+	id := fmt.Sprintf("job-%d", r.nextID)
+	r.queue = append(r.queue, queued{id: id, params: params})
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+	return id, nil
+}
+
+// Cancel stops the job with id if it is running, delivering its partial
+// result, or drops it from the queue with a cancelled result
+func (r *Runner) Cancel(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.current != nil && r.current.id == id {
+		r.current.proc.Stop()
+		return nil
+	}
+	for i, job := range r.queue {
+		if job.id == id {
+			r.queue = append(r.queue[:i], r.queue[i+1:]...)
+			go r.deliver(Result{JobID: id, Cancelled: true})
+			return nil
+		}
+	}
+	return fmt.Errorf("no queued or running job %s", id)
+}
+
+// Events delivers the log lines of the running job
+func (r *Runner) Events() <-chan Event {
+	return r.events
+}
+
+// Results delivers the outcome of every job, in the order they finish
+func (r *Runner) Results() <-chan Result {
+	return r.results
+}
+
+// Close cancels the running job, drops the queued ones and waits for the
+// runner to stop. Results of dropped jobs are not delivered.
+func (r *Runner) Close() {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.closed = true
+	r.queue = nil
+	if r.current != nil {
+		r.current.proc.Stop()
+	}
+	r.mu.Unlock()
+	close(r.stop)
+	close(r.wake)
+	<-r.done
+}
+
+// Observe sets the observer of the jobs started from then on; with one
+// set, events and results nobody receives are dropped rather than waited
+// for
+func (r *Runner) Observe(o Observer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.observer = o
+}
+
+// observing returns the observer in effect
+func (r *Runner) observing() Observer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.observer
+}
+
+// notify calls an observer callback, one at a time
+func (r *Runner) notify(call func()) {
+	r.observeMu.Lock()
+	defer r.observeMu.Unlock()
+	call()
+}
+
+// deliver sends a result unless the runner is closed, and passes it to the
+// observer
+func (r *Runner) deliver(result Result) {
+	o := r.observing()
+	if o.OnJobDone != nil {
+		r.notify(func() { o.OnJobDone(result) })
+	}
+	if o.set() {
+		select {
+		case r.results <- result:
+		default:
+		}
+		return
+	}
+	select {
+	case r.results <- result:
+	case <-r.stop:
+	}
+}
+
+// observe passes the progress of job's processor to the observer
+func (r *Runner) observe(job queued, o Observer) {
+	var po processor.Observer
+	if o.OnFileStart != nil {
+		po.OnFileStart = func(index, total int, file string) {
+			r.notify(func() { o.OnFileStart(job.id, index, total, file) })
+		}
+	}
+	if o.OnStep != nil {
+		po.OnStep = func(s processor.Step) {
+			step := Step{File: s.File, Number: s.Number, Total: s.Total, Post: s.Post, Message: s.Message}
+			r.notify(func() { o.OnStep(job.id, step) })
+		}
+	}
+	if o.OnFileDone != nil {
+		po.OnFileDone = func(f processor.FileResult) {
+			r.notify(func() { o.OnFileDone(job.id, newFileResult(f)) })
+		}
+	}
+	job.proc.Observe(po)
+}
+
+// params turns a job into processor parameters, filling in and checking
+// the pipeline parameters against the script's schema
+func (r *Runner) params(job Job) (processor.Params, error) {
+	params := processor.DefaultParams()
+	params.ScriptDir = r.opts.ScriptDir
+	params.Python = processor.PythonEnv{CondaEnv: r.opts.CondaEnv, CondaPrefix: r.opts.CondaPrefix, CloudComPy: r.opts.CloudComPy}
+	params.InputDir = job.InputDir
+	if params.InputDir == "" && len(job.Files) == 0 {
+		return params, fmt.Errorf("job has neither an input directory nor files")
+	}
+	params.Files = job.Files
+	params.Include = job.Include
+	params.Exclude = job.Exclude
+	for _, patterns := range [][]string{job.Include, job.Exclude} {
+		if err := processor.CheckPatterns(patterns); err != nil {
+			return params, err
+		}
+	}
+	if job.OutputSubdir != "" {
+		params.OutputSubdir = job.OutputSubdir
+	}
+
+	schema := processor.DefaultSchema
+	if job.Script != "" {
+		params.Script = job.Script
+		var err error
+		if schema, err = processor.LoadSchema(job.Script); err != nil {
+			return params, err
+		}
+	}
+	params.Values = processor.Defaults(schema)
+	for name, value := range job.Values {
+		found := false
+		for _, spec := range schema {
+			if spec.Name != name {
+				continue
+			}
+			v, err := spec.Validate(value)
+			if err != nil {
+				return params, fmt.Errorf("%s: %v", name, err)
+			}
+			params.Values[name] = v
+			found = true
+		}
+		if !found {
+			return params, fmt.Errorf("unknown pipeline parameter: %s", name)
+		}
+	}
+
+	params.Env = job.Env
+	params.Threads = job.Threads
+	params.LowPriority = job.LowPriority
+	params.Deterministic = job.Deterministic
+	params.SkipExisting = job.SkipExisting
+	params.Metadata = job.Metadata
+	params.Labels = job.Labels
+	params.StallAfter = job.StallAfter
+	params.Abort = processor.AbortPolicy{After: job.AbortAfter, Rate: job.AbortRate}
+	if err := processor.CheckAbort(params.Abort); err != nil {
+		return params, err
+	}
+	params.Acceptance = processor.Acceptance{MinFaces: job.MinFaces, MaxDeviation: job.MaxDeviation, MinDensity: job.MinDensity, Fail: job.RejectFailed}
+	if err := processor.CheckAcceptance(params.Acceptance); err != nil {
+		return params, err
+	}
+	params.CrashRecovery = job.CrashRecovery
+	params.CrashCooldown = job.CrashCooldown
+	params.MemoryLimit = job.MemoryLimit
+	params.Prefetch = job.Prefetch
+	if job.Archive != "" {
+		if err := processor.CheckArchive(job.Archive); err != nil {
+			return params, err
+		}
+		params.Archive = job.Archive
+	}
+	if job.OutputLayout != "" {
+		if err := processor.CheckOutputLayout(job.OutputLayout); err != nil {
+			return params, err
+		}
+		params.OutputLayout = job.OutputLayout
+	}
+	params.MetadataLookup = processor.MetadataLookup{CSV: job.MetadataCSV, Command: job.MetadataCommand}
+	if err := processor.CheckMetadataLookup(params.MetadataLookup); err != nil {
+		return params, err
+	}
+	return params, nil
+}
+
+// loop runs the queued jobs until the runner is closed
+func (r *Runner) loop() {
+	defer close(r.done)
+	for range r.wake {
+		for {
+			r.mu.Lock()
+			if r.closed || len(r.queue) == 0 {
+				r.mu.Unlock()
+				break
+			}
+			job := r.queue[0]
+			r.queue = r.queue[1:]
+			job.proc = processor.New(job.params)
+			r.current = &job
+			r.mu.Unlock()
+
+			result := r.run(job)
+
+			r.mu.Lock()
+			r.current = nil
+			r.mu.Unlock()
+			r.deliver(result)
+		}
+	}
+}
+
+// run processes one job, forwarding its log as events
+func (r *Runner) run(job queued) Result {
+	if err := job.proc.ValidateInputDir(); err != nil {
+		return Result{JobID: job.id, Err: err}
+	}
+	o := r.observing()
+	r.observe(job, o)
+	if err := job.proc.Start(); err != nil {
+		return Result{JobID: job.id, Err: err}
+	}
+
+	send := func(entry processor.LogEntry) {
+		event := Event{JobID: job.id, Time: time.Now(), Level: Level(entry.Level), Message: entry.Message,
+			Batch: entry.Batch, FileIndex: entry.FileIndex, File: entry.File, Outcome: string(entry.Outcome), Silent: entry.Silent}
+		if t := entry.Transfer; t != nil {
+			event.Transfer = &Transfer{Copied: t.Copied, Size: t.Size, Done: t.Done}
+		}
+		if o.set() {
+			select {
+			case r.events <- event:
+			default:
+			}
+			return
+		}
+		select {
+		case r.events <- event:
+		case <-r.stop:
+		}
+	}
+	// The log channel is closed after the last entry, just before the
+	// result
+	for entry := range job.proc.LogChan() {
+		send(entry)
+	}
+	return newResult(job.id, <-job.proc.ResultChan())
+}
+
+// newResult converts a processor result
+func newResult(id string, result processor.ProcessingResult) Result {
+	res := Result{
+		JobID:      id,
+		BatchID:    result.BatchID,
+		Total:      result.TotalFiles,
+		Succeeded:  result.SuccessCount,
+		Warned:     result.WarningCount,
+		Failed:     result.FailedCount,
+		Skipped:    result.Skipped,
+		Cancelled:  result.Stopped,
+		Aborted:    result.Aborted,
+		OutputDir:  result.OutputDir,
+		ReportPath: result.ReportPath,
+
+		DroppedLogs: result.DroppedLogs,
+	}
+	for _, f := range result.Files {
+		res.Files = append(res.Files, newFileResult(f))
+	}
+	return res
+}
+
+// newFileResult converts a processor file result
+func newFileResult(f processor.FileResult) FileResult {
+	return FileResult{
+		Input:    f.InputFile,
+		Output:   f.OutputFile,
+		Success:  f.Success,
+		Error:    f.Error,
+		Warnings: f.Warnings,
+		Duration: f.Duration,
+		Points:   f.Points,
+		Archive:  f.Archive,
+		Metadata: f.Metadata,
+		Quality:  newMeshQuality(f.Quality),
+	}
+}
+
+// newMeshQuality converts a processor mesh quality
+func newMeshQuality(q *report.MeshQuality) *MeshQuality {
+	if q == nil {
+		return nil
+	}
+	quality := &MeshQuality{
+		Watertight:          q.Watertight,
+		BoundaryEdges:       q.BoundaryEdges,
+		NonManifoldEdges:    q.NonManifoldEdges,
+		DegenerateTriangles: q.DegenerateTriangles,
+		BBoxMin:             q.BBoxMin,
+		BBoxMax:             q.BBoxMax,
+	}
+	if q.Deviation != nil {
+		quality.Deviation = &Deviation{Mean: q.Deviation.Mean, P95: q.Deviation.P95}
+	}
+	return quality
+}
@@ -7,9 +7,13 @@ This script processes LAS point cloud files through CloudComPy:
 1. Load LAS file
 2. Compute normals using triangulation model with MST orientation
 3. Convert normals to DIP/Dip Direction scalar fields
-4. Run Poisson Surface Reconstruction with density scalar field
+4. Run Poisson Surface Reconstruction with density scalar field,
+   optionally trimming low-density triangles
 5. Save both cloud and mesh to a single .bin file
 
+With --stage-dir, the cloud with normals and DIP fields is kept after step 3,
+and --from-stage poisson starts from it at step 4 to re-tune the mesh.
+
 Prerequisites:
 - CloudComPy (Python bindings for CloudCompare)
   https://github.com/CloudCompare/CloudComPy
@@ -18,11 +22,32 @@ Author: Sharif Bayoumy
 License: Apache-2.0
 """
 
+__version__ = "0.2.0"
+
+# Pipeline stages that can be skipped to with --from-stage
+STAGES = ["all", "poisson"]
+
+# Processing steps, announced per file so the caller can show them: the
+# name, a short name for narrow terminals and an icon
+STEPS = [
+    ("Loading point cloud", "Load", "\U0001F4C2"),
+    ("Computing normals", "Normals", "\U0001F9ED"),
+    ("Converting to DIP", "DIP", "\U0001F504"),
+    ("Poisson reconstruction", "Poisson", "\U0001F53A"),
+    ("Saving project", "Save", "\U0001F4BE"),
+]
+
+# Cloud with normals and DIP fields kept in the stage directory
+STAGE_CLOUD = "normals.bin"
+
 import argparse
+import os
+import random
 import sys
+import time
 from dataclasses import dataclass
 from pathlib import Path
-from typing import Optional
+from typing import Dict, List, Optional
 
 
 @dataclass
@@ -41,6 +66,7 @@ class PoissonParams:
     samples_per_node: float = 1.5
     point_weight: float = 2.0
     boundary_type: int = 2  # 0=FREE, 1=DIRICHLET, 2=NEUMANN
+    density_trim: float = 0.0  # Density percentile to trim below (0 = off)
 
 
 class CloudComPyProcessor:
@@ -57,10 +83,24 @@ class CloudComPyProcessor:
         normal_params: Optional[NormalParams] = None,
         poisson_params: Optional[PoissonParams] = None,
         verbose: bool = True,
+        metadata: Optional[Dict[str, str]] = None,
+        stage_dir: Optional[Path] = None,
+        from_stage: str = "all",
+        mesh_format: str = "none",
+        snapshots: str = "none",
+        sync_save: bool = False,
+        deviation: bool = False,
     ):
         self.verbose = verbose
         self.normal_params = normal_params or NormalParams()
         self.poisson_params = poisson_params or PoissonParams()
+        self.metadata = metadata or {}
+        self.stage_dir = stage_dir
+        self.from_stage = from_stage
+        self.mesh_format = mesh_format
+        self.snapshots = snapshots
+        self.sync_save = sync_save
+        self.deviation = deviation
 
         # Initialize CloudComPy
         self._init_cloudcompy()
@@ -69,10 +109,21 @@ class CloudComPyProcessor:
         if self.verbose:
             print(f"[{level}] {message}", flush=True)
 
-    def _log_step(self, step: int, total: int, message: str):
-        """Log a processing step with flush for real-time output."""
+    def _log_step(self, step: int, message: str):
+        """Log the start of one of STEPS with flush for real-time output."""
         if self.verbose:
-            print(f"[INFO] [{step}/{total}] {message}", flush=True)
+            print(f"[INFO] [{step}/{len(STEPS)}] {message}", flush=True)
+
+    def _step_labels(self) -> List[str]:
+        """Return STEPS as announced: with short names, and icons where the output is UTF-8."""
+        utf8 = (sys.stdout.encoding or "").lower().replace("-", "") == "utf8"
+        labels = []
+        for name, short, icon in STEPS:
+            label = f"{name}; short={short}"
+            if utf8:
+                label += f"; icon={icon}"
+            labels.append(label)
+        return labels
 
     def _init_cloudcompy(self):
         """Initialize CloudComPy and check for PoissonRecon plugin."""
@@ -100,24 +151,65 @@ class CloudComPyProcessor:
         self.PoissonRecon = cloudComPy.PoissonRecon
         self._log("PoissonRecon plugin loaded")
 
-    def process_file(self, input_file: Path, output_file: Path) -> bool:
-        """Process a single LAS file through the complete pipeline."""
-        cc = self.cc
+    def process_file(
+        self, input_file: Path, output_file: Path, save_as: Optional[Path] = None
+    ) -> bool:
+        """Process a single LAS file through the complete pipeline.
 
+        The project is written to save_as when given, for the caller to
+        move to output_file once it has checked it.
+        """
         self._log("=" * 70)
         self._log(f"Processing: {input_file.name}")
         self._log(f"Output: {output_file}")
+        self._log("Steps: " + " | ".join(self._step_labels()))
+
+        if self.from_stage == "poisson":
+            cloud = self._load_stage_cloud()
+        else:
+            cloud = self._compute_normals(input_file)
+        if cloud is None:
+            return False
+
+        return self._reconstruct(cloud, input_file, output_file, save_as or output_file)
+
+    def _load_stage_cloud(self):
+        """Steps 1-3 from the stage directory: the cloud with normals and DIP."""
+        cc = self.cc
+        path = self.stage_dir / STAGE_CLOUD
+        self._log_step(1, "Loading cached cloud with normals...")
+        cloud = cc.loadPointCloud(str(path))
+        if cloud is None:
+            self._log(f"Failed to load stage cloud: {path}", "ERROR")
+            return None
+        self._log(f"Loaded {cloud.size():,} points", "SUCCESS")
+        self._log_step(2, "Normals reused from the previous run")
+        self._log_step(3, "DIP scalar fields reused from the previous run")
+        return cloud
+
+    def _save_stage_cloud(self, cloud):
+        """Keep the cloud with normals and DIP for --from-stage poisson."""
+        if self.stage_dir is None:
+            return
+        self.stage_dir.mkdir(parents=True, exist_ok=True)
+        path = self.stage_dir / STAGE_CLOUD
+        if self.cc.SaveEntities([cloud], str(path)) != 0:
+            self._log(f"Failed to save stage cloud: {path}", "WARNING")
+
+    def _compute_normals(self, input_file: Path):
+        """Steps 1-3: load the LAS file, compute normals and DIP fields."""
+        cc = self.cc
 
         # Step 1: Load point cloud
-        self._log_step(1, 5, "Loading point cloud...")
+        self._log_step(1, "Loading point cloud...")
         cloud = cc.loadPointCloud(str(input_file))
         if cloud is None:
             self._log(f"Failed to load: {input_file}", "ERROR")
-            return False
+            return None
         self._log(f"Loaded {cloud.size():,} points", "SUCCESS")
 
         # Step 2: Compute normals
-        self._log_step(2, 5, "Computing normals (this may take a few minutes)...")
+        self._log_step(2, "Computing normals (this may take a few minutes)...")
         success = cc.computeNormals(
             [cloud],
             model=cc.LOCAL_MODEL_TYPES.TRI,  # Triangulation
@@ -131,20 +223,27 @@ class CloudComPyProcessor:
         )
         if not success:
             self._log("Failed to compute normals", "ERROR")
-            return False
+            return None
         self._log("Normals computed", "SUCCESS")
 
         # Step 3: Convert normals to DIP/Dip Direction
-        self._log_step(3, 5, "Converting normals to DIP/Dip Direction...")
+        self._log_step(3, "Converting normals to DIP/Dip Direction...")
         success = cloud.convertNormalToDipDirSFs()
         if not success:
             self._log("Failed to convert normals to DIP", "ERROR")
-            return False
+            return None
         self._log("DIP scalar fields created", "SUCCESS")
 
+        self._save_stage_cloud(cloud)
+        return cloud
+
+    def _reconstruct(self, cloud, input_file: Path, output_file: Path, save_path: Path) -> bool:
+        """Steps 4-5: Poisson reconstruction and saving the project."""
+        cc = self.cc
+
         # Step 4: Poisson Surface Reconstruction
         depth = self.poisson_params.octree_depth
-        self._log_step(4, 5, f"Poisson Reconstruction (depth={depth})...")
+        self._log_step(4, f"Poisson Reconstruction (depth={depth})...")
         self._log(
             f"This step can take 5-30+ minutes depending on point count and depth"
         )
@@ -187,24 +286,199 @@ class CloudComPyProcessor:
         else:
             self._log("Source cloud has no colors (skipping transfer)")
 
+        # Step 4c: Trim the low-density surface Poisson closes over sparse areas
+        if self.poisson_params.density_trim > 0:
+            mesh = self._trim_by_density(mesh, self.poisson_params.density_trim)
+        self._mesh_quality(mesh, cloud if self.deviation else None)
+
         # Step 5: Save both cloud and mesh to single .bin file
-        self._log_step(5, 5, "Saving project file...")
+        self._log_step(5, "Saving project file...")
+        save_started = time.monotonic()
 
         # Ensure output directory exists
-        output_file.parent.mkdir(parents=True, exist_ok=True)
+        save_path.parent.mkdir(parents=True, exist_ok=True)
+
+        self._apply_metadata([cloud, mesh])
 
-        ret = cc.SaveEntities([cloud, mesh], str(output_file))
+        ret = cc.SaveEntities([cloud, mesh], str(save_path))
         if ret != 0:
-            self._log(f"Failed to save: {output_file}", "ERROR")
+            self._log(f"Failed to save: {save_path}", "ERROR")
             return False
-        self._log(f"Saved: {output_file.name}", "SUCCESS")
+        if self.sync_save:
+            self._sync(save_path)
+        self._log(f"Saved: {output_file.name} ({self._throughput(save_path, save_started)})", "SUCCESS")
+        if self.mesh_format != "none" or self.snapshots != "none":
+            self._export_mesh(mesh, save_path.with_suffix(".ply"))
         self._log(f"Successfully processed: {input_file.name}", "SUCCESS")
         return True
 
+    def _sync(self, path: Path):
+        """Flush a saved file to disk, so the write is done when the step is."""
+        try:
+            fd = os.open(str(path), os.O_RDONLY)
+            try:
+                os.fsync(fd)
+            finally:
+                os.close(fd)
+        except OSError as e:
+            self._log(f"Failed to flush {path.name}: {e}", "WARNING")
+
+    @staticmethod
+    def _throughput(path: Path, started: float) -> str:
+        """Describe how fast a file was written, e.g. 2.1 GB in 40.2 s, 53.2 MB/s."""
+        seconds = max(time.monotonic() - started, 1e-3)
+        try:
+            size = path.stat().st_size
+        except OSError:
+            return f"{seconds:.1f} s"
+        amount = f"{size / 1e9:.1f} GB" if size >= 1e9 else f"{size / 1e6:.1f} MB"
+        return f"{amount} in {seconds:.1f} s, {size / 1e6 / seconds:.1f} MB/s"
+
+    def _export_mesh(self, mesh, path: Path):
+        """Export the mesh alone as PLY next to the project.
+
+        glTF formats are converted from the PLY by the caller, which also
+        renders the snapshots from it.
+        """
+        ret = self.cc.SaveMesh(mesh, str(path))
+        if ret != 0:
+            self._log(f"Failed to export mesh: {path.name}", "WARNING")
+            return
+        self._log(f"Mesh exported: {path.name}")
+
+    def _apply_metadata(self, entities):
+        """Record the run metadata on the saved entities."""
+        if not self.metadata:
+            return
+        try:
+            for entity in entities:
+                for key, value in self.metadata.items():
+                    entity.setMetaData(key, value)
+        except Exception as e:
+            self._log(f"Could not record metadata in the project: {e}", "WARNING")
+            return
+        self._log(f"Metadata recorded: {', '.join(sorted(self.metadata))}")
+
+    def _mesh_quality(self, mesh, cloud=None):
+        """Log the quality of the final mesh, for acceptance checks.
+
+        The line is parsed by the runner: the mesh is watertight when no
+        edge bounds a single triangle, an edge shared by more than two is
+        non-manifold, and a triangle of (nearly) no area is degenerate.
+        Given the cloud, the distances of its points to the mesh are
+        measured too, which takes about as long as computing normals.
+        """
+        try:
+            import numpy as np
+
+            triangles = np.asarray(mesh.IndexesToNpArray(), dtype=np.int64)
+            points = np.asarray(mesh.getAssociatedCloud().toNpArray(), dtype=np.float64)
+            if len(triangles) == 0 or len(points) == 0:
+                return
+
+            edges = np.concatenate(
+                [triangles[:, [0, 1]], triangles[:, [1, 2]], triangles[:, [2, 0]]]
+            )
+            edges.sort(axis=1)
+            _, counts = np.unique(edges[:, 0] * len(points) + edges[:, 1], return_counts=True)
+            boundary = int(np.count_nonzero(counts == 1))
+            non_manifold = int(np.count_nonzero(counts > 2))
+
+            low, high = points.min(axis=0), points.max(axis=0)
+            a, b, c = (points[triangles[:, i]] for i in range(3))
+            area = np.linalg.norm(np.cross(b - a, c - a), axis=1)
+            eps = (float(np.linalg.norm(high - low)) * 1e-9) ** 2
+            degenerate = int(np.count_nonzero(area <= eps))
+        except Exception as e:
+            self._log(f"Could not measure mesh quality: {e}")
+            return
+
+        watertight = "yes" if boundary == 0 and non_manifold == 0 else "no"
+        line = (
+            f"Mesh quality: watertight={watertight} boundary_edges={boundary} "
+            f"non_manifold_edges={non_manifold} degenerate_triangles={degenerate} "
+            f"bbox_min={','.join(f'{v:.3f}' for v in low)} "
+            f"bbox_max={','.join(f'{v:.3f}' for v in high)}"
+        )
+        if cloud is not None:
+            distances = self._cloud_to_mesh(cloud, mesh)
+            if distances is not None:
+                line += (
+                    f" c2m_mean={float(np.mean(distances)):.4f}"
+                    f" c2m_p95={float(np.percentile(distances, 95)):.4f}"
+                )
+        self._log(line)
+
+    def _cloud_to_mesh(self, cloud, mesh):
+        """Return the distances of the cloud's points to the mesh, or None.
+
+        The scalar field CloudCompare adds for them is removed again, so
+        the saved cloud is the same with and without the measure.
+        """
+        cc = self.cc
+        self._log("Measuring cloud to mesh distances...")
+        try:
+            import numpy as np
+
+            params = cc.Cloud2MeshDistancesComputationParams()
+            params.octreeLevel = cc.DistanceComputationTools.determineBestOctreeLevel(cloud, mesh)
+            cc.DistanceComputationTools.computeCloud2MeshDistances(cloud, mesh, params)
+            index = cloud.getScalarFieldIndexByName("C2M absolute distances")
+            if index < 0:
+                self._log("Cloud to mesh distances were not computed", "WARNING")
+                return None
+            distances = np.abs(np.asarray(cloud.getScalarField(index).toNpArray(), dtype=np.float64))
+            cloud.deleteScalarField(index)
+        except Exception as e:
+            self._log(f"Could not measure cloud to mesh distances: {e}", "WARNING")
+            return None
+        return distances
+
+    def _trim_by_density(self, mesh, percentile: float):
+        """Remove triangles whose density is below the given percentile.
+
+        Returns the trimmed mesh, or the original mesh if trimming fails.
+        """
+        cc = self.cc
+        try:
+            import numpy as np
+
+            mesh_cloud = mesh.getAssociatedCloud()
+            sf_index = mesh_cloud.getScalarFieldIndexByName("Density")
+            if sf_index < 0:
+                self._log("Mesh has no density field (skipping trim)", "WARNING")
+                return mesh
+            sf = mesh_cloud.getScalarField(sf_index)
+            threshold = float(np.percentile(sf.toNpArray(), percentile))
+
+            mesh_cloud.setCurrentOutScalarField(sf_index)
+            trimmed = cc.filterBySFValue(threshold, sf.getMax(), mesh)
+            if trimmed is None or trimmed.size() == 0:
+                self._log("Density trim removed the whole mesh (keeping it untrimmed)", "WARNING")
+                return mesh
+        except Exception as e:
+            self._log(f"Density trim failed (keeping mesh untrimmed): {e}", "WARNING")
+            return mesh
+
+        self._log(
+            f"Mesh trimmed to {trimmed.size():,} faces "
+            f"(density >= {threshold:.3f}, {percentile:g}th percentile)",
+            "SUCCESS",
+        )
+        removed = 100.0 * (1 - trimmed.size() / max(mesh.size(), 1))
+        if removed > 50:
+            self._log(f"Density trim removed {removed:.0f}% of the mesh", "WARNING")
+        return trimmed
+
     def process_directory(
-        self, input_dir: Path, output_subdir: str = "Processed"
+        self,
+        input_dir: Path,
+        output_subdir: str = "Processed",
+        files: Optional[List[Path]] = None,
+        output_name: Optional[str] = None,
+        save_as: Optional[Path] = None,
     ) -> dict:
-        """Process all LAS files in a directory."""
+        """Process all LAS files in a directory, or only the given files."""
         input_dir = Path(input_dir).resolve()
         output_dir = input_dir / output_subdir
 
@@ -214,9 +488,12 @@ class CloudComPyProcessor:
         self._log(f"Input directory:  {input_dir}")
         self._log(f"Output directory: {output_dir}")
 
-        # Find all LAS files
-        las_files = list(input_dir.glob("*.las")) + list(input_dir.glob("*.LAS"))
-        las_files = list(set(las_files))
+        # Find all LAS files (unless an explicit file list was given)
+        if files is not None:
+            las_files = [Path(f).resolve() for f in files]
+        else:
+            las_files = list(input_dir.glob("*.las")) + list(input_dir.glob("*.LAS"))
+            las_files = list(set(las_files))
         las_files.sort()
 
         if not las_files:
@@ -231,9 +508,9 @@ class CloudComPyProcessor:
 
         for i, las_file in enumerate(las_files, 1):
             self._log(f"\nFile {i}/{len(las_files)}")
-            output_file = output_dir / f"{las_file.stem}.bin"
+            output_file = output_dir / f"{output_name or las_file.stem}.bin"
 
-            if self.process_file(las_file, output_file):
+            if self.process_file(las_file, output_file, save_as):
                 success_count += 1
             else:
                 failed_count += 1
@@ -256,6 +533,17 @@ class CloudComPyProcessor:
         }
 
 
+def seed_random(seed: int):
+    """Seed Python's and NumPy's random numbers, so any sampling repeats."""
+    random.seed(seed)
+    try:
+        import numpy as np
+
+        np.random.seed(seed)
+    except ImportError:
+        pass
+
+
 def main():
     """Main entry point."""
     parser = argparse.ArgumentParser(
@@ -288,7 +576,7 @@ Output:
         type=str,
         nargs="?",
         default=".",
-        help="Directory containing LAS files (default: current directory)",
+        help="Directory containing LAS files, or a single LAS file (default: current directory)",
     )
 
     parser.add_argument(
@@ -336,6 +624,88 @@ Output:
         help="Boundary type: 0=Free, 1=Dirichlet, 2=Neumann (default: 2)",
     )
 
+    parser.add_argument(
+        "--density-trim",
+        type=float,
+        default=0.0,
+        help="Trim mesh triangles below this density percentile, 0-99 (default: 0 = off)",
+    )
+
+    parser.add_argument(
+        "--mesh-format",
+        choices=["none", "ply", "glb", "gltf"],
+        default="none",
+        help="Also export the mesh as PLY next to the project; glb and gltf are "
+        "converted from it by the TUI and worker (default: none)",
+    )
+
+    parser.add_argument(
+        "--snapshots",
+        choices=["none", "views", "turntable"],
+        default="none",
+        help="Also export the mesh as PLY for the TUI and worker to render top and "
+        "isometric snapshots from; turntable adds a rotating GIF (default: none)",
+    )
+
+    parser.add_argument(
+        "--output-name",
+        type=str,
+        help="Output file name without extension (single input file only)",
+    )
+
+    parser.add_argument(
+        "--save-as",
+        type=str,
+        help="Save the project to this temporary path instead, for the caller to "
+        "move into place (single input file only)",
+    )
+
+    parser.add_argument(
+        "--meta",
+        action="append",
+        default=[],
+        metavar="KEY=VALUE",
+        help="Metadata recorded in the saved project, e.g. project=Harbour (repeatable)",
+    )
+
+    parser.add_argument(
+        "--stage-dir",
+        type=str,
+        help="Directory to keep the cloud with normals in, for --from-stage (single input file only)",
+    )
+
+    parser.add_argument(
+        "--from-stage",
+        choices=STAGES,
+        default="all",
+        help="Start at this stage: all, or poisson to reuse the normals in --stage-dir (default: all)",
+    )
+
+    parser.add_argument(
+        "--sync-save",
+        action="store_true",
+        help="Flush the project to disk before reporting it saved, so the write is not left to the cache",
+    )
+
+    parser.add_argument(
+        "--deviation",
+        action="store_true",
+        help="Also measure the distances of the cloud's points to the mesh for the quality line",
+    )
+
+    parser.add_argument(
+        "--seed",
+        type=int,
+        default=0,
+        help="Seed for random numbers, recorded in the report for reproducing a run (default: 0)",
+    )
+
+    parser.add_argument(
+        "--version",
+        action="version",
+        version=f"%(prog)s {__version__}",
+    )
+
     parser.add_argument(
         "--quiet",
         action="store_true",
@@ -343,6 +713,17 @@ Output:
     )
 
     args = parser.parse_args()
+    if not 0 <= args.density_trim <= 99:
+        parser.error("--density-trim must be between 0 and 99")
+    if args.from_stage != "all" and not args.stage_dir:
+        parser.error("--from-stage needs --stage-dir")
+    seed_random(args.seed)
+    metadata = {}
+    for entry in args.meta:
+        key, sep, value = entry.partition("=")
+        if not sep or not key:
+            parser.error(f"--meta expects KEY=VALUE, got {entry!r}")
+        metadata[key] = value
 
     # Create parameter objects
     normal_params = NormalParams(knn=args.knn)
@@ -352,6 +733,7 @@ Output:
         samples_per_node=args.samples_per_node,
         point_weight=args.point_weight,
         boundary_type=args.boundary_type,
+        density_trim=args.density_trim,
     )
 
     try:
@@ -359,9 +741,29 @@ Output:
             normal_params=normal_params,
             poisson_params=poisson_params,
             verbose=not args.quiet,
+            metadata=metadata,
+            stage_dir=Path(args.stage_dir) if args.stage_dir else None,
+            from_stage=args.from_stage,
+            mesh_format=args.mesh_format,
+            snapshots=args.snapshots,
+            sync_save=args.sync_save,
+            deviation=args.deviation,
         )
 
-        result = processor.process_directory(Path(args.input_dir), args.output_dir)
+        input_path = Path(args.input_dir)
+        if input_path.is_file():
+            # Single file: output goes next to it, as for a directory run
+            result = processor.process_directory(
+                input_path.parent,
+                args.output_dir,
+                files=[input_path],
+                output_name=args.output_name,
+                save_as=Path(args.save_as) if args.save_as else None,
+            )
+        else:
+            if args.output_name or args.stage_dir or args.save_as:
+                parser.error("--output-name, --stage-dir and --save-as need a single input file")
+            result = processor.process_directory(input_path, args.output_dir)
         sys.exit(result["failed"])
 
     except RuntimeError as e:
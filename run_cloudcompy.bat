@@ -2,7 +2,8 @@
 REM Wrapper script to run process_las_files.py with CloudComPy environment
 REM Usage: run_cloudcompy.bat [arguments for process_las_files.py]
 
-setlocal EnableDelayedExpansion
+REM No delayed expansion: it would strip ! from the arguments passed on in %*
+setlocal
 
 REM Set CloudComPy installation path
 set CLOUDCOMPY_PATH=C:\bin\CloudComPy311
@@ -11,6 +12,9 @@ REM Store the current directory BEFORE any changes
 set "ORIGINAL_DIR=%cd%"
 set "PYTHON_SCRIPT=%~dp0process_las_files.py"
 
+REM Set CLOUDCOMPY_SCRIPT to run another pipeline script
+if defined CLOUDCOMPY_SCRIPT set "PYTHON_SCRIPT=%CLOUDCOMPY_SCRIPT%"
+
 REM Check if conda is available
 where conda >nul 2>&1
 if %ERRORLEVEL% neq 0 (
@@ -0,0 +1,11 @@
+// Package cloudcompare embeds the files shipped next to the binary that
+// the Go code also needs at build time.
+package cloudcompare
+
+import _ "embed"
+
+// ParamsSchema is process_las_files.params.yaml, the parameter schema of
+// the bundled script
+//
+//go:embed process_las_files.params.yaml
+var ParamsSchema []byte